@@ -10,6 +10,7 @@ import (
 
 	"github.com/timlinux/macaco/internal/game"
 	"github.com/timlinux/macaco/internal/stats"
+	"github.com/timlinux/macaco/internal/vim"
 )
 
 // Client is an HTTP client for the MoCaCo API
@@ -47,6 +48,7 @@ func (c *Client) HealthCheck() error {
 type SessionResponse struct {
 	SessionID        string                 `json:"session_id"`
 	RoundType        string                 `json:"round_type"`
+	RoundCode        string                 `json:"round_code,omitempty"`
 	TotalTasks       int                    `json:"total_tasks"`
 	CurrentTaskIndex int                    `json:"current_task_index"`
 	StartedAt        time.Time              `json:"started_at"`
@@ -54,11 +56,67 @@ type SessionResponse struct {
 	BufferState      string                 `json:"buffer_state"`
 	CursorPosition   int                    `json:"cursor_position"`
 	ElapsedTimeMs    int64                  `json:"elapsed_time_ms"`
+	RoundComplete    bool                   `json:"round_complete"`
+	TaskTimeLeftMs   *int64                 `json:"task_time_remaining_ms,omitempty"`  // Missing if tasks aren't timed
+	RoundTimeLeftMs  *int64                 `json:"round_time_remaining_ms,omitempty"` // Missing if the round isn't timed
+	OvertimeMs       int64                  `json:"overtime_ms,omitempty"`
+	Drill            *game.Drill            `json:"drill,omitempty"`  // Progress of a drill
+	Lesson           *game.Lesson           `json:"lesson,omitempty"` // Unit a lesson practises
 }
 
 // CreateSession creates a new game session
 func (c *Client) CreateSession(roundType string) (*SessionResponse, error) {
-	body := map[string]string{"round_type": roundType}
+	return c.createSession(map[string]interface{}{"round_type": roundType})
+}
+
+// CreateSessionWithText creates a session generating tasks from prose or
+// code, whatever the round's own kind of text
+func (c *Client) CreateSessionWithText(roundType, text string) (*SessionResponse, error) {
+	return c.createSession(map[string]interface{}{"round_type": roundType, "text": text})
+}
+
+// CreateSessionFromCode creates a session that replays a round code
+func (c *Client) CreateSessionFromCode(code string) (*SessionResponse, error) {
+	return c.createSession(map[string]interface{}{"round_code": code})
+}
+
+// CreateDrill creates a drill of the tasks a spec picks, which runs until
+// they're mastered
+func (c *Client) CreateDrill(spec game.DrillSpec) (*SessionResponse, error) {
+	return c.createSession(map[string]interface{}{"round_type": game.RoundDrill, "drill": spec})
+}
+
+// CreateLesson creates a session of a unit's practice tasks. Locked units
+// fail until the one before them is passed.
+func (c *Client) CreateLesson(unitID string) (*SessionResponse, error) {
+	return c.createSession(map[string]interface{}{"round_type": game.RoundLesson, "unit": unitID})
+}
+
+// GetLessons retrieves the units of the curriculum with the player's
+// progress on them
+func (c *Client) GetLessons() ([]game.UnitStatus, error) {
+	resp, err := c.httpClient.Get(c.baseURL + "/lessons")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.parseError(resp)
+	}
+
+	var result struct {
+		Units []game.UnitStatus `json:"units"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return result.Units, nil
+}
+
+// createSession posts a session request
+func (c *Client) createSession(body map[string]interface{}) (*SessionResponse, error) {
 	jsonBody, _ := json.Marshal(body)
 
 	resp, err := c.httpClient.Post(c.baseURL+"/sessions", "application/json", bytes.NewReader(jsonBody))
@@ -117,18 +175,22 @@ func (c *Client) DeleteSession(sessionID string) error {
 
 // KeystrokeResponse represents a keystroke response from the API
 type KeystrokeResponse struct {
-	BufferState    string `json:"buffer_state"`
-	CursorPosition int    `json:"cursor_position"`
-	CurrentMode    string `json:"current_mode"`
-	MatchStatus    string `json:"match_status"`
-	TaskCompleted  bool   `json:"task_completed"`
-	ElapsedTimeMs  int64  `json:"elapsed_time_ms"`
+	BufferState     string `json:"buffer_state"`
+	CursorPosition  int    `json:"cursor_position"`
+	CurrentMode     string `json:"current_mode"`
+	MatchStatus     string `json:"match_status"`
+	TaskCompleted   bool   `json:"task_completed"`
+	TaskTimedOut    bool   `json:"task_timed_out"`
+	ElapsedTimeMs   int64  `json:"elapsed_time_ms"`
+	TaskTimeLeftMs  *int64 `json:"task_time_remaining_ms,omitempty"`  // Missing if tasks aren't timed
+	RoundTimeLeftMs *int64 `json:"round_time_remaining_ms,omitempty"` // Missing if the round isn't timed
+	OvertimeMs      int64  `json:"overtime_ms,omitempty"`
 }
 
 // SendKeystroke sends a single keystroke to the session
-func (c *Client) SendKeystroke(sessionID, key string) (*KeystrokeResponse, error) {
+func (c *Client) SendKeystroke(sessionID string, key vim.Key) (*KeystrokeResponse, error) {
 	body := map[string]interface{}{
-		"key":       key,
+		"key":       key.String(),
 		"modifiers": []string{},
 	}
 	jsonBody, _ := json.Marshal(body)
@@ -156,9 +218,9 @@ func (c *Client) SendKeystroke(sessionID, key string) (*KeystrokeResponse, error
 }
 
 // SendKeystrokes sends multiple keystrokes to the session
-func (c *Client) SendKeystrokes(sessionID string, keys []string) (*KeystrokeResponse, error) {
+func (c *Client) SendKeystrokes(sessionID string, keys []vim.Key) (*KeystrokeResponse, error) {
 	body := map[string]interface{}{
-		"keys": keys,
+		"sequence": vim.FormatKeys(keys),
 	}
 	jsonBody, _ := json.Marshal(body)
 
@@ -236,6 +298,25 @@ func (c *Client) SkipTask(sessionID string) error {
 	return nil
 }
 
+// EndDrill stops a drill before it's mastered
+func (c *Client) EndDrill(sessionID string) error {
+	resp, err := c.httpClient.Post(
+		c.baseURL+"/sessions/"+sessionID+"/end",
+		"application/json",
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return c.parseError(resp)
+	}
+
+	return nil
+}
+
 // ResetTask resets the current task
 func (c *Client) ResetTask(sessionID string) (*KeystrokeResponse, error) {
 	resp, err := c.httpClient.Post(
@@ -3,16 +3,20 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/timlinux/macaco/internal/config"
 	"github.com/timlinux/macaco/internal/game"
+	"github.com/timlinux/macaco/internal/scheduler"
+	"github.com/timlinux/macaco/internal/vim"
 )
 
 // Server represents the REST API server
@@ -44,8 +48,16 @@ func (s *Server) Start() error {
 	mux.HandleFunc("/api/v1/tasks/", s.handleTaskByID)
 	mux.HandleFunc("/api/v1/rounds", s.handleRounds)
 	mux.HandleFunc("/api/v1/rounds/", s.handleRoundByType)
+	mux.HandleFunc("/api/v1/daily", s.handleDaily)
+	mux.HandleFunc("/api/v1/lessons", s.handleLessons)
+	mux.HandleFunc("/api/v1/lessons/", s.handleLessonByID)
+	mux.HandleFunc("/api/v1/packs", s.handlePacks)
+	mux.HandleFunc("/api/v1/sources", s.handleSources)
+	mux.HandleFunc("/api/v1/templates", s.handleTemplates)
 	mux.HandleFunc("/api/v1/stats/lifetime", s.handleLifetimeStats)
 	mux.HandleFunc("/api/v1/stats/export", s.handleStatsExport)
+	mux.HandleFunc("/api/v1/stats/coach", s.handleCoachReport)
+	mux.HandleFunc("/api/v1/stats/review", s.handleReviewSchedule)
 
 	// Create PID file
 	s.writePIDFile()
@@ -57,6 +69,10 @@ func (s *Server) Start() error {
 		WriteTimeout: 10 * time.Second,
 	}
 
+	for _, problem := range s.engine.TaskProblems() {
+		log.Printf("Skipping invalid %v", problem)
+	}
+
 	log.Printf("Starting MoCaCo API server on %s", s.cfg.ServerAddr)
 	return s.server.ListenAndServe()
 }
@@ -148,7 +164,11 @@ func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
 func (s *Server) createSession(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		RoundType string `json:"round_type"`
-		UserID    string `json:"user_id,omitempty"`
+		RoundCode string `json:"round_code,omitempty"`
+		Text      string          `json:"text,omitempty"` // prose or code
+		UserID    string          `json:"user_id,omitempty"`
+		Drill     *game.DrillSpec `json:"drill,omitempty"` // What a drill practises
+		Unit      string          `json:"unit,omitempty"`  // Unit a lesson practises
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -160,7 +180,46 @@ func (s *Server) createSession(w http.ResponseWriter, r *http.Request) {
 		req.RoundType = "beginner"
 	}
 
-	session := s.engine.CreateSession(req.RoundType)
+	var session *game.Session
+	var err error
+	if req.RoundCode != "" {
+		session, err = s.engine.CreateSessionFromCode(req.RoundCode)
+	} else if req.RoundType == game.RoundDrill {
+		var spec game.DrillSpec
+		if req.Drill != nil {
+			spec = *req.Drill
+		}
+		if spec.Text == "" {
+			spec.Text = req.Text
+		}
+		session, err = s.engine.CreateDrill(spec)
+	} else if req.RoundType == game.RoundLesson {
+		session, err = s.engine.CreateLesson(req.Unit)
+	} else {
+		session, err = s.engine.CreateSessionWithText(req.RoundType, req.Text)
+	}
+	if err == game.ErrDailyAlreadyPlayed {
+		writeError(w, http.StatusConflict, "DAILY_ALREADY_PLAYED", err.Error())
+		return
+	} else if errors.Is(err, game.ErrInvalidRoundCode) {
+		writeError(w, http.StatusBadRequest, "INVALID_ROUND_CODE", err.Error())
+		return
+	} else if err == game.ErrUnknownText || err == game.ErrNoLocalText || err == game.ErrNoBooks {
+		writeError(w, http.StatusBadRequest, "INVALID_TEXT", err.Error())
+		return
+	} else if err == game.ErrUnknownUnit {
+		writeError(w, http.StatusNotFound, "UNIT_NOT_FOUND", err.Error())
+		return
+	} else if err == game.ErrUnitLocked {
+		writeError(w, http.StatusForbidden, "UNIT_LOCKED", err.Error())
+		return
+	} else if isDrillError(err) {
+		writeError(w, http.StatusBadRequest, "INVALID_DRILL", err.Error())
+		return
+	} else if err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_ROUND_TYPE", err.Error())
+		return
+	}
 	task := session.CurrentTask()
 
 	response := map[string]interface{}{
@@ -171,6 +230,19 @@ func (s *Server) createSession(w http.ResponseWriter, r *http.Request) {
 		"started_at":         session.StartedAt,
 		"current_task":       taskToMap(task),
 	}
+	if session.Daily != nil {
+		response["daily"] = session.Daily
+	}
+	if session.RoundCode != "" {
+		response["round_code"] = session.RoundCode
+	}
+	if session.Drill != nil {
+		response["drill"] = session.Drill
+	}
+	if session.Lesson != nil {
+		response["lesson"] = session.Lesson
+	}
+	addTimeRemaining(response, session)
 
 	writeJSON(w, http.StatusCreated, response)
 }
@@ -201,12 +273,18 @@ func (s *Server) handleSessionByID(w http.ResponseWriter, r *http.Request) {
 		case "skip":
 			s.handleSkipTask(w, r, sessionID)
 			return
+		case "end":
+			s.handleEndDrill(w, r, sessionID)
+			return
 		case "reset":
 			s.handleResetTask(w, r, sessionID)
 			return
 		case "stats":
 			s.handleSessionStats(w, r, sessionID)
 			return
+		case "review":
+			s.handleSessionReview(w, r, sessionID)
+			return
 		}
 	}
 
@@ -221,6 +299,7 @@ func (s *Server) handleSessionByID(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) getSession(w http.ResponseWriter, r *http.Request, sessionID string) {
+	s.engine.CheckTime(sessionID)
 	session := s.engine.GetSession(sessionID)
 	if session == nil {
 		writeError(w, http.StatusNotFound, "SESSION_NOT_FOUND", "Session not found")
@@ -237,7 +316,18 @@ func (s *Server) getSession(w http.ResponseWriter, r *http.Request, sessionID st
 		"buffer_state":       session.BufferText(),
 		"cursor_position":    session.CursorIndex(),
 		"elapsed_time_ms":    session.ElapsedTime().Milliseconds(),
+		"round_complete":     session.IsComplete(),
 	}
+	if session.RoundCode != "" {
+		response["round_code"] = session.RoundCode
+	}
+	if session.Drill != nil {
+		response["drill"] = session.Drill
+	}
+	if session.Lesson != nil {
+		response["lesson"] = session.Lesson
+	}
+	addTimeRemaining(response, session)
 
 	writeJSON(w, http.StatusOK, response)
 }
@@ -263,13 +353,23 @@ func (s *Server) handleKeystroke(w http.ResponseWriter, r *http.Request, session
 		return
 	}
 
-	session := s.engine.GetSession(sessionID)
-	if session == nil {
+	key, err := parseKeystroke(req.Key, req.Modifiers)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_KEY", err.Error())
+		return
+	}
+
+	timedOut, err := s.engine.CheckTime(sessionID)
+	if err != nil {
 		writeError(w, http.StatusNotFound, "SESSION_NOT_FOUND", "Session not found")
 		return
 	}
+	session := s.engine.GetSession(sessionID)
 
-	status := session.ProcessKey(req.Key)
+	status := game.MatchNone
+	if !timedOut {
+		status = session.ProcessKey(key)
+	}
 
 	response := map[string]interface{}{
 		"buffer_state":    session.BufferText(),
@@ -277,8 +377,10 @@ func (s *Server) handleKeystroke(w http.ResponseWriter, r *http.Request, session
 		"current_mode":    session.Mode().String(),
 		"match_status":    status.String(),
 		"task_completed":  status == game.MatchComplete,
+		"task_timed_out":  timedOut,
 		"elapsed_time_ms": session.ElapsedTime().Milliseconds(),
 	}
+	addTimeRemaining(response, session)
 
 	writeJSON(w, http.StatusOK, response)
 }
@@ -290,7 +392,8 @@ func (s *Server) handleKeystrokes(w http.ResponseWriter, r *http.Request, sessio
 	}
 
 	var req struct {
-		Keys []string `json:"keys"`
+		Keys     []string `json:"keys"`
+		Sequence string   `json:"sequence,omitempty"` // Vim notation, e.g. "cwnew<Esc>"
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -298,14 +401,29 @@ func (s *Server) handleKeystrokes(w http.ResponseWriter, r *http.Request, sessio
 		return
 	}
 
-	session := s.engine.GetSession(sessionID)
-	if session == nil {
+	var keys []vim.Key
+	for _, k := range req.Keys {
+		key, err := vim.ParseKey(k)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_KEY", err.Error())
+			return
+		}
+		keys = append(keys, key)
+	}
+	keys = append(keys, vim.ParseKeys(req.Sequence)...)
+
+	timedOut, err := s.engine.CheckTime(sessionID)
+	if err != nil {
 		writeError(w, http.StatusNotFound, "SESSION_NOT_FOUND", "Session not found")
 		return
 	}
+	session := s.engine.GetSession(sessionID)
 
 	var status game.MatchStatus
-	for _, key := range req.Keys {
+	if timedOut {
+		keys = nil
+	}
+	for _, key := range keys {
 		status = session.ProcessKey(key)
 		if status == game.MatchComplete {
 			break
@@ -318,8 +436,10 @@ func (s *Server) handleKeystrokes(w http.ResponseWriter, r *http.Request, sessio
 		"current_mode":    session.Mode().String(),
 		"match_status":    status.String(),
 		"task_completed":  status == game.MatchComplete,
+		"task_timed_out":  timedOut,
 		"elapsed_time_ms": session.ElapsedTime().Milliseconds(),
 	}
+	addTimeRemaining(response, session)
 
 	writeJSON(w, http.StatusOK, response)
 }
@@ -344,6 +464,16 @@ func (s *Server) handleCompleteTask(w http.ResponseWriter, r *http.Request, sess
 		"result":          result,
 	}
 
+	if session != nil {
+		response["review"] = session.Review(len(session.TaskResults) - 1)
+		if session.Drill != nil {
+			response["drill"] = session.Drill
+		}
+		if session.Lesson != nil {
+			response["lesson"] = session.Lesson
+		}
+	}
+
 	if session != nil && !session.IsComplete() {
 		response["tasks_remaining"] = session.TotalTasks - session.CurrentIndex
 		response["next_task"] = taskToMap(session.CurrentTask())
@@ -380,6 +510,28 @@ func (s *Server) handleSkipTask(w http.ResponseWriter, r *http.Request, sessionI
 	writeJSON(w, http.StatusOK, response)
 }
 
+func (s *Server) handleEndDrill(w http.ResponseWriter, r *http.Request, sessionID string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := s.engine.EndDrill(sessionID); err == game.ErrNotDrill {
+		writeError(w, http.StatusBadRequest, "NOT_A_DRILL", err.Error())
+		return
+	} else if err != nil {
+		writeError(w, http.StatusNotFound, "SESSION_NOT_FOUND", err.Error())
+		return
+	}
+
+	session := s.engine.GetSession(sessionID)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"round_complete": true,
+		"tasks_played":   len(session.TaskResults),
+		"drill":          session.Drill,
+	})
+}
+
 func (s *Server) handleResetTask(w http.ResponseWriter, r *http.Request, sessionID string) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -398,6 +550,7 @@ func (s *Server) handleResetTask(w http.ResponseWriter, r *http.Request, session
 		"cursor_position": session.CursorIndex(),
 		"elapsed_time_ms": session.ElapsedTime().Milliseconds(),
 	}
+	addTimeRemaining(response, session)
 
 	writeJSON(w, http.StatusOK, response)
 }
@@ -417,6 +570,24 @@ func (s *Server) handleSessionStats(w http.ResponseWriter, r *http.Request, sess
 	writeJSON(w, http.StatusOK, stats)
 }
 
+func (s *Server) handleSessionReview(w http.ResponseWriter, r *http.Request, sessionID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session := s.engine.GetSession(sessionID)
+	if session == nil {
+		writeError(w, http.StatusNotFound, "SESSION_NOT_FOUND", "Session not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"session_id": session.ID,
+		"reviews":    session.Reviews(),
+	})
+}
+
 func (s *Server) handleTasks(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -466,14 +637,188 @@ func (s *Server) handleRoundByType(w http.ResponseWriter, r *http.Request) {
 
 	roundType := strings.TrimPrefix(r.URL.Path, "/api/v1/rounds/")
 
-	// Just return round info
+	if roundType == game.RoundDrill {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"round_type":         roundType,
+			"description":        "Tasks of one category, tag or set of commands until mastered",
+			"categories":         game.RoundCategories(),
+			"tags":               s.engine.GetTemplates().Tags(),
+			"commands":           s.engine.GetTemplates().Commands(),
+			"default_solves":     game.DefaultDrillSolves,
+			"default_efficiency": game.DefaultDrillEfficiency,
+		})
+		return
+	}
+
+	// Review and daily rounds aren't defined in the task database
+	if roundType == game.RoundReview || roundType == game.RoundDaily {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"round_type": roundType,
+			"task_count": 30,
+		})
+		return
+	}
+
+	def, ok := s.engine.GetRound(roundType)
+	if !ok {
+		writeError(w, http.StatusNotFound, "ROUND_NOT_FOUND", "Round type not found")
+		return
+	}
+
+	minDiff, maxDiff := def.Difficulty()
+
 	response := map[string]interface{}{
-		"round_type": roundType,
-		"task_count": 30,
+		"round_type":       roundType,
+		"name":             def.Name,
+		"description":      def.Description,
+		"source":           s.engine.RoundSource(def),
+		"task_count":       s.engine.RoundLength(def),
+		"difficulty_range": []int{minDiff, maxDiff},
+		"text":             def.TextKind(),
+		"shuffle":          def.Shuffled(),
+		"max_hints":        def.MaxHints,
+		"task_time_limit":  def.TaskTimeLimit,
+		"round_time_limit": def.RoundTimeLimit,
+		"overtime_penalty": def.OvertimePenalty,
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleSources(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	response := make(map[string]interface{})
+	for _, text := range []string{game.TextProse, game.TextCode, game.TextLocal, game.TextBooks} {
+		sources := []map[string]interface{}{}
+		for _, src := range s.engine.GetTextSources(text) {
+			sources = append(sources, map[string]interface{}{
+				"name":        src.Name,
+				"author":      src.Author,
+				"year":        src.Year,
+				"language":    src.Language,
+				"license":     src.License,
+				"attribution": src.Attribution,
+				"lines":       len(src.Sentences),
+			})
+		}
+		response[text] = sources
 	}
 	writeJSON(w, http.StatusOK, response)
 }
 
+func (s *Server) handleTemplates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	registry := s.engine.GetTemplates()
+	found := registry.Templates()
+	if tag := r.URL.Query().Get("tag"); tag != "" {
+		found = registry.WithTag(tag)
+	}
+
+	templates := []game.TemplateInfo{}
+	for _, t := range found {
+		templates = append(templates, t.Info())
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"templates": templates,
+		"tags":      registry.Tags(),
+	})
+}
+
+func (s *Server) handleLessons(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"version": s.engine.GetCurriculum().Version,
+		"units":   s.engine.GetLessonStatus(),
+	})
+}
+
+func (s *Server) handleLessonByID(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	unitID := strings.TrimPrefix(r.URL.Path, "/api/v1/lessons/")
+	unit, index := s.engine.GetCurriculum().Unit(unitID)
+	if unit == nil {
+		writeError(w, http.StatusNotFound, "UNIT_NOT_FOUND", "Unit not found")
+		return
+	}
+
+	status := s.engine.GetLessonStatus()[index]
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":             unit.ID,
+		"title":          unit.Title,
+		"summary":        unit.Summary,
+		"commands":       unit.Commands,
+		"known_commands": unit.Known(),
+		"screens":        unit.Screens,
+		"examples":       unit.Examples,
+		"practice_tasks": len(unit.Practice),
+		"pass":           unit.Pass,
+		"unlocked":       status.Unlocked,
+		"progress":       status.Progress,
+	})
+}
+
+func (s *Server) handlePacks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	packs := []map[string]interface{}{}
+	for _, pack := range s.engine.GetPacks() {
+		rounds := []string{}
+		for roundType := range pack.Manifest.Rounds {
+			rounds = append(rounds, roundType)
+		}
+		sort.Strings(rounds)
+
+		packs = append(packs, map[string]interface{}{
+			"name":        pack.Manifest.Name,
+			"version":     pack.Manifest.Version,
+			"author":      pack.Manifest.Author,
+			"license":     pack.Manifest.License,
+			"description": pack.Manifest.Description,
+			"requires":    pack.Manifest.Requires,
+			"path":        pack.Path,
+			"task_count":  len(pack.Tasks),
+			"rounds":      rounds,
+		})
+	}
+
+	problems := []string{}
+	for _, problem := range s.engine.TaskProblems() {
+		problems = append(problems, problem.Error())
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"packs":    packs,
+		"problems": problems,
+	})
+}
+
+func (s *Server) handleDaily(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.engine.GetDailyStatus())
+}
+
 func (s *Server) handleLifetimeStats(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -492,6 +837,39 @@ func (s *Server) handleLifetimeStats(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, stats)
 }
 
+func (s *Server) handleCoachReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.engine.GetCoachReport())
+}
+
+func (s *Server) handleReviewSchedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	now := time.Now()
+	categories := s.engine.GetReviewItems(scheduler.KindCategory)
+	commands := s.engine.GetReviewItems(scheduler.KindCommand)
+	due := 0
+	for _, item := range append(append([]scheduler.Item{}, categories...), commands...) {
+		if item.IsDue(now) {
+			due++
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"round_type": game.RoundReview,
+		"due":        due,
+		"categories": categories,
+		"commands":   commands,
+	})
+}
+
 func (s *Server) handleStatsExport(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -527,6 +905,40 @@ func (s *Server) handleStatsExport(w http.ResponseWriter, r *http.Request) {
 
 // Helper functions
 
+// parseKeystroke builds a key from a key name and optional modifiers
+func parseKeystroke(name string, modifiers []string) (vim.Key, error) {
+	for _, mod := range modifiers {
+		switch strings.ToLower(mod) {
+		case "ctrl", "control":
+			if len(name) == 1 {
+				return vim.CtrlKey(rune(name[0])), nil
+			}
+		}
+	}
+	return vim.ParseKey(name)
+}
+
+// isDrillError returns true if err says what's wrong with a drill spec
+func isDrillError(err error) bool {
+	switch err {
+	case game.ErrDrillCategory, game.ErrDrillDifficulty, game.ErrDrillMastery, game.ErrDrillNoTasks:
+		return true
+	}
+	return false
+}
+
+// addTimeRemaining adds the time left on the task and round clocks of a
+// timed session to a response
+func addTimeRemaining(response map[string]interface{}, session *game.Session) {
+	if remaining, ok := session.TaskTimeRemaining(); ok {
+		response["task_time_remaining_ms"] = remaining.Milliseconds()
+	}
+	if remaining, ok := session.RoundTimeRemaining(); ok {
+		response["round_time_remaining_ms"] = remaining.Milliseconds()
+		response["overtime_ms"] = session.Overtime().Milliseconds()
+	}
+}
+
 func writeJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -549,14 +961,21 @@ func taskToMap(task *game.Task) map[string]interface{} {
 		return nil
 	}
 	return map[string]interface{}{
-		"task_id":      task.ID,
-		"category":     task.Category,
-		"difficulty":   task.Difficulty,
-		"initial":      task.Initial,
-		"desired":      task.Desired,
-		"cursor_start": task.CursorStart,
-		"cursor_end":   task.CursorEnd,
-		"description":  task.Description,
-		"hint":         task.Hint,
+		"task_id":          task.ID,
+		"category":         task.Category,
+		"difficulty":       task.Difficulty,
+		"difficulty_score": task.DifficultyScore,
+		"initial":          task.Initial,
+		"desired":          task.Desired,
+		"cursor_start":     task.CursorStart,
+		"cursor_end":       task.CursorEnd,
+		"cursor_start_pos": task.StartPosition(),
+		"cursor_end_pos":   task.EndPosition(),
+		"multiline":        task.IsMultiline(),
+		"optimal_keys":     task.OptimalKeys,
+		"optimal_count":    task.OptimalCount,
+		"alternative_keys": task.AlternativeKeys,
+		"description":      task.Description,
+		"hint":             task.Hint,
 	}
 }
@@ -0,0 +1,217 @@
+// Package coach looks for inefficient vim habits in the keys players use
+package coach
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/timlinux/macaco/internal/replay"
+	"github.com/timlinux/macaco/internal/stats"
+	"github.com/timlinux/macaco/internal/vim"
+)
+
+// maxEvidence is how many examples a habit keeps in a report
+const maxEvidence = 5
+
+// Attempt is one task attempt as the coach sees it
+type Attempt struct {
+	SessionID   string
+	TaskID      string
+	Category    string
+	Keys        []vim.Key
+	Commands    [][]vim.Key // Keys split into commands
+	OptimalKeys int
+	CompletedAt time.Time
+}
+
+// NewAttempt creates an attempt from the keys used, in vim notation, and
+// the recording of the attempt if there is one
+func NewAttempt(sessionID, taskID, category, keys string, optimal int, completedAt time.Time, rec *replay.Recording) *Attempt {
+	parsed := vim.ParseKeys(keys)
+	return &Attempt{
+		SessionID:   sessionID,
+		TaskID:      taskID,
+		Category:    category,
+		Keys:        parsed,
+		Commands:    splitAttempt(parsed, rec),
+		OptimalKeys: optimal,
+		CompletedAt: completedAt,
+	}
+}
+
+// AttemptsFromSessions returns the task attempts recorded in sessions
+func AttemptsFromSessions(sessions []*stats.SessionStats) []*Attempt {
+	var attempts []*Attempt
+	for _, session := range sessions {
+		for _, task := range session.Tasks {
+			attempts = append(attempts, NewAttempt(session.SessionID, task.TaskID, task.Category,
+				task.KeysUsed, task.OptimalKeystrokes, task.CompletedAt, task.Replay))
+		}
+	}
+	return attempts
+}
+
+// splitAttempt cuts keys into commands by replaying them on the task's
+// text from its recording, starting over at each reset. Where a command
+// ends can depend on the text: cw enters insert mode only if there is a
+// word to change. Keys without a recording are replayed on an empty
+// buffer.
+func splitAttempt(keys []vim.Key, rec *replay.Recording) [][]vim.Key {
+	if rec == nil || rec.Len() != len(keys) {
+		return vim.SplitKeys(vim.NewEngine(""), keys)
+	}
+
+	var commands [][]vim.Key
+	start := 0
+	for i := 1; i <= len(keys); i++ {
+		if i == len(keys) || rec.IsReset(i) {
+			commands = append(commands, vim.SplitKeys(rec.NewEngine(), keys[start:i])...)
+			start = i
+		}
+	}
+	return commands
+}
+
+// Evidence shows where in an attempt a rule spotted a habit
+type Evidence struct {
+	SessionID string    `json:"session_id,omitempty"`
+	TaskID    string    `json:"task_id"`
+	Keys      string    `json:"keys"`             // The commands that show the habit
+	Better    string    `json:"better,omitempty"` // What would have been shorter
+	Wasted    int       `json:"wasted"`           // Keystrokes the better way saves
+	At        time.Time `json:"at"`
+}
+
+// String describes the evidence, as in "lllll could be f{char} or t{char} (-3)"
+func (e Evidence) String() string {
+	if e.Better == "" {
+		return e.Keys
+	}
+	return fmt.Sprintf("%s could be %s (-%d)", e.Keys, e.Better, e.Wasted)
+}
+
+// Drill suggests practice for a habit
+type Drill struct {
+	Description string   `json:"description"`
+	Category    string   `json:"category,omitempty"` // Task category to practise
+	Commands    []string `json:"commands,omitempty"` // Commands to practise
+}
+
+// Rule spots one inefficient habit. Check returns the evidence found in an
+// attempt, leaving SessionID, TaskID and At for the coach to fill in.
+type Rule interface {
+	ID() string
+	Name() string
+	Description() string
+	Drill() Drill
+	Check(attempt *Attempt) []Evidence
+}
+
+// Habit is a rule's findings across all analysed attempts
+type Habit struct {
+	RuleID      string     `json:"rule_id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Occurrences int        `json:"occurrences"`
+	Sessions    int        `json:"sessions"` // Sessions the habit showed up in
+	Wasted      int        `json:"wasted"`   // Keystrokes lost to the habit
+	Evidence    []Evidence `json:"evidence"` // Most recent first
+	Drill       Drill      `json:"drill"`
+}
+
+// Report ranks the habits found in a set of attempts, worst first
+type Report struct {
+	Attempts int     `json:"attempts"`
+	Habits   []Habit `json:"habits"`
+}
+
+// Top returns the n worst habits
+func (r *Report) Top(n int) []Habit {
+	if len(r.Habits) <= n {
+		return r.Habits
+	}
+	return r.Habits[:n]
+}
+
+// Coach runs a set of rules over task attempts
+type Coach struct {
+	rules []Rule
+}
+
+// New creates a coach with the given rules
+func New(rules ...Rule) *Coach {
+	return &Coach{rules: rules}
+}
+
+// Default creates a coach with the built-in rules
+func Default() *Coach {
+	return New(DefaultRules()...)
+}
+
+// Register adds a rule, replacing any rule with the same ID
+func (c *Coach) Register(rule Rule) {
+	for i, r := range c.rules {
+		if r.ID() == rule.ID() {
+			c.rules[i] = rule
+			return
+		}
+	}
+	c.rules = append(c.rules, rule)
+}
+
+// Rules returns the registered rules
+func (c *Coach) Rules() []Rule {
+	return c.rules
+}
+
+// Analyse runs every rule over the attempts and ranks the habits found by
+// the keystrokes they cost, then by how many sessions they showed up in
+func (c *Coach) Analyse(attempts []*Attempt) *Report {
+	report := &Report{Attempts: len(attempts), Habits: []Habit{}}
+
+	for _, rule := range c.rules {
+		habit := Habit{
+			RuleID:      rule.ID(),
+			Name:        rule.Name(),
+			Description: rule.Description(),
+			Drill:       rule.Drill(),
+		}
+		sessions := make(map[string]bool)
+		var evidence []Evidence
+
+		for _, attempt := range attempts {
+			for _, ev := range rule.Check(attempt) {
+				ev.SessionID = attempt.SessionID
+				ev.TaskID = attempt.TaskID
+				ev.At = attempt.CompletedAt
+				evidence = append(evidence, ev)
+				habit.Occurrences++
+				habit.Wasted += ev.Wasted
+				sessions[attempt.SessionID] = true
+			}
+		}
+		if habit.Occurrences == 0 {
+			continue
+		}
+
+		sort.SliceStable(evidence, func(i, j int) bool {
+			return evidence[i].At.After(evidence[j].At)
+		})
+		if len(evidence) > maxEvidence {
+			evidence = evidence[:maxEvidence]
+		}
+		habit.Evidence = evidence
+		habit.Sessions = len(sessions)
+		report.Habits = append(report.Habits, habit)
+	}
+
+	sort.SliceStable(report.Habits, func(i, j int) bool {
+		a, b := report.Habits[i], report.Habits[j]
+		if a.Wasted != b.Wasted {
+			return a.Wasted > b.Wasted
+		}
+		return a.Sessions > b.Sessions
+	})
+	return report
+}
@@ -0,0 +1,164 @@
+package coach
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/timlinux/macaco/internal/replay"
+	"github.com/timlinux/macaco/internal/vim"
+)
+
+// checkRule returns the evidence a built-in rule finds in keys typed on text
+func checkRule(t *testing.T, id, text, keys string) []Evidence {
+	t.Helper()
+	for _, rule := range DefaultRules() {
+		if rule.ID() == id {
+			return rule.Check(&Attempt{Commands: vim.SplitKeys(vim.NewEngine(text), vim.ParseKeys(keys))})
+		}
+	}
+	t.Fatalf("no rule %q", id)
+	return nil
+}
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		rule string
+		text string
+		keys string
+		want []Evidence
+	}{
+		{"repeated-hl", "one two three", "llll", []Evidence{{Keys: "llll", Better: "f{char} or t{char}", Wasted: 2}}},
+		{"repeated-hl", "one two three", "$hhh", []Evidence{{Keys: "hhh", Better: "F{char} or T{char}", Wasted: 1}}},
+		{"repeated-hl", "one two three", "ll", nil},
+		{"repeated-hl", "one two three", "lllwlll", []Evidence{
+			{Keys: "lll", Better: "f{char} or t{char}", Wasted: 1},
+			{Keys: "lll", Better: "f{char} or t{char}", Wasted: 1},
+		}},
+		{"x-chain", "one two three", "xxxx", []Evidence{{Keys: "xxxx", Better: "dw, daw or D", Wasted: 2}}},
+		{"x-chain", "one two three", "xx", nil},
+		{"delete-then-insert", "one two", "dwinew <Esc>", []Evidence{{Keys: "dw + inew <Esc>", Better: "cwnew <Esc>", Wasted: 1}}},
+		{"delete-then-insert", "one two", "2dwinew<Esc>", []Evidence{{Keys: "2dw + inew<Esc>", Better: "2cwnew<Esc>", Wasted: 1}}},
+		{"delete-then-insert", "one two", "ddinew<Esc>", nil},
+		{"delete-then-insert", "one two", "dwanew<Esc>", nil},
+		{"repeated-motion", "a b c d e f", "wwww", []Evidence{{Keys: "wwww", Better: "4w", Wasted: 2}}},
+		{"repeated-motion", "a b c d e f", "www", []Evidence{{Keys: "www", Better: "3w", Wasted: 1}}},
+		{"repeated-motion", "a b c d e f", "ww", nil},
+		{"repeated-motion", "a\nb\nc\nd", "jjj", []Evidence{{Keys: "jjj", Better: "3j", Wasted: 1}}},
+		{"visual-for-object", "one two three", "wvex", nil}, // As short as an operator and object
+		{"visual-for-object", "one two three", "wvlld", []Evidence{{Keys: "vlld", Better: "d{text object}", Wasted: 1}}},
+		{"visual-for-object", "one two three", "wviwd", []Evidence{{Keys: "viwd", Better: "diw", Wasted: 1}}},
+		{"visual-for-object", "one two three", "wviwcx<Esc>", []Evidence{{Keys: "viwc", Better: "ciw", Wasted: 1}}},
+		{"visual-for-object", "one two three", "diw", nil},
+	}
+	for _, tt := range tests {
+		got := checkRule(t, tt.rule, tt.text, tt.keys)
+		if len(got) == 0 && len(tt.want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s on %q: evidence %+v, want %+v", tt.rule, tt.keys, got, tt.want)
+		}
+	}
+}
+
+func TestNewAttemptSplitsOnTheRecordedText(t *testing.T) {
+	engine := vim.NewEngine("one two")
+	keys := vim.ParseKeys("cwnew<Esc>")
+	rec := replay.FromKeys(engine, keys, time.Millisecond)
+
+	withRecording := NewAttempt("s", "t", "change", "cwnew<Esc>", 6, time.Time{}, rec)
+	if got := len(withRecording.Commands); got != 1 {
+		t.Errorf("with the recording: %d commands %q, want cwnew<Esc> as one", got, withRecording.Commands)
+	}
+
+	// Without the text, cw has no word to change
+	without := NewAttempt("s", "t", "change", "cwnew<Esc>", 6, time.Time{}, nil)
+	if got := len(without.Commands); got == 1 {
+		t.Errorf("without a recording: %q split as one command", without.Commands)
+	}
+}
+
+func TestSplitAttemptRestartsAtResets(t *testing.T) {
+	engine := vim.NewEngine("one two")
+	rec := replay.NewRecorder(engine)
+	keys := vim.ParseKeys("dwcwx<Esc>")
+	for i, key := range keys {
+		if i == 2 {
+			// Reset before the cw, back on the initial text
+			rec.Reset()
+			engine = vim.NewEngine("one two")
+		}
+		engine.ProcessKey(key)
+		rec.Record(key, time.Duration(i)*time.Millisecond, engine)
+	}
+
+	got := splitAttempt(keys, rec.Recording(false))
+	want := [][]vim.Key{{"d", "w"}, {"c", "w", "x", vim.KeyEsc}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("splitAttempt = %q, want %q", got, want)
+	}
+}
+
+func TestAnalyse(t *testing.T) {
+	day := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	var attempts []*Attempt
+	for i := 0; i < 7; i++ {
+		attempts = append(attempts, NewAttempt("s1", "x", "delete", "xxxx", 2, day.Add(time.Duration(i)*time.Hour), nil))
+	}
+	attempts = append(attempts, NewAttempt("s2", "w", "motion", "wwww", 2, day, nil))
+
+	report := Default().Analyse(attempts)
+	if report.Attempts != 8 {
+		t.Errorf("attempts = %d, want 8", report.Attempts)
+	}
+	if len(report.Habits) != 2 {
+		t.Fatalf("habits = %+v, want x-chain and repeated-motion", report.Habits)
+	}
+
+	worst := report.Habits[0]
+	if worst.RuleID != "x-chain" || worst.Occurrences != 7 || worst.Wasted != 14 || worst.Sessions != 1 {
+		t.Errorf("worst habit = %+v, want x-chain 7 times wasting 14 keys in one session", worst)
+	}
+	if len(worst.Evidence) != maxEvidence {
+		t.Errorf("kept %d pieces of evidence, want %d", len(worst.Evidence), maxEvidence)
+	}
+	if !worst.Evidence[0].At.Equal(day.Add(6*time.Hour)) || worst.Evidence[0].SessionID != "s1" {
+		t.Errorf("first evidence %+v, want the most recent", worst.Evidence[0])
+	}
+	if top := report.Top(1); len(top) != 1 || top[0].RuleID != "x-chain" {
+		t.Errorf("Top(1) = %+v", top)
+	}
+}
+
+func TestRegisterReplacesRule(t *testing.T) {
+	c := Default()
+	n := len(c.Rules())
+	never := func(*Attempt) []Evidence { return nil }
+	c.Register(NewRule("x-chain", "Replaced", "", Drill{}, never))
+	if len(c.Rules()) != n {
+		t.Errorf("registering a rule with a used ID gave %d rules, want %d", len(c.Rules()), n)
+	}
+	c.Register(NewRule("new", "New", "", Drill{}, never))
+	if len(c.Rules()) != n+1 {
+		t.Errorf("registering a new rule gave %d rules, want %d", len(c.Rules()), n+1)
+	}
+	if report := c.Analyse([]*Attempt{NewAttempt("s", "t", "delete", "xxxx", 2, time.Time{}, nil)}); len(report.Habits) != 0 {
+		t.Errorf("replaced rule still found %+v", report.Habits)
+	}
+}
+
+func TestEvidenceString(t *testing.T) {
+	tests := []struct {
+		evidence Evidence
+		want     string
+	}{
+		{Evidence{Keys: "lllll", Better: "f{char} or t{char}", Wasted: 3}, "lllll could be f{char} or t{char} (-3)"},
+		{Evidence{Keys: "vlld"}, "vlld"},
+	}
+	for _, tt := range tests {
+		if got := tt.evidence.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
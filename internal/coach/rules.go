@@ -0,0 +1,192 @@
+package coach
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/timlinux/macaco/internal/vim"
+)
+
+// funcRule is a Rule backed by a check function
+type funcRule struct {
+	id          string
+	name        string
+	description string
+	drill       Drill
+	check       func(*Attempt) []Evidence
+}
+
+func (r *funcRule) ID() string                  { return r.id }
+func (r *funcRule) Name() string                { return r.name }
+func (r *funcRule) Description() string         { return r.description }
+func (r *funcRule) Drill() Drill                { return r.drill }
+func (r *funcRule) Check(a *Attempt) []Evidence { return r.check(a) }
+
+// NewRule creates a rule from a check function
+func NewRule(id, name, description string, drill Drill, check func(*Attempt) []Evidence) Rule {
+	return &funcRule{id: id, name: name, description: description, drill: drill, check: check}
+}
+
+// DefaultRules returns the built-in rules
+func DefaultRules() []Rule {
+	return []Rule{
+		NewRule("repeated-hl", "Crawling with h/l",
+			"Moving along a line one character at a time instead of jumping with f or t",
+			Drill{Description: "Reach the target character in one jump", Category: "motion", Commands: []string{"f", "t", "F", "T"}},
+			checkRepeatedHL),
+		NewRule("x-chain", "Chains of x",
+			"Deleting character by character instead of deleting the word or the rest of the line",
+			Drill{Description: "Delete whole words and line ends at once", Category: "delete", Commands: []string{"dw", "daw", "D"}},
+			checkXChain),
+		NewRule("delete-then-insert", "Delete, then insert",
+			"Deleting with d and then entering insert mode instead of changing with c",
+			Drill{Description: "Replace text with a single change", Category: "change", Commands: []string{"cw", "ciw", "ct"}},
+			checkDeleteThenInsert),
+		NewRule("repeated-motion", "Repeating motions",
+			"Pressing the same motion several times instead of giving it a count",
+			Drill{Description: "Put a count in front of repeated motions", Category: "motion", Commands: []string{"3w", "2b", "4j"}},
+			checkRepeatedMotion),
+		NewRule("visual-for-object", "Visual mode for text objects",
+			"Selecting text in visual mode where an operator and a text object would do",
+			Drill{Description: "Apply operators to text objects directly", Category: "visual", Commands: []string{"diw", "daw", "ci\""}},
+			checkVisualForObject),
+	}
+}
+
+// runs calls fn for every run of two or more identical single-key commands
+// in keys; the run is passed as its key and its length
+func runs(commands [][]vim.Key, keys string, fn func(key vim.Key, n int)) {
+	for i := 0; i < len(commands); {
+		cmd := commands[i]
+		if len(cmd) != 1 || !strings.Contains(keys, string(cmd[0])) {
+			i++
+			continue
+		}
+		j := i + 1
+		for j < len(commands) && len(commands[j]) == 1 && commands[j][0] == cmd[0] {
+			j++
+		}
+		if j-i > 1 {
+			fn(cmd[0], j-i)
+		}
+		i = j
+	}
+}
+
+func checkRepeatedHL(a *Attempt) []Evidence {
+	var evidence []Evidence
+	runs(a.Commands, "hl", func(key vim.Key, n int) {
+		// f or t plus the target character is two keys
+		if n > 2 {
+			better := "f{char} or t{char}"
+			if key == "h" {
+				better = "F{char} or T{char}"
+			}
+			evidence = append(evidence, Evidence{Keys: strings.Repeat(string(key), n), Better: better, Wasted: n - 2})
+		}
+	})
+	return evidence
+}
+
+func checkXChain(a *Attempt) []Evidence {
+	var evidence []Evidence
+	runs(a.Commands, "x", func(key vim.Key, n int) {
+		if n > 2 {
+			evidence = append(evidence, Evidence{Keys: strings.Repeat("x", n), Better: "dw, daw or D", Wasted: n - 2})
+		}
+	})
+	return evidence
+}
+
+func checkDeleteThenInsert(a *Attempt) []Evidence {
+	var evidence []Evidence
+	for i := 0; i+1 < len(a.Commands); i++ {
+		del, ins := a.Commands[i], a.Commands[i+1]
+		if ins[0] != "i" || len(del) < 2 {
+			continue
+		}
+
+		// [count]d[count]{w,e,W,E}
+		before := digits(del)
+		if before >= len(del)-1 {
+			continue
+		}
+		after := digits(del[before+1:])
+		if del[before] != "d" || len(del) != before+after+2 || !strings.Contains("weWE", string(del[len(del)-1])) {
+			continue
+		}
+
+		change := append([]vim.Key{}, del...)
+		change[before] = "c"
+		change = append(change, ins[1:]...)
+		evidence = append(evidence, Evidence{
+			Keys:   vim.FormatKeys(del) + " + " + vim.FormatKeys(ins),
+			Better: vim.FormatKeys(change),
+			Wasted: 1,
+		})
+	}
+	return evidence
+}
+
+// digits returns how many leading keys of cmd are a count
+func digits(cmd []vim.Key) int {
+	n := 0
+	for n < len(cmd) && len(cmd[n]) == 1 && cmd[n][0] >= '0' && cmd[n][0] <= '9' {
+		if n == 0 && cmd[n] == "0" {
+			break // 0 on its own is a motion
+		}
+		n++
+	}
+	return n
+}
+
+func checkRepeatedMotion(a *Attempt) []Evidence {
+	var evidence []Evidence
+	runs(a.Commands, "wbeWBEjk{}", func(key vim.Key, n int) {
+		count := strconv.Itoa(n)
+		if wasted := n - len(count) - 1; wasted > 0 {
+			evidence = append(evidence, Evidence{Keys: strings.Repeat(string(key), n), Better: count + string(key), Wasted: wasted})
+		}
+	})
+	return evidence
+}
+
+func checkVisualForObject(a *Attempt) []Evidence {
+	var evidence []Evidence
+	for _, cmd := range a.Commands {
+		if len(cmd) < 3 || cmd[0] != "v" {
+			continue
+		}
+
+		// The selection ends at the operator; c and s go on in insert mode
+		end := -1
+		for i := 1; i < len(cmd) && end < 0; i++ {
+			switch k := string(cmd[i]); {
+			case len(k) != 1:
+			case strings.Contains("dxcsy", k):
+				end = i
+			case strings.Contains("fFtTia", k):
+				i++ // Skip the argument
+			}
+		}
+		if end < 0 {
+			continue
+		}
+		op := map[vim.Key]string{"d": "d", "x": "d", "c": "c", "s": "c", "y": "y"}[cmd[end]]
+		selection := vim.FormatKeys(cmd[1:end])
+
+		better := op + "{text object}"
+		if len(selection) == 2 && strings.ContainsAny(selection[:1], "ia") {
+			better = op + selection
+		}
+		// An operator and a text object is three keys
+		if wasted := end + 1 - 3; wasted > 0 {
+			evidence = append(evidence, Evidence{
+				Keys:   vim.FormatKeys(cmd[:end+1]),
+				Better: better,
+				Wasted: wasted,
+			})
+		}
+	}
+	return evidence
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+
+	"github.com/timlinux/macaco/internal/stats"
 )
 
 // Config holds application configuration
@@ -14,17 +16,27 @@ type Config struct {
 	// Game settings
 	AutoAdvanceDelay int  `json:"auto_advance_delay_ms"`
 	ShowHints        bool `json:"show_hints"`
+	ShowReview       bool `json:"show_review"` // Review each task's keys against the optimal solution
 	EnableSounds     bool `json:"enable_sounds"`
 	AnimationSpeed   float64 `json:"animation_speed"`
 
+	// Grades replaces the built-in grade thresholds, best grade first
+	Grades []stats.GradeThreshold `json:"grades,omitempty"`
+
+	// Vim options
+	ScrollOff int `json:"scrolloff"` // Lines of context kept around the cursor
+	TextWidth int `json:"textwidth"` // Line length for gq/gw, 0 means 79
+
 	// Appearance
 	Theme    string `json:"theme"` // "dark", "light", "high-contrast"
 	FontSize int    `json:"font_size"`
 
 	// Data paths
-	DataDir   string `json:"data_dir"`
-	StatsFile string `json:"stats_file"`
-	TasksFile string `json:"tasks_file"`
+	DataDir     string   `json:"data_dir"`
+	StatsFile   string   `json:"stats_file"`
+	TasksFile   string   `json:"tasks_file"`
+	CorpusPaths []string `json:"corpus_paths"` // Files and directories of local text
+	LessonsFile string   `json:"lessons_file"` // Curriculum replacing the built-in one
 }
 
 // Default returns the default configuration
@@ -36,6 +48,7 @@ func Default() *Config {
 		ServerAddr:       "localhost:8080",
 		AutoAdvanceDelay: 500,
 		ShowHints:        true,
+		ShowReview:       true,
 		EnableSounds:     false,
 		AnimationSpeed:   1.0,
 		Theme:            "dark",
@@ -97,6 +110,21 @@ func (c *Config) Save(path string) error {
 	return os.WriteFile(path, data, 0644)
 }
 
+// SourcesDir returns the directory of imported prose sources
+func (c *Config) SourcesDir() string {
+	return filepath.Join(c.DataDir, "sources")
+}
+
+// PacksDir returns the directory task packs are installed in
+func (c *Config) PacksDir() string {
+	return filepath.Join(c.DataDir, "packs")
+}
+
+// SessionFile returns the file an unfinished round is saved to
+func (c *Config) SessionFile() string {
+	return filepath.Join(c.DataDir, "session.json")
+}
+
 // EnsureDataDir creates the data directory if it doesn't exist
 func (c *Config) EnsureDataDir() error {
 	return os.MkdirAll(c.DataDir, 0755)
@@ -0,0 +1,207 @@
+package game
+
+import (
+	"math"
+	"unicode/utf8"
+
+	"github.com/timlinux/macaco/internal/stats"
+	"github.com/timlinux/macaco/internal/vim"
+)
+
+// Difficulty scores run from MinDifficultyScore to MaxDifficultyScore, the
+// same scale as Task.Difficulty. Each difficulty level owns a band of it,
+// see ScoreBand.
+const (
+	MinDifficultyScore = 1.0
+	MaxDifficultyScore = 4.0
+)
+
+// scoreBandFloors is the lowest score of each difficulty level. Most tasks
+// take a few keys and score under 2.5, so the lower levels get narrower
+// bands than an even split would give them.
+var scoreBandFloors = [4]float64{MinDifficultyScore, 1.4, 1.9, 2.5}
+
+// What each signal adds to a task's difficulty score
+const (
+	scorePerKey        = 0.15 // Per key of the optimal solution after the first, not counting typed text
+	scorePerTypedKey   = 0.02 // Per key typed in insert mode
+	scorePerCommand    = 0.4  // Per distinct command after the first
+	scoreCount         = 0.3  // For a count
+	scoreRegister      = 0.3  // For yanking or putting
+	scoreTextObject    = 0.3  // For a text object
+	scorePerLine       = 0.25 // Per line the cursor travels
+	scorePerColumn     = 0.03 // Per column the cursor travels
+	maxTravelScore     = 0.8
+	scoreFailureRate   = 1.5  // At a history of failing every task with the commands
+	scoreHintRate      = 0.75 // At a history of asking for a hint on every one
+	minCommandAttempts = 3    // Attempts with a command before its history counts
+)
+
+// calibrationCandidates is how many tasks a generated round tries for a
+// slot before keeping the one whose score is nearest the slot's band. Each
+// candidate costs a generation and validation, so only one retry is made.
+const calibrationCandidates = 2
+
+// maxCalibrationSessions is how many recent sessions the player's history
+// is taken from
+const maxCalibrationSessions = 50
+
+// CommandHistory is how a player did on tasks practising a command
+type CommandHistory struct {
+	Attempts  int `json:"attempts"`
+	Completed int `json:"completed"`
+	Hinted    int `json:"hinted"` // Attempts where a hint was used
+}
+
+// Calibration is what a player's history adds to difficulty scores. The
+// zero value has no history, and scores tasks from their solutions alone.
+type Calibration struct {
+	Commands map[string]CommandHistory
+}
+
+// CalibrationFromSessions collects the completion and hint history of each
+// command from the tasks of sessions
+func CalibrationFromSessions(sessions []*stats.SessionStats) Calibration {
+	c := Calibration{Commands: make(map[string]CommandHistory)}
+	for _, session := range sessions {
+		for _, task := range session.Tasks {
+			for _, name := range task.Commands {
+				h := c.Commands[name]
+				h.Attempts++
+				if task.Success {
+					h.Completed++
+				}
+				if task.HintsUsed > 0 {
+					h.Hinted++
+				}
+				c.Commands[name] = h
+			}
+		}
+	}
+	return c
+}
+
+// adjustment returns what the history of the commands adds to a score:
+// the more often the player failed or needed hints with them, the harder
+// the task is for them
+func (c Calibration) adjustment(commands []string) float64 {
+	var failures, hints float64
+	known := 0
+	for _, name := range commands {
+		h, ok := c.Commands[name]
+		if !ok || h.Attempts < minCommandAttempts {
+			continue
+		}
+		failures += 1 - float64(h.Completed)/float64(h.Attempts)
+		hints += float64(h.Hinted) / float64(h.Attempts)
+		known++
+	}
+	if known == 0 {
+		return 0
+	}
+	return (scoreFailureRate*failures + scoreHintRate*hints) / float64(known)
+}
+
+// CalibratedDifficulty scores how hard the task is from its optimal
+// solution: its length, how many distinct commands it takes, whether it
+// needs counts, the register or text objects, and how far the cursor
+// travels, adjusted by the player's history with those commands
+func (t *Task) CalibratedDifficulty(c Calibration) float64 {
+	commands := vim.SplitCommands(t.newEngine(), t.OptimalKeySequence(), nil)
+	keys, typed := 0, 0
+	var count, register, object bool
+	for _, cmd := range commands {
+		traits := vim.Traits(cmd.Keys)
+		keys += len(cmd.Keys) - traits.Typed
+		typed += traits.Typed
+		count = count || traits.Count
+		register = register || traits.Register
+		object = object || traits.TextObject
+	}
+
+	names := t.Commands()
+	score := MinDifficultyScore
+	score += scorePerKey*float64(max(keys-1, 0)) + scorePerTypedKey*float64(typed)
+	score += scorePerCommand * float64(max(len(names)-1, 0))
+	if count {
+		score += scoreCount
+	}
+	if register {
+		score += scoreRegister
+	}
+	if object {
+		score += scoreTextObject
+	}
+	score += t.travelScore()
+	score += c.adjustment(names)
+
+	score = math.Min(math.Max(score, MinDifficultyScore), MaxDifficultyScore)
+	return math.Round(score*100) / 100
+}
+
+// travelScore scores how far the cursor has to go: to the target of a
+// motion task, or to the first change of an edit
+func (t *Task) travelScore() float64 {
+	target := t.CursorEnd
+	if !t.IsMotionTask() {
+		target = firstDifference(t.Initial, t.Desired)
+	}
+	from := TextPosition(t.Initial, t.CursorStart)
+	to := TextPosition(t.Initial, target)
+	lines := math.Abs(float64(to.Line - from.Line))
+	cols := math.Abs(float64(to.Col - from.Col))
+	return math.Min(scorePerLine*lines+scorePerColumn*cols, maxTravelScore)
+}
+
+// firstDifference returns the rune index where two texts first differ
+func firstDifference(a, b string) int {
+	i := 0
+	for len(a) > 0 && len(b) > 0 {
+		ra, na := utf8.DecodeRuneInString(a)
+		rb, nb := utf8.DecodeRuneInString(b)
+		if ra != rb {
+			break
+		}
+		a, b = a[na:], b[nb:]
+		i++
+	}
+	return i
+}
+
+// Calibrate sets the task's difficulty score
+func (t *Task) Calibrate(c Calibration) {
+	t.DifficultyScore = t.CalibratedDifficulty(c)
+}
+
+// ScoreBand returns the difficulty scores a difficulty level owns, from lo
+// up to but not including hi, apart from the top level, which includes
+// MaxDifficultyScore
+func ScoreBand(level int) (lo, hi float64) {
+	if level >= 4 {
+		return scoreBandFloors[3], MaxDifficultyScore
+	}
+	return scoreBandFloors[level-1], scoreBandFloors[level]
+}
+
+// ScoreLevel returns the difficulty level whose band a score falls in
+func ScoreLevel(score float64) int {
+	for level := 1; level < 4; level++ {
+		if _, hi := ScoreBand(level); score < hi {
+			return level
+		}
+	}
+	return 4
+}
+
+// bandDistance returns how far a score is outside a level's band, 0 if
+// it's inside
+func bandDistance(score float64, level int) float64 {
+	lo, hi := ScoreBand(level)
+	switch {
+	case score < lo:
+		return lo - score
+	case score >= hi && level < 4:
+		return score - hi + 0.01 // Scores are rounded to hundredths
+	}
+	return 0
+}
@@ -0,0 +1,144 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/timlinux/macaco/internal/stats"
+)
+
+func TestScoreLevel(t *testing.T) {
+	tests := []struct {
+		score float64
+		want  int
+	}{
+		{1.0, 1},
+		{1.39, 1},
+		{1.4, 2},
+		{1.89, 2},
+		{1.9, 3},
+		{2.49, 3},
+		{2.5, 4},
+		{4.0, 4},
+	}
+	for _, tt := range tests {
+		if got := ScoreLevel(tt.score); got != tt.want {
+			t.Errorf("ScoreLevel(%v) = %d, want %d", tt.score, got, tt.want)
+		}
+	}
+	if lo, hi := ScoreBand(2); lo != 1.4 || hi != 1.9 {
+		t.Errorf("ScoreBand(2) = %v, %v, want 1.4, 1.9", lo, hi)
+	}
+	if lo, hi := ScoreBand(4); lo != 2.5 || hi != MaxDifficultyScore {
+		t.Errorf("ScoreBand(4) = %v, %v, want 2.5, %v", lo, hi, MaxDifficultyScore)
+	}
+}
+
+func TestBandDistance(t *testing.T) {
+	tests := []struct {
+		score float64
+		level int
+		want  float64
+	}{
+		{1.2, 1, 0},
+		{1.6, 2, 0},
+		{1.2, 2, 0.2},
+		{1.4, 1, 0.01},
+		{3.0, 2, 1.11},
+		{2.2, 4, 0.3},
+		{4.0, 4, 0},
+	}
+	for _, tt := range tests {
+		got := bandDistance(tt.score, tt.level)
+		if got < tt.want-1e-9 || got > tt.want+1e-9 {
+			t.Errorf("bandDistance(%v, %d) = %v, want %v", tt.score, tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestFirstDifference(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"same", "same", 4},
+		{"one two", "one three", 5},
+		{"abc", "xbc", 0},
+		{"café one", "café two", 5},
+		{"", "x", 0},
+	}
+	for _, tt := range tests {
+		if got := firstDifference(tt.a, tt.b); got != tt.want {
+			t.Errorf("firstDifference(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCalibratedDifficulty(t *testing.T) {
+	tests := []struct {
+		name string
+		task Task
+		want float64
+	}{
+		{"one command", Task{Initial: "one two three", Desired: "one three", CursorStart: 4, OptimalKeys: "dw"}, 1.18},
+		{"count", Task{Initial: "one two three four", Desired: "one four", CursorStart: 4, OptimalKeys: "2dw"}, 1.6},
+		{"text object", Task{Initial: "say (hello)", Desired: "say ()", CursorStart: 6, OptimalKeys: "di("}, 1.63},
+		{"typed", Task{Initial: "one two", Desired: "one three", CursorStart: 4, OptimalKeys: "cwthree<Esc>"}, 1.43},
+		{"register", Task{Initial: "one two", Desired: "two one", CursorStart: 0, OptimalKeys: "dwwP"}, 2.55},
+		{"motion travel", Task{Initial: "one\ntwo\nthree", Desired: "one\ntwo\nthree", CursorStart: 0, CursorEnd: 8, OptimalKeys: "2j"}, 1.95},
+		{"capped", Task{Initial: "a b c d e f g h", Desired: "", CursorStart: 0,
+			OptimalKeys: "dwdwdwdwdwdwdwdwdwdwdwdwdwdwdwdwdwdwdwdwdwdw"}, MaxDifficultyScore},
+	}
+	for _, tt := range tests {
+		if got := tt.task.CalibratedDifficulty(Calibration{}); got != tt.want {
+			t.Errorf("%s: CalibratedDifficulty() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCalibrationHistory(t *testing.T) {
+	session := &stats.SessionStats{Tasks: []*stats.TaskStats{
+		{Commands: []string{"dw"}, Success: true},
+		{Commands: []string{"dw"}, Success: false},
+		{Commands: []string{"dw", "p"}, Success: true, HintsUsed: 1},
+		{Commands: []string{"p"}, Success: false},
+	}}
+	c := CalibrationFromSessions([]*stats.SessionStats{session})
+	if got, want := c.Commands["dw"], (CommandHistory{Attempts: 3, Completed: 2, Hinted: 1}); got != want {
+		t.Errorf("dw history %+v, want %+v", got, want)
+	}
+	if got, want := c.Commands["p"], (CommandHistory{Attempts: 2, Completed: 1, Hinted: 1}); got != want {
+		t.Errorf("p history %+v, want %+v", got, want)
+	}
+
+	task := Task{Initial: "one two three", Desired: "one three", CursorStart: 4, OptimalKeys: "dw"}
+	base := task.CalibratedDifficulty(Calibration{})
+	// dw failed a third of the time and was hinted a third: 1.5/3 + 0.75/3
+	if got := task.CalibratedDifficulty(c); got != base+0.75 {
+		t.Errorf("calibrated %v, want %v", got, base+0.75)
+	}
+
+	// Commands with too few attempts don't count
+	task = Task{Initial: "ab", Desired: "ba", OptimalKeys: "xp"}
+	if got, want := task.CalibratedDifficulty(c), task.CalibratedDifficulty(Calibration{}); got != want {
+		t.Errorf("calibrated %v from p's two attempts, want %v", got, want)
+	}
+}
+
+func TestStartTaskKeepsScore(t *testing.T) {
+	// The round was filled by the score of the generator's solution, so
+	// solving the task when it's played mustn't change it
+	task := validTask()
+	task.OptimalKeys = "xxxx"
+	task.OptimalCount = 4
+	task.Calibrate(Calibration{})
+	score := task.DifficultyScore
+
+	s := NewSession("test", []*Task{&task})
+	s.StartTask()
+	if !task.Solved || task.OptimalKeys != "dw" {
+		t.Fatalf("StartTask() solved the task as %q, want dw", task.OptimalKeys)
+	}
+	if task.DifficultyScore != score {
+		t.Errorf("DifficultyScore = %v after solving, want %v", task.DifficultyScore, score)
+	}
+}
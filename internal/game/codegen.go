@@ -0,0 +1,538 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+)
+
+// codeLineAttempts is how many lines a code generator tries before giving
+// up on finding the structure it needs
+const codeLineAttempts = 5
+
+// Words that code tasks type in
+var (
+	codeNames  = []string{"count", "total", "result", "limit", "items", "name"}
+	codeValues = []string{"debug", "config.yaml", "localhost", "v2", "main"}
+	codeArgs   = []string{"ctx", "opts", "nil", "true", "0"}
+)
+
+// codeKeywords are words that aren't renamed as identifiers
+var codeKeywords = map[string]bool{
+	"func": true, "return": true, "range": true, "defer": true, "make": true,
+	"append": true, "nil": true, "err": true, "def": true, "for": true,
+	"class": true, "with": true, "not": true, "True": true, "False": true,
+	"true": true, "false": true, "lambda": true, "raise": true, "then": true,
+	"done": true, "echo": true, "while": true, "read": true, "type": true,
+}
+
+// codePair is a matched pair of brackets or quotes on a line
+type codePair struct {
+	open, close int // Indexes of the opening and closing character
+}
+
+// inner returns the text between the pair
+func (p codePair) inner(line string) string {
+	return line[p.open+1 : p.close]
+}
+
+// codeSpan is a range of a line, end exclusive
+type codeSpan struct {
+	start, end int
+}
+
+// codeTemplates returns the built-in templates aimed at code structures:
+// brackets, quotes, arguments and identifiers
+func codeTemplates() []TaskTemplate {
+	return []TaskTemplate{
+		codeTemplate(TemplateInfo{
+			Name: "code-match", Category: CategoryMotion,
+			Tags: []string{"motion", "brackets"}, Commands: []string{"%"},
+		}, (*TaskGenerator).codeMotionTask),
+		codeTemplate(TemplateInfo{
+			Name: "code-delete", Category: CategoryDelete,
+			Tags:     []string{"delete", "brackets", "quotes", "arguments", "text-object"},
+			Commands: []string{"di(", "di[", "di{", `di"`, "df", "dt"},
+		}, (*TaskGenerator).codeDeleteTask),
+		codeTemplate(TemplateInfo{
+			Name: "code-change", Category: CategoryChange,
+			Tags:     []string{"change", "identifiers", "quotes", "brackets", "text-object"},
+			Commands: []string{"ciw", `ci"`, "ci(", "ci[", "ci{"},
+		}, (*TaskGenerator).codeChangeTask),
+		codeTemplate(TemplateInfo{
+			Name: "code-insert", Category: CategoryInsert,
+			Tags: []string{"insert", "brackets", "arguments"}, Commands: []string{"a", "i"},
+		}, (*TaskGenerator).codeInsertTask),
+		codeTemplate(TemplateInfo{
+			Name: "code-visual", Category: CategoryVisual,
+			Tags: []string{"visual", "brackets"}, Commands: []string{"vd"},
+		}, (*TaskGenerator).codeVisualTask),
+		codeTemplate(TemplateInfo{
+			Name: "code-complex", Category: CategoryComplex,
+			Tags: []string{"complex", "identifiers", "quotes"}, Commands: []string{"ciw", `ci"`},
+		}, (*TaskGenerator).codeComplexTask),
+	}
+}
+
+// codeTemplate makes a template of a code generator method, which gets a
+// random code line and returns false if the line lacks what it needs. Code
+// templates serve every difficulty and are picked well ahead of the prose
+// templates, which still get code lines now and then.
+func codeTemplate(info TemplateInfo, generate func(g *TaskGenerator, line string, diff int) (Task, bool)) TaskTemplate {
+	info.MinDifficulty, info.MaxDifficulty = 1, 4
+	info.Tags = append([]string{"code"}, info.Tags...)
+	info.Text = TextCode
+	info.Weight = 6
+	return builtinTemplate(info, func(g *TaskGenerator, diff int) (Task, bool) {
+		return g.generateCodeTask(info.Category, diff, generate)
+	})
+}
+
+// generateCodeTask generates a task of the category from a random code
+// line, trying a few lines before giving up
+func (g *TaskGenerator) generateCodeTask(cat TaskCategory, diff int, generate func(g *TaskGenerator, line string, diff int) (Task, bool)) (Task, bool) {
+	sources := g.corpus().CodeSources()
+	if len(sources) == 0 {
+		return Task{}, false
+	}
+
+	for attempt := 0; attempt < codeLineAttempts; attempt++ {
+		source := sources[g.rng.Intn(len(sources))]
+		line, language := source.Sentences[g.rng.Intn(len(source.Sentences))], source.Language
+		if !isASCII(line) {
+			continue
+		}
+
+		if task, ok := generate(g, line, diff); ok {
+			task.Category = cat
+			task.Initial = line
+			task.OptimalCount = len(task.OptimalKeySequence())
+			task.Tags = []string{string(cat), "code", language, "procedural"}
+			return task, true
+		}
+	}
+	return Task{}, false
+}
+
+// codeMotionTask jumps between matching brackets
+func (g *TaskGenerator) codeMotionTask(line string, diff int) (Task, bool) {
+	pairs := bracketPairs(line)
+	if len(pairs) == 0 {
+		return Task{}, false
+	}
+	pair := pairs[g.rng.Intn(len(pairs))]
+
+	task := Task{Difficulty: diff, Desired: line}
+	switch {
+	case diff == 1:
+		task.CursorStart, task.CursorEnd = pair.open, pair.close
+		task.OptimalKeys = "%"
+		task.Description = fmt.Sprintf("Jump to the matching '%c'", line[pair.close])
+		task.Hint = "Use '%' to jump to the matching bracket"
+		task.ID = fmt.Sprintf("gen-code-motion-match-%d", g.rng.Int())
+	case diff == 2:
+		task.CursorStart, task.CursorEnd = pair.close, pair.open
+		task.OptimalKeys = "%"
+		task.Description = fmt.Sprintf("Jump back to the matching '%c'", line[pair.open])
+		task.Hint = "'%' works from closing brackets too"
+		task.ID = fmt.Sprintf("gen-code-motion-match-back-%d", g.rng.Int())
+	default:
+		if pair.open == 0 {
+			return Task{}, false
+		}
+		c := line[pair.open]
+		task.CursorStart, task.CursorEnd = 0, pair.close
+		task.OptimalKeys = fmt.Sprintf("%sf%c%%", countPrefix(findCount(line, c, 1, pair.open)), c)
+		task.Description = fmt.Sprintf("Jump to the end of the '%c' group", c)
+		task.Hint = fmt.Sprintf("Find the '%c' with 'f%c', then '%%' to its match", c, c)
+		task.ID = fmt.Sprintf("gen-code-motion-fmatch-%d", g.rng.Int())
+	}
+	return task, true
+}
+
+// codeDeleteTask empties brackets or quotes, or deletes an argument
+func (g *TaskGenerator) codeDeleteTask(line string, diff int) (Task, bool) {
+	task := Task{Difficulty: diff}
+	switch diff {
+	case 1:
+		pair, ok := g.randomPair(bracketPairs(line))
+		if !ok {
+			return Task{}, false
+		}
+		cursor, ok := g.innerCursor(line, pair)
+		if !ok {
+			return Task{}, false
+		}
+		obj := line[pair.open]
+		task.Desired = line[:pair.open+1] + line[pair.close:]
+		task.CursorStart = cursor
+		task.HighlightStart, task.HighlightEnd = pair.open+1, pair.close
+		task.OptimalKeys = fmt.Sprintf("di%c", obj)
+		task.Description = fmt.Sprintf("Empty the '%c%c'", obj, line[pair.close])
+		task.Hint = fmt.Sprintf("Use 'di%c' to delete inside the brackets", obj)
+		task.ID = fmt.Sprintf("gen-code-delete-di-bracket-%d", g.rng.Int())
+	case 2:
+		pair, ok := g.randomPair(quotedStrings(line))
+		if !ok {
+			return Task{}, false
+		}
+		task.Desired = line[:pair.open+1] + line[pair.close:]
+		task.CursorStart = pair.open + 1 + g.rng.Intn(pair.close-pair.open-1)
+		task.HighlightStart, task.HighlightEnd = pair.open+1, pair.close
+		task.OptimalKeys = `di"`
+		task.Description = "Empty the string"
+		task.Hint = `Use 'di"' to delete inside the quotes`
+		task.ID = fmt.Sprintf("gen-code-delete-di-quote-%d", g.rng.Int())
+	default:
+		pair, args, i, ok := g.randomArgument(line)
+		if !ok {
+			return Task{}, false
+		}
+		arg := args[i]
+		task.CursorStart = arg.start
+		if i < len(args)-1 {
+			// Take the comma and space after the argument with it
+			task.Desired = line[:arg.start] + line[args[i+1].start:]
+			task.HighlightStart, task.HighlightEnd = arg.start, args[i+1].start
+			task.OptimalKeys = "df,x"
+		} else {
+			// Take the comma and space before the last argument, which
+			// dt can only reach if the argument has no closing bracket
+			if strings.IndexByte(line[arg.start:arg.end], line[pair.close]) >= 0 {
+				return Task{}, false
+			}
+			task.Desired = line[:args[i-1].end] + line[arg.end:]
+			task.HighlightStart, task.HighlightEnd = args[i-1].end, arg.end
+			task.OptimalKeys = fmt.Sprintf("F,dt%c", line[pair.close])
+		}
+		task.Description = fmt.Sprintf("Delete the argument '%s'", line[arg.start:arg.end])
+		task.Hint = "Delete the argument with its comma, as with 'df,' then 'x'"
+		task.ID = fmt.Sprintf("gen-code-delete-arg-%d", g.rng.Int())
+	}
+	return task, true
+}
+
+// codeChangeTask renames an identifier or changes inside quotes or brackets
+func (g *TaskGenerator) codeChangeTask(line string, diff int) (Task, bool) {
+	task := Task{Difficulty: diff}
+	switch diff {
+	case 1:
+		idents := identifiers(line)
+		if len(idents) == 0 {
+			return Task{}, false
+		}
+		ident := idents[g.rng.Intn(len(idents))]
+		name := g.pickOther(codeNames, line[ident.start:ident.end])
+		task.Desired = line[:ident.start] + name + line[ident.end:]
+		task.CursorStart = ident.start + (ident.end-ident.start)/2
+		task.HighlightStart, task.HighlightEnd = ident.start, ident.end
+		task.OptimalKeys = fmt.Sprintf("ciw%s<Esc>", name)
+		task.Description = fmt.Sprintf("Rename '%s' to '%s'", line[ident.start:ident.end], name)
+		task.Hint = "Use 'ciw' to change the identifier from anywhere inside it"
+		task.ID = fmt.Sprintf("gen-code-change-rename-%d", g.rng.Int())
+	case 2:
+		pair, ok := g.randomPair(quotedStrings(line))
+		if !ok {
+			return Task{}, false
+		}
+		value := g.pickOther(codeValues, pair.inner(line))
+		task.Desired = line[:pair.open+1] + value + line[pair.close:]
+		task.CursorStart = pair.open + 1 + g.rng.Intn(pair.close-pair.open-1)
+		task.HighlightStart, task.HighlightEnd = pair.open+1, pair.close
+		task.OptimalKeys = fmt.Sprintf(`ci"%s<Esc>`, value)
+		task.Description = fmt.Sprintf("Change the string to \"%s\"", value)
+		task.Hint = `Use 'ci"' to change inside the quotes`
+		task.ID = fmt.Sprintf("gen-code-change-ci-quote-%d", g.rng.Int())
+	default:
+		pair, ok := g.randomPair(bracketPairs(line))
+		if !ok {
+			return Task{}, false
+		}
+		cursor, ok := g.innerCursor(line, pair)
+		if !ok {
+			return Task{}, false
+		}
+		obj := line[pair.open]
+		value := g.pickOther(codeArgs, pair.inner(line))
+		task.Desired = line[:pair.open+1] + value + line[pair.close:]
+		task.CursorStart = cursor
+		task.HighlightStart, task.HighlightEnd = pair.open+1, pair.close
+		task.OptimalKeys = fmt.Sprintf("ci%c%s<Esc>", obj, value)
+		task.Description = fmt.Sprintf("Replace what's inside the '%c%c' with '%s'", obj, line[pair.close], value)
+		task.Hint = fmt.Sprintf("Use 'ci%c' to change inside the brackets", obj)
+		task.ID = fmt.Sprintf("gen-code-change-ci-bracket-%d", g.rng.Int())
+	}
+	return task, true
+}
+
+// codeInsertTask adds an argument at the start or end of brackets
+func (g *TaskGenerator) codeInsertTask(line string, diff int) (Task, bool) {
+	pair, ok := g.randomPair(bracketPairs(line))
+	if !ok {
+		return Task{}, false
+	}
+	arg := codeArgs[g.rng.Intn(len(codeArgs))]
+
+	task := Task{Difficulty: diff, CursorStart: pair.open}
+	if diff == 1 {
+		task.Desired = line[:pair.open+1] + arg + ", " + line[pair.open+1:]
+		task.OptimalKeys = fmt.Sprintf("a%s, <Esc>", arg)
+		task.Description = fmt.Sprintf("Add '%s' first in the '%c%c'", arg, line[pair.open], line[pair.close])
+		task.Hint = "Use 'a' to insert after the bracket under the cursor"
+		task.ID = fmt.Sprintf("gen-code-insert-first-%d", g.rng.Int())
+	} else {
+		task.Desired = line[:pair.close] + ", " + arg + line[pair.close:]
+		task.OptimalKeys = fmt.Sprintf("%%i, %s<Esc>", arg)
+		task.Description = fmt.Sprintf("Add '%s' last in the '%c%c'", arg, line[pair.open], line[pair.close])
+		task.Hint = "Jump to the closing bracket with '%', then insert before it with 'i'"
+		task.ID = fmt.Sprintf("gen-code-insert-last-%d", g.rng.Int())
+	}
+	return task, true
+}
+
+// codeVisualTask selects brackets with their contents and deletes them
+func (g *TaskGenerator) codeVisualTask(line string, diff int) (Task, bool) {
+	pair, ok := g.randomPair(bracketPairs(line))
+	if !ok {
+		return Task{}, false
+	}
+
+	return Task{
+		ID:             fmt.Sprintf("gen-code-visual-match-%d", g.rng.Int()),
+		Difficulty:     max(diff, 2), // Visual is at least level 2
+		Desired:        line[:pair.open] + line[pair.close+1:],
+		CursorStart:    pair.open,
+		HighlightStart: pair.open,
+		HighlightEnd:   pair.close + 1,
+		OptimalKeys:    "v%d",
+		Description:    fmt.Sprintf("Delete the '%c%c' and everything in them", line[pair.open], line[pair.close]),
+		Hint:           "Start a selection with 'v', extend it to the match with '%', then 'd'",
+	}, true
+}
+
+// codeComplexTask renames an identifier and changes the string after it
+func (g *TaskGenerator) codeComplexTask(line string, diff int) (Task, bool) {
+	idents := identifiers(line)
+	strs := quotedStrings(line)
+	for _, i := range g.rng.Perm(len(idents)) {
+		ident := idents[i]
+		for _, str := range strs {
+			if str.open < ident.end {
+				continue
+			}
+
+			name := g.pickOther(codeNames, line[ident.start:ident.end])
+			value := g.pickOther(codeValues, str.inner(line))
+			quotes := findCount(line, '"', ident.end, str.open)
+			return Task{
+				ID:          fmt.Sprintf("gen-code-complex-rename-%d", g.rng.Int()),
+				Difficulty:  max(diff, 3), // Complex is at least level 3
+				Desired:     line[:ident.start] + name + line[ident.end:str.open+1] + value + line[str.close:],
+				CursorStart: ident.start,
+				OptimalKeys: fmt.Sprintf(`ciw%s<Esc>%sf"ci"%s<Esc>`, name, countPrefix(quotes), value),
+				Description: fmt.Sprintf("Rename '%s' to '%s' and set its string to \"%s\"", line[ident.start:ident.end], name, value),
+				Hint:        `Change the identifier with 'ciw', find the string with 'f"', then 'ci"'`,
+			}, true
+		}
+	}
+	return Task{}, false
+}
+
+// randomPair picks a pair with something between its characters
+func (g *TaskGenerator) randomPair(pairs []codePair) (codePair, bool) {
+	var filled []codePair
+	for _, p := range pairs {
+		if p.close-p.open > 1 {
+			filled = append(filled, p)
+		}
+	}
+	if len(filled) == 0 {
+		return codePair{}, false
+	}
+	return filled[g.rng.Intn(len(filled))], true
+}
+
+// randomArgument picks an argument from brackets holding several
+func (g *TaskGenerator) randomArgument(line string) (codePair, []codeSpan, int, bool) {
+	pairs := bracketPairs(line)
+	for _, i := range g.rng.Perm(len(pairs)) {
+		if args := arguments(line, pairs[i]); len(args) >= 2 {
+			return pairs[i], args, g.rng.Intn(len(args)), true
+		}
+	}
+	return codePair{}, nil, 0, false
+}
+
+// innerCursor picks a cursor position inside a pair from which i( and
+// friends act on that pair, leaving out nested brackets of the same kind
+func (g *TaskGenerator) innerCursor(line string, pair codePair) (int, bool) {
+	var spots []int
+	depth := 0
+	for i := pair.open + 1; i < pair.close; i++ {
+		switch line[i] {
+		case line[pair.open]:
+			depth++
+		case line[pair.close]:
+			depth--
+		default:
+			if depth == 0 {
+				spots = append(spots, i)
+			}
+		}
+	}
+	if len(spots) == 0 {
+		return 0, false
+	}
+	return spots[g.rng.Intn(len(spots))], true
+}
+
+// pickOther picks a word other than current
+func (g *TaskGenerator) pickOther(words []string, current string) string {
+	word := words[g.rng.Intn(len(words))]
+	if word == current {
+		word = words[(indexOf(words, word)+1)%len(words)]
+	}
+	return word
+}
+
+// indexOf returns the index of s in list, or -1
+func indexOf(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// bracketPairs returns the matching (), [] and {} pairs of a line, as '%'
+// pairs them
+func bracketPairs(line string) []codePair {
+	var pairs []codePair
+	var stack []int
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; c {
+		case '(', '[', '{':
+			stack = append(stack, i)
+		case ')', ']', '}':
+			if len(stack) == 0 {
+				continue
+			}
+			open := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if closingBracket(line[open]) == c {
+				pairs = append(pairs, codePair{open: open, close: i})
+			}
+		}
+	}
+	return pairs
+}
+
+// closingBracket returns the bracket closing open
+func closingBracket(open byte) byte {
+	switch open {
+	case '(':
+		return ')'
+	case '[':
+		return ']'
+	}
+	return '}'
+}
+
+// quotedStrings returns the non-empty double quoted strings of a line,
+// pairing quotes in order as 'i"' does
+func quotedStrings(line string) []codePair {
+	var pairs []codePair
+	open := -1
+	for i := 0; i < len(line); i++ {
+		if line[i] != '"' {
+			continue
+		}
+		if open < 0 {
+			open = i
+			continue
+		}
+		if i-open > 1 {
+			pairs = append(pairs, codePair{open: open, close: i})
+		}
+		open = -1
+	}
+	return pairs
+}
+
+// identifiers returns the identifiers of a line worth renaming: three or
+// more word characters, not keywords, outside strings
+func identifiers(line string) []codeSpan {
+	strs := quotedStrings(line)
+	inString := func(i int) bool {
+		for _, s := range strs {
+			if i > s.open && i < s.close {
+				return true
+			}
+		}
+		return false
+	}
+
+	var idents []codeSpan
+	for i := 0; i < len(line); {
+		if !isWordByte(line[i]) {
+			i++
+			continue
+		}
+		start := i
+		for i < len(line) && isWordByte(line[i]) {
+			i++
+		}
+		word := line[start:i]
+		if len(word) >= 3 && !isDigit(word[0]) && !codeKeywords[word] && !inString(start) {
+			idents = append(idents, codeSpan{start: start, end: i})
+		}
+	}
+	return idents
+}
+
+// arguments splits what's inside brackets at ", " into arguments. Nothing
+// is returned unless every argument is separated by exactly ", " and holds
+// no comma of its own, so deleting one leaves tidy code.
+func arguments(line string, pair codePair) []codeSpan {
+	inner := pair.inner(line)
+	if inner == "" || strings.Count(inner, ",") != strings.Count(inner, ", ") {
+		return nil
+	}
+	for _, p := range bracketPairs(inner) {
+		if strings.Contains(inner[p.open:p.close], ",") {
+			return nil
+		}
+	}
+
+	var args []codeSpan
+	start := pair.open + 1
+	for _, part := range strings.Split(inner, ", ") {
+		if strings.TrimSpace(part) != part || part == "" || strings.Count(part, `"`)%2 != 0 {
+			return nil
+		}
+		args = append(args, codeSpan{start: start, end: start + len(part)})
+		start += len(part) + len(", ")
+	}
+	return args
+}
+
+// isWordByte returns true for the characters 'w' and 'iw' treat as a word
+func isWordByte(c byte) bool {
+	return isLetter(c) || isDigit(c) || c == '_'
+}
+
+// isDigit returns true for 0 to 9
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isASCII returns true if every character of s is ASCII, so byte and
+// character indexes agree
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,137 @@
+package game
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBracketPairs(t *testing.T) {
+	tests := []struct {
+		line string
+		want []codePair
+	}{
+		{"no brackets", nil},
+		{"f(x)", []codePair{{1, 3}}},
+		{"a[b(c)]", []codePair{{3, 5}, {1, 6}}},
+		{"{ x }", []codePair{{0, 4}}},
+		{"f(x]", nil},
+		{") stray (", nil},
+	}
+	for _, tt := range tests {
+		if got := bracketPairs(tt.line); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("bracketPairs(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestQuotedStrings(t *testing.T) {
+	tests := []struct {
+		line string
+		want []codePair
+	}{
+		{"no quotes", nil},
+		{`say("hi")`, []codePair{{4, 7}}},
+		{`"a" + "bc"`, []codePair{{0, 2}, {6, 9}}},
+		{`"" + "x"`, []codePair{{5, 7}}},
+		{`"unclosed`, nil},
+	}
+	for _, tt := range tests {
+		if got := quotedStrings(tt.line); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("quotedStrings(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestIdentifiers(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"total += scores[name]", []string{"total", "scores", "name"}},
+		{"for i, item := range items {", []string{"item", "items"}},
+		{`log.Printf("loaded %d", count)`, []string{"log", "Printf", "count"}},
+		{"x = 123abc", nil},
+		{"return nil, err", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, span := range identifiers(tt.line) {
+			got = append(got, tt.line[span.start:span.end])
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("identifiers(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestArguments(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"go worker(ctx, jobs, results)", []string{"ctx", "jobs", "results"}},
+		{"f(a)", []string{"a"}},
+		{"f()", nil},
+		{"f(a,b)", nil},
+		{"f(a,  b)", nil},
+		{"f(g(a, b), c)", nil},
+		{`f("a, b", c)`, nil},
+		{"f(g(a), b)", []string{"g(a)", "b"}},
+	}
+	for _, tt := range tests {
+		pairs := bracketPairs(tt.line)
+		outer := pairs[len(pairs)-1]
+		var got []string
+		for _, span := range arguments(tt.line, outer) {
+			got = append(got, tt.line[span.start:span.end])
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("arguments(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestCodeSources(t *testing.T) {
+	languages := make(map[string]bool)
+	for _, source := range GetCodeSources() {
+		if source.Language == "" || source.License == "" || len(source.Sentences) == 0 {
+			t.Errorf("source %q lacks a language, license or lines", source.Name)
+		}
+		if languages[source.Language] {
+			t.Errorf("two sources of %s", source.Language)
+		}
+		languages[source.Language] = true
+	}
+}
+
+func TestCodeTemplates(t *testing.T) {
+	corpus := Corpus{Text: TextCode, Sources: GetCodeSources()}
+	for _, template := range codeTemplates() {
+		info := template.Info()
+		if info.Text != TextCode {
+			t.Errorf("%s: text %q, want code", info.Name, info.Text)
+		}
+		g := NewSeededTaskGenerator(1)
+		for diff := 1; diff <= 4; diff++ {
+			made := 0
+			for i := 0; i < 20; i++ {
+				task, err := g.generateFrom(template, corpus, diff)
+				if errors.Is(err, ErrTemplateNoGeneration) {
+					continue
+				} else if err != nil {
+					t.Errorf("%s at difficulty %d: %v", info.Name, diff, err)
+					continue
+				}
+				made++
+				if !strings.Contains(strings.Join(task.Tags, " "), "code") {
+					t.Errorf("%s: task tags %v lack code", info.Name, task.Tags)
+				}
+			}
+			if made == 0 {
+				t.Errorf("%s made no tasks at difficulty %d", info.Name, diff)
+			}
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package game
+
+// Kinds of text that generated tasks are built from
+const (
+	TextProse = "prose" // Sentences from public domain books
+	TextCode  = "code"  // Lines of source code
+	TextLocal = "local" // The player's own files, see LoadLocalSources
+	TextBooks = "books" // Books the player imported, see LoadImportedSources
+)
+
+// ErrUnknownText is returned for a text kind other than prose, code, local
+// or books
+const ErrUnknownText GameError = "text should be prose, code, local or books"
+
+// ErrNoBooks is returned for books when no books are imported
+const ErrNoBooks GameError = "no imported books; add Project Gutenberg .txt books to the sources directory"
+
+// Languages of the built-in code sources
+const (
+	LanguageGo     = "go"
+	LanguagePython = "python"
+	LanguageJSON   = "json"
+	LanguageShell  = "shell"
+	LanguageHTML   = "html"
+)
+
+// codeAttribution credits the built-in code snippets
+const codeAttribution = "Code snippets written for MoCaCo, MIT License"
+
+// IsTextKind returns true if text is a kind tasks can be generated from
+func IsTextKind(text string) bool {
+	return text == TextProse || text == TextCode || text == TextLocal || text == TextBooks
+}
+
+// GetCodeSources returns the built-in code sources, one per language. Each
+// line is a complete line of code, so tasks made from it look like edits
+// to real files: brackets, quotes, operators and indentation included.
+func GetCodeSources() []TextSource {
+	return []TextSource{
+		{
+			Name:        "Go snippets",
+			Language:    LanguageGo,
+			License:     "MIT",
+			Attribution: codeAttribution,
+			Sentences: []string{
+				`func parseConfig(path string, strict bool) (*Config, error) {`,
+				`data, err := os.ReadFile(filepath.Join(dir, "config.json"))`,
+				`if err != nil { return nil, fmt.Errorf("read %s: %w", path, err) }`,
+				`users := make(map[string]*User, len(names))`,
+				`for i, item := range items[start:end] {`,
+				`log.Printf("loaded %d tasks from %s", count, path)`,
+				`ctx, cancel := context.WithTimeout(ctx, 5*time.Second)`,
+				`return strings.Join(parts[1:], ", "), nil`,
+				`srv := &http.Server{Addr: addr, Handler: mux}`,
+				`if len(args) < 2 || args[0] != "run" {`,
+				`total += scores[player.Name] * weights[level]`,
+				`defer file.Close()`,
+				`result = append(result, Task{ID: id, Difficulty: level})`,
+				`err = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})`,
+				`go worker(ctx, jobs, results)`,
+			},
+		},
+		{
+			Name:        "Python snippets",
+			Language:    LanguagePython,
+			License:     "MIT",
+			Attribution: codeAttribution,
+			Sentences: []string{
+				`def load_scores(path, limit=10, reverse=True):`,
+				`    with open(os.path.join(base_dir, "scores.csv")) as handle:`,
+				`    names = [user.name for user in users if user.active]`,
+				`    print(f"saved {count} rows to {path}")`,
+				`    config = {"host": "localhost", "port": 8080, "debug": False}`,
+				`    total = sum(values[start:end]) / max(len(values), 1)`,
+				`    raise ValueError("expected a list, got %s" % type(data))`,
+				`    return sorted(items, key=lambda item: item["score"], reverse=True)`,
+				`    parser.add_argument("--round", default="beginner", help="round type")`,
+				`    if not isinstance(value, (int, float)):`,
+				`    result = requests.get(url, params={"page": page}, timeout=5)`,
+				`class Player(Base):`,
+				`    self.history.append((task_id, elapsed, keys))`,
+				`    for index, line in enumerate(lines, start=1):`,
+				`    logger.info("round %s finished in %.1fs", round_type, elapsed)`,
+			},
+		},
+		{
+			Name:        "JSON snippets",
+			Language:    LanguageJSON,
+			License:     "MIT",
+			Attribution: codeAttribution,
+			Sentences: []string{
+				`{"id": "motion-001", "category": "motion", "difficulty": 1}`,
+				`  "name": "macaco",`,
+				`  "version": "1.2.0",`,
+				`  "tags": ["vim", "motions", "practice"],`,
+				`  "scripts": {"build": "make build", "test": "go test ./..."},`,
+				`  "theme": {"primary": "#7aa2f7", "error": "#f7768e"},`,
+				`  "rounds": {"beginner": {"difficulty_range": [1, 1]}},`,
+				`  "author": {"name": "Ada Lovelace", "email": "ada@example.com"},`,
+				`  "ports": [8080, 8443, 9090],`,
+				`  "enabled": true, "retries": 3, "timeout": "30s",`,
+				`{"event": "login", "user": "alice", "ok": true}`,
+				`  "dependencies": {"lipgloss": "^0.9.1", "bubbletea": "^0.25.0"},`,
+			},
+		},
+		{
+			Name:        "Shell snippets",
+			Language:    LanguageShell,
+			License:     "MIT",
+			Attribution: codeAttribution,
+			Sentences: []string{
+				`for file in "$DIR"/*.json; do`,
+				`if [ -z "$GOPATH" ]; then export GOPATH="$HOME/go"; fi`,
+				`tar -czf "backup-$(date +%Y%m%d).tar.gz" "$SRC_DIR"`,
+				`grep -rn "TODO" src/ | sort | uniq -c`,
+				`docker run --rm -v "$(pwd):/src" -p 8080:8080 macaco:latest`,
+				`echo "Deploying $VERSION to $TARGET"`,
+				`find . -name "*.log" -mtime +7 -exec rm {} \;`,
+				`curl -s "https://api.example.com/v1/rounds" | jq '.rounds[]'`,
+				`while read -r name score; do echo "$name: $score"; done < scores.txt`,
+				`ssh -i ~/.ssh/deploy "$USER@$HOST" "systemctl restart macaco"`,
+				`git log --oneline --since="2 weeks ago" -- internal/`,
+				`count=$(wc -l < "$INPUT")`,
+			},
+		},
+		{
+			Name:        "HTML snippets",
+			Language:    LanguageHTML,
+			License:     "MIT",
+			Attribution: codeAttribution,
+			Sentences: []string{
+				`<a href="/docs/rounds.html" class="nav-link">Rounds</a>`,
+				`<img src="images/logo.png" alt="MoCaCo logo" width="64">`,
+				`<input type="text" name="username" placeholder="Your name" required>`,
+				`<div class="card" id="stats-panel" data-round="mixed">`,
+				`<button type="submit" class="btn btn-primary">Start round</button>`,
+				`<link rel="stylesheet" href="css/theme.css">`,
+				`<script src="js/app.js" defer></script>`,
+				`<li class="task done"><span>dw</span> delete word</li>`,
+				`<meta name="viewport" content="width=device-width, initial-scale=1">`,
+				`<td class="score">{{ .Score }}</td>`,
+				`<form action="/api/v1/sessions" method="post">`,
+				`<label for="round">Round type</label>`,
+			},
+		},
+	}
+}
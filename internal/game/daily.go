@@ -0,0 +1,73 @@
+package game
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+
+	"github.com/timlinux/macaco/internal/stats"
+)
+
+// RoundDaily is the round type of the daily challenge
+const RoundDaily = "daily"
+
+// DailyChallengeVersion is mixed into the daily seed. Bump it whenever a
+// change to the generator would make installs disagree about a day's
+// tasks, so that old and new versions don't share a challenge by accident.
+const DailyChallengeVersion = 5
+
+// ErrDailyAlreadyPlayed is returned when today's challenge was attempted
+const ErrDailyAlreadyPlayed GameError = "today's daily challenge has already been played"
+
+// dailyEpoch is the day of daily challenge #1
+var dailyEpoch = time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// DailyChallenge identifies the challenge of one UTC day
+type DailyChallenge struct {
+	Date    string `json:"date"`   // UTC date, YYYY-MM-DD
+	Number  int    `json:"number"` // Days since the first challenge, counting from 1
+	Version int    `json:"version"`
+	Seed    int64  `json:"seed"`
+}
+
+// DailyStatus is today's challenge and the player's daily record
+type DailyStatus struct {
+	Challenge DailyChallenge   `json:"challenge"`
+	Played    bool             `json:"played"` // Today's one attempt has been used
+	Stats     stats.DailyStats `json:"stats"`
+}
+
+// DailyChallengeFor returns the challenge of the UTC day containing t
+func DailyChallengeFor(t time.Time) DailyChallenge {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	date := day.Format("2006-01-02")
+
+	h := fnv.New64a()
+	fmt.Fprintf(h, "macaco-daily-v%d-%s", DailyChallengeVersion, date)
+
+	return DailyChallenge{
+		Date:    date,
+		Number:  int(day.Sub(dailyEpoch).Hours()/24) + 1,
+		Version: DailyChallengeVersion,
+		Seed:    int64(h.Sum64()),
+	}
+}
+
+// String returns the challenge as shown to players, "Daily #42 (2026-02-11)"
+func (c DailyChallenge) String() string {
+	return fmt.Sprintf("Daily #%d (%s)", c.Number, c.Date)
+}
+
+// GenerateDailyTasks generates the tasks of a daily challenge. The
+// generator is seeded from the challenge and only uses the built-in texts,
+// so every install gets the same tasks offline. Tasks get harder as the
+// round goes on, by difficulty score.
+func GenerateDailyTasks(challenge DailyChallenge) []Task {
+	tasks := NewSeededTaskGenerator(challenge.Seed).GenerateTasksForRound("mixed")
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].DifficultyScore < tasks[j].DifficultyScore
+	})
+	return tasks
+}
@@ -0,0 +1,71 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDailyChallengeFor(t *testing.T) {
+	morning := time.Date(2026, 2, 11, 0, 30, 0, 0, time.UTC)
+	evening := time.Date(2026, 2, 11, 23, 59, 0, 0, time.UTC)
+	nextDay := time.Date(2026, 2, 12, 0, 0, 0, 0, time.UTC)
+	// Still the 11th in UTC
+	newYork := time.Date(2026, 2, 10, 22, 0, 0, 0, time.FixedZone("EST", -5*3600))
+
+	tests := []struct {
+		at         time.Time
+		wantDate   string
+		wantNumber int
+	}{
+		{dailyEpoch, "2026-01-01", 1},
+		{morning, "2026-02-11", 42},
+		{evening, "2026-02-11", 42},
+		{newYork, "2026-02-11", 42},
+		{nextDay, "2026-02-12", 43},
+	}
+	for _, tt := range tests {
+		c := DailyChallengeFor(tt.at)
+		if c.Date != tt.wantDate || c.Number != tt.wantNumber || c.Version != DailyChallengeVersion {
+			t.Errorf("DailyChallengeFor(%v) = %+v, want %s #%d", tt.at, c, tt.wantDate, tt.wantNumber)
+		}
+	}
+
+	if DailyChallengeFor(morning).Seed != DailyChallengeFor(evening).Seed {
+		t.Error("one day has two seeds")
+	}
+	if DailyChallengeFor(morning).Seed == DailyChallengeFor(nextDay).Seed {
+		t.Error("two days share a seed")
+	}
+	if got := DailyChallengeFor(morning).String(); got != "Daily #42 (2026-02-11)" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestGenerateDailyTasks(t *testing.T) {
+	challenge := DailyChallengeFor(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC))
+	first := GenerateDailyTasks(challenge)
+	second := GenerateDailyTasks(challenge)
+
+	if len(first) == 0 {
+		t.Fatal("no daily tasks")
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Error("a challenge gave different tasks twice")
+	}
+	for i := 1; i < len(first); i++ {
+		if first[i].DifficultyScore < first[i-1].DifficultyScore {
+			t.Errorf("task %d scores %v after %v; want easiest first", i, first[i].DifficultyScore, first[i-1].DifficultyScore)
+		}
+	}
+	for _, task := range first {
+		if err := task.Validate(); err != nil {
+			t.Errorf("daily task doesn't validate: %v", err)
+		}
+	}
+
+	other := GenerateDailyTasks(DailyChallengeFor(time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)))
+	if reflect.DeepEqual(first, other) {
+		t.Error("two days gave the same tasks")
+	}
+}
@@ -0,0 +1,261 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/timlinux/macaco/internal/solver"
+	"github.com/timlinux/macaco/internal/stats"
+)
+
+// RoundDrill is the round type of drills: tasks of one kind until the
+// player has mastered it
+const RoundDrill = "drill"
+
+// Mastery a drill asks for unless it says otherwise
+const (
+	DefaultDrillSolves     = 5
+	DefaultDrillEfficiency = 90.0
+)
+
+// drillQueue is how many tasks a drill keeps ready, the current one and
+// the next one to preview
+const drillQueue = 2
+
+// Drill errors
+const (
+	ErrDrillCategory   GameError = "drill category is unknown"
+	ErrDrillDifficulty GameError = "drill difficulty should be 1 to 4, or 0 for any"
+	ErrDrillMastery    GameError = "drill mastery should be 1 to 100 clean solves at 0 to 100% efficiency"
+	ErrDrillNoTasks    GameError = "no task template matches the drill"
+	ErrNotDrill        GameError = "session is not a drill"
+)
+
+// DrillSpec says what a drill practises and when it's mastered. Tasks
+// match every filter that is set: the category, a tag of the template
+// that made them, and at least one of the commands, named as
+// Task.Commands names them.
+type DrillSpec struct {
+	Category   TaskCategory `json:"category,omitempty"`
+	Tag        string       `json:"tag,omitempty"`
+	Commands   []string     `json:"commands,omitempty"`
+	Difficulty int          `json:"difficulty,omitempty"` // 0 for any the templates serve
+	Text       string       `json:"text,omitempty"`       // TextProse, TextCode, TextLocal or TextBooks; prose if empty
+
+	// The drill is mastered after Solves clean solves in a row: solved at
+	// Efficiency or better, without hints or resets
+	Solves     int     `json:"solves,omitempty"`
+	Efficiency float64 `json:"efficiency,omitempty"`
+}
+
+// withDefaults returns the spec with the default mastery filled in
+func (d DrillSpec) withDefaults() DrillSpec {
+	if d.Solves == 0 {
+		d.Solves = DefaultDrillSolves
+	}
+	if d.Efficiency == 0 {
+		d.Efficiency = DefaultDrillEfficiency
+	}
+	return d
+}
+
+// validate checks the spec's filters and mastery
+func (d DrillSpec) validate() error {
+	switch {
+	case d.Category != "" && !isRoundCategory(d.Category):
+		return ErrDrillCategory
+	case d.Difficulty < 0 || d.Difficulty > 4:
+		return ErrDrillDifficulty
+	case d.Text != "" && !IsTextKind(d.Text):
+		return ErrUnknownText
+	case d.Solves < 1 || d.Solves > 100 || d.Efficiency < 0 || d.Efficiency > 100:
+		return ErrDrillMastery
+	}
+	return nil
+}
+
+// String describes what the drill practises, such as "commands f, t"
+func (d DrillSpec) String() string {
+	var parts []string
+	if d.Category != "" {
+		parts = append(parts, string(d.Category))
+	}
+	if d.Tag != "" {
+		parts = append(parts, "tag "+d.Tag)
+	}
+	if len(d.Commands) > 0 {
+		parts = append(parts, "commands "+strings.Join(d.Commands, ", "))
+	}
+	if d.Difficulty > 0 {
+		parts = append(parts, fmt.Sprintf("level %d", d.Difficulty))
+	}
+	if len(parts) == 0 {
+		return "any task"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Goal describes the drill's mastery criterion
+func (d DrillSpec) Goal() string {
+	return fmt.Sprintf("%d clean solves in a row at %.0f%%+", d.Solves, d.Efficiency)
+}
+
+// matchesTemplate returns true if a template makes tasks the drill wants
+func (d DrillSpec) matchesTemplate(info TemplateInfo) bool {
+	if d.Category != "" && info.Category != d.Category {
+		return false
+	}
+	if d.Tag != "" && !info.HasTag(d.Tag) {
+		return false
+	}
+	if d.Difficulty > 0 && (d.Difficulty < info.MinDifficulty || d.Difficulty > info.MaxDifficulty) {
+		return false
+	}
+	return len(d.Commands) == 0 || sharesCommand(d.Commands, info.Commands)
+}
+
+// matchesTask returns true if a task's optimal solution uses one of the
+// drill's commands, as optimising may find one without them
+func (d DrillSpec) matchesTask(task Task) bool {
+	return len(d.Commands) == 0 || sharesCommand(d.Commands, task.Commands())
+}
+
+// sharesCommand returns true if the lists have a command in common
+func sharesCommand(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Drill is a drill's spec and how the player is doing on it
+type Drill struct {
+	DrillSpec
+	Streak   int  `json:"streak"` // Clean solves in a row
+	Clean    int  `json:"clean"`  // Clean solves in all
+	Mastered bool `json:"mastered"`
+}
+
+// IsClean returns true if a result counts toward mastering the drill
+func (d *Drill) IsClean(result TaskResult) bool {
+	return result.Success && result.HintsUsed == 0 && result.Resets == 0 && result.Efficiency >= d.Efficiency
+}
+
+// record counts a task's result toward mastery
+func (d *Drill) record(result TaskResult) {
+	if d.IsClean(result) {
+		d.Streak++
+		d.Clean++
+	} else {
+		d.Streak = 0
+	}
+	d.Mastered = d.Streak >= d.Solves
+}
+
+// GenerateDrillTask generates a task for a drill from the templates that
+// match it, at the drill's difficulty or one the template serves
+func (g *TaskGenerator) GenerateDrillTask(spec DrillSpec) (Task, bool) {
+	text := g.Text()
+	if spec.Text != "" {
+		g.SetText(spec.Text)
+	} else {
+		g.SetText(TextProse)
+	}
+	defer g.SetText(text)
+
+	corpus := g.corpus()
+	var templates []TaskTemplate
+	for _, t := range g.templates.Templates() {
+		if info := t.Info(); spec.matchesTemplate(info) && corpus.Suits(info.Text) {
+			templates = append(templates, t)
+		}
+	}
+	if len(templates) == 0 {
+		g.problems = append(g.problems, fmt.Errorf("drill of %s: %w", spec, ErrDrillNoTasks))
+		return Task{}, false
+	}
+
+	var err error
+	for attempt := 0; attempt < maxGenerateAttempts*2; attempt++ {
+		template := pickTemplate(g.rng, templates)
+		info := template.Info()
+		diff := spec.Difficulty
+		if diff == 0 {
+			diff = g.randomDifficulty(info.MinDifficulty, info.MaxDifficulty)
+		}
+		var task Task
+		if task, err = g.generateFrom(template, corpus, diff); err == nil {
+			// Drills match on the optimal solution, so it's solved now
+			task.Optimize(solver.DefaultOptions())
+			task.Calibrate(g.calibration)
+			if spec.matchesTask(task) {
+				return task, true
+			}
+			err = &TemplateError{Template: info.Name, Err: ErrTemplateNoCommands}
+		}
+	}
+
+	g.problems = append(g.problems, err)
+	return Task{}, false
+}
+
+// IsDrill returns true if the session is a drill
+func (s *Session) IsDrill() bool {
+	return s.Drill != nil
+}
+
+// recordDrill counts a finished task toward the drill and keeps tasks
+// queued until it's mastered. It returns true if the drill is over.
+func (s *Session) recordDrill(result TaskResult) bool {
+	s.Drill.record(result)
+	if s.Drill.Mastered {
+		s.Tasks = s.Tasks[:len(s.TaskResults)]
+		s.TotalTasks = len(s.Tasks)
+		return true
+	}
+	for len(s.Tasks)-len(s.TaskResults) < drillQueue && s.nextDrillTask != nil {
+		task, ok := s.nextDrillTask()
+		if !ok {
+			break
+		}
+		s.Tasks = append(s.Tasks, &task)
+	}
+	s.TotalTasks = len(s.Tasks)
+	return false
+}
+
+// EndDrill stops a drill before it's mastered. The task in play is left
+// out of the results.
+func (s *Session) EndDrill() {
+	if s.Drill == nil || s.IsComplete() {
+		return
+	}
+	s.Tasks = s.Tasks[:len(s.TaskResults)]
+	s.TotalTasks = len(s.Tasks)
+	s.end()
+}
+
+// drillStats makes the stats record of a finished drill from its session
+// stats
+func drillStats(session *Session, sessionStats *stats.SessionStats) *stats.DrillStats {
+	drill := session.Drill
+	return &stats.DrillStats{
+		SessionID:     session.ID,
+		Drill:         drill.String(),
+		Category:      string(drill.Category),
+		Tag:           drill.Tag,
+		Commands:      drill.Commands,
+		Goal:          drill.Goal(),
+		Mastered:      drill.Mastered,
+		CleanSolves:   drill.Clean,
+		StartedAt:     sessionStats.StartedAt,
+		CompletedAt:   sessionStats.CompletedAt,
+		TotalTimeMs:   sessionStats.TotalTimeMs,
+		AvgEfficiency: sessionStats.AvgEfficiency,
+		Tasks:         sessionStats.Tasks,
+	}
+}
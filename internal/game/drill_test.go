@@ -0,0 +1,258 @@
+package game
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/timlinux/macaco/internal/vim"
+)
+
+func TestDrillSpecValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		spec DrillSpec
+		want error
+	}{
+		{"any task", DrillSpec{}, nil},
+		{"everything set", DrillSpec{Category: CategoryDelete, Tag: "word", Commands: []string{"dw"}, Difficulty: 2, Text: TextCode, Solves: 3, Efficiency: 80}, nil},
+		{"unknown category", DrillSpec{Category: "teleport"}, ErrDrillCategory},
+		{"difficulty too low", DrillSpec{Difficulty: -1}, ErrDrillDifficulty},
+		{"difficulty too high", DrillSpec{Difficulty: 5}, ErrDrillDifficulty},
+		{"unknown text", DrillSpec{Text: "poetry"}, ErrUnknownText},
+		{"too many solves", DrillSpec{Solves: 101}, ErrDrillMastery},
+		{"negative solves", DrillSpec{Solves: -1}, ErrDrillMastery},
+		{"efficiency too high", DrillSpec{Efficiency: 101}, ErrDrillMastery},
+		{"negative efficiency", DrillSpec{Efficiency: -5}, ErrDrillMastery},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.spec.withDefaults().validate(); !errors.Is(err, tt.want) {
+				t.Errorf("validate() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDrillSpecDefaults(t *testing.T) {
+	spec := DrillSpec{}.withDefaults()
+	if spec.Solves != DefaultDrillSolves || spec.Efficiency != DefaultDrillEfficiency {
+		t.Errorf("withDefaults() = %+v, want the default mastery", spec)
+	}
+	spec = DrillSpec{Solves: 3, Efficiency: 50}.withDefaults()
+	if spec.Solves != 3 || spec.Efficiency != 50 {
+		t.Errorf("withDefaults() = %+v, want the spec's own mastery", spec)
+	}
+}
+
+func TestDrillSpecString(t *testing.T) {
+	tests := []struct {
+		spec DrillSpec
+		want string
+	}{
+		{DrillSpec{}, "any task"},
+		{DrillSpec{Commands: []string{"f", "t"}}, "commands f, t"},
+		{DrillSpec{Category: CategoryChange, Tag: "word", Difficulty: 3}, "change, tag word, level 3"},
+	}
+	for _, tt := range tests {
+		if got := tt.spec.String(); got != tt.want {
+			t.Errorf("String(%+v) = %q, want %q", tt.spec, got, tt.want)
+		}
+	}
+	if got, want := (DrillSpec{}).withDefaults().Goal(), "5 clean solves in a row at 90%+"; got != want {
+		t.Errorf("Goal() = %q, want %q", got, want)
+	}
+}
+
+func TestDrillSpecMatchesTemplate(t *testing.T) {
+	info := TemplateInfo{
+		Category:      CategoryDelete,
+		Tags:          []string{"delete", "word"},
+		Commands:      []string{"dw", "x"},
+		MinDifficulty: 1,
+		MaxDifficulty: 2,
+	}
+	tests := []struct {
+		name string
+		spec DrillSpec
+		want bool
+	}{
+		{"any", DrillSpec{}, true},
+		{"category", DrillSpec{Category: CategoryDelete}, true},
+		{"other category", DrillSpec{Category: CategoryChange}, false},
+		{"tag", DrillSpec{Tag: "word"}, true},
+		{"other tag", DrillSpec{Tag: "find"}, false},
+		{"one of the commands", DrillSpec{Commands: []string{"f", "x"}}, true},
+		{"other commands", DrillSpec{Commands: []string{"f", "t"}}, false},
+		{"difficulty served", DrillSpec{Difficulty: 2}, true},
+		{"difficulty not served", DrillSpec{Difficulty: 3}, false},
+		{"every filter", DrillSpec{Category: CategoryDelete, Tag: "delete", Commands: []string{"dw"}, Difficulty: 1}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.spec.matchesTemplate(info); got != tt.want {
+				t.Errorf("matchesTemplate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDrillRecord(t *testing.T) {
+	clean := TaskResult{Success: true, Efficiency: 100}
+	tests := []struct {
+		name   string
+		result TaskResult
+		want   bool
+	}{
+		{"clean", clean, true},
+		{"at the efficiency", TaskResult{Success: true, Efficiency: 90}, true},
+		{"below the efficiency", TaskResult{Success: true, Efficiency: 89}, false},
+		{"failed", TaskResult{Efficiency: 100}, false},
+		{"hint", TaskResult{Success: true, Efficiency: 100, HintsUsed: 1}, false},
+		{"reset", TaskResult{Success: true, Efficiency: 100, Resets: 1}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Drill{DrillSpec: DrillSpec{Solves: 1}.withDefaults()}
+			if got := d.IsClean(tt.result); got != tt.want {
+				t.Errorf("IsClean() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// A result that isn't clean starts the streak again
+	d := &Drill{DrillSpec: DrillSpec{Solves: 3}.withDefaults()}
+	for i, result := range []TaskResult{clean, clean, {}, clean, clean} {
+		d.record(result)
+		if d.Mastered {
+			t.Fatalf("mastered after %d results", i+1)
+		}
+	}
+	if d.Streak != 2 || d.Clean != 4 {
+		t.Errorf("streak %d of %d clean, want 2 of 4", d.Streak, d.Clean)
+	}
+	d.record(clean)
+	if !d.Mastered || d.Streak != 3 {
+		t.Errorf("streak %d, mastered %v, want mastered after 3 in a row", d.Streak, d.Mastered)
+	}
+}
+
+func TestGenerateDrillTask(t *testing.T) {
+	tests := []struct {
+		name string
+		spec DrillSpec
+	}{
+		{"command", DrillSpec{Commands: []string{"dw"}}},
+		{"category and level", DrillSpec{Category: CategoryChange, Difficulty: 2}},
+		{"tag", DrillSpec{Tag: "find"}},
+		{"code", DrillSpec{Category: CategoryMotion, Tag: "brackets", Text: TextCode}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewSeededTaskGenerator(1)
+			for i := 0; i < 5; i++ {
+				task, ok := g.GenerateDrillTask(tt.spec)
+				if !ok {
+					t.Fatalf("GenerateDrillTask failed: %v", g.Problems())
+				}
+				if err := task.Validate(); err != nil {
+					t.Errorf("task %q: %v", task.ID, err)
+				}
+				if !tt.spec.matchesTask(task) {
+					t.Errorf("task %q uses %v, want one of %v", task.ID, task.Commands(), tt.spec.Commands)
+				}
+				if tt.spec.Category != "" && task.Category != tt.spec.Category {
+					t.Errorf("task %q is %s, want %s", task.ID, task.Category, tt.spec.Category)
+				}
+				if tt.spec.Difficulty > 0 && task.Difficulty != tt.spec.Difficulty {
+					t.Errorf("task %q is level %d, want %d", task.ID, task.Difficulty, tt.spec.Difficulty)
+				}
+			}
+			if g.Text() != TextProse {
+				t.Errorf("Text() = %q after the drill, want it put back", g.Text())
+			}
+		})
+	}
+
+	g := NewSeededTaskGenerator(1)
+	if _, ok := g.GenerateDrillTask(DrillSpec{Tag: "teleport"}); ok {
+		t.Fatal("GenerateDrillTask made a task no template matches")
+	}
+	problems := g.Problems()
+	if len(problems) == 0 || !errors.Is(problems[len(problems)-1], ErrDrillNoTasks) {
+		t.Errorf("Problems() = %v, want %v", problems, ErrDrillNoTasks)
+	}
+}
+
+// drillSession returns a started drill that generates delete tasks
+func drillSession(spec DrillSpec) *Session {
+	next := func() (Task, bool) {
+		task := validTask()
+		task.OptimalCount = 2
+		return task, true
+	}
+	var tasks []*Task
+	for len(tasks) < drillQueue {
+		task, _ := next()
+		tasks = append(tasks, &task)
+	}
+	s := NewSession(RoundDrill, tasks)
+	s.Drill = &Drill{DrillSpec: spec.withDefaults()}
+	s.nextDrillTask = next
+	s.StartTask()
+	return s
+}
+
+// solve plays the optimal keys of the current task and completes it
+func solve(s *Session) {
+	for _, key := range vim.ParseKeys("dw") {
+		s.ProcessKey(key)
+	}
+	s.CompleteTask()
+}
+
+func TestSessionDrill(t *testing.T) {
+	s := drillSession(DrillSpec{Solves: 2})
+	if !s.IsDrill() || NewSession("test", nil).IsDrill() {
+		t.Fatal("IsDrill() doesn't tell drills from rounds")
+	}
+
+	solve(s)
+	if s.IsComplete() || len(s.Tasks) != 3 || s.TotalTasks != 3 {
+		t.Fatalf("after a solve: complete %v with %d tasks, want the queue topped up", s.IsComplete(), len(s.Tasks))
+	}
+
+	// Skips are free in drills, and break the streak
+	skips := s.SkipsRemaining
+	if !s.SkipTask() || s.SkipsRemaining != skips || s.Drill.Streak != 0 {
+		t.Errorf("skip used a skip or kept the streak of %d", s.Drill.Streak)
+	}
+
+	solve(s)
+	solve(s)
+	if !s.IsComplete() || !s.Drill.Mastered {
+		t.Fatalf("complete %v, mastered %v, want the drill over", s.IsComplete(), s.Drill.Mastered)
+	}
+	if len(s.Tasks) != 4 || s.TotalTasks != 4 || len(s.TaskResults) != 4 {
+		t.Errorf("%d tasks and %d results, want the queued task dropped", len(s.Tasks), len(s.TaskResults))
+	}
+}
+
+func TestSessionEndDrill(t *testing.T) {
+	s := drillSession(DrillSpec{})
+	solve(s)
+	s.EndDrill()
+	if !s.IsComplete() || s.Drill.Mastered {
+		t.Errorf("complete %v, mastered %v, want an unmastered drill ended", s.IsComplete(), s.Drill.Mastered)
+	}
+	if len(s.Tasks) != 1 || s.TotalTasks != 1 || len(s.TaskResults) != 1 {
+		t.Errorf("%d tasks and %d results, want the task in play left out", len(s.Tasks), len(s.TaskResults))
+	}
+
+	// Ending twice, or a round, does nothing
+	s.EndDrill()
+	round := NewSession("test", []*Task{{}})
+	round.EndDrill()
+	if round.IsComplete() {
+		t.Error("EndDrill() ended a round")
+	}
+}
@@ -1,12 +1,27 @@
 package game
 
 import (
+	"errors"
+	"os"
 	"sync"
+	"time"
 
+	"github.com/timlinux/macaco/internal/coach"
 	"github.com/timlinux/macaco/internal/config"
+	"github.com/timlinux/macaco/internal/scheduler"
 	"github.com/timlinux/macaco/internal/stats"
+	"github.com/timlinux/macaco/internal/vim"
 )
 
+// maxCoachSessions is how many recent sessions the coach analyses
+const maxCoachSessions = 100
+
+// RoundReview is the round type that drills due and weak commands
+const RoundReview = "review"
+
+// reviewRoundTasks is the number of tasks in a review round
+const reviewRoundTasks = 30
+
 // Engine manages game sessions and state
 type Engine struct {
 	cfg          *config.Config
@@ -14,17 +29,26 @@ type Engine struct {
 	generator    *TaskGenerator
 	sessions     map[string]*Session
 	statsTracker *stats.Tracker
+	coach        *coach.Coach
+	scheduler    *scheduler.Scheduler
+	packs        []*Pack
+	curriculum   *Curriculum
+	loadProblems []error // Why the tasks file, packs or parts of them were left out
 	mu           sync.RWMutex
 }
 
-// NewEngine creates a new game engine
+// NewEngine creates a new game engine. Tasks come from the tasks file if
+// one is set, or are generated, and the packs in the packs directory are
+// added to them. Whatever fails to load is reported by TaskProblems.
 func NewEngine(cfg *config.Config) *Engine {
 	var taskDB *TaskDatabase
+	var loadProblems []error
 	if cfg.TasksFile != "" {
 		var err error
 		taskDB, err = LoadTaskDatabase(cfg.TasksFile)
 		if err != nil {
 			// Fall back to generated tasks
+			loadProblems = append(loadProblems, err)
 			taskDB = NewGeneratedTaskDatabase()
 		}
 	} else {
@@ -32,44 +56,339 @@ func NewEngine(cfg *config.Config) *Engine {
 		taskDB = NewGeneratedTaskDatabase()
 	}
 
+	packs, packProblems := LoadPacks(cfg.PacksDir())
+	loadProblems = append(loadProblems, packProblems...)
+	for _, pack := range packs {
+		loadProblems = append(loadProblems, taskDB.AddPack(pack)...)
+	}
+
+	curriculum := DefaultCurriculum()
+	if cfg.LessonsFile != "" {
+		if loaded, err := LoadCurriculum(cfg.LessonsFile); err != nil {
+			// Fall back to the built-in curriculum
+			loadProblems = append(loadProblems, err)
+		} else {
+			curriculum = loaded
+		}
+	}
+	loadProblems = append(loadProblems, curriculum.Problems()...)
+
 	tracker, _ := stats.NewTracker(cfg.StatsFile)
 	generator := NewTaskGenerator()
-
-	return &Engine{
+	local, corpusProblems := LoadLocalSources(cfg.CorpusPaths)
+	loadProblems = append(loadProblems, corpusProblems...)
+	generator.SetLocalSources(local)
+	imported, importProblems := LoadImportedSources(cfg.SourcesDir())
+	loadProblems = append(loadProblems, importProblems...)
+	generator.SetImportedSources(imported)
+
+	e := &Engine{
 		cfg:          cfg,
 		taskDB:       taskDB,
 		generator:    generator,
 		sessions:     make(map[string]*Session),
 		statsTracker: tracker,
+		coach:        coach.Default(),
+		scheduler:    scheduler.New(scheduler.PathFor(cfg.StatsFile)),
+		packs:        packs,
+		curriculum:   curriculum,
+		loadProblems: loadProblems,
+	}
+	e.calibrate()
+	return e
+}
+
+// calibrate gives the generator the player's history with each command, so
+// difficulty scores reflect how hard tasks are for them
+func (e *Engine) calibrate() {
+	if e.statsTracker != nil {
+		e.generator.SetCalibration(CalibrationFromSessions(e.statsTracker.GetRecentSessions(maxCalibrationSessions)))
+	}
+}
+
+// CreateSession creates a new game session for a round type defined in
+// the task database, or a review or daily round. The daily challenge can be
+// started once per UTC day; after that it returns ErrDailyAlreadyPlayed.
+// Generated rounds get a fresh round code that replays them, unless they use
+// local text or imported books.
+func (e *Engine) CreateSession(roundType string) (*Session, error) {
+	return e.CreateSessionWithText(roundType, "")
+}
+
+// CreateSessionWithText creates a session like CreateSession, generating
+// tasks from text, TextProse, TextCode, TextLocal or TextBooks, instead of
+// the round's own kind of text. An empty text keeps the round's. Curated
+// tasks, review and daily rounds aren't affected. Rounds from local text or
+// imported books get no round code, and fail with ErrNoLocalText or
+// ErrNoBooks when there is none.
+func (e *Engine) CreateSessionWithText(roundType, text string) (*Session, error) {
+	if text != "" && !IsTextKind(text) {
+		return nil, ErrUnknownText
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var tasks []Task
+	var daily *DailyChallenge
+	switch roundType {
+	case RoundReview:
+		tasks = e.generator.GenerateReviewRound(e.reviewPlan(time.Now()), reviewRoundTasks)
+	case RoundDaily:
+		challenge := DailyChallengeFor(time.Now())
+		if e.statsTracker != nil && e.statsTracker.HasDailyAttempt(challenge.Date) {
+			return nil, ErrDailyAlreadyPlayed
+		}
+		daily = &challenge
+		tasks = GenerateDailyTasks(challenge)
+	default:
+		def, ok := e.taskDB.Rounds[roundType]
+		if !ok {
+			return nil, ErrUnknownRoundType
+		}
+		if text != "" {
+			def.Text = text
+		}
+		source := e.taskDB.RoundSource(def)
+		if def.TextKind() == TextLocal && source != RoundSourceCurated {
+			if len(e.generator.LocalSources()) == 0 {
+				return nil, ErrNoLocalText
+			}
+			if source == RoundSourceGenerated {
+				// Other players don't have the files, so there is no code
+				return e.newSession(roundType, def.GenerateRound(e.generator), e.vimOptions(), def.HintLimit(), def.TimePolicy()), nil
+			}
+		}
+		if def.TextKind() == TextBooks && source != RoundSourceCurated {
+			if !e.generator.HasImportedSources() {
+				return nil, ErrNoBooks
+			}
+			if source == RoundSourceGenerated {
+				// Other players may not have the same books, so there is no code
+				return e.newSession(roundType, def.GenerateRound(e.generator), e.vimOptions(), def.HintLimit(), def.TimePolicy()), nil
+			}
+		}
+		if source == RoundSourceGenerated {
+			seed := uint64(e.generator.rng.Int63n(1 << roundCodeSeedBits))
+			return e.sessionFromCode(RoundCodeFor(seed, roundType, def, e.vimOptions())), nil
+		}
+		tasks = e.taskDB.BuildRound(def, e.generator)
+		return e.newSession(roundType, tasks, e.vimOptions(), def.HintLimit(), def.TimePolicy()), nil
+	}
+
+	session := e.newSession(roundType, tasks, e.vimOptions(), UnlimitedHints, TimePolicy{})
+	session.Daily = daily
+
+	if daily != nil && e.statsTracker != nil {
+		e.statsTracker.StartDaily(daily.Date, daily.Number, session.ID)
+		e.statsTracker.Save()
+	}
+
+	return session, nil
+}
+
+// CreateDrill creates a drill: a session that generates tasks matching
+// spec, one after another, until the player has mastered them. Drills are
+// untimed and their skips are free.
+func (e *Engine) CreateDrill(spec DrillSpec) (*Session, error) {
+	spec = spec.withDefaults()
+	if err := spec.validate(); err != nil {
+		return nil, err
+	}
+	if spec.Text == TextLocal && len(e.generator.LocalSources()) == 0 {
+		return nil, ErrNoLocalText
+	}
+	if spec.Text == TextBooks && !e.generator.HasImportedSources() {
+		return nil, ErrNoBooks
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var tasks []Task
+	for len(tasks) < drillQueue {
+		task, ok := e.generator.GenerateDrillTask(spec)
+		if !ok {
+			break
+		}
+		tasks = append(tasks, task)
+	}
+	if len(tasks) == 0 {
+		return nil, ErrDrillNoTasks
+	}
+
+	session := e.newSession(RoundDrill, tasks, e.vimOptions(), UnlimitedHints, TimePolicy{})
+	session.Drill = &Drill{DrillSpec: spec}
+	e.attachDrill(session)
+	return session, nil
+}
+
+// attachDrill lets a drill session generate its next tasks
+func (e *Engine) attachDrill(session *Session) {
+	if session.Drill == nil {
+		return
+	}
+	spec := session.Drill.DrillSpec
+	session.nextDrillTask = func() (Task, bool) {
+		return e.generator.GenerateDrillTask(spec)
 	}
 }
 
-// CreateSession creates a new game session with procedurally generated tasks
-func (e *Engine) CreateSession(roundType string) *Session {
+// CreateLesson creates a session of a unit's practice tasks, in random
+// order. Units are locked until the one before them is passed. Lessons
+// are untimed.
+func (e *Engine) CreateLesson(unitID string) (*Session, error) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
-	// Generate fresh tasks for this session using public domain texts
-	tasks := e.generator.GenerateTasksForRound(roundType)
+	unit, index := e.curriculum.Unit(unitID)
+	if unit == nil {
+		return nil, ErrUnknownUnit
+	}
+	if !e.unitUnlocked(index) {
+		return nil, ErrUnitLocked
+	}
 
+	tasks := append([]Task{}, unit.Practice...)
+	e.generator.shuffle(tasks)
+	session := e.newSession(RoundLesson, tasks, e.vimOptions(), UnlimitedHints, TimePolicy{})
+	session.Lesson = &Lesson{Unit: unit.ID, Title: unit.Title, Pass: unit.Pass}
+	return session, nil
+}
+
+// GetCurriculum returns the curriculum lessons are taken from
+func (e *Engine) GetCurriculum() *Curriculum {
+	return e.curriculum
+}
+
+// GetLessonStatus returns every unit of the curriculum, in order, with
+// whether it is unlocked and the player's progress on it
+func (e *Engine) GetLessonStatus() []UnitStatus {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var units []UnitStatus
+	for i, unit := range e.curriculum.Units {
+		status := UnitStatus{
+			ID:       unit.ID,
+			Title:    unit.Title,
+			Summary:  unit.Summary,
+			Commands: unit.Commands,
+			Unlocked: e.unitUnlocked(i),
+		}
+		if e.statsTracker != nil {
+			status.Progress = e.statsTracker.GetLessonProgress(unit.ID)
+		}
+		units = append(units, status)
+	}
+	return units
+}
+
+// unitUnlocked returns true if the first unit or the one before it has
+// been passed. The caller must hold the lock.
+func (e *Engine) unitUnlocked(index int) bool {
+	if index == 0 {
+		return true
+	}
+	if e.statsTracker == nil {
+		return false
+	}
+	p := e.statsTracker.GetLessonProgress(e.curriculum.Units[index-1].ID)
+	return p != nil && p.Passed
+}
+
+// CreateSessionFromCode creates a session with the tasks and vim options of
+// a round code, so players with the same code race the same round
+func (e *Engine) CreateSessionFromCode(code string) (*Session, error) {
+	rc, err := ParseRoundCode(code)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.sessionFromCode(rc), nil
+}
+
+// sessionFromCode creates the session of a round code. Codes that are too
+// big to share still give a session, just without a code. The caller must
+// hold the lock.
+func (e *Engine) sessionFromCode(rc *RoundCode) *Session {
+	tasks, problems := rc.Generate()
+	e.generator.problems = append(e.generator.problems, problems...)
+
+	session := e.newSession(rc.RoundType, tasks, rc.Vim, rc.MaxHints, rc.Time)
+	if rc.validate() == "" {
+		session.RoundCode = rc.String()
+	}
+	return session
+}
+
+// vimOptions returns the vim options from the config
+func (e *Engine) vimOptions() vim.Options {
+	return vim.Options{
+		ScrollOff: e.cfg.ScrollOff,
+		TextWidth: e.cfg.TextWidth,
+	}
+}
+
+// newSession creates, starts and registers a session. Tasks are scored
+// with the player's history, even in rounds that were filled without it.
+// Rounds without hints don't get the tasks' hint texts. The caller must
+// hold the lock.
+func (e *Engine) newSession(roundType string, tasks []Task, opts vim.Options, maxHints int, policy TimePolicy) *Session {
 	// Convert to pointers
 	taskPtrs := make([]*Task, len(tasks))
 	for i := range tasks {
+		tasks[i].Calibrate(e.generator.calibration)
+		if maxHints == 0 {
+			tasks[i].Hint = ""
+		}
 		taskPtrs[i] = &tasks[i]
 	}
 
 	session := NewSession(roundType, taskPtrs)
+	session.HintsRemaining = maxHints
+	session.Time = policy
+	session.SetVimOptions(opts)
 	session.StartTask()
 
 	e.sessions[session.ID] = session
 	return session
 }
 
-// GetTextAttribution returns attribution for the public domain texts used
+// GetDailyStatus returns today's challenge, whether it has been played and
+// the daily streak
+func (e *Engine) GetDailyStatus() *DailyStatus {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	challenge := DailyChallengeFor(time.Now())
+	status := &DailyStatus{Challenge: challenge}
+	if e.statsTracker != nil {
+		status.Played = e.statsTracker.HasDailyAttempt(challenge.Date)
+		status.Stats = e.statsTracker.GetDaily(challenge.Date)
+	}
+	return status
+}
+
+// GetTextAttribution returns attribution for the texts tasks are
+// generated from
 func (e *Engine) GetTextAttribution() string {
 	return e.generator.GetAttribution()
 }
 
+// GetTextSources returns the sources of a kind of text
+func (e *Engine) GetTextSources(text string) []TextSource {
+	return e.generator.Sources(text)
+}
+
+// GetTemplates returns the templates generated tasks come from
+func (e *Engine) GetTemplates() *TemplateRegistry {
+	return e.generator.Templates()
+}
+
 // GetSession retrieves a session by ID
 func (e *Engine) GetSession(sessionID string) *Session {
 	e.mu.RLock()
@@ -85,7 +404,7 @@ func (e *Engine) DeleteSession(sessionID string) {
 }
 
 // ProcessKey processes a keystroke for a session
-func (e *Engine) ProcessKey(sessionID string, key string) (MatchStatus, error) {
+func (e *Engine) ProcessKey(sessionID string, key vim.Key) (MatchStatus, error) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
@@ -131,7 +450,51 @@ func (e *Engine) SkipTask(sessionID string) error {
 	if !session.SkipTask() {
 		return ErrNoSkipsRemaining
 	}
+	if session.IsComplete() && e.statsTracker != nil {
+		e.saveSessionStats(session)
+	}
+
+	return nil
+}
+
+// CheckTime times out the current task of a session if its time is up, and
+// saves the stats if that ended the round. It returns true if a task timed
+// out.
+func (e *Engine) CheckTime(sessionID string) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	session, ok := e.sessions[sessionID]
+	if !ok {
+		return false, ErrSessionNotFound
+	}
+
+	if !session.CheckTime() {
+		return false, nil
+	}
+	if session.IsComplete() && e.statsTracker != nil {
+		e.saveSessionStats(session)
+	}
+	return true, nil
+}
+
+// EndDrill stops a drill before it's mastered and saves its stats
+func (e *Engine) EndDrill(sessionID string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 
+	session, ok := e.sessions[sessionID]
+	if !ok {
+		return ErrSessionNotFound
+	}
+	if session.Drill == nil {
+		return ErrNotDrill
+	}
+
+	session.EndDrill()
+	if e.statsTracker != nil {
+		e.saveSessionStats(session)
+	}
 	return nil
 }
 
@@ -159,7 +522,9 @@ func (e *Engine) UseHint(sessionID string) error {
 		return ErrSessionNotFound
 	}
 
-	session.UseHint()
+	if !session.UseHint() {
+		return ErrNoHintsRemaining
+	}
 	return nil
 }
 
@@ -191,6 +556,60 @@ func (e *Engine) ResumeSession(sessionID string) error {
 	return nil
 }
 
+// SaveSession saves a session so it can be resumed, or removes the saved
+// session once the round is finished
+func (e *Engine) SaveSession(sessionID string) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	session, ok := e.sessions[sessionID]
+	if !ok {
+		return ErrSessionNotFound
+	}
+
+	if session.IsComplete() {
+		return e.DiscardSavedSession()
+	}
+	return SaveSnapshot(e.cfg.SessionFile(), session.Snapshot())
+}
+
+// SavedSession returns the saved unfinished round, or nil if there is
+// none. A saved round that is too old or can't be resumed is discarded, and
+// the error says why.
+func (e *Engine) SavedSession() (*SessionSnapshot, error) {
+	return LoadSnapshot(e.cfg.SessionFile())
+}
+
+// RestoreSavedSession resumes the saved unfinished round
+func (e *Engine) RestoreSavedSession() (*Session, error) {
+	snap, err := e.SavedSession()
+	if err != nil {
+		return nil, err
+	}
+	if snap == nil {
+		return nil, ErrSessionNotFound
+	}
+	session, err := RestoreSession(snap)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.attachDrill(session)
+	e.sessions[session.ID] = session
+	return session, nil
+}
+
+// DiscardSavedSession removes the saved unfinished round, if there is one
+func (e *Engine) DiscardSavedSession() error {
+	err := os.Remove(e.cfg.SessionFile())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
 // GetSessionStats returns statistics for a completed session
 func (e *Engine) GetSessionStats(sessionID string) (*stats.SessionStats, error) {
 	e.mu.RLock()
@@ -204,6 +623,35 @@ func (e *Engine) GetSessionStats(sessionID string) (*stats.SessionStats, error)
 	return e.calculateSessionStats(session), nil
 }
 
+// RecordSession saves the stats of a finished session played on it
+// directly rather than through the engine, as the TUI does. A session's
+// stats are only saved once.
+func (e *Engine) RecordSession(sessionID string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	session, ok := e.sessions[sessionID]
+	if !ok {
+		return ErrSessionNotFound
+	}
+	if session.IsComplete() && e.statsTracker != nil {
+		e.saveSessionStats(session)
+	}
+	return nil
+}
+
+// GetGhost returns the player's best recorded run of a round code of tasks
+// tasks to race, or nil if there is none
+func (e *Engine) GetGhost(roundCode string, tasks int) *Ghost {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if e.statsTracker == nil {
+		return nil
+	}
+	return NewGhost(e.statsTracker.BestRun(roundCode, tasks))
+}
+
 // GetLifetimeStats returns lifetime statistics
 func (e *Engine) GetLifetimeStats() *stats.LifetimeStats {
 	if e.statsTracker != nil {
@@ -212,14 +660,97 @@ func (e *Engine) GetLifetimeStats() *stats.LifetimeStats {
 	return nil
 }
 
+// Coach returns the coach that looks for habits in the stats history, so
+// more rules can be registered
+func (e *Engine) Coach() *coach.Coach {
+	return e.coach
+}
+
+// GetCoachReport analyses the tasks in the stats history and ranks the
+// player's inefficient habits
+func (e *Engine) GetCoachReport() *coach.Report {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var sessions []*stats.SessionStats
+	if e.statsTracker != nil {
+		sessions = e.statsTracker.GetRecentSessions(maxCoachSessions)
+	}
+	return e.coach.Analyse(coach.AttemptsFromSessions(sessions))
+}
+
+// reviewPlan turns the schedule into the weights of a review round. A
+// category's difficulty grows with the reviews in a row the player got
+// right.
+func (e *Engine) reviewPlan(now time.Time) ReviewPlan {
+	plan := ReviewPlan{
+		Categories: make(map[TaskCategory]float64),
+		Commands:   e.scheduler.Priorities(scheduler.KindCommand, now),
+		Difficulty: make(map[TaskCategory]int),
+	}
+	for _, item := range e.scheduler.Items(scheduler.KindCategory, now) {
+		cat := TaskCategory(item.Name)
+		plan.Categories[cat] = item.Priority(now)
+		plan.Difficulty[cat] = min(1+item.Repetitions/2, 4)
+	}
+	return plan
+}
+
+// GetReviewItems returns the schedule of every practised command or
+// category, most urgent first
+func (e *Engine) GetReviewItems(kind scheduler.Kind) []scheduler.Item {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var items []scheduler.Item
+	for _, item := range e.scheduler.Items(kind, time.Now()) {
+		items = append(items, *item)
+	}
+	return items
+}
+
 // GetTask returns a task by ID
 func (e *Engine) GetTask(taskID string) *Task {
 	return e.taskDB.GetTask(taskID)
 }
 
-// GetRoundTypes returns available round types
+// TaskProblems returns why the tasks file, packs, tasks or rounds were left
+// out of the task database, and the validation errors of tasks given up on
+// while generating rounds
+func (e *Engine) TaskProblems() []error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	problems := append([]error{}, e.loadProblems...)
+	problems = append(problems, e.taskDB.Problems()...)
+	return append(problems, e.generator.Problems()...)
+}
+
+// GetPacks returns the loaded task packs
+func (e *Engine) GetPacks() []*Pack {
+	return e.packs
+}
+
+// GetRoundTypes returns the round types defined in the task database,
+// followed by the review and daily rounds
 func (e *Engine) GetRoundTypes() []string {
-	return []string{"beginner", "intermediate", "advanced", "expert", "mixed"}
+	return append(e.taskDB.RoundTypes(), RoundReview, RoundDaily)
+}
+
+// GetRound returns the definition of a round type from the task database
+func (e *Engine) GetRound(roundType string) (RoundDef, bool) {
+	def, ok := e.taskDB.Rounds[roundType]
+	return def, ok
+}
+
+// RoundSource returns where the tasks of a defined round come from
+func (e *Engine) RoundSource(def RoundDef) string {
+	return e.taskDB.RoundSource(def)
+}
+
+// RoundLength returns how many tasks a defined round asks for
+func (e *Engine) RoundLength(def RoundDef) int {
+	return e.taskDB.RoundLength(def)
 }
 
 // calculateSessionStats calculates statistics for a session
@@ -231,6 +762,7 @@ func (e *Engine) calculateSessionStats(session *Session) *stats.SessionStats {
 	sessionStats := &stats.SessionStats{
 		SessionID:      session.ID,
 		RoundType:      session.RoundType,
+		RoundCode:      session.RoundCode,
 		StartedAt:      session.StartedAt,
 		TotalTimeMs:    session.TotalElapsedTime().Milliseconds(),
 		TasksCompleted: 0,
@@ -251,6 +783,10 @@ func (e *Engine) calculateSessionStats(session *Session) *stats.SessionStats {
 		}
 		totalEfficiency += result.Efficiency
 		totalTimeMs += result.TimeMs
+		sessionStats.Penalty += result.Penalty
+		if result.TimedOut {
+			sessionStats.TimedOut++
+		}
 
 		// Update category stats
 		cat := string(result.Category)
@@ -264,6 +800,26 @@ func (e *Engine) calculateSessionStats(session *Session) *stats.SessionStats {
 		}
 		cs.TotalTimeMs += result.TimeMs
 		cs.TotalEfficiency += result.Efficiency
+
+		sessionStats.Tasks = append(sessionStats.Tasks, &stats.TaskStats{
+			TaskID:            result.TaskID,
+			Category:          cat,
+			Difficulty:        result.Difficulty,
+			TimeMs:            result.TimeMs,
+			Keystrokes:        result.Keystrokes,
+			OptimalKeystrokes: result.OptimalKeystrokes,
+			Efficiency:        result.Efficiency,
+			Success:           result.Success,
+			KeysUsed:          result.KeysUsed,
+			Commands:          result.Commands,
+			Resets:            result.Resets,
+			HintsUsed:         result.HintsUsed,
+			TimedOut:          result.TimedOut,
+			OvertimeMs:        result.OvertimeMs,
+			Penalty:           result.Penalty,
+			Replay:            result.Replay,
+			CompletedAt:       result.CompletedAt,
+		})
 	}
 
 	if sessionStats.TasksAttempted > 0 {
@@ -271,49 +827,50 @@ func (e *Engine) calculateSessionStats(session *Session) *stats.SessionStats {
 		sessionStats.AvgTimeMs = totalTimeMs / int64(sessionStats.TasksAttempted)
 	}
 
-	sessionStats.Grade = calculateGrade(sessionStats)
+	sessionStats.Score(e.scoringModel())
 
 	return sessionStats
 }
 
-// saveSessionStats saves session statistics
+// saveSessionStats saves session statistics, once per session
 func (e *Engine) saveSessionStats(session *Session) {
+	if session.statsSaved {
+		return
+	}
+	session.statsSaved = true
 	sessionStats := e.calculateSessionStats(session)
-	if sessionStats != nil {
-		e.statsTracker.RecordSession(sessionStats)
-		e.statsTracker.Save()
+	if sessionStats == nil {
+		return
 	}
-}
 
-// calculateGrade calculates the grade for a session
-func calculateGrade(s *stats.SessionStats) string {
-	if s.TasksAttempted == 0 {
-		return "F"
+	switch {
+	case session.Drill != nil:
+		// Drills and lessons are kept apart from graded rounds, but still
+		// count as practice for reviews
+		e.statsTracker.RecordDrill(drillStats(session, sessionStats))
+	case session.Lesson != nil:
+		session.Lesson.Passed = session.Lesson.Pass.Passed(sessionStats)
+		e.statsTracker.RecordLesson(session.Lesson.Unit, sessionStats, session.Lesson.Passed)
+	default:
+		e.statsTracker.RecordSession(sessionStats)
+		if session.Daily != nil {
+			e.statsTracker.CompleteDaily(session.Daily.Date, sessionStats)
+		}
 	}
+	e.statsTracker.Save()
+	e.scheduler.RecordSession(sessionStats)
+	e.scheduler.Save()
+	e.calibrate()
+}
 
-	completionRate := float64(s.TasksCompleted) / float64(s.TasksAttempted)
-	efficiency := s.AvgEfficiency
-
-	// Target times by difficulty (in ms)
-	// Simplified: using overall average
-	avgTargetTime := int64(8000) // 8 seconds average
-
-	if completionRate == 1.0 && efficiency >= 95 && s.AvgTimeMs <= avgTargetTime {
-		return "S"
-	}
-	if completionRate == 1.0 && efficiency >= 85 && s.AvgTimeMs <= int64(float64(avgTargetTime)*1.2) {
-		return "A"
-	}
-	if completionRate >= 0.9 && efficiency >= 75 {
-		return "B"
-	}
-	if completionRate >= 0.75 && efficiency >= 60 {
-		return "C"
-	}
-	if completionRate >= 0.5 {
-		return "D"
+// scoringModel returns the model sessions are scored with: the built-in
+// one, with the grade thresholds from the config if it has any
+func (e *Engine) scoringModel() *stats.ScoringModel {
+	model := stats.DefaultScoring()
+	if len(e.cfg.Grades) > 0 {
+		model.Grades = e.cfg.Grades
 	}
-	return "F"
+	return model
 }
 
 // Errors
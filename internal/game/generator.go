@@ -2,20 +2,24 @@ package game
 
 import (
 	"fmt"
+	"math"
 	"math/rand"
 	"strings"
 	"time"
 	"unicode"
+
+	"github.com/timlinux/macaco/internal/vim"
 )
 
-// TextSource represents a source of public domain text
+// TextSource represents a source of public domain text or code
 type TextSource struct {
-	Name        string
-	Author      string
-	Year        int
-	License     string
-	Attribution string
-	Sentences   []string
+	Name        string   `json:"name"`
+	Author      string   `json:"author,omitempty"`
+	Language    string   `json:"language,omitempty"` // Set for code sources, see GetCodeSources
+	Year        int      `json:"year,omitempty"`
+	License     string   `json:"license,omitempty"`
+	Attribution string   `json:"attribution,omitempty"`
+	Sentences   []string `json:"sentences"`
 }
 
 // GetPublicDomainSources returns text sources from public domain works
@@ -172,23 +176,38 @@ func GetPublicDomainSources() []TextSource {
 
 // TaskGenerator generates procedural vim training tasks
 type TaskGenerator struct {
-	sources []TextSource
-	rng     *rand.Rand
+	sources     []TextSource
+	imported    []TextSource // Books added with ImportGutenberg
+	code        []TextSource
+	local       []TextSource // The player's files, see LoadLocalSources
+	text        string       // TextProse, TextCode, TextLocal or TextBooks
+	rng         *rand.Rand
+	templates   *TemplateRegistry // Where tasks come from, DefaultTemplates unless set
+	calibration Calibration       // The player's history for difficulty scores
+	problems    []error           // Tasks that kept failing validation
 }
 
 // NewTaskGenerator creates a new task generator
 func NewTaskGenerator() *TaskGenerator {
 	return &TaskGenerator{
-		sources: GetPublicDomainSources(),
-		rng:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		sources:   GetPublicDomainSources(),
+		code:      GetCodeSources(),
+		text:      TextProse,
+		rng:       rand.New(rand.NewSource(time.Now().UnixNano())),
+		templates: DefaultTemplates(),
 	}
 }
 
-// NewSeededTaskGenerator creates a task generator with a specific seed for reproducibility
+// NewSeededTaskGenerator creates a task generator with a specific seed for
+// reproducibility. It only uses the built-in templates, so a seed gives the
+// same tasks whatever templates were registered.
 func NewSeededTaskGenerator(seed int64) *TaskGenerator {
 	return &TaskGenerator{
-		sources: GetPublicDomainSources(),
-		rng:     rand.New(rand.NewSource(seed)),
+		sources:   GetPublicDomainSources(),
+		code:      GetCodeSources(),
+		text:      TextProse,
+		rng:       rand.New(rand.NewSource(seed)),
+		templates: seededTemplates,
 	}
 }
 
@@ -197,19 +216,116 @@ func (g *TaskGenerator) GetAttribution() string {
 	var lines []string
 	lines = append(lines, "Text sources used in MoCaCo (all Public Domain):")
 	lines = append(lines, "")
-	for _, src := range g.sources {
+	for _, src := range append(append([]TextSource{}, g.sources...), g.imported...) {
+		if src.Year == 0 {
+			lines = append(lines, fmt.Sprintf("- '%s' by %s", src.Name, src.Author))
+			continue
+		}
 		lines = append(lines, fmt.Sprintf("- '%s' by %s (%d)", src.Name, src.Author, src.Year))
 	}
 	lines = append(lines, "")
 	lines = append(lines, "All texts sourced from Project Gutenberg (https://www.gutenberg.org)")
 	lines = append(lines, "These works are in the public domain in the United States.")
+	if len(g.code) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, "Code sources:")
+		lines = append(lines, "")
+		for _, src := range g.code {
+			lines = append(lines, fmt.Sprintf("- %s: %s", src.Name, src.Attribution))
+		}
+	}
+	if len(g.local) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, "Local sources:")
+		lines = append(lines, "")
+		for _, src := range g.local {
+			lines = append(lines, fmt.Sprintf("- %s (%d lines): %s", src.Name, len(src.Sentences), src.Attribution))
+		}
+	}
 	return strings.Join(lines, "\n")
 }
 
-// randomSentence returns a random sentence from the sources
+// SetText sets the kind of text tasks are generated from, TextProse,
+// TextCode, TextLocal or TextBooks
+func (g *TaskGenerator) SetText(text string) {
+	g.text = text
+}
+
+// SetImportedSources sets the imported books. They are kept apart from
+// the built-in prose, which other players have too, and only used for
+// TextBooks.
+func (g *TaskGenerator) SetImportedSources(sources []TextSource) {
+	g.imported = sources
+}
+
+// HasImportedSources returns true if books were imported
+func (g *TaskGenerator) HasImportedSources() bool {
+	return len(g.imported) > 0
+}
+
+// proseSources returns the prose tasks are made from: the imported books
+// for TextBooks, the built-in prose otherwise
+func (g *TaskGenerator) proseSources() []TextSource {
+	if g.text == TextBooks && len(g.imported) > 0 {
+		return g.imported
+	}
+	return g.sources
+}
+
+// SetLocalSources sets the sources of local text
+func (g *TaskGenerator) SetLocalSources(sources []TextSource) {
+	g.local = sources
+}
+
+// LocalSources returns the sources of local text
+func (g *TaskGenerator) LocalSources() []TextSource {
+	return g.local
+}
+
+// Sources returns the sources of a kind of text
+func (g *TaskGenerator) Sources(text string) []TextSource {
+	switch text {
+	case TextCode:
+		return g.code
+	case TextLocal:
+		return g.local
+	case TextBooks:
+		return g.imported
+	}
+	return g.sources
+}
+
+// Text returns the kind of text tasks are generated from
+func (g *TaskGenerator) Text() string {
+	return g.text
+}
+
+// SetTemplates sets the registry tasks are generated from
+func (g *TaskGenerator) SetTemplates(templates *TemplateRegistry) {
+	g.templates = templates
+}
+
+// Templates returns the registry tasks are generated from
+func (g *TaskGenerator) Templates() *TemplateRegistry {
+	return g.templates
+}
+
+// SetCalibration sets the player's history that difficulty scores take
+// into account. Leave it empty for rounds that have to come out the same
+// for every player.
+func (g *TaskGenerator) SetCalibration(c Calibration) {
+	g.calibration = c
+}
+
+// corpus returns the text tasks are generated from
+func (g *TaskGenerator) corpus() Corpus {
+	return Corpus{Text: g.text, Sources: g.textSources()}
+}
+
+// randomSentence returns a random sentence or line from the sources of
+// the generator's kind of text
 func (g *TaskGenerator) randomSentence() string {
-	source := g.sources[g.rng.Intn(len(g.sources))]
-	return source.Sentences[g.rng.Intn(len(source.Sentences))]
+	return g.corpus().Sentence(g.rng)
 }
 
 // randomWord returns a random word from a sentence
@@ -230,464 +346,645 @@ func (g *TaskGenerator) randomWord(sentence string) (word string, startIdx int)
 			startIdx++
 		}
 	}
-	startIdx = strings.Index(sentence, word)
-
 	return word, startIdx
 }
 
-// GenerateMotionTask generates a motion task
-func (g *TaskGenerator) GenerateMotionTask(difficulty int) Task {
+// wordStarts returns the start index of each word in a sentence, in the
+// order strings.Fields returns them
+func wordStarts(sentence string) []int {
+	var starts []int
+	for i := 0; i < len(sentence); i++ {
+		if sentence[i] != ' ' && (i == 0 || sentence[i-1] == ' ') {
+			starts = append(starts, i)
+		}
+	}
+	return starts
+}
+
+// findCount returns how many times c occurs in sentence from index from up
+// to and including index to, the count a find motion needs to reach to
+func findCount(sentence string, c byte, from, to int) int {
+	return strings.Count(sentence[from:to+1], string(c))
+}
+
+// countPrefix formats a count for a key sequence, omitting a count of one
+func countPrefix(count int) string {
+	if count <= 1 {
+		return ""
+	}
+	return fmt.Sprint(count)
+}
+
+// builtinTemplates returns the templates every generator starts with
+func builtinTemplates() []TaskTemplate {
+	templates := proseTemplates()
+	templates = append(templates, codeTemplates()...)
+	return append(templates, linesTemplates()...)
+}
+
+// builtinTemplate makes a template of a generator method, called on a
+// generator over the template's corpus
+func builtinTemplate(info TemplateInfo, generate func(g *TaskGenerator, difficulty int) (Task, bool)) TaskTemplate {
+	return NewTemplate(info, func(rng *rand.Rand, corpus Corpus, difficulty int) (Task, bool) {
+		return generate(corpus.generator(rng), difficulty)
+	})
+}
+
+// proseTemplates returns the built-in templates that work on one sentence
+// or line of any text
+func proseTemplates() []TaskTemplate {
+	return []TaskTemplate{
+		builtinTemplate(TemplateInfo{
+			Name: "motion-word", Category: CategoryMotion, MinDifficulty: 1, MaxDifficulty: 1,
+			Tags: []string{"motion", "word"}, Commands: []string{"w", "e", "b", "0", "$"}, Weight: 3,
+		}, (*TaskGenerator).motionWordTask),
+		builtinTemplate(TemplateInfo{
+			Name: "motion-count-find", Category: CategoryMotion, MinDifficulty: 2, MaxDifficulty: 2,
+			Tags: []string{"motion", "count", "find"}, Commands: []string{"w", "f", "$"}, Weight: 3,
+		}, (*TaskGenerator).motionCountFindTask),
+		builtinTemplate(TemplateInfo{
+			Name: "motion-till", Category: CategoryMotion, MinDifficulty: 3, MaxDifficulty: 4,
+			Tags: []string{"motion", "find"}, Commands: []string{"t", "0"}, Weight: 3,
+		}, (*TaskGenerator).motionTillTask),
+		builtinTemplate(TemplateInfo{
+			Name: "delete-word", Category: CategoryDelete, MinDifficulty: 1, MaxDifficulty: 1,
+			Tags: []string{"delete", "word"}, Commands: []string{"dw", "x"}, Weight: 3,
+		}, (*TaskGenerator).deleteWordTask),
+		builtinTemplate(TemplateInfo{
+			Name: "delete-object", Category: CategoryDelete, MinDifficulty: 2, MaxDifficulty: 2,
+			Tags: []string{"delete", "word", "text-object"}, Commands: []string{"daw"}, Weight: 3,
+		}, (*TaskGenerator).deleteObjectTask),
+		builtinTemplate(TemplateInfo{
+			Name: "delete-till", Category: CategoryDelete, MinDifficulty: 3, MaxDifficulty: 4,
+			Tags: []string{"delete", "find"}, Commands: []string{"dt"}, Weight: 3,
+		}, (*TaskGenerator).deleteTillTask),
+		builtinTemplate(TemplateInfo{
+			Name: "change-word", Category: CategoryChange, MinDifficulty: 1, MaxDifficulty: 1,
+			Tags: []string{"change", "word"}, Commands: []string{"cw"}, Weight: 3,
+		}, (*TaskGenerator).changeWordTask),
+		builtinTemplate(TemplateInfo{
+			Name: "change-object", Category: CategoryChange, MinDifficulty: 2, MaxDifficulty: 2,
+			Tags: []string{"change", "word", "text-object"}, Commands: []string{"ciw"}, Weight: 3,
+		}, (*TaskGenerator).changeObjectTask),
+		builtinTemplate(TemplateInfo{
+			Name: "change-line", Category: CategoryChange, MinDifficulty: 3, MaxDifficulty: 4,
+			Tags: []string{"change", "line"}, Commands: []string{"cc"}, Weight: 3,
+		}, (*TaskGenerator).changeLineTask),
+		builtinTemplate(TemplateInfo{
+			Name: "insert-before", Category: CategoryInsert, MinDifficulty: 1, MaxDifficulty: 1,
+			Tags: []string{"insert", "word"}, Commands: []string{"i"}, Weight: 3,
+		}, (*TaskGenerator).insertBeforeTask),
+		builtinTemplate(TemplateInfo{
+			Name: "insert-append", Category: CategoryInsert, MinDifficulty: 2, MaxDifficulty: 2,
+			Tags: []string{"insert", "line"}, Commands: []string{"A"}, Weight: 3,
+		}, (*TaskGenerator).insertAppendTask),
+		builtinTemplate(TemplateInfo{
+			Name: "insert-open-line", Category: CategoryInsert, MinDifficulty: 3, MaxDifficulty: 4,
+			Tags: []string{"insert", "line"}, Commands: []string{"o"}, Weight: 3,
+		}, (*TaskGenerator).insertOpenLineTask),
+		builtinTemplate(TemplateInfo{
+			Name: "visual-word", Category: CategoryVisual, MinDifficulty: 1, MaxDifficulty: 4,
+			Tags: []string{"visual", "word", "text-object"}, Commands: []string{"vd"}, Weight: 3,
+		}, (*TaskGenerator).visualWordTask),
+		builtinTemplate(TemplateInfo{
+			Name: "complex-swap", Category: CategoryComplex, MinDifficulty: 1, MaxDifficulty: 4,
+			Tags: []string{"complex", "word", "register"}, Commands: []string{"dw", "P"}, Weight: 3,
+		}, (*TaskGenerator).complexSwapTask),
+		builtinTemplate(TemplateInfo{
+			Name: "complex-reflow", Category: CategoryComplex, MinDifficulty: 3, MaxDifficulty: 4,
+			Tags: []string{"complex", "format"}, Commands: []string{"gqG"}, Text: TextProse, Weight: 3,
+		}, (*TaskGenerator).complexReflowTask),
+	}
+}
+
+// motionTask starts a motion task on a random sentence
+func (g *TaskGenerator) motionTask(difficulty int) (Task, string, []string) {
 	sentence := g.randomSentence()
-	words := strings.Fields(sentence)
+	return Task{
+		Category:   CategoryMotion,
+		Difficulty: difficulty,
+		Initial:    sentence,
+		Desired:    sentence, // Same for motion tasks
+		Tags:       []string{"motion", "procedural"},
+	}, sentence, strings.Fields(sentence)
+}
 
-	var task Task
-	task.Category = CategoryMotion
-	task.Difficulty = difficulty
-	task.Initial = sentence
-	task.Desired = sentence // Same for motion tasks
-
-	switch difficulty {
-	case 1:
-		// Simple word motion: w, b, e, 0, $
-		motions := []struct {
-			name        string
-			keys        string
-			description string
-			hint        string
-			setup       func() (cursorStart, cursorEnd int)
-		}{
-			{
-				name: "w", keys: "w", description: "Move to next word",
-				hint: "Use 'w' to move to the start of the next word",
-				setup: func() (int, int) {
-					if len(words) < 2 {
-						return 0, len(sentence) - 1
-					}
-					startIdx := 0
-					endIdx := strings.Index(sentence, words[1])
-					return startIdx, endIdx
-				},
+// motionWordTask moves by a word or to either end of the line: w, b, e,
+// 0, $
+func (g *TaskGenerator) motionWordTask(difficulty int) (Task, bool) {
+	task, sentence, words := g.motionTask(difficulty)
+	motions := []struct {
+		name        string
+		keys        string
+		description string
+		hint        string
+		setup       func() (cursorStart, cursorEnd int)
+	}{
+		{
+			name: "w", keys: "w", description: "Move to next word",
+			hint: "Use 'w' to move to the start of the next word",
+			setup: func() (int, int) {
+				if len(words) < 2 {
+					return 0, len(sentence) - 1
+				}
+				startIdx := 0
+				endIdx := strings.Index(sentence, words[1])
+				return startIdx, endIdx
 			},
-			{
-				name: "e", keys: "e", description: "Move to end of word",
-				hint: "Use 'e' to move to the end of the current word",
-				setup: func() (int, int) {
-					if len(words) < 1 {
-						return 0, 0
-					}
-					return 0, len(words[0]) - 1
-				},
+		},
+		{
+			name: "e", keys: "e", description: "Move to end of word",
+			hint: "Use 'e' to move to the end of the current word",
+			setup: func() (int, int) {
+				if len(words) < 1 {
+					return 0, 0
+				}
+				return 0, len(words[0]) - 1
 			},
-			{
-				name: "0", keys: "0", description: "Move to start of line",
-				hint: "Use '0' to move to the beginning of the line",
-				setup: func() (int, int) {
-					startPos := len(sentence) / 2
-					if startPos < 1 {
-						startPos = 1
-					}
-					return startPos, 0
-				},
+		},
+		{
+			name: "0", keys: "0", description: "Move to start of line",
+			hint: "Use '0' to move to the beginning of the line",
+			setup: func() (int, int) {
+				startPos := len(sentence) / 2
+				if startPos < 1 {
+					startPos = 1
+				}
+				return startPos, 0
 			},
-			{
-				name: "$", keys: "$", description: "Move to end of line",
-				hint: "Use '$' to move to the end of the line",
-				setup: func() (int, int) {
-					return 0, len(sentence) - 1
-				},
+		},
+		{
+			name: "$", keys: "$", description: "Move to end of line",
+			hint: "Use '$' to move to the end of the line",
+			setup: func() (int, int) {
+				return 0, len(sentence) - 1
 			},
-			{
-				name: "b", keys: "b", description: "Move to previous word",
-				hint: "Use 'b' to move back to the start of the previous word",
-				setup: func() (int, int) {
-					if len(words) < 2 {
-						return len(sentence) - 1, 0
-					}
-					// Start at second word, move to first
-					startIdx := strings.Index(sentence, words[1])
-					return startIdx, 0
-				},
+		},
+		{
+			name: "b", keys: "b", description: "Move to previous word",
+			hint: "Use 'b' to move back to the start of the previous word",
+			setup: func() (int, int) {
+				if len(words) < 2 {
+					return len(sentence) - 1, 0
+				}
+				// Start at second word, move to first
+				startIdx := strings.Index(sentence, words[1])
+				return startIdx, 0
 			},
-		}
+		},
+	}
 
-		choice := motions[g.rng.Intn(len(motions))]
-		task.CursorStart, task.CursorEnd = choice.setup()
-		task.OptimalKeys = choice.keys
-		task.OptimalCount = len(choice.keys)
-		task.Description = choice.description
-		task.Hint = choice.hint
-		task.ID = fmt.Sprintf("gen-motion-%s-%d", choice.name, g.rng.Int())
-
-	case 2:
-		// Motion with counts or find
-		if len(words) >= 3 && g.rng.Float32() < 0.5 {
-			// Count motion: 2w, 3w
-			count := 2
-			if len(words) > 3 {
-				count = 2 + g.rng.Intn(2) // 2 or 3
-			}
-			task.CursorStart = 0
-			targetWord := words[min(count, len(words)-1)]
-			task.CursorEnd = strings.Index(sentence, targetWord)
-			task.OptimalKeys = fmt.Sprintf("%dw", count)
-			task.OptimalCount = 2
-			task.Description = fmt.Sprintf("Move forward %d words", count)
-			task.Hint = fmt.Sprintf("Use '%dw' to move forward %d words", count, count)
-			task.ID = fmt.Sprintf("gen-motion-%dw-%d", count, g.rng.Int())
-		} else {
-			// Find motion: f{char}
-			word, wordStart := g.randomWord(sentence)
-			if len(word) > 0 && wordStart > 0 {
-				targetChar := word[0]
-				task.CursorStart = 0
-				task.CursorEnd = wordStart
-				task.OptimalKeys = fmt.Sprintf("f%c", targetChar)
-				task.OptimalCount = 2
-				task.Description = fmt.Sprintf("Find '%c'", targetChar)
-				task.Hint = fmt.Sprintf("Use 'f%c' to jump to the next '%c'", targetChar, targetChar)
-				task.ID = fmt.Sprintf("gen-motion-f%c-%d", targetChar, g.rng.Int())
-			} else {
-				// Fallback to $ motion
-				task.CursorStart = 0
-				task.CursorEnd = len(sentence) - 1
-				task.OptimalKeys = "$"
-				task.OptimalCount = 1
-				task.Description = "Move to end of line"
-				task.Hint = "Use '$' to move to the end of the line"
-				task.ID = fmt.Sprintf("gen-motion-dollar-%d", g.rng.Int())
-			}
-		}
+	choice := motions[g.rng.Intn(len(motions))]
+	task.CursorStart, task.CursorEnd = choice.setup()
+	task.OptimalKeys = choice.keys
+	task.OptimalCount = len(choice.keys)
+	task.Description = choice.description
+	task.Hint = choice.hint
+	task.ID = fmt.Sprintf("gen-motion-%s-%d", choice.name, g.rng.Int())
+	return task, true
+}
 
-	default:
-		// Advanced: t motion, multiple finds
-		word, wordStart := g.randomWord(sentence)
-		if len(word) > 0 && wordStart > 1 {
-			targetChar := word[0]
-			task.CursorStart = 0
-			task.CursorEnd = wordStart - 1
-			task.OptimalKeys = fmt.Sprintf("t%c", targetChar)
-			task.OptimalCount = 2
-			task.Description = fmt.Sprintf("Move until '%c'", targetChar)
-			task.Hint = fmt.Sprintf("Use 't%c' to move to just before '%c'", targetChar, targetChar)
-			task.ID = fmt.Sprintf("gen-motion-t%c-%d", targetChar, g.rng.Int())
-		} else {
-			task.CursorStart = len(sentence) - 1
-			task.CursorEnd = 0
-			task.OptimalKeys = "0"
-			task.OptimalCount = 1
-			task.Description = "Move to start of line"
-			task.Hint = "Use '0' to move to the beginning"
-			task.ID = fmt.Sprintf("gen-motion-zero-%d", g.rng.Int())
+// motionCountFindTask moves several words with a count, or finds a
+// character with f
+func (g *TaskGenerator) motionCountFindTask(difficulty int) (Task, bool) {
+	task, sentence, words := g.motionTask(difficulty)
+	if len(words) >= 3 && g.rng.Float32() < 0.5 {
+		// Count motion: 2w, 3w
+		count := 2
+		if len(words) > 3 {
+			count = 2 + g.rng.Intn(2) // 2 or 3
 		}
+		task.CursorStart = 0
+		targetWord := words[min(count, len(words)-1)]
+		task.CursorEnd = strings.Index(sentence, targetWord)
+		task.OptimalKeys = fmt.Sprintf("%dw", count)
+		task.OptimalCount = 2
+		task.Description = fmt.Sprintf("Move forward %d words", count)
+		task.Hint = fmt.Sprintf("Use '%dw' to move forward %d words", count, count)
+		task.ID = fmt.Sprintf("gen-motion-%dw-%d", count, g.rng.Int())
+		return task, true
 	}
 
-	task.Tags = []string{"motion", "procedural"}
-	return task
+	// Find motion: f{char}
+	word, wordStart := g.randomWord(sentence)
+	if len(word) > 0 && wordStart > 0 {
+		targetChar := word[0]
+		task.CursorStart = 0
+		task.CursorEnd = wordStart
+		// The character may also occur earlier in the sentence
+		task.OptimalKeys = fmt.Sprintf("%sf%c", countPrefix(findCount(sentence, targetChar, 1, wordStart)), targetChar)
+		task.OptimalCount = len(task.OptimalKeySequence())
+		task.Description = fmt.Sprintf("Find '%c'", targetChar)
+		task.Hint = fmt.Sprintf("Use 'f%c' to jump to the next '%c'", targetChar, targetChar)
+		task.ID = fmt.Sprintf("gen-motion-f%c-%d", targetChar, g.rng.Int())
+	} else {
+		// Fallback to $ motion
+		task.CursorStart = 0
+		task.CursorEnd = len(sentence) - 1
+		task.OptimalKeys = "$"
+		task.OptimalCount = 1
+		task.Description = "Move to end of line"
+		task.Hint = "Use '$' to move to the end of the line"
+		task.ID = fmt.Sprintf("gen-motion-dollar-%d", g.rng.Int())
+	}
+	return task, true
 }
 
-// GenerateDeleteTask generates a delete task
-func (g *TaskGenerator) GenerateDeleteTask(difficulty int) Task {
+// motionTillTask moves to just before a character with t
+func (g *TaskGenerator) motionTillTask(difficulty int) (Task, bool) {
+	task, sentence, _ := g.motionTask(difficulty)
+	word, wordStart := g.randomWord(sentence)
+	if len(word) > 0 && wordStart > 1 {
+		targetChar := word[0]
+		task.CursorStart = 0
+		task.CursorEnd = wordStart - 1
+		task.OptimalKeys = fmt.Sprintf("%st%c", countPrefix(findCount(sentence, targetChar, 1, wordStart)), targetChar)
+		task.OptimalCount = len(task.OptimalKeySequence())
+		task.Description = fmt.Sprintf("Move until '%c'", targetChar)
+		task.Hint = fmt.Sprintf("Use 't%c' to move to just before '%c'", targetChar, targetChar)
+		task.ID = fmt.Sprintf("gen-motion-t%c-%d", targetChar, g.rng.Int())
+	} else {
+		task.CursorStart = len(sentence) - 1
+		task.CursorEnd = 0
+		task.OptimalKeys = "0"
+		task.OptimalCount = 1
+		task.Description = "Move to start of line"
+		task.Hint = "Use '0' to move to the beginning"
+		task.ID = fmt.Sprintf("gen-motion-zero-%d", g.rng.Int())
+	}
+	return task, true
+}
+
+// deleteWordTask deletes a word with dw, or a character with x on a line
+// of one word
+func (g *TaskGenerator) deleteWordTask(difficulty int) (Task, bool) {
 	sentence := g.randomSentence()
 	words := strings.Fields(sentence)
+	task := Task{Category: CategoryDelete, Difficulty: difficulty, Initial: sentence, Tags: []string{"delete", "procedural"}}
 
-	var task Task
-	task.Category = CategoryDelete
-	task.Difficulty = difficulty
-	task.Tags = []string{"delete", "procedural"}
-
-	switch difficulty {
-	case 1:
-		// Simple delete: x, dw, dd
-		if len(words) >= 2 {
-			// Delete a word with dw
-			wordIdx := g.rng.Intn(len(words) - 1) // Not the last word
-			wordToDelete := words[wordIdx]
-			startIdx := strings.Index(sentence, wordToDelete)
-
-			task.Initial = sentence
-			// Remove the word and the space after it
-			task.Desired = strings.Replace(sentence, wordToDelete+" ", "", 1)
-			task.CursorStart = startIdx
-			// Highlight the word to be deleted (including trailing space)
-			task.HighlightStart = startIdx
-			task.HighlightEnd = startIdx + len(wordToDelete) + 1 // +1 for space
-			task.OptimalKeys = "dw"
-			task.OptimalCount = 2
-			task.Description = "Delete word"
-			task.Hint = "Use 'dw' to delete the word under the cursor"
-			task.ID = fmt.Sprintf("gen-delete-dw-%d", g.rng.Int())
-		} else {
-			// Delete character with x
-			if len(sentence) > 1 {
-				pos := g.rng.Intn(len(sentence))
-				task.Initial = sentence
-				task.Desired = sentence[:pos] + sentence[pos+1:]
-				task.CursorStart = pos
-				// Highlight the character to be deleted
-				task.HighlightStart = pos
-				task.HighlightEnd = pos + 1
-				task.OptimalKeys = "x"
-				task.OptimalCount = 1
-				task.Description = "Delete character"
-				task.Hint = "Use 'x' to delete the character under the cursor"
-				task.ID = fmt.Sprintf("gen-delete-x-%d", g.rng.Int())
-			}
-		}
-
-	case 2:
-		// Text object delete: daw, diw
-		if len(words) >= 2 {
-			wordIdx := g.rng.Intn(len(words))
-			wordToDelete := words[wordIdx]
-			startIdx := strings.Index(sentence, wordToDelete)
-			// Position cursor in middle of word
-			cursorPos := startIdx + len(wordToDelete)/2
-
-			task.Initial = sentence
-			// daw removes word and surrounding space
-			if wordIdx == 0 {
-				task.Desired = strings.TrimPrefix(sentence, wordToDelete+" ")
-				task.HighlightStart = 0
-				task.HighlightEnd = len(wordToDelete) + 1
-			} else if wordIdx == len(words)-1 {
-				task.Desired = strings.TrimSuffix(sentence, " "+wordToDelete)
-				task.HighlightStart = startIdx - 1 // Include leading space
-				task.HighlightEnd = startIdx + len(wordToDelete)
-			} else {
-				task.Desired = strings.Replace(sentence, " "+wordToDelete, "", 1)
-				task.HighlightStart = startIdx - 1 // Include leading space
-				task.HighlightEnd = startIdx + len(wordToDelete)
-			}
-			task.CursorStart = cursorPos
-			task.OptimalKeys = "daw"
-			task.OptimalCount = 3
-			task.Description = "Delete a word"
-			task.Hint = "Use 'daw' to delete 'a word' including surrounding space"
-			task.ID = fmt.Sprintf("gen-delete-daw-%d", g.rng.Int())
-		}
+	if len(words) >= 2 {
+		wordIdx := g.rng.Intn(len(words) - 1) // Not the last word
+		wordToDelete := words[wordIdx]
+		startIdx := wordStarts(sentence)[wordIdx]
 
-	default:
-		// Advanced: dt, df
-		// Find a punctuation or specific character to delete until
-		word, wordStart := g.randomWord(sentence)
-		if len(word) > 0 && wordStart > 0 {
-			targetChar := word[0]
-			task.Initial = sentence
-			task.Desired = sentence[wordStart:]
-			task.CursorStart = 0
-			// Highlight from cursor to target
-			task.HighlightStart = 0
-			task.HighlightEnd = wordStart
-			task.OptimalKeys = fmt.Sprintf("dt%c", targetChar)
-			task.OptimalCount = 3
-			task.Description = fmt.Sprintf("Delete until '%c'", targetChar)
-			task.Hint = fmt.Sprintf("Use 'dt%c' to delete until '%c'", targetChar, targetChar)
-			task.ID = fmt.Sprintf("gen-delete-dt-%d", g.rng.Int())
-		}
+		// Remove the word and the space after it
+		task.Desired = sentence[:startIdx] + sentence[startIdx+len(wordToDelete)+1:]
+		task.CursorStart = startIdx
+		// Highlight the word to be deleted (including trailing space)
+		task.HighlightStart = startIdx
+		task.HighlightEnd = startIdx + len(wordToDelete) + 1 // +1 for space
+		task.OptimalKeys = "dw"
+		task.OptimalCount = 2
+		task.Description = "Delete word"
+		task.Hint = "Use 'dw' to delete the word under the cursor"
+		task.ID = fmt.Sprintf("gen-delete-dw-%d", g.rng.Int())
+		return task, true
 	}
 
-	return task
+	if len(sentence) <= 1 {
+		return Task{}, false
+	}
+	pos := g.rng.Intn(len(sentence))
+	task.Desired = sentence[:pos] + sentence[pos+1:]
+	task.CursorStart = pos
+	// Highlight the character to be deleted
+	task.HighlightStart = pos
+	task.HighlightEnd = pos + 1
+	task.OptimalKeys = "x"
+	task.OptimalCount = 1
+	task.Description = "Delete character"
+	task.Hint = "Use 'x' to delete the character under the cursor"
+	task.ID = fmt.Sprintf("gen-delete-x-%d", g.rng.Int())
+	return task, true
 }
 
-// GenerateChangeTask generates a change task
-func (g *TaskGenerator) GenerateChangeTask(difficulty int) Task {
+// deleteObjectTask deletes a word from inside it with daw
+func (g *TaskGenerator) deleteObjectTask(difficulty int) (Task, bool) {
 	sentence := g.randomSentence()
 	words := strings.Fields(sentence)
-	replacementWords := []string{"new", "changed", "updated", "modified", "different"}
+	if len(words) < 2 {
+		return Task{}, false
+	}
+	task := Task{Category: CategoryDelete, Difficulty: difficulty, Initial: sentence, Tags: []string{"delete", "procedural"}}
 
-	var task Task
-	task.Category = CategoryChange
-	task.Difficulty = difficulty
-	task.Tags = []string{"change", "procedural"}
+	wordIdx := g.rng.Intn(len(words))
+	wordToDelete := words[wordIdx]
+	startIdx := wordStarts(sentence)[wordIdx]
+	endIdx := startIdx + len(wordToDelete)
+	// Position cursor in middle of word
+	cursorPos := startIdx + len(wordToDelete)/2
 
-	replacement := replacementWords[g.rng.Intn(len(replacementWords))]
+	// daw removes the word with the space after it, or before it for the
+	// last word
+	if wordIdx < len(words)-1 {
+		task.Desired = sentence[:startIdx] + sentence[endIdx+1:]
+		task.HighlightStart = startIdx
+		task.HighlightEnd = endIdx + 1 // Include trailing space
+	} else {
+		task.Desired = sentence[:startIdx-1]
+		task.HighlightStart = startIdx - 1 // Include leading space
+		task.HighlightEnd = endIdx
+	}
+	task.CursorStart = cursorPos
+	task.OptimalKeys = "daw"
+	task.OptimalCount = 3
+	task.Description = "Delete a word"
+	task.Hint = "Use 'daw' to delete 'a word' including surrounding space"
+	task.ID = fmt.Sprintf("gen-delete-daw-%d", g.rng.Int())
+	return task, true
+}
 
-	switch difficulty {
-	case 1:
-		// Simple change: cw, r
-		if len(words) >= 1 {
-			wordIdx := g.rng.Intn(len(words))
-			oldWord := words[wordIdx]
-			startIdx := strings.Index(sentence, oldWord)
-
-			task.Initial = sentence
-			task.Desired = strings.Replace(sentence, oldWord, replacement, 1)
-			task.CursorStart = startIdx
-			// Highlight the word to be changed
-			task.HighlightStart = startIdx
-			task.HighlightEnd = startIdx + len(oldWord)
-			task.OptimalKeys = fmt.Sprintf("cw%s<ESC>", replacement)
-			task.OptimalCount = 2 + len(replacement) + 1 // cw + word + ESC
-			task.Description = fmt.Sprintf("Change word to '%s'", replacement)
-			task.Hint = "Use 'cw' to change the word, type the new word, press ESC"
-			task.ID = fmt.Sprintf("gen-change-cw-%d", g.rng.Int())
-		}
+// deleteTillTask deletes up to the start of a word with dt
+func (g *TaskGenerator) deleteTillTask(difficulty int) (Task, bool) {
+	sentence := g.randomSentence()
+	word, wordStart := g.randomWord(sentence)
+	if len(word) == 0 || wordStart == 0 {
+		return Task{}, false
+	}
 
-	case 2:
-		// Text object change: ciw
-		if len(words) >= 1 {
-			wordIdx := g.rng.Intn(len(words))
-			oldWord := words[wordIdx]
-			startIdx := strings.Index(sentence, oldWord)
-			// Position cursor in middle of word
-			cursorPos := startIdx + len(oldWord)/2
-
-			task.Initial = sentence
-			task.Desired = strings.Replace(sentence, oldWord, replacement, 1)
-			task.CursorStart = cursorPos
-			// Highlight the word to be changed
-			task.HighlightStart = startIdx
-			task.HighlightEnd = startIdx + len(oldWord)
-			task.OptimalKeys = fmt.Sprintf("ciw%s<ESC>", replacement)
-			task.OptimalCount = 3 + len(replacement) + 1
-			task.Description = fmt.Sprintf("Change inner word to '%s'", replacement)
-			task.Hint = "Use 'ciw' to change the word regardless of cursor position"
-			task.ID = fmt.Sprintf("gen-change-ciw-%d", g.rng.Int())
-		}
+	targetChar := word[0]
+	task := Task{
+		ID:          fmt.Sprintf("gen-delete-dt-%d", g.rng.Int()),
+		Category:    CategoryDelete,
+		Difficulty:  difficulty,
+		Initial:     sentence,
+		Desired:     sentence[wordStart:],
+		CursorStart: 0,
+		// Highlight from cursor to target
+		HighlightStart: 0,
+		HighlightEnd:   wordStart,
+		OptimalKeys:    fmt.Sprintf("d%st%c", countPrefix(findCount(sentence, targetChar, 1, wordStart)), targetChar),
+		Description:    fmt.Sprintf("Delete until '%c'", targetChar),
+		Hint:           fmt.Sprintf("Use 'dt%c' to delete until '%c'", targetChar, targetChar),
+		Tags:           []string{"delete", "procedural"},
+	}
+	task.OptimalCount = len(task.OptimalKeySequence())
+	return task, true
+}
 
-	default:
-		// Advanced: C, cc - entire line
-		task.Initial = sentence
-		task.Desired = replacement
-		task.CursorStart = 0
-		// Highlight entire line
-		task.HighlightStart = 0
-		task.HighlightEnd = len(sentence)
-		task.OptimalKeys = fmt.Sprintf("cc%s<ESC>", replacement)
-		task.OptimalCount = 2 + len(replacement) + 1
-		task.Description = "Change entire line"
-		task.Hint = "Use 'cc' to change the entire line"
-		task.ID = fmt.Sprintf("gen-change-cc-%d", g.rng.Int())
+// replacementWords are words change tasks type in
+var replacementWords = []string{"new", "changed", "updated", "modified", "different"}
+
+// changeWordTask changes a word from its start with cw
+func (g *TaskGenerator) changeWordTask(difficulty int) (Task, bool) {
+	sentence := g.randomSentence()
+	words := strings.Fields(sentence)
+	replacement := replacementWords[g.rng.Intn(len(replacementWords))]
+	if len(words) == 0 {
+		return Task{}, false
 	}
 
-	return task
+	wordIdx := g.rng.Intn(len(words))
+	oldWord := words[wordIdx]
+	startIdx := wordStarts(sentence)[wordIdx]
+	return Task{
+		ID:          fmt.Sprintf("gen-change-cw-%d", g.rng.Int()),
+		Category:    CategoryChange,
+		Difficulty:  difficulty,
+		Initial:     sentence,
+		Desired:     sentence[:startIdx] + replacement + sentence[startIdx+len(oldWord):],
+		CursorStart: startIdx,
+		// Highlight the word to be changed
+		HighlightStart: startIdx,
+		HighlightEnd:   startIdx + len(oldWord),
+		OptimalKeys:    fmt.Sprintf("cw%s<Esc>", replacement),
+		OptimalCount:   2 + len(replacement) + 1, // cw + word + ESC
+		Description:    fmt.Sprintf("Change word to '%s'", replacement),
+		Hint:           "Use 'cw' to change the word, type the new word, press ESC",
+		Tags:           []string{"change", "procedural"},
+	}, true
 }
 
-// GenerateInsertTask generates an insert task
-func (g *TaskGenerator) GenerateInsertTask(difficulty int) Task {
+// changeObjectTask changes a word from inside it with ciw
+func (g *TaskGenerator) changeObjectTask(difficulty int) (Task, bool) {
 	sentence := g.randomSentence()
-	insertWords := []string{"very", "quite", "rather", "extremely", "somewhat"}
+	words := strings.Fields(sentence)
+	replacement := replacementWords[g.rng.Intn(len(replacementWords))]
+	if len(words) == 0 {
+		return Task{}, false
+	}
 
-	var task Task
-	task.Category = CategoryInsert
-	task.Difficulty = difficulty
-	task.Tags = []string{"insert", "procedural"}
+	wordIdx := g.rng.Intn(len(words))
+	oldWord := words[wordIdx]
+	startIdx := wordStarts(sentence)[wordIdx]
+	return Task{
+		ID:         fmt.Sprintf("gen-change-ciw-%d", g.rng.Int()),
+		Category:   CategoryChange,
+		Difficulty: difficulty,
+		Initial:    sentence,
+		Desired:    sentence[:startIdx] + replacement + sentence[startIdx+len(oldWord):],
+		// Position cursor in middle of word
+		CursorStart:    startIdx + len(oldWord)/2,
+		HighlightStart: startIdx,
+		HighlightEnd:   startIdx + len(oldWord),
+		OptimalKeys:    fmt.Sprintf("ciw%s<Esc>", replacement),
+		OptimalCount:   3 + len(replacement) + 1,
+		Description:    fmt.Sprintf("Change inner word to '%s'", replacement),
+		Hint:           "Use 'ciw' to change the word regardless of cursor position",
+		Tags:           []string{"change", "procedural"},
+	}, true
+}
 
-	insertion := insertWords[g.rng.Intn(len(insertWords))]
+// changeLineTask replaces the whole line with cc
+func (g *TaskGenerator) changeLineTask(difficulty int) (Task, bool) {
+	sentence := g.randomSentence()
+	replacement := replacementWords[g.rng.Intn(len(replacementWords))]
+	return Task{
+		ID:          fmt.Sprintf("gen-change-cc-%d", g.rng.Int()),
+		Category:    CategoryChange,
+		Difficulty:  difficulty,
+		Initial:     sentence,
+		Desired:     replacement,
+		CursorStart: 0,
+		// Highlight entire line
+		HighlightStart: 0,
+		HighlightEnd:   len(sentence),
+		OptimalKeys:    fmt.Sprintf("cc%s<Esc>", replacement),
+		OptimalCount:   2 + len(replacement) + 1,
+		Description:    "Change entire line",
+		Hint:           "Use 'cc' to change the entire line",
+		Tags:           []string{"change", "procedural"},
+	}, true
+}
 
-	switch difficulty {
-	case 1:
-		// Simple insert: i, a, A
-		words := strings.Fields(sentence)
-		if len(words) >= 2 {
-			// Insert before second word
-			secondWord := words[1]
-			insertPos := strings.Index(sentence, secondWord)
-
-			task.Initial = sentence
-			task.Desired = sentence[:insertPos] + insertion + " " + sentence[insertPos:]
-			task.CursorStart = insertPos
-			task.OptimalKeys = fmt.Sprintf("i%s <ESC>", insertion)
-			task.OptimalCount = 1 + len(insertion) + 1 + 1
-			task.Description = fmt.Sprintf("Insert '%s' before cursor", insertion)
-			task.Hint = "Use 'i' to insert before the cursor"
-			task.ID = fmt.Sprintf("gen-insert-i-%d", g.rng.Int())
-		}
+// insertWords are words insert tasks type in
+var insertWords = []string{"very", "quite", "rather", "extremely", "somewhat"}
 
-	case 2:
-		// Append: A (end of line)
-		task.Initial = sentence
-		task.Desired = sentence + " " + insertion
-		task.CursorStart = 0
-		task.OptimalKeys = fmt.Sprintf("A %s<ESC>", insertion)
-		task.OptimalCount = 1 + 1 + len(insertion) + 1
-		task.Description = "Append at end of line"
-		task.Hint = "Use 'A' to append at the end of the line"
-		task.ID = fmt.Sprintf("gen-insert-A-%d", g.rng.Int())
-
-	default:
-		// Open line: o, O
-		task.Initial = sentence
-		task.Desired = sentence + "\n" + insertion
-		task.CursorStart = 0
-		task.OptimalKeys = fmt.Sprintf("o%s<ESC>", insertion)
-		task.OptimalCount = 1 + len(insertion) + 1
-		task.Description = "Open new line below"
-		task.Hint = "Use 'o' to open a new line below and enter insert mode"
-		task.ID = fmt.Sprintf("gen-insert-o-%d", g.rng.Int())
+// insertBeforeTask inserts a word before the second word with i
+func (g *TaskGenerator) insertBeforeTask(difficulty int) (Task, bool) {
+	sentence := g.randomSentence()
+	insertion := insertWords[g.rng.Intn(len(insertWords))]
+	if len(strings.Fields(sentence)) < 2 {
+		return Task{}, false
 	}
 
-	return task
+	insertPos := wordStarts(sentence)[1]
+	return Task{
+		ID:           fmt.Sprintf("gen-insert-i-%d", g.rng.Int()),
+		Category:     CategoryInsert,
+		Difficulty:   difficulty,
+		Initial:      sentence,
+		Desired:      sentence[:insertPos] + insertion + " " + sentence[insertPos:],
+		CursorStart:  insertPos,
+		OptimalKeys:  fmt.Sprintf("i%s <Esc>", insertion),
+		OptimalCount: 1 + len(insertion) + 1 + 1,
+		Description:  fmt.Sprintf("Insert '%s' before cursor", insertion),
+		Hint:         "Use 'i' to insert before the cursor",
+		Tags:         []string{"insert", "procedural"},
+	}, true
 }
 
-// GenerateVisualTask generates a visual mode task
-func (g *TaskGenerator) GenerateVisualTask(difficulty int) Task {
+// insertAppendTask appends a word to the line with A
+func (g *TaskGenerator) insertAppendTask(difficulty int) (Task, bool) {
 	sentence := g.randomSentence()
-	words := strings.Fields(sentence)
+	insertion := insertWords[g.rng.Intn(len(insertWords))]
+	return Task{
+		ID:           fmt.Sprintf("gen-insert-A-%d", g.rng.Int()),
+		Category:     CategoryInsert,
+		Difficulty:   difficulty,
+		Initial:      sentence,
+		Desired:      sentence + " " + insertion,
+		CursorStart:  0,
+		OptimalKeys:  fmt.Sprintf("A %s<Esc>", insertion),
+		OptimalCount: 1 + 1 + len(insertion) + 1,
+		Description:  "Append at end of line",
+		Hint:         "Use 'A' to append at the end of the line",
+		Tags:         []string{"insert", "procedural"},
+	}, true
+}
 
-	var task Task
-	task.Category = CategoryVisual
-	task.Difficulty = max(difficulty, 2) // Visual is at least level 2
-	task.Tags = []string{"visual", "procedural"}
+// insertOpenLineTask opens a line below with o
+func (g *TaskGenerator) insertOpenLineTask(difficulty int) (Task, bool) {
+	sentence := g.randomSentence()
+	insertion := insertWords[g.rng.Intn(len(insertWords))]
+	return Task{
+		ID:           fmt.Sprintf("gen-insert-o-%d", g.rng.Int()),
+		Category:     CategoryInsert,
+		Difficulty:   difficulty,
+		Initial:      sentence,
+		Desired:      sentence + "\n" + insertion,
+		CursorStart:  0,
+		OptimalKeys:  fmt.Sprintf("o%s<Esc>", insertion),
+		OptimalCount: 1 + len(insertion) + 1,
+		Description:  "Open new line below",
+		Hint:         "Use 'o' to open a new line below and enter insert mode",
+		Tags:         []string{"insert", "procedural"},
+	}, true
+}
 
-	if len(words) >= 2 {
-		// Visual delete word
-		wordIdx := g.rng.Intn(len(words))
-		wordToDelete := words[wordIdx]
-		startIdx := strings.Index(sentence, wordToDelete)
-
-		task.Initial = sentence
-		// Remove word
-		if wordIdx < len(words)-1 {
-			task.Desired = strings.Replace(sentence, wordToDelete+" ", "", 1)
-		} else {
-			task.Desired = strings.Replace(sentence, " "+wordToDelete, "", 1)
-		}
-		task.CursorStart = startIdx
-		task.OptimalKeys = "viwd"
-		task.OptimalCount = 4
-		task.Description = "Visually select and delete word"
-		task.Hint = "Use 'viw' to visually select the word, then 'd' to delete"
-		task.ID = fmt.Sprintf("gen-visual-viwd-%d", g.rng.Int())
+// visualWordTask selects a word with its space and deletes it
+func (g *TaskGenerator) visualWordTask(difficulty int) (Task, bool) {
+	sentence := g.randomSentence()
+	words := strings.Fields(sentence)
+	if len(words) < 2 {
+		return Task{}, false
 	}
 
-	return task
+	wordIdx := g.rng.Intn(len(words))
+	wordToDelete := words[wordIdx]
+	startIdx := wordStarts(sentence)[wordIdx]
+	endIdx := startIdx + len(wordToDelete)
+
+	// Remove word with the space after it, or before it for the last word
+	var desired string
+	if wordIdx < len(words)-1 {
+		desired = sentence[:startIdx] + sentence[endIdx+1:]
+	} else {
+		desired = sentence[:startIdx-1]
+	}
+	return Task{
+		ID:           fmt.Sprintf("gen-visual-viwd-%d", g.rng.Int()),
+		Category:     CategoryVisual,
+		Difficulty:   max(difficulty, 2), // Visual is at least level 2
+		Initial:      sentence,
+		Desired:      desired,
+		CursorStart:  startIdx,
+		OptimalKeys:  "vawd",
+		OptimalCount: 4,
+		Description:  "Visually select and delete word",
+		Hint:         "Use 'vaw' to visually select a word with its space, then 'd' to delete",
+		Tags:         []string{"visual", "procedural"},
+	}, true
 }
 
-// GenerateComplexTask generates a complex multi-step task
-func (g *TaskGenerator) GenerateComplexTask(difficulty int) Task {
+// complexSwapTask swaps the first two words, pasting before the third
+func (g *TaskGenerator) complexSwapTask(difficulty int) (Task, bool) {
 	sentence := g.randomSentence()
 	words := strings.Fields(sentence)
+	if len(words) < 3 {
+		return Task{}, false
+	}
 
-	var task Task
-	task.Category = CategoryComplex
-	task.Difficulty = max(difficulty, 3) // Complex is at least level 3
-	task.Tags = []string{"complex", "procedural"}
+	newWords := make([]string, len(words))
+	copy(newWords, words)
+	newWords[0], newWords[1] = newWords[1], newWords[0]
+	return Task{
+		ID:           fmt.Sprintf("gen-complex-swap-%d", g.rng.Int()),
+		Category:     CategoryComplex,
+		Difficulty:   max(difficulty, 3), // Complex is at least level 3
+		Initial:      sentence,
+		Desired:      strings.Join(newWords, " "),
+		CursorStart:  0,
+		OptimalKeys:  "dwwP",
+		OptimalCount: 4,
+		Description:  "Swap first two words",
+		Hint:         "Delete first word, move to next word, paste before",
+		Tags:         []string{"complex", "procedural"},
+	}, true
+}
 
-	if len(words) >= 2 {
-		// Swap first two words
-		task.Initial = sentence
-		newWords := make([]string, len(words))
-		copy(newWords, words)
-		newWords[0], newWords[1] = newWords[1], newWords[0]
-		task.Desired = strings.Join(newWords, " ")
-		task.CursorStart = 0
-		task.OptimalKeys = "dwwP"
-		task.OptimalCount = 4
-		task.Description = "Swap first two words"
-		task.Hint = "Delete first word, move to next word, paste before"
-		task.ID = fmt.Sprintf("gen-complex-swap-%d", g.rng.Int())
+// complexReflowTask generates a paragraph formatting task from consecutive
+// sentences of one source, each starting on its own line
+func (g *TaskGenerator) complexReflowTask(difficulty int) (Task, bool) {
+	sources := g.proseSources()
+	source := sources[g.rng.Intn(len(sources))]
+	lines := source.Sentences
+	if len(lines) > 3 {
+		start := g.rng.Intn(len(lines) - 2)
+		lines = lines[start : start+3]
 	}
+	initial := strings.Join(lines, "\n")
 
-	return task
+	// Let the engine do the formatting so the task matches what gq produces
+	width := 30 + 10*g.rng.Intn(2)
+	engine := vim.NewEngine(initial)
+	engine.SetOptions(vim.Options{TextWidth: width})
+	engine.ProcessKeys(vim.ParseKeys("gqG"))
+
+	return Task{
+		ID:           fmt.Sprintf("gen-complex-gq-%d", g.rng.Int()),
+		Category:     CategoryComplex,
+		Difficulty:   max(difficulty, 3),
+		Initial:      initial,
+		Desired:      engine.Text(),
+		CursorStart:  0,
+		TextWidth:    width,
+		OptimalKeys:  "gqG",
+		OptimalCount: 3,
+		Description:  fmt.Sprintf("Reflow the paragraph to %d columns", width),
+		Hint:         "Use 'gq' with a motion over the paragraph, such as 'gqip' or 'gqG'",
+		Tags:         []string{"complex", "format", "procedural"},
+	}, true
+}
+
+// roundCategories lists the task categories in the order rounds use them
+var roundCategories = []TaskCategory{
+	CategoryMotion, CategoryDelete, CategoryChange,
+	CategoryInsert, CategoryVisual, CategoryComplex,
 }
 
 // GenerateTasksForRound generates all tasks for a round
 func (g *TaskGenerator) GenerateTasksForRound(roundType string) []Task {
-	var tasks []Task
+	minDiff, maxDiff := RoundDifficulty(roundType)
+	tasks := g.GenerateRound(RoundDistribution(), minDiff, maxDiff)
+	g.shuffle(tasks)
+	return tasks
+}
 
-	// Distribution per round: 6 motion, 6 delete, 6 change, 6 insert, 3 visual, 3 complex = 30
-	distribution := map[TaskCategory]int{
+// RoundDistribution returns the number of tasks per category in a round:
+// 6 motion, 6 delete, 6 change, 6 insert, 3 visual, 3 complex = 30
+func RoundDistribution() map[TaskCategory]int {
+	return map[TaskCategory]int{
 		CategoryMotion:  6,
 		CategoryDelete:  6,
 		CategoryChange:  6,
@@ -695,56 +992,203 @@ func (g *TaskGenerator) GenerateTasksForRound(roundType string) []Task {
 		CategoryVisual:  3,
 		CategoryComplex: 3,
 	}
+}
 
-	// Difficulty range based on round type
-	minDiff, maxDiff := 1, 1
+// RoundDifficulty returns the difficulty range of a round type
+func RoundDifficulty(roundType string) (minDiff, maxDiff int) {
 	switch roundType {
-	case "beginner":
-		minDiff, maxDiff = 1, 1
 	case "intermediate":
-		minDiff, maxDiff = 1, 2
+		return 1, 2
 	case "advanced":
-		minDiff, maxDiff = 2, 3
+		return 2, 3
 	case "expert":
-		minDiff, maxDiff = 3, 4
+		return 3, 4
 	case "mixed":
-		minDiff, maxDiff = 1, 4
+		return 1, 4
 	}
+	return 1, 1
+}
+
+// GenerateRound generates the given number of tasks per category, each
+// with a difficulty between minDiff and maxDiff, in category order
+func (g *TaskGenerator) GenerateRound(distribution map[TaskCategory]int, minDiff, maxDiff int) []Task {
+	var tasks []Task
 
-	for cat, count := range distribution {
+	// Walk the categories in a fixed order so a seeded generator always
+	// produces the same round
+	for _, cat := range roundCategories {
+		count := distribution[cat]
 		for i := 0; i < count; i++ {
-			diff := minDiff
-			if maxDiff > minDiff {
-				diff = minDiff + g.rng.Intn(maxDiff-minDiff+1)
+			diff := g.randomDifficulty(minDiff, maxDiff)
+			if task, ok := g.generateCalibratedTask(cat, diff, minDiff, maxDiff); ok {
+				tasks = append(tasks, task)
 			}
+		}
+	}
 
-			var task Task
-			switch cat {
-			case CategoryMotion:
-				task = g.GenerateMotionTask(diff)
-			case CategoryDelete:
-				task = g.GenerateDeleteTask(diff)
-			case CategoryChange:
-				task = g.GenerateChangeTask(diff)
-			case CategoryInsert:
-				task = g.GenerateInsertTask(diff)
-			case CategoryVisual:
-				task = g.GenerateVisualTask(diff)
-			case CategoryComplex:
-				task = g.GenerateComplexTask(diff)
-			}
-			tasks = append(tasks, task)
+	return tasks
+}
+
+// randomDifficulty picks a difficulty between minDiff and maxDiff
+func (g *TaskGenerator) randomDifficulty(minDiff, maxDiff int) int {
+	if maxDiff > minDiff {
+		return minDiff + g.rng.Intn(maxDiff-minDiff+1)
+	}
+	return minDiff
+}
+
+// generateCalibratedTask generates a task for a slot of difficulty diff,
+// keeping the first whose difficulty score falls in the slot's band, or
+// else the nearest of a few. Candidates after the first may be asked for
+// at any difficulty of the round, since generators asked for one level
+// often make tasks that score as another.
+func (g *TaskGenerator) generateCalibratedTask(cat TaskCategory, diff, minDiff, maxDiff int) (Task, bool) {
+	var best Task
+	bestDistance := math.Inf(1)
+	level := diff
+	for i := 0; i < calibrationCandidates && bestDistance > 0; i++ {
+		if i > 0 {
+			level = g.randomDifficulty(minDiff, maxDiff)
+		}
+		task, ok := g.generateValidTask(cat, level)
+		if !ok {
+			continue
+		}
+		if distance := bandDistance(task.DifficultyScore, diff); distance < bestDistance {
+			best, bestDistance = task, distance
 		}
 	}
+	return best, !math.IsInf(bestDistance, 1)
+}
 
-	// Shuffle tasks
+// shuffle puts tasks in random order
+func (g *TaskGenerator) shuffle(tasks []Task) {
 	g.rng.Shuffle(len(tasks), func(i, j int) {
 		tasks[i], tasks[j] = tasks[j], tasks[i]
 	})
+}
+
+// ReviewPlan weights a review round toward the categories and commands
+// that are due or weak. Anything missing from the maps has never been
+// practised and counts as due.
+type ReviewPlan struct {
+	Categories map[TaskCategory]float64 // Review priority per category
+	Commands   map[string]float64       // Review priority per command
+	Difficulty map[TaskCategory]int     // Difficulty per category, 1 if missing
+}
+
+// reviewCandidates is how many tasks a review round generates for each slot
+// before keeping the one whose commands are most urgent
+const reviewCandidates = 3
+
+// GenerateReviewRound generates count tasks, picking categories in
+// proportion to their priority and, within a category, the task whose
+// commands most need review
+func (g *TaskGenerator) GenerateReviewRound(plan ReviewPlan, count int) []Task {
+	categories := roundCategories
+	weights := make([]float64, len(categories))
+	total := 0.0
+	for i, cat := range categories {
+		weights[i] = 1
+		if p, ok := plan.Categories[cat]; ok {
+			weights[i] = p
+		}
+		weights[i] = math.Max(weights[i], 0.1) // Keep every category in play
+		total += weights[i]
+	}
+
+	var tasks []Task
+	for slot := 0; slot < count*2 && len(tasks) < count; slot++ {
+		pick := g.rng.Float64() * total
+		cat := categories[len(categories)-1]
+		for i, w := range weights {
+			if pick < w {
+				cat = categories[i]
+				break
+			}
+			pick -= w
+		}
+
+		diff := max(plan.Difficulty[cat], 1)
+		var best Task
+		bestScore := -1.0
+		for i := 0; i < reviewCandidates; i++ {
+			task, ok := g.generateValidTask(cat, diff)
+			if !ok {
+				continue
+			}
+			score := 0.0
+			for _, name := range task.Commands() {
+				if p, ok := plan.Commands[name]; ok {
+					score += p
+				} else {
+					score++
+				}
+			}
+			if score > bestScore {
+				best, bestScore = task, score
+			}
+		}
+		if bestScore < 0 {
+			continue
+		}
+		tasks = append(tasks, best)
+	}
 
 	return tasks
 }
 
+// generateValidTask generates a task of the category from a template the
+// registry has for it, retrying with fresh text and maybe another template
+// while the task fails its template's check or validation
+func (g *TaskGenerator) generateValidTask(cat TaskCategory, diff int) (Task, bool) {
+	corpus := g.corpus()
+	templates := g.templates.Fitting(cat, diff, corpus)
+	if len(templates) == 0 {
+		g.problems = append(g.problems, fmt.Errorf("%s tasks at difficulty %d: %w", cat, diff, ErrNoTemplate))
+		return Task{}, false
+	}
+
+	var err error
+	for attempt := 0; attempt < maxGenerateAttempts; attempt++ {
+		var task Task
+		if task, err = g.generateFrom(pickTemplate(g.rng, templates), corpus, diff); err == nil {
+			return task, true
+		}
+	}
+
+	g.problems = append(g.problems, err)
+	return Task{}, false
+}
+
+// generateFrom makes one task with a template, validated and scored, or
+// says why the template's task was no good. The template's keys are
+// trusted once they validate: the solver is left until the task is played,
+// see Session.StartTask.
+func (g *TaskGenerator) generateFrom(template TaskTemplate, corpus Corpus, diff int) (Task, error) {
+	name := template.Info().Name
+	task, ok := template.Generate(g.rng, corpus, diff)
+	if !ok {
+		return Task{}, &TemplateError{Template: name, Err: ErrTemplateNoGeneration}
+	}
+	if err := template.Check(task); err != nil {
+		return Task{}, &TemplateError{Template: name, Err: err}
+	}
+	if task.OptimalCount == 0 {
+		task.OptimalCount = len(task.OptimalKeySequence())
+	}
+	if err := task.Validate(); err != nil {
+		return Task{}, err
+	}
+	task.Calibrate(g.calibration)
+	return task, nil
+}
+
+// Problems returns the validation errors of tasks the generator gave up on
+func (g *TaskGenerator) Problems() []error {
+	return g.problems
+}
+
 // Helper function
 func min(a, b int) int {
 	if a < b {
@@ -0,0 +1,97 @@
+package game
+
+import (
+	"time"
+
+	"github.com/timlinux/macaco/internal/replay"
+	"github.com/timlinux/macaco/internal/stats"
+)
+
+// Ghost is the player's best run of a round code, raced task by task in a
+// new attempt at the same round
+type Ghost struct {
+	RoundCode   string      `json:"round_code"`
+	SessionID   string      `json:"session_id"` // Of the best run
+	PlayedAt    time.Time   `json:"played_at"`
+	TotalTimeMs int64       `json:"total_time_ms"`
+	Tasks       []GhostTask `json:"tasks"`
+}
+
+// GhostTask is the best run's attempt at one task of the round
+type GhostTask struct {
+	TaskID  string            `json:"task_id"`
+	TimeMs  int64             `json:"time_ms"`
+	Success bool              `json:"success"`
+	Replay  *replay.Recording `json:"replay"`
+}
+
+// GhostDelta compares the time the player took on a task with the ghost's.
+// A negative delta means the player was ahead.
+type GhostDelta struct {
+	Index       int    `json:"index"`
+	TaskID      string `json:"task_id"`
+	TimeMs      int64  `json:"time_ms"`
+	GhostTimeMs int64  `json:"ghost_time_ms"`
+	DeltaMs     int64  `json:"delta_ms"`
+	TotalMs     int64  `json:"total_ms"` // Sum of the deltas up to this task
+}
+
+// Ahead returns true if the player beat the ghost on the task
+func (d GhostDelta) Ahead() bool {
+	return d.DeltaMs < 0
+}
+
+// NewGhost makes a ghost of a recorded run, or returns nil if the run
+// doesn't have a replay of every task
+func NewGhost(run *stats.SessionStats) *Ghost {
+	if run == nil || !run.HasReplays() {
+		return nil
+	}
+	g := &Ghost{
+		RoundCode:   run.RoundCode,
+		SessionID:   run.SessionID,
+		PlayedAt:    run.StartedAt,
+		TotalTimeMs: run.TotalTimeMs,
+	}
+	for _, task := range run.Tasks {
+		g.Tasks = append(g.Tasks, GhostTask{
+			TaskID:  task.TaskID,
+			TimeMs:  task.TimeMs,
+			Success: task.Success,
+			Replay:  task.Replay,
+		})
+	}
+	return g
+}
+
+// Task returns the ghost's attempt at task i of the round, or nil if it has
+// none or it was at a different task
+func (g *Ghost) Task(i int, taskID string) *GhostTask {
+	if g == nil || i < 0 || i >= len(g.Tasks) || g.Tasks[i].TaskID != taskID {
+		return nil
+	}
+	return &g.Tasks[i]
+}
+
+// Deltas compares the player's time on each finished task with the ghost's
+func (g *Ghost) Deltas(results []TaskResult) []GhostDelta {
+	var deltas []GhostDelta
+	var total int64
+	for i, result := range results {
+		task := g.Task(i, result.TaskID)
+		if task == nil {
+			continue
+		}
+		delta := result.TimeMs - task.TimeMs
+		total += delta
+		deltas = append(deltas, GhostDelta{
+			Index:       i,
+			TaskID:      result.TaskID,
+			TimeMs:      result.TimeMs,
+			GhostTimeMs: task.TimeMs,
+			DeltaMs:     delta,
+			TotalMs:     total,
+		})
+	}
+	return deltas
+}
@@ -0,0 +1,167 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/timlinux/macaco/internal/replay"
+	"github.com/timlinux/macaco/internal/stats"
+)
+
+// ghostRun returns a recorded run of tasks with the given times, each with
+// a replay
+func ghostRun(times map[string]int64, ids ...string) *stats.SessionStats {
+	run := &stats.SessionStats{
+		SessionID: "best",
+		RoundCode: "MCC3-3F9A-mixed-3-O",
+		StartedAt: time.Date(2026, 2, 11, 9, 0, 0, 0, time.UTC),
+	}
+	for _, id := range ids {
+		run.TotalTimeMs += times[id]
+		run.Tasks = append(run.Tasks, &stats.TaskStats{
+			TaskID:  id,
+			TimeMs:  times[id],
+			Success: true,
+			Replay:  &replay.Recording{Initial: "text", Keys: "dw"},
+		})
+	}
+	return run
+}
+
+func TestNewGhost(t *testing.T) {
+	run := ghostRun(map[string]int64{"a": 1000, "b": 2000}, "a", "b")
+	g := NewGhost(run)
+	if g == nil {
+		t.Fatal("NewGhost returned nil for a run with replays")
+	}
+	if g.RoundCode != run.RoundCode || g.SessionID != "best" || !g.PlayedAt.Equal(run.StartedAt) || g.TotalTimeMs != 3000 {
+		t.Errorf("NewGhost = %+v, want the run's code, ID, start and total", g)
+	}
+	if len(g.Tasks) != 2 || g.Tasks[1].TaskID != "b" || g.Tasks[1].TimeMs != 2000 || g.Tasks[1].Replay != run.Tasks[1].Replay {
+		t.Errorf("NewGhost tasks = %+v, want one per task of the run", g.Tasks)
+	}
+
+	noReplay := ghostRun(map[string]int64{"a": 1000, "b": 2000}, "a", "b")
+	noReplay.Tasks[1].Replay = nil
+	tests := []struct {
+		name string
+		run  *stats.SessionStats
+	}{
+		{"nil run", nil},
+		{"no tasks", &stats.SessionStats{RoundCode: "MCC3-3F9A-mixed-3-O"}},
+		{"missing replay", noReplay},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if g := NewGhost(tt.run); g != nil {
+				t.Errorf("NewGhost = %+v, want nil", g)
+			}
+		})
+	}
+}
+
+func TestGhostTask(t *testing.T) {
+	g := NewGhost(ghostRun(map[string]int64{"a": 1000, "b": 2000}, "a", "b"))
+	tests := []struct {
+		name   string
+		ghost  *Ghost
+		index  int
+		taskID string
+		want   string
+	}{
+		{"first", g, 0, "a", "a"},
+		{"second", g, 1, "b", "b"},
+		{"different task", g, 1, "a", ""},
+		{"negative index", g, -1, "a", ""},
+		{"past the end", g, 2, "c", ""},
+		{"no ghost", nil, 0, "a", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := tt.ghost.Task(tt.index, tt.taskID)
+			got := ""
+			if task != nil {
+				got = task.TaskID
+			}
+			if got != tt.want {
+				t.Errorf("Task(%d, %q) = %q, want %q", tt.index, tt.taskID, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGhostDeltas(t *testing.T) {
+	g := NewGhost(ghostRun(map[string]int64{"a": 1000, "b": 2000, "c": 1500}, "a", "b", "c"))
+	tests := []struct {
+		name    string
+		results []TaskResult
+		want    []GhostDelta
+	}{
+		{
+			name:    "none finished",
+			results: nil,
+			want:    nil,
+		},
+		{
+			name: "ahead then behind",
+			results: []TaskResult{
+				{TaskID: "a", TimeMs: 800},
+				{TaskID: "b", TimeMs: 2500},
+			},
+			want: []GhostDelta{
+				{Index: 0, TaskID: "a", TimeMs: 800, GhostTimeMs: 1000, DeltaMs: -200, TotalMs: -200},
+				{Index: 1, TaskID: "b", TimeMs: 2500, GhostTimeMs: 2000, DeltaMs: 500, TotalMs: 300},
+			},
+		},
+		{
+			name: "skips a different task",
+			results: []TaskResult{
+				{TaskID: "a", TimeMs: 1000},
+				{TaskID: "x", TimeMs: 9000},
+				{TaskID: "c", TimeMs: 1000},
+			},
+			want: []GhostDelta{
+				{Index: 0, TaskID: "a", TimeMs: 1000, GhostTimeMs: 1000, DeltaMs: 0, TotalMs: 0},
+				{Index: 2, TaskID: "c", TimeMs: 1000, GhostTimeMs: 1500, DeltaMs: -500, TotalMs: -500},
+			},
+		},
+		{
+			name: "more results than ghost tasks",
+			results: []TaskResult{
+				{TaskID: "a", TimeMs: 1000},
+				{TaskID: "b", TimeMs: 2000},
+				{TaskID: "c", TimeMs: 1500},
+				{TaskID: "d", TimeMs: 100},
+			},
+			want: []GhostDelta{
+				{Index: 0, TaskID: "a", TimeMs: 1000, GhostTimeMs: 1000},
+				{Index: 1, TaskID: "b", TimeMs: 2000, GhostTimeMs: 2000},
+				{Index: 2, TaskID: "c", TimeMs: 1500, GhostTimeMs: 1500},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := g.Deltas(tt.results); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Deltas = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGhostDeltaAhead(t *testing.T) {
+	tests := []struct {
+		delta int64
+		want  bool
+	}{
+		{-1, true},
+		{0, false},
+		{1, false},
+	}
+	for _, tt := range tests {
+		if got := (GhostDelta{DeltaMs: tt.delta}).Ahead(); got != tt.want {
+			t.Errorf("Ahead() with delta %d = %v, want %v", tt.delta, got, tt.want)
+		}
+	}
+}
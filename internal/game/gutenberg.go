@@ -0,0 +1,268 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Limits on imported books
+const (
+	minImportedLine      = 15   // Shortest sentence kept
+	minImportedWords     = 3    // Fewest words in a sentence kept
+	maxImportedSentences = 1000 // Sentences kept per book, spread through it
+	maxPublicDomainYear  = 1930 // Latest year taken as the publication year
+)
+
+// Import errors
+const (
+	ErrNotGutenberg      GameError = "no Project Gutenberg START and END markers"
+	ErrNoTitle           GameError = "no title in the header"
+	ErrNoAuthor          GameError = "no author in the header"
+	ErrNoSentences       GameError = "no usable sentences"
+	ErrSourceNoName      GameError = "source has no name"
+	ErrSourceNoSentences GameError = "source has no usable sentences"
+)
+
+// gutenbergMarker matches the lines around the text of a Gutenberg book
+var gutenbergMarker = regexp.MustCompile(`(?m)^\*\*\* ?(START|END) OF (THE|THIS) PROJECT GUTENBERG E?BOOK.*$`)
+
+// headerField matches a field of the header, such as "Author: Jane Austen"
+var headerField = regexp.MustCompile(`(?m)^(Title|Author|Release [Dd]ate|Original publication):\s*(.+?)\s*$`)
+
+// yearPattern matches a plausible publication year
+var yearPattern = regexp.MustCompile(`\b1[4-9]\d\d\b`)
+
+// importedLine matches the characters an imported sentence may have
+var importedLine = regexp.MustCompile(`^[A-Za-z0-9 .,;:!?'"()-]+$`)
+
+// typography maps the typographic characters of Gutenberg texts to plain
+// ones. Underscores mark italics.
+var typography = strings.NewReplacer(
+	"‘", "'", "’", "'", "“", `"`, "”", `"`,
+	"—", " - ", "–", "-", "--", " - ", "_", "",
+	"\u00a0", " ",
+)
+
+// ImportGutenberg reads a Project Gutenberg plain text book as a prose
+// source. The licence header and footer are stripped, the text is split
+// into sentences, and sentences that are too long to play or have odd
+// characters are dropped. Name and Author come from the header, and Year
+// from its original publication date or the first year in the front
+// matter; Year is 0 if neither has one.
+func ImportGutenberg(path string) (*TextSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+
+	markers := gutenbergMarker.FindAllStringSubmatchIndex(text, -1)
+	start, end := -1, -1
+	for _, m := range markers {
+		switch text[m[2]:m[3]] {
+		case "START":
+			if start < 0 {
+				start = m[1]
+			}
+		case "END":
+			end = m[0]
+		}
+	}
+	if start < 0 || end < start {
+		return nil, ErrNotGutenberg
+	}
+	header, body := text[:start], text[start:end]
+
+	fields := make(map[string]string)
+	for _, m := range headerField.FindAllStringSubmatch(header, -1) {
+		fields[strings.ToLower(m[1])] = m[2]
+	}
+	src := &TextSource{
+		Name:    fields["title"],
+		Author:  fields["author"],
+		License: "Public Domain",
+	}
+	switch {
+	case src.Name == "":
+		return nil, ErrNoTitle
+	case src.Author == "":
+		return nil, ErrNoAuthor
+	}
+	src.Year = publicationYear(fields["original publication"], body)
+	src.Attribution = sourceAttribution(src)
+
+	src.Sentences = bookSentences(body)
+	if len(src.Sentences) == 0 {
+		return nil, ErrNoSentences
+	}
+	return src, nil
+}
+
+// publicationYear returns the year of the original publication field, or
+// else the first plausible year in the first lines of the body
+func publicationYear(original, body string) int {
+	frontMatter := body
+	if lines := strings.SplitN(body, "\n", 100); len(lines) == 100 {
+		frontMatter = strings.Join(lines[:99], "\n")
+	}
+	for _, text := range []string{original, frontMatter} {
+		for _, match := range yearPattern.FindAllString(text, -1) {
+			if year, _ := strconv.Atoi(match); year <= maxPublicDomainYear {
+				return year
+			}
+		}
+	}
+	return 0
+}
+
+// sourceAttribution credits a book in the style of the built-in sources
+func sourceAttribution(src *TextSource) string {
+	if src.Year == 0 {
+		return fmt.Sprintf("Text from '%s' by %s, sourced from Project Gutenberg", src.Name, src.Author)
+	}
+	return fmt.Sprintf("Text from '%s' by %s (%d), sourced from Project Gutenberg", src.Name, src.Author, src.Year)
+}
+
+// bookSentences returns the playable sentences of a book's text, at most
+// maxImportedSentences spread evenly through it
+func bookSentences(body string) []string {
+	var lines []string
+	for _, sentence := range splitSentences(typography.Replace(body)) {
+		for _, fragment := range sentenceFragments(sentence) {
+			fragment = balanceQuotes(strings.TrimSpace(fragment))
+			if importableLine(fragment) {
+				lines = append(lines, fragment)
+			}
+		}
+	}
+	lines = dedupe(lines)
+
+	if len(lines) <= maxImportedSentences {
+		return lines
+	}
+	spread := make([]string, maxImportedSentences)
+	for i := range spread {
+		spread[i] = lines[i*len(lines)/maxImportedSentences]
+	}
+	return spread
+}
+
+// balanceQuotes removes the double quotes of a sentence whose quotes don't
+// pair up, as when dialogue runs over several sentences
+func balanceQuotes(s string) string {
+	if strings.Count(s, `"`)%2 == 0 {
+		return s
+	}
+	return strings.TrimSpace(strings.ReplaceAll(s, `"`, ""))
+}
+
+// importableLine returns true for a sentence worth practising on: playable
+// length, plain characters, a few words, and not a heading in capitals
+func importableLine(s string) bool {
+	if len(s) < minImportedLine || len(s) > maxCorpusLine || !importedLine.MatchString(s) {
+		return false
+	}
+	if len(strings.Fields(s)) < minImportedWords || strings.ToUpper(s) == s {
+		return false
+	}
+	return isLetter(s[0]) || s[0] == '"' || s[0] == '\''
+}
+
+// SaveTextSource writes a source file, creating its directory if needed
+func SaveTextSource(path string, src *TextSource) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(src, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// LoadImportedSources loads the source files in dir. Gutenberg .txt books
+// in dir are imported first and saved next to them, as pg2701.json for
+// pg2701.txt, unless that file exists, so dropping a book into dir is
+// enough to practise on it. Sources are returned sorted by file name; the
+// errors are for files that can't be imported or loaded.
+func LoadImportedSources(dir string) ([]TextSource, []error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, []error{err}
+	}
+
+	var problems []error
+	for _, entry := range entries {
+		p := filepath.Join(dir, entry.Name())
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(p), ".txt") {
+			continue
+		}
+		saved := strings.TrimSuffix(p, filepath.Ext(p)) + ".json"
+		if _, err := os.Stat(saved); err == nil {
+			continue
+		}
+
+		src, err := ImportGutenberg(p)
+		if err == nil {
+			err = SaveTextSource(saved, src)
+		}
+		if err != nil {
+			problems = append(problems, fmt.Errorf("source %s: %w", p, err))
+		}
+	}
+
+	// Read the directory again for the sources just imported
+	entries, _ = os.ReadDir(dir)
+	var sources []TextSource
+	for _, entry := range entries {
+		p := filepath.Join(dir, entry.Name())
+		if entry.IsDir() || filepath.Ext(p) != ".json" {
+			continue
+		}
+		src, err := loadTextSource(p)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("source %s: %w", p, err))
+			continue
+		}
+		sources = append(sources, *src)
+	}
+	return sources, problems
+}
+
+// loadTextSource loads a source file, keeping the sentences the generators
+// can use
+func loadTextSource(path string) (*TextSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var src TextSource
+	if err := decodeJSON(filepath.Base(path), data, &src, true); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(src.Name) == "" {
+		return nil, ErrSourceNoName
+	}
+
+	var sentences []string
+	for _, s := range src.Sentences {
+		if usableLine(s) {
+			sentences = append(sentences, s)
+		}
+	}
+	if len(sentences) == 0 {
+		return nil, ErrSourceNoSentences
+	}
+	src.Sentences = sentences
+	if src.Attribution == "" {
+		src.Attribution = sourceAttribution(&src)
+	}
+	return &src, nil
+}
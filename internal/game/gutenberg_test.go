@@ -0,0 +1,198 @@
+package game
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// gutenbergBook is a small book in Project Gutenberg's plain text format
+const gutenbergBook = `The Project Gutenberg eBook of A Small Test
+
+Title: A Small Test
+Author: Jane Example
+Release date: May 1, 2004 [eBook #12345]
+Original publication: London: Example Press, 1851
+
+*** START OF THE PROJECT GUTENBERG EBOOK A SMALL TEST ***
+
+CHAPTER I.
+
+It was a bright and windy morning in the old town.
+Mr. Brown walked slowly to the harbour, as he always did.
+
+“Is the boat in?” asked he. Nobody answered him—not even the dog.
+
+The _sea_ was calm; the gulls were loud.
+
+*** END OF THE PROJECT GUTENBERG EBOOK A SMALL TEST ***
+
+Licence text that is not part of the book at all.
+`
+
+func TestImportGutenberg(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "book.txt")
+	if err := os.WriteFile(p, []byte(strings.ReplaceAll(gutenbergBook, "\n", "\r\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	src, err := ImportGutenberg(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if src.Name != "A Small Test" || src.Author != "Jane Example" || src.Year != 1851 || src.License != "Public Domain" {
+		t.Errorf("imported %q by %q (%d), %s", src.Name, src.Author, src.Year, src.License)
+	}
+	if want := "Text from 'A Small Test' by Jane Example (1851), sourced from Project Gutenberg"; src.Attribution != want {
+		t.Errorf("Attribution = %q, want %q", src.Attribution, want)
+	}
+	want := []string{
+		"It was a bright and windy morning in the old town.",
+		"Mr. Brown walked slowly to the harbour, as he always did.",
+		`"Is the boat in?" asked he.`,
+		"Nobody answered him - not even the dog.",
+		"The sea was calm; the gulls were loud.",
+	}
+	if !reflect.DeepEqual(src.Sentences, want) {
+		t.Errorf("Sentences = %q, want %q", src.Sentences, want)
+	}
+}
+
+func TestImportGutenbergErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		book string
+		want error
+	}{
+		{"no markers", "Title: A\nAuthor: B\n\nSome text of the book.\n", ErrNotGutenberg},
+		{"no end", strings.Split(gutenbergBook, "*** END")[0], ErrNotGutenberg},
+		{"no title", strings.Replace(gutenbergBook, "Title:", "Name:", 1), ErrNoTitle},
+		{"no author", strings.Replace(gutenbergBook, "Author:", "Writer:", 1), ErrNoAuthor},
+		{"no sentences", "Title: A\nAuthor: B\n*** START OF THE PROJECT GUTENBERG EBOOK A ***\nTHE END.\n" +
+			"*** END OF THE PROJECT GUTENBERG EBOOK A ***\n", ErrNoSentences},
+	}
+	for _, tt := range tests {
+		p := filepath.Join(t.TempDir(), "book.txt")
+		if err := os.WriteFile(p, []byte(tt.book), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ImportGutenberg(p); !errors.Is(err, tt.want) {
+			t.Errorf("%s: ImportGutenberg() = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestPublicationYear(t *testing.T) {
+	tests := []struct {
+		original, body string
+		want           int
+	}{
+		{"1851", "Printed in 1902", 1851},
+		{"", "First printed in 1902 and again in 1910", 1902},
+		{"", "A reprint of 2004", 0},
+		{"Unknown", "Published 1999, written 1890", 1890},
+		{"", strings.Repeat("line\n", 100) + "1850", 0},
+	}
+	for _, tt := range tests {
+		if got := publicationYear(tt.original, tt.body); got != tt.want {
+			t.Errorf("publicationYear(%q, %q) = %d, want %d", tt.original, tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestImportableLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{"It was a bright morning.", true},
+		{`"Come here," said she.`, true},
+		{"CHAPTER THE FIRST PART", false},
+		{"Too short.", false},
+		{"Two words..........", false},
+		{"- a dash starts this one", false},
+		{"It cost 5 [five] pounds.", false},
+		{strings.Repeat("long ", 17), false},
+	}
+	for _, tt := range tests {
+		if got := importableLine(tt.line); got != tt.want {
+			t.Errorf("importableLine(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestBalanceQuotes(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{`"Come here," said she.`, `"Come here," said she.`},
+		{`"Come here, and sit down.`, `Come here, and sit down.`},
+		{`No quotes at all.`, `No quotes at all.`},
+	}
+	for _, tt := range tests {
+		if got := balanceQuotes(tt.in); got != tt.want {
+			t.Errorf("balanceQuotes(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLoadImportedSources(t *testing.T) {
+	dir := writeFiles(t, t.TempDir(), map[string]string{
+		"pg1.txt":       gutenbergBook,
+		"notes.txt":     "Not a Gutenberg book at all.",
+		"saved.json":    `{"name": "Saved Book", "author": "A", "sentences": ["A sentence saved earlier.", "short"]}`,
+		"nameless.json": `{"sentences": ["A sentence without a name."]}`,
+		"readme.md":     "Not a source.",
+	})
+
+	sources, problems := LoadImportedSources(dir)
+	if _, err := os.Stat(filepath.Join(dir, "pg1.json")); err != nil {
+		t.Errorf("the book wasn't saved: %v", err)
+	}
+	var names []string
+	for _, src := range sources {
+		names = append(names, src.Name)
+	}
+	if want := []string{"A Small Test", "Saved Book"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("loaded %q, want %q", names, want)
+	}
+	if len(sources) == 2 && !reflect.DeepEqual(sources[1].Sentences, []string{"A sentence saved earlier."}) {
+		t.Errorf("saved sentences %q, want the usable one", sources[1].Sentences)
+	}
+
+	var notBook, noName bool
+	for _, problem := range problems {
+		notBook = notBook || errors.Is(problem, ErrNotGutenberg)
+		noName = noName || errors.Is(problem, ErrSourceNoName)
+	}
+	if len(problems) != 2 || !notBook || !noName {
+		t.Errorf("problems %v, want notes.txt and nameless.json", problems)
+	}
+
+	// Books already imported aren't imported again
+	if err := os.WriteFile(filepath.Join(dir, "pg1.txt"), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if sources, _ := LoadImportedSources(dir); len(sources) != 2 {
+		t.Errorf("loaded %d sources again, want 2", len(sources))
+	}
+}
+
+func TestBooksText(t *testing.T) {
+	book := TextSource{Name: "Book", Author: "A", Sentences: []string{"The only sentence of the book."}}
+	g := NewSeededTaskGenerator(1)
+	g.SetImportedSources([]TextSource{book})
+
+	g.SetText(TextProse)
+	if reflect.DeepEqual(g.proseSources(), []TextSource{book}) {
+		t.Error("prose uses the imported books")
+	}
+	g.SetText(TextBooks)
+	if !reflect.DeepEqual(g.proseSources(), []TextSource{book}) || !reflect.DeepEqual(g.Sources(TextBooks), []TextSource{book}) {
+		t.Error("books don't use the imported books")
+	}
+	if !strings.Contains(g.GetAttribution(), "'Book' by A") {
+		t.Error("the attribution leaves out the imported book")
+	}
+}
@@ -0,0 +1,342 @@
+package game
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/timlinux/macaco/internal/solver"
+	"github.com/timlinux/macaco/internal/stats"
+	"github.com/timlinux/macaco/internal/vim"
+)
+
+// RoundLesson is the round type of a unit's practice tasks
+const RoundLesson = "lesson"
+
+// Pass mark of units that don't set their own
+const (
+	DefaultLessonCompletion = 80.0
+	DefaultLessonEfficiency = 70.0
+)
+
+// Lesson errors
+const (
+	ErrNoCurriculum   GameError = "curriculum has no units"
+	ErrUnitID         GameError = "unit id should be lower case letters, digits, - and _"
+	ErrUnitDuplicate  GameError = "unit id is used twice"
+	ErrUnitTitle      GameError = "unit title is missing"
+	ErrUnitNoCommands GameError = "unit teaches no commands"
+	ErrUnitNoScreens  GameError = "unit has no explanation screens"
+	ErrUnitNoPractice GameError = "unit has no valid practice tasks"
+	ErrUnitPass       GameError = "pass should be 0 to 100 percent"
+	ErrLessonCommand  GameError = "solution uses a command that isn't taught yet"
+	ErrUnknownUnit    GameError = "unit isn't in the curriculum"
+	ErrUnitLocked     GameError = "unit is locked until the one before it is passed"
+	ErrLessonsOffline GameError = "lessons need the local game engine"
+)
+
+// builtinCurriculum is the curriculum MoCaCo ships with, in the same format
+// as a lessons_file
+//
+//go:embed curriculum.json
+var builtinCurriculum []byte
+
+// Curriculum is an ordered list of units. Each unit unlocks when the one
+// before it is passed, and practises only the commands taught up to it.
+type Curriculum struct {
+	Version  string  `json:"version"`
+	Units    []*Unit `json:"units"`
+	problems []error // Units, examples and tasks left out while loading
+}
+
+// Unit teaches a few commands: screens explaining them, worked examples
+// played back key by key, then practice tasks to pass
+type Unit struct {
+	ID       string          `json:"id"`
+	Title    string          `json:"title"`
+	Summary  string          `json:"summary,omitempty"`
+	Commands []string        `json:"commands"` // Taught here, named as Task.Commands names them
+	Screens  []LessonScreen  `json:"screens"`
+	Examples []LessonExample `json:"examples,omitempty"`
+	Practice []Task          `json:"practice"`
+	Pass     LessonPass      `json:"pass"`
+	known    []string        // Commands taught up to and including this unit
+}
+
+// LessonScreen is a page of explanation
+type LessonScreen struct {
+	Title string `json:"title"`
+	Text  string `json:"text"`
+}
+
+// LessonExample is a task solved for the player, its optimal keys played
+// back through the engine with an explanation
+type LessonExample struct {
+	Task
+	Explanation string `json:"explanation"`
+}
+
+// LessonPass is what a unit's practice needs to pass. Missing values take
+// the defaults.
+type LessonPass struct {
+	Completion float64 `json:"completion,omitempty"` // Percent of practice tasks solved
+	Efficiency float64 `json:"efficiency,omitempty"` // Average efficiency, percent
+}
+
+// LessonError reports a unit, or part of one, that failed to load
+type LessonError struct {
+	Unit string
+	Err  error
+}
+
+func (e *LessonError) Error() string {
+	return fmt.Sprintf("unit %s: %v", e.Unit, e.Err)
+}
+
+func (e *LessonError) Unwrap() error {
+	return e.Err
+}
+
+// DefaultCurriculum returns the built-in curriculum
+func DefaultCurriculum() *Curriculum {
+	c, err := parseCurriculum("curriculum.json", builtinCurriculum)
+	if err != nil {
+		return &Curriculum{problems: []error{err}}
+	}
+	return c
+}
+
+// LoadCurriculum loads a curriculum from a JSON file. Broken units,
+// examples and tasks are left out and reported by Problems.
+func LoadCurriculum(path string) (*Curriculum, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseCurriculum(path, data)
+}
+
+// parseCurriculum decodes and prepares a curriculum
+func parseCurriculum(name string, data []byte) (*Curriculum, error) {
+	var c Curriculum
+	if err := decodeJSON(name, data, &c, true); err != nil {
+		return nil, err
+	}
+	c.prepare()
+	if len(c.Units) == 0 {
+		return nil, fmt.Errorf("%s: %w", name, ErrNoCurriculum)
+	}
+	return &c, nil
+}
+
+// prepare validates the units in order, leaving out those that can't be
+// played. A unit's tasks may only use the commands taught up to it, so
+// the commands of a unit that is left out aren't taught either.
+func (c *Curriculum) prepare() {
+	var units []*Unit
+	var known []string
+	seen := make(map[string]bool)
+	for i, unit := range c.Units {
+		if unit.ID == "" {
+			unit.ID = fmt.Sprintf("unit-%d", i+1)
+		}
+		var err error
+		if seen[unit.ID] {
+			err = ErrUnitDuplicate
+		} else {
+			err = unit.validate()
+		}
+		seen[unit.ID] = true
+		if err != nil {
+			c.problems = append(c.problems, &LessonError{Unit: unit.ID, Err: err})
+			continue
+		}
+
+		unit.known = append(append([]string{}, known...), unit.Commands...)
+		c.problems = append(c.problems, unit.prepare()...)
+		if len(unit.Practice) == 0 {
+			c.problems = append(c.problems, &LessonError{Unit: unit.ID, Err: ErrUnitNoPractice})
+			continue
+		}
+		known = unit.known
+		units = append(units, unit)
+	}
+	c.Units = units
+}
+
+// validate checks the unit's fields, filling in the default pass mark
+func (u *Unit) validate() error {
+	if strings.Trim(u.ID, "abcdefghijklmnopqrstuvwxyz0123456789-_") != "" {
+		return ErrUnitID
+	}
+	switch {
+	case u.Title == "":
+		return ErrUnitTitle
+	case len(u.Commands) == 0:
+		return ErrUnitNoCommands
+	case len(u.Screens) == 0:
+		return ErrUnitNoScreens
+	case u.Pass.Completion < 0 || u.Pass.Completion > 100 || u.Pass.Efficiency < 0 || u.Pass.Efficiency > 100:
+		return ErrUnitPass
+	}
+	if u.Pass.Completion == 0 {
+		u.Pass.Completion = DefaultLessonCompletion
+	}
+	if u.Pass.Efficiency == 0 {
+		u.Pass.Efficiency = DefaultLessonEfficiency
+	}
+	return nil
+}
+
+// prepare readies the unit's examples and practice tasks, leaving out
+// those that fail validation or use commands not taught yet
+func (u *Unit) prepare() []error {
+	var problems []error
+
+	var examples []LessonExample
+	for i, example := range u.Examples {
+		if example.ID == "" {
+			example.ID = fmt.Sprintf("%s-example-%d", u.ID, i+1)
+		}
+		if err := u.prepareTask(&example.Task, false); err != nil {
+			problems = append(problems, &LessonError{Unit: u.ID, Err: err})
+			continue
+		}
+		examples = append(examples, example)
+	}
+	u.Examples = examples
+
+	var practice []Task
+	for i, task := range u.Practice {
+		if task.ID == "" {
+			task.ID = fmt.Sprintf("%s-%d", u.ID, i+1)
+		}
+		if err := u.prepareTask(&task, true); err != nil {
+			problems = append(problems, &LessonError{Unit: u.ID, Err: err})
+			continue
+		}
+		practice = append(practice, task)
+	}
+	u.Practice = practice
+	return problems
+}
+
+// prepareTask checks that a hand-written task is solved by its keys using
+// only the commands taught so far. Practice tasks are then optimised, but
+// only with those commands, so efficiency is measured against what the
+// player has learnt.
+func (u *Unit) prepareTask(task *Task, optimize bool) error {
+	if task.Difficulty == 0 {
+		task.Difficulty = 1
+	}
+	task.resolvePositions()
+	task.OptimalKeys = vim.NormalizeKeys(task.OptimalKeys)
+	task.OptimalCount = len(task.OptimalKeySequence())
+	if err := task.Validate(); err != nil {
+		return err
+	}
+	if !u.Teaches(task.Commands()) {
+		return &TaskError{TaskID: task.ID, Err: ErrLessonCommand}
+	}
+	if optimize {
+		u.optimize(task)
+	}
+	task.Calibrate(Calibration{})
+	return nil
+}
+
+// optimize lets the solver look for shorter solutions, keeping only those
+// made of commands the unit allows
+func (u *Unit) optimize(task *Task) {
+	optimized := *task
+	optimized.Optimize(solver.DefaultOptions())
+	if u.Teaches(optimized.Commands()) {
+		task.OptimalKeys = optimized.OptimalKeys
+		task.OptimalCount = optimized.OptimalCount
+	}
+	task.Solved = true // Sessions mustn't solve it again with every command
+
+	task.AlternativeKeys = nil
+	for _, keys := range optimized.AlternativeKeys {
+		alt := Task{OptimalKeys: keys}
+		if keys != task.OptimalKeys && u.Teaches(alt.Commands()) {
+			task.AlternativeKeys = append(task.AlternativeKeys, keys)
+		}
+	}
+}
+
+// Teaches returns true if every command has been taught by the end of the
+// unit
+func (u *Unit) Teaches(commands []string) bool {
+	for _, cmd := range commands {
+		found := false
+		for _, k := range u.known {
+			if k == cmd {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Known returns the commands taught up to and including the unit
+func (u *Unit) Known() []string {
+	return append([]string{}, u.known...)
+}
+
+// Problems returns why units, examples and tasks were left out
+func (c *Curriculum) Problems() []error {
+	return c.problems
+}
+
+// Unit returns the unit with an ID and its index, or nil and -1
+func (c *Curriculum) Unit(id string) (*Unit, int) {
+	for i, unit := range c.Units {
+		if unit.ID == id {
+			return unit, i
+		}
+	}
+	return nil, -1
+}
+
+// Passed returns true if a lesson's stats meet the pass mark
+func (p LessonPass) Passed(s *stats.SessionStats) bool {
+	if s == nil || s.TasksAttempted == 0 {
+		return false
+	}
+	completion := float64(s.TasksCompleted) / float64(s.TasksAttempted) * 100
+	return completion >= p.Completion && s.AvgEfficiency >= p.Efficiency
+}
+
+// String describes the pass mark
+func (p LessonPass) String() string {
+	return fmt.Sprintf("%.0f%% of tasks solved at %.0f%% average efficiency", p.Completion, p.Efficiency)
+}
+
+// Lesson is the unit a lesson session practises and whether it passed
+type Lesson struct {
+	Unit   string     `json:"unit"`
+	Title  string     `json:"title"`
+	Pass   LessonPass `json:"pass"`
+	Passed bool       `json:"passed"` // Set once the lesson's stats are saved
+}
+
+// UnitStatus is a unit of the curriculum with the player's progress on it
+type UnitStatus struct {
+	ID       string                `json:"id"`
+	Title    string                `json:"title"`
+	Summary  string                `json:"summary,omitempty"`
+	Commands []string              `json:"commands"`
+	Unlocked bool                  `json:"unlocked"`
+	Progress *stats.LessonProgress `json:"progress,omitempty"`
+}
+
+// IsLesson returns true if the session practises a unit
+func (s *Session) IsLesson() bool {
+	return s.Lesson != nil
+}
@@ -0,0 +1,237 @@
+package game
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/timlinux/macaco/internal/config"
+	"github.com/timlinux/macaco/internal/stats"
+)
+
+// testCurriculum has two playable units, delete and words, among units
+// that fail to load for every reason they can
+const testCurriculum = `{
+  "version": "1.0.0",
+  "units": [
+    {
+      "id": "delete", "title": "Deleting", "commands": ["dw", "x"],
+      "screens": [{"title": "dw", "text": "dw deletes a word"}],
+      "examples": [
+        {"category": "delete", "initial": "one two three", "desired": "one three", "cursor_start": 4, "optimal_keys": "dw", "explanation": "dw"},
+        {"category": "motion", "initial": "one two three", "desired": "one two three", "cursor_start": 0, "cursor_end": 4, "optimal_keys": "w", "explanation": "w isn't taught yet"}
+      ],
+      "practice": [
+        {"category": "delete", "initial": "one two three", "desired": "one three", "cursor_start": 4, "optimal_keys": "dw"},
+        {"category": "motion", "initial": "one two three", "desired": "one two three", "cursor_start": 0, "cursor_end": 4, "optimal_keys": "w"}
+      ]
+    },
+    {
+      "id": "delete", "title": "Again", "commands": ["x"],
+      "screens": [{"title": "x", "text": "x"}],
+      "practice": [{"category": "delete", "initial": "one", "desired": "ne", "cursor_start": 0, "optimal_keys": "x"}]
+    },
+    {"id": "Bad ID", "title": "Bad", "commands": ["x"], "screens": [{"title": "x", "text": "x"}]},
+    {"id": "untitled", "commands": ["x"], "screens": [{"title": "x", "text": "x"}]},
+    {"id": "commandless", "title": "No commands", "screens": [{"title": "x", "text": "x"}]},
+    {"id": "screenless", "title": "No screens", "commands": ["x"]},
+    {"id": "pass", "title": "Bad pass", "commands": ["x"], "screens": [{"title": "x", "text": "x"}], "pass": {"completion": 120}},
+    {
+      "id": "untaught", "title": "Back", "commands": ["b"],
+      "screens": [{"title": "b", "text": "b"}],
+      "practice": [{"category": "motion", "initial": "one two three", "desired": "one two three", "cursor_start": 0, "cursor_end": 4, "optimal_keys": "w"}]
+    },
+    {
+      "id": "words", "title": "Words", "commands": ["w"],
+      "screens": [{"title": "w", "text": "w"}],
+      "practice": [
+        {"id": "w", "category": "motion", "initial": "one two three", "desired": "one two three", "cursor_start": 0, "cursor_end": 4, "optimal_keys": "w"},
+        {"category": "motion", "initial": "one two three", "desired": "one two three", "cursor_start": 4, "cursor_end": 0, "optimal_keys": "b"}
+      ],
+      "pass": {"completion": 100, "efficiency": 50}
+    }
+  ]
+}`
+
+func TestDefaultCurriculum(t *testing.T) {
+	c := DefaultCurriculum()
+	if problems := c.Problems(); len(problems) > 0 {
+		t.Fatalf("Problems() = %v", problems)
+	}
+	if len(c.Units) == 0 {
+		t.Fatal("the built-in curriculum has no units")
+	}
+	var known []string
+	for _, unit := range c.Units {
+		known = append(known, unit.Commands...)
+		if !reflect.DeepEqual(unit.Known(), known) {
+			t.Errorf("unit %s knows %v, want %v", unit.ID, unit.Known(), known)
+		}
+		for _, task := range unit.Practice {
+			if err := task.Validate(); err != nil {
+				t.Errorf("unit %s task %s: %v", unit.ID, task.ID, err)
+			}
+			if !unit.Teaches(task.Commands()) {
+				t.Errorf("unit %s task %s uses %v", unit.ID, task.ID, task.Commands())
+			}
+		}
+	}
+}
+
+func TestLoadCurriculum(t *testing.T) {
+	dir := writeFiles(t, t.TempDir(), map[string]string{"lessons.json": testCurriculum})
+	c, err := LoadCurriculum(filepath.Join(dir, "lessons.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for _, unit := range c.Units {
+		ids = append(ids, unit.ID)
+	}
+	if want := []string{"delete", "words"}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("units %v, want %v", ids, want)
+	}
+
+	problems := c.Problems()
+	for _, want := range []error{ErrUnitDuplicate, ErrUnitID, ErrUnitTitle, ErrUnitNoCommands, ErrUnitNoScreens, ErrUnitPass, ErrUnitNoPractice, ErrLessonCommand} {
+		found := false
+		for _, err := range problems {
+			var lessonErr *LessonError
+			if errors.Is(err, want) && errors.As(err, &lessonErr) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Problems() = %v, want %v", problems, want)
+		}
+	}
+
+	// The left out unit doesn't teach b, so the words unit can't use it
+	deleteUnit, words := c.Units[0], c.Units[1]
+	if want := []string{"dw", "x", "w"}; !reflect.DeepEqual(words.Known(), want) {
+		t.Errorf("Known() = %v, want %v", words.Known(), want)
+	}
+	if len(words.Practice) != 1 || words.Practice[0].ID != "w" {
+		t.Errorf("words practice %+v, want only the w task", words.Practice)
+	}
+	if len(deleteUnit.Examples) != 1 || deleteUnit.Examples[0].ID != "delete-example-1" {
+		t.Errorf("delete examples %+v, want the dw example", deleteUnit.Examples)
+	}
+	if len(deleteUnit.Practice) != 1 || deleteUnit.Practice[0].ID != "delete-1" || !deleteUnit.Practice[0].Solved {
+		t.Errorf("delete practice %+v, want the solved dw task", deleteUnit.Practice)
+	}
+	if deleteUnit.Pass != (LessonPass{Completion: DefaultLessonCompletion, Efficiency: DefaultLessonEfficiency}) {
+		t.Errorf("delete pass %+v, want the default", deleteUnit.Pass)
+	}
+	if words.Pass != (LessonPass{Completion: 100, Efficiency: 50}) {
+		t.Errorf("words pass %+v, want its own", words.Pass)
+	}
+
+	if unit, i := c.Unit("words"); unit != words || i != 1 {
+		t.Errorf("Unit(words) = %v, %d", unit, i)
+	}
+	if unit, i := c.Unit("untaught"); unit != nil || i != -1 {
+		t.Errorf("Unit(untaught) = %v, %d, want a left out unit missing", unit, i)
+	}
+}
+
+func TestLoadCurriculumErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    error
+	}{
+		{"no units", `{"version": "1", "units": []}`, ErrNoCurriculum},
+		{"no playable units", `{"units": [{"id": "x", "title": "X", "commands": ["x"]}]}`, ErrNoCurriculum},
+		{"unknown field", `{"units": [], "lessons": []}`, nil},
+		{"bad JSON", `{"units": [`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, t.TempDir(), map[string]string{"lessons.json": tt.content})
+			_, err := LoadCurriculum(filepath.Join(dir, "lessons.json"))
+			if err == nil || tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("LoadCurriculum() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+	if _, err := LoadCurriculum(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("LoadCurriculum() loaded a missing file")
+	}
+}
+
+func TestLessonPass(t *testing.T) {
+	pass := LessonPass{Completion: 80, Efficiency: 70}
+	tests := []struct {
+		name  string
+		stats *stats.SessionStats
+		want  bool
+	}{
+		{"no stats", nil, false},
+		{"nothing attempted", &stats.SessionStats{AvgEfficiency: 100}, false},
+		{"at the mark", &stats.SessionStats{TasksAttempted: 10, TasksCompleted: 8, AvgEfficiency: 70}, true},
+		{"too few solved", &stats.SessionStats{TasksAttempted: 10, TasksCompleted: 7, AvgEfficiency: 100}, false},
+		{"too inefficient", &stats.SessionStats{TasksAttempted: 10, TasksCompleted: 10, AvgEfficiency: 69}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pass.Passed(tt.stats); got != tt.want {
+				t.Errorf("Passed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got, want := pass.String(), "80% of tasks solved at 70% average efficiency"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestEngineLessons(t *testing.T) {
+	dir := writeFiles(t, t.TempDir(), map[string]string{"lessons.json": testCurriculum})
+	cfg := config.Default()
+	cfg.DataDir = dir
+	cfg.StatsFile = filepath.Join(dir, "stats.json")
+	cfg.LessonsFile = filepath.Join(dir, "lessons.json")
+	e := NewEngine(cfg)
+
+	if _, err := e.CreateLesson("untaught"); !errors.Is(err, ErrUnknownUnit) {
+		t.Errorf("CreateLesson(untaught) = %v, want %v", err, ErrUnknownUnit)
+	}
+	if _, err := e.CreateLesson("words"); !errors.Is(err, ErrUnitLocked) {
+		t.Errorf("CreateLesson(words) = %v, want %v", err, ErrUnitLocked)
+	}
+
+	session, err := e.CreateLesson("delete")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !session.IsLesson() || session.RoundType != RoundLesson || session.Lesson.Unit != "delete" || len(session.Tasks) != 1 {
+		t.Errorf("lesson %+v with %d tasks, want the delete unit's", session.Lesson, len(session.Tasks))
+	}
+
+	status := e.GetLessonStatus()
+	if len(status) != 2 || !status[0].Unlocked || status[1].Unlocked || status[0].Progress != nil {
+		t.Fatalf("GetLessonStatus() = %+v, want only the first unit unlocked", status)
+	}
+
+	// A failed attempt keeps the next unit locked, a pass unlocks it for good
+	at := time.Date(2026, 2, 11, 9, 0, 0, 0, time.UTC)
+	e.statsTracker.RecordLesson("delete", &stats.SessionStats{TasksAttempted: 1, AvgEfficiency: 40, CompletedAt: at}, false)
+	if e.GetLessonStatus()[1].Unlocked {
+		t.Error("a failed attempt unlocked the next unit")
+	}
+	e.statsTracker.RecordLesson("delete", &stats.SessionStats{TasksAttempted: 1, TasksCompleted: 1, AvgEfficiency: 90, CompletedAt: at}, true)
+	e.statsTracker.RecordLesson("delete", &stats.SessionStats{TasksAttempted: 1, AvgEfficiency: 10, CompletedAt: at.Add(time.Hour)}, false)
+	status = e.GetLessonStatus()
+	if !status[1].Unlocked {
+		t.Error("passing the unit didn't unlock the next one")
+	}
+	if p := status[0].Progress; p == nil || p.Attempts != 3 || !p.Passed || !p.PassedAt.Equal(at) || p.BestEfficiency != 90 || p.BestCompletion != 100 {
+		t.Errorf("progress %+v, want 3 attempts passed at the second", p)
+	}
+	if _, err := e.CreateLesson("words"); err != nil {
+		t.Errorf("CreateLesson(words) after a pass: %v", err)
+	}
+}
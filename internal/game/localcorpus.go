@@ -0,0 +1,305 @@
+package game
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits on what is read from local corpus paths
+const (
+	maxCorpusFileSize = 256 << 10 // Bigger files are skipped
+	maxCorpusFiles    = 1000      // Files read per corpus path
+	minCorpusLine     = 12        // Shortest line or sentence kept
+	maxCorpusLine     = 80        // Longest line or sentence kept
+)
+
+// ErrNoLocalText is returned for local text when no corpus is configured
+const ErrNoLocalText GameError = "no local text; set corpus_paths in the config"
+
+// codeExtensions maps file extensions to the language of their lines
+var codeExtensions = map[string]string{
+	".go":   LanguageGo,
+	".py":   LanguagePython,
+	".json": LanguageJSON,
+	".sh":   LanguageShell,
+	".bash": LanguageShell,
+	".zsh":  LanguageShell,
+	".html": LanguageHTML,
+	".htm":  LanguageHTML,
+	".js":   "javascript",
+	".ts":   "typescript",
+	".rs":   "rust",
+	".c":    "c",
+	".h":    "c",
+	".java": "java",
+	".rb":   "ruby",
+	".lua":  "lua",
+	".css":  "css",
+	".sql":  "sql",
+	".yaml": "yaml",
+	".yml":  "yaml",
+	".toml": "toml",
+}
+
+// proseExtensions are the extensions of files split into sentences
+var proseExtensions = map[string]bool{
+	".md": true, ".txt": true, ".rst": true, ".adoc": true,
+}
+
+// skippedDirs are directories never read: version control, dependencies
+// and build output
+var skippedDirs = map[string]bool{
+	"node_modules": true, "vendor": true, "dist": true, "build": true,
+	"target": true, "__pycache__": true,
+}
+
+// secretFiles matches the names of files that hold credentials
+var secretFiles = regexp.MustCompile(`(?i)^(\.env.*|id_(rsa|dsa|ecdsa|ed25519).*|.*\.(pem|key|p12|pfx|jks|keystore)|\.netrc|\.npmrc|\.pgpass|.*(secret|credential).*)$`)
+
+// secretLines matches lines that look like they hold a credential: an
+// assignment to a password or token, a key block, a known token format,
+// or a long opaque string
+var secretLines = regexp.MustCompile(`(?i)(passw(or)?d|secret|token|api[_-]?key|private[_-]?key)\S*\s*[:=]|-----BEGIN|AKIA[0-9A-Z]{16}|gh[pousr]_[A-Za-z0-9]{20,}|xox[abpr]-|[A-Za-z0-9+/_\-]{32,}`)
+
+// LoadLocalSources reads the files and directories at paths as text
+// sources, one per file. Code files are split into lines and prose files
+// into sentences, keeping lines of a playable length. Hidden and dependency
+// directories, binaries, files over 256 KiB, files named like credentials
+// and lines that look like secrets are skipped. The errors are for paths
+// that can't be read.
+func LoadLocalSources(paths []string) ([]TextSource, []error) {
+	var sources []TextSource
+	var problems []error
+	for _, root := range paths {
+		info, err := os.Stat(root)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("corpus: %w", err))
+			continue
+		}
+		if !info.IsDir() {
+			if src, ok := localSource(root, filepath.Base(root)); ok {
+				sources = append(sources, src)
+			}
+			continue
+		}
+
+		files := 0
+		err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil // Skip what can't be read
+			}
+			name := d.Name()
+			if d.IsDir() {
+				if p != root && (strings.HasPrefix(name, ".") || skippedDirs[name]) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if files == maxCorpusFiles {
+				return filepath.SkipAll
+			}
+
+			rel, _ := filepath.Rel(root, p)
+			if src, ok := localSource(p, filepath.Join(filepath.Base(root), rel)); ok {
+				sources = append(sources, src)
+				files++
+			}
+			return nil
+		})
+		if err != nil {
+			problems = append(problems, fmt.Errorf("corpus: %w", err))
+		}
+	}
+	return sources, problems
+}
+
+// localSource reads a file as a text source named name. It returns false
+// for files that are skipped or leave no usable lines.
+func localSource(p, name string) (TextSource, bool) {
+	base := filepath.Base(p)
+	ext := strings.ToLower(filepath.Ext(base))
+	language, isCode := codeExtensions[ext]
+	isProse := proseExtensions[ext] || ext == "" && strings.ToUpper(base) == base
+	if !isCode && !isProse || strings.HasPrefix(base, ".") || secretFiles.MatchString(base) {
+		return TextSource{}, false
+	}
+
+	info, err := os.Stat(p)
+	if err != nil || !info.Mode().IsRegular() || info.Size() > maxCorpusFileSize {
+		return TextSource{}, false
+	}
+	data, err := os.ReadFile(p)
+	if err != nil || isBinary(data) {
+		return TextSource{}, false
+	}
+
+	var lines []string
+	if isCode {
+		lines = codeLines(string(data))
+	} else {
+		lines = proseLines(string(data))
+	}
+	if len(lines) == 0 {
+		return TextSource{}, false
+	}
+
+	return TextSource{
+		Name:        name,
+		Language:    language,
+		License:     "Local file",
+		Attribution: "Text from " + p,
+		Sentences:   lines,
+	}, true
+}
+
+// isBinary returns true for data that isn't UTF-8 text
+func isBinary(data []byte) bool {
+	head := data
+	if len(head) > 8000 {
+		head = head[:8000]
+	}
+	return bytes.IndexByte(head, 0) >= 0 || !utf8.Valid(data)
+}
+
+// codeLines returns the lines of a code file worth practising on, with
+// tabs expanded to four spaces
+func codeLines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(strings.ReplaceAll(line, "\t", "    "), " \r")
+		if usableLine(line) && strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return dedupe(lines)
+}
+
+// markdownLink matches a markdown link or image, keeping its text
+var markdownLink = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+
+// proseLines returns the sentences of a prose file, splitting long ones at
+// commas and semicolons. Markdown code blocks, headings and markup are
+// dropped.
+func proseLines(text string) []string {
+	var kept []string
+	inCode := false
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "|") {
+			kept = append(kept, "") // Ends the paragraph
+			continue
+		}
+		trimmed = strings.TrimLeft(trimmed, "-*+> ")
+		trimmed = markdownLink.ReplaceAllString(trimmed, "$1")
+		trimmed = strings.NewReplacer("`", "", "**", "", "__", "").Replace(trimmed)
+		kept = append(kept, trimmed)
+	}
+
+	var lines []string
+	for _, sentence := range splitSentences(strings.Join(kept, "\n")) {
+		for _, fragment := range sentenceFragments(sentence) {
+			if usableLine(fragment) {
+				lines = append(lines, fragment)
+			}
+		}
+	}
+	return dedupe(lines)
+}
+
+// abbreviations end with a full stop without ending a sentence
+var abbreviations = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "dr": true, "st": true, "jr": true,
+	"sr": true, "prof": true, "capt": true, "col": true, "gen": true,
+	"lieut": true, "rev": true, "hon": true, "messrs": true, "mme": true,
+	"vs": true, "etc": true, "no": true, "vol": true, "ch": true, "viz": true,
+	"i.e": true, "e.g": true,
+}
+
+// splitSentences splits text into sentences at ., ! and ? and at blank
+// lines. Line breaks within a sentence become spaces.
+func splitSentences(text string) []string {
+	var sentences []string
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		words := strings.Fields(paragraph)
+		start := 0
+		for i, word := range words {
+			if i == len(words)-1 || endsSentence(word, words[i+1]) {
+				sentences = append(sentences, strings.Join(words[start:i+1], " "))
+				start = i + 1
+			}
+		}
+	}
+	return sentences
+}
+
+// endsSentence returns true if word ends a sentence, given the word after
+// it. Closing quotes may follow the punctuation. Abbreviations and initials
+// don't end sentences, and neither does dialogue carried on in lower case,
+// as in "Stop!" said he.
+func endsSentence(word, next string) bool {
+	core := strings.TrimRight(word, `"')]`)
+	if core == "" || !strings.ContainsAny(core[len(core)-1:], ".!?") {
+		return false
+	}
+	if first := strings.TrimLeft(next, `"'([`); first != "" && first[0] >= 'a' && first[0] <= 'z' {
+		return false
+	}
+	if core[len(core)-1] == '.' {
+		stem := strings.ToLower(strings.TrimLeft(core[:len(core)-1], `"'([`))
+		if abbreviations[stem] || len(stem) == 1 && isLetter(stem[0]) {
+			return false
+		}
+	}
+	return true
+}
+
+// clauseBreak matches the punctuation long sentences are split at
+var clauseBreak = regexp.MustCompile(`[,;:]\s+`)
+
+// sentenceFragments splits a sentence too long to play at commas and
+// semicolons, dropping the punctuation it was split at
+func sentenceFragments(sentence string) []string {
+	if len(sentence) <= maxCorpusLine {
+		return []string{sentence}
+	}
+	var fragments []string
+	for _, part := range clauseBreak.Split(sentence, -1) {
+		fragments = append(fragments, strings.TrimSpace(part))
+	}
+	return fragments
+}
+
+// usableLine returns true for a line the generators can use: ASCII, of a
+// playable length, with a letter in it and nothing that looks secret
+func usableLine(line string) bool {
+	if len(strings.TrimSpace(line)) < minCorpusLine || len(line) > maxCorpusLine || !isASCII(line) {
+		return false
+	}
+	if !strings.ContainsFunc(line, func(r rune) bool { return r < 0x80 && isLetter(byte(r)) }) {
+		return false
+	}
+	return !secretLines.MatchString(line)
+}
+
+// dedupe removes repeated lines, keeping the first of each
+func dedupe(lines []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, line := range lines {
+		if !seen[line] {
+			seen[line] = true
+			unique = append(unique, line)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,462 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/timlinux/macaco/internal/vim"
+)
+
+// Limits on the lines of a multi-line task
+const (
+	maxTaskLines  = 6  // Lines of text, not counting a blank paragraph break
+	maxLineMotion = 12 // Most words a w count crosses on one line
+)
+
+// Words and short lines that multi-line prose tasks type in
+var (
+	proseLineWords   = []string{"quiet", "bright", "strange", "gentle", "sudden"}
+	proseLineInserts = []string{"Meanwhile.", "Later that day.", "The end.", "And then?"}
+)
+
+// lineSpan picks the size of a multi-line task: lines of text, and how far
+// the cursor has to travel between them. Both grow with difficulty: two or
+// three lines a line apart at difficulty 1, up to six lines four apart at
+// difficulty 4.
+func (g *TaskGenerator) lineSpan(diff int) (lines, distance int) {
+	lines = min(diff+1+g.rng.Intn(2), maxTaskLines)
+	distance = max(min(diff-1+g.rng.Intn(2), lines-1), 1)
+	return lines, distance
+}
+
+// textSources returns the sources of the generator's kind of text, the
+// built-in prose when there is no local text
+func (g *TaskGenerator) textSources() []TextSource {
+	switch {
+	case g.text == TextCode:
+		return g.code
+	case g.text == TextLocal && len(g.local) > 0:
+		return g.local
+	}
+	return g.proseSources()
+}
+
+// linesTemplates returns the built-in templates over several consecutive
+// lines: moving between them with j, k, { and }, and deleting, opening,
+// joining and moving whole lines
+func linesTemplates() []TaskTemplate {
+	return []TaskTemplate{
+		linesTemplate(TemplateInfo{
+			Name: "lines-motion", Category: CategoryMotion,
+			Tags: []string{"motion", "paragraph"}, Commands: []string{"j", "k", "{", "}"},
+		}, (*TaskGenerator).linesMotionTask),
+		linesTemplate(TemplateInfo{
+			Name: "lines-delete", Category: CategoryDelete,
+			Tags: []string{"delete", "linewise"}, Commands: []string{"dd", "dG"},
+		}, (*TaskGenerator).linesDeleteTask),
+		linesTemplate(TemplateInfo{
+			Name: "lines-change", Category: CategoryChange,
+			Tags: []string{"change", "word", "line"}, Commands: []string{"cw", "cc", "C"},
+		}, (*TaskGenerator).linesChangeTask),
+		linesTemplate(TemplateInfo{
+			Name: "lines-insert", Category: CategoryInsert,
+			Tags: []string{"insert", "line"}, Commands: []string{"o", "O", "A"},
+		}, (*TaskGenerator).linesInsertTask),
+		linesTemplate(TemplateInfo{
+			Name: "lines-visual", Category: CategoryVisual,
+			Tags: []string{"visual", "linewise", "join"}, Commands: []string{"Vd", "VJ"},
+		}, (*TaskGenerator).linesVisualTask),
+		linesTemplate(TemplateInfo{
+			Name: "lines-complex", Category: CategoryComplex,
+			Tags: []string{"complex", "linewise", "join", "register"}, Commands: []string{"dd", "J", "p", "P"},
+		}, (*TaskGenerator).linesComplexTask),
+	}
+}
+
+// linesTemplate makes a template of a multi-line generator method, which
+// gets a block of lines sized for the difficulty. Multi-line templates
+// serve every difficulty and are picked less often than those for one
+// line, so most tasks stay on one line.
+func linesTemplate(info TemplateInfo, generate func(g *TaskGenerator, b *lineBlock, diff, distance int) (Task, bool)) TaskTemplate {
+	info.MinDifficulty, info.MaxDifficulty = 1, 4
+	info.Tags = append([]string{"multiline"}, info.Tags...)
+	info.Weight = 1
+	return builtinTemplate(info, func(g *TaskGenerator, diff int) (Task, bool) {
+		return g.generateLinesTask(info.Category, diff, generate)
+	})
+}
+
+// generateLinesTask generates a task of the category over several
+// consecutive lines. It returns false when no source has enough lines.
+func (g *TaskGenerator) generateLinesTask(cat TaskCategory, diff int, generate func(g *TaskGenerator, b *lineBlock, diff, distance int) (Task, bool)) (Task, bool) {
+	n, distance := g.lineSpan(diff)
+	lines, language, ok := g.corpus().Lines(g.rng, n)
+	if !ok {
+		return Task{}, false
+	}
+	block := &lineBlock{lines: append([]string{}, lines...), language: language}
+
+	task, ok := generate(g, block, diff, distance)
+	if !ok {
+		return Task{}, false
+	}
+
+	task.Category = cat
+	task.Difficulty = diff
+	task.OptimalCount = len(task.OptimalKeySequence())
+	task.Tags = []string{string(cat), "multiline", "procedural"}
+	if language != "" {
+		task.Tags = append(task.Tags, "code", language)
+	}
+	task.ID = fmt.Sprintf("gen-%s-lines-%d", cat, g.rng.Int())
+	task.setPositions()
+	return task, true
+}
+
+// lineBlock is the text of a multi-line task
+type lineBlock struct {
+	lines    []string
+	language string // Empty for prose
+}
+
+// text returns the lines joined into the task's initial text
+func (b *lineBlock) text() string {
+	return strings.Join(b.lines, "\n")
+}
+
+// last returns the index of the last line
+func (b *lineBlock) last() int {
+	return len(b.lines) - 1
+}
+
+// breakParagraph puts a blank line after line at, so the block becomes two
+// paragraphs, and returns the index of the blank line
+func (b *lineBlock) breakParagraph(at int) int {
+	b.lines = append(b.lines[:at+1], append([]string{""}, b.lines[at+1:]...)...)
+	return at + 1
+}
+
+// newLine returns a short line to type into the block: a comment in code,
+// a short sentence in prose
+func (g *TaskGenerator) newLine(b *lineBlock) string {
+	if b.language == "" {
+		return proseLineInserts[g.rng.Intn(len(proseLineInserts))]
+	}
+	return lineComment(b.language, "TODO")
+}
+
+// lineComment returns text as a comment in a language
+func lineComment(language, text string) string {
+	switch language {
+	case LanguagePython, LanguageShell, "ruby", "yaml", "toml":
+		return "# " + text
+	case LanguageHTML:
+		return "<!-- " + text + " -->"
+	case "lua", "sql":
+		return "-- " + text
+	case LanguageJSON, "css":
+		return text
+	}
+	return "// " + text
+}
+
+// play runs keys on the block from a start position and returns the task
+// they make: the text vim leaves, or for a motion the cursor position it
+// reaches. Working it out with the engine keeps the task true to vim.
+func (b *lineBlock) play(start Position, keys string, motion bool) (Task, bool) {
+	initial := b.text()
+	task := Task{
+		Initial:     initial,
+		CursorStart: TextIndex(initial, start),
+		OptimalKeys: keys,
+	}
+	if task.CursorStart < 0 {
+		return Task{}, false
+	}
+
+	engine := vim.NewEngine(initial)
+	engine.SetCursorIndex(task.CursorStart)
+	engine.ProcessKeys(vim.ParseKeys(keys))
+	if motion {
+		task.Desired = initial
+		task.CursorEnd = engine.CursorIndex()
+		return task, task.CursorEnd != task.CursorStart
+	}
+	task.Desired = engine.Text()
+	return task, task.Desired != initial
+}
+
+// lineMotion returns the keys that move the cursor from the start of line
+// to column col: nothing, ^ or $ when they get there, a count of w, or
+// else a find of the character there
+func lineMotion(line string, col int) string {
+	switch {
+	case col == 0:
+		return ""
+	case col == len(line)-1:
+		return "$"
+	case col == len(line)-len(strings.TrimLeft(line, " ")):
+		return "^"
+	}
+
+	engine := vim.NewEngine(line)
+	for n := 1; n <= maxLineMotion; n++ {
+		engine.ProcessKeys(vim.ParseKeys("w"))
+		if engine.CursorIndex() == col {
+			return countPrefix(n) + "w"
+		}
+	}
+	c := line[col]
+	return fmt.Sprintf("%sf%c", countPrefix(findCount(line, c, 1, col)), c)
+}
+
+// lineKeys returns the keys that move the cursor down (or up for a
+// negative count) a number of lines
+func lineKeys(lines int) string {
+	if lines < 0 {
+		return countPrefix(-lines) + "k"
+	}
+	if lines == 0 {
+		return ""
+	}
+	return countPrefix(lines) + "j"
+}
+
+// targetColumn picks a column of a line to move to: a word start or the
+// end of the line
+func (g *TaskGenerator) targetColumn(line string) int {
+	starts := wordStarts(line)
+	if len(starts) < 2 || g.rng.Intn(4) == 0 {
+		return len(line) - 1
+	}
+	return starts[1+g.rng.Intn(len(starts)-1)]
+}
+
+// linesMotionTask moves the cursor to another line: down with j, then over
+// a paragraph break with }, and at the top difficulty back up with { and k
+func (g *TaskGenerator) linesMotionTask(b *lineBlock, diff, distance int) (Task, bool) {
+	var start Position
+	var keys, hint string
+	switch diff {
+	case 1, 2:
+		line := b.lines[distance]
+		col := len(line) - len(strings.TrimLeft(line, " "))
+		if diff == 2 {
+			col = g.targetColumn(line)
+		}
+		keys = lineKeys(distance) + lineMotion(line, col)
+		hint = "Use 'j' with a count to move down lines, then move along the line"
+	case 3:
+		// Down into the second paragraph
+		blank := b.breakParagraph(g.rng.Intn(b.last()))
+		target := min(blank+distance, b.last())
+		line := b.lines[target]
+		keys = "}" + lineKeys(target-blank) + lineMotion(line, g.targetColumn(line))
+		hint = "Use '}' to jump to the blank line after a paragraph"
+	default:
+		// Up into the first paragraph
+		blank := b.breakParagraph(g.rng.Intn(b.last()))
+		target := max(blank-distance, 0)
+		line := b.lines[target]
+		start = Position{Line: b.last()}
+		keys = "{" + lineKeys(target-blank) + lineMotion(line, g.targetColumn(line))
+		hint = "Use '{' to jump back to the blank line before a paragraph"
+	}
+
+	task, ok := b.play(start, keys, true)
+	if !ok {
+		return Task{}, false
+	}
+	task.Description = fmt.Sprintf("Move the cursor to %s", task.EndPosition())
+	task.Hint = hint
+	return task, true
+}
+
+// linesDeleteTask deletes whole lines with dd, a count of dd and dG, and a
+// paragraph found with {
+func (g *TaskGenerator) linesDeleteTask(b *lineBlock, diff, distance int) (Task, bool) {
+	var start Position
+	var keys, desc, hint string
+	switch diff {
+	case 1:
+		keys = lineKeys(distance) + "dd"
+		desc = fmt.Sprintf("Delete line %d", distance+1)
+		hint = "Use 'j' to reach the line, then 'dd' to delete it"
+	case 2:
+		distance = min(distance, b.last()-1)
+		keys = lineKeys(distance) + "2dd"
+		desc = fmt.Sprintf("Delete lines %d and %d", distance+1, distance+2)
+		hint = "Give 'dd' a count to delete several lines: '2dd'"
+	case 3:
+		keys = lineKeys(distance) + "dG"
+		desc = fmt.Sprintf("Delete from line %d to the end", distance+1)
+		hint = "Use 'dG' to delete to the last line"
+	default:
+		blank := b.breakParagraph(g.rng.Intn(b.last()))
+		start = Position{Line: b.last()}
+		keys = "{dG"
+		desc = fmt.Sprintf("Delete the last paragraph, from the blank line %d", blank+1)
+		hint = "Use '{' to jump to the blank line, then 'dG' to delete to the end"
+	}
+
+	task, ok := b.play(start, keys, false)
+	if !ok {
+		return Task{}, false
+	}
+	task.Description = desc
+	task.Hint = hint
+	return task, true
+}
+
+// linesChangeTask changes a word, a whole line or the end of a line on
+// another line
+func (g *TaskGenerator) linesChangeTask(b *lineBlock, diff, distance int) (Task, bool) {
+	var start Position
+	target := distance
+	if diff >= 4 {
+		// Work upwards from the last line
+		start = Position{Line: b.last()}
+		target = b.last() - distance
+	}
+	line := b.lines[target]
+	idents := identifiers(line)
+	if len(idents) == 0 {
+		return Task{}, false
+	}
+	ident := idents[g.rng.Intn(len(idents))]
+	names := codeNames
+	if b.language == "" {
+		names = proseLineWords
+	}
+	word := g.pickOther(names, line[ident.start:ident.end])
+	move := lineKeys(target-start.Line) + lineMotion(line, ident.start)
+
+	var keys, desc, hint string
+	switch diff {
+	case 1, 4:
+		keys = move + "cw" + word + "<Esc>"
+		desc = fmt.Sprintf("Change '%s' on line %d to '%s'", line[ident.start:ident.end], target+1, word)
+		hint = "Move to the line with 'j' or 'k', then to the word, and use 'cw'"
+	case 2:
+		word = g.newLine(b)
+		keys = lineKeys(target) + "cc" + word + "<Esc>"
+		desc = fmt.Sprintf("Replace line %d with '%s'", target+1, word)
+		hint = "Use 'cc' to change the whole line"
+	default:
+		keys = move + "C" + word + "<Esc>"
+		desc = fmt.Sprintf("Change line %d from '%s' to the end into '%s'", target+1, line[ident.start:ident.end], word)
+		hint = "Use 'C' to change to the end of the line"
+	}
+
+	task, ok := b.play(start, keys, false)
+	if !ok {
+		return Task{}, false
+	}
+	task.Description = desc
+	task.Hint = hint
+	return task, true
+}
+
+// linesInsertTask opens a line below or above another line with o and O,
+// appends to another line, or adds a line to the end of a paragraph
+func (g *TaskGenerator) linesInsertTask(b *lineBlock, diff, distance int) (Task, bool) {
+	text := g.newLine(b)
+	var keys, desc, hint string
+	switch diff {
+	case 1:
+		keys = lineKeys(distance) + "o" + text + "<Esc>"
+		desc = fmt.Sprintf("Add '%s' as a new line after line %d", text, distance+1)
+		hint = "Use 'o' to open a line below the cursor"
+	case 2:
+		keys = lineKeys(distance) + "O" + text + "<Esc>"
+		desc = fmt.Sprintf("Add '%s' as a new line before line %d", text, distance+1)
+		hint = "Use 'O' to open a line above the cursor"
+	case 3:
+		word := codeArgs[g.rng.Intn(len(codeArgs))]
+		if b.language == "" {
+			word = "indeed"
+		}
+		keys = lineKeys(distance) + "A " + word + "<Esc>"
+		desc = fmt.Sprintf("Append ' %s' to line %d", word, distance+1)
+		hint = "Use 'A' to append at the end of the line"
+	default:
+		blank := b.breakParagraph(g.rng.Intn(b.last()))
+		keys = "}O" + text + "<Esc>"
+		desc = fmt.Sprintf("Add '%s' as the last line of the first paragraph, before line %d", text, blank+1)
+		hint = "Use '}' to reach the blank line, then 'O' to open a line above it"
+	}
+
+	task, ok := b.play(Position{}, keys, false)
+	if !ok {
+		return Task{}, false
+	}
+	task.Description = desc
+	task.Hint = hint
+	return task, true
+}
+
+// linesVisualTask selects whole lines with V to delete or join them
+func (g *TaskGenerator) linesVisualTask(b *lineBlock, diff, distance int) (Task, bool) {
+	var keys, desc, hint string
+	switch diff {
+	case 1:
+		from := distance - 1
+		keys = lineKeys(from) + "Vjd"
+		desc = fmt.Sprintf("Delete lines %d and %d with a line selection", from+1, from+2)
+		hint = "Use 'V' to select the line, 'j' to extend, 'd' to delete"
+	case 2:
+		keys = "V" + lineKeys(distance) + "d"
+		desc = fmt.Sprintf("Delete lines 1 to %d with a line selection", distance+1)
+		hint = "Use 'V' and a count of 'j' to select several lines"
+	case 3:
+		keys = "V" + lineKeys(distance) + "J"
+		desc = fmt.Sprintf("Join lines 1 to %d into one line", distance+1)
+		hint = "Select the lines with 'V', then join them with 'J'"
+	default:
+		blank := b.breakParagraph(min(distance, b.last()-1))
+		keys = "V}d"
+		desc = fmt.Sprintf("Delete the first paragraph and the blank line %d", blank+1)
+		hint = "Use 'V' then '}' to select to the end of the paragraph"
+	}
+
+	task, ok := b.play(Position{}, keys, false)
+	if !ok {
+		return Task{}, false
+	}
+	task.Description = desc
+	task.Hint = hint
+	return task, true
+}
+
+// linesComplexTask swaps, joins and moves lines with dd, J and p
+func (g *TaskGenerator) linesComplexTask(b *lineBlock, diff, distance int) (Task, bool) {
+	var keys, desc, hint string
+	switch diff {
+	case 1:
+		from := min(distance-1, b.last()-1)
+		keys = lineKeys(from) + "ddp"
+		desc = fmt.Sprintf("Swap lines %d and %d", from+1, from+2)
+		hint = "Use 'ddp' to swap a line with the one below it"
+	case 2:
+		from := min(distance, b.last()-1)
+		keys = lineKeys(from) + "J"
+		desc = fmt.Sprintf("Join line %d with the line after it", from+1)
+		hint = "Use 'J' to join the line below onto the cursor line"
+	case 3:
+		keys = "dd" + lineKeys(distance-1) + "p"
+		desc = fmt.Sprintf("Move line 1 down below line %d", distance+1)
+		hint = "Delete the line with 'dd', move down, and put it back with 'p'"
+	default:
+		keys = "GddggP"
+		desc = fmt.Sprintf("Move the last line, line %d, to the top", len(b.lines))
+		hint = "Use 'G' and 'dd' to cut the last line, then 'gg' and 'P' to put it first"
+	}
+
+	task, ok := b.play(Position{}, keys, false)
+	if !ok {
+		return Task{}, false
+	}
+	task.Description = desc
+	task.Hint = hint
+	return task, true
+}
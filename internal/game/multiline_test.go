@@ -0,0 +1,119 @@
+package game
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTextPosition(t *testing.T) {
+	text := "one\ntwo words\n\nfïn"
+	tests := []struct {
+		index int
+		want  Position
+	}{
+		{0, Position{0, 0}},
+		{2, Position{0, 2}},
+		{4, Position{1, 0}},
+		{8, Position{1, 4}},
+		{14, Position{2, 0}},
+		{17, Position{3, 2}},
+	}
+	for _, tt := range tests {
+		got := TextPosition(text, tt.index)
+		if got != tt.want {
+			t.Errorf("TextPosition(%d) = %v, want %v", tt.index, got, tt.want)
+		}
+		if index := TextIndex(text, got); index != tt.index {
+			t.Errorf("TextIndex(%v) = %d, want %d", got, index, tt.index)
+		}
+	}
+
+	for _, p := range []Position{{-1, 0}, {4, 0}, {0, 3}, {0, -1}, {2, 1}} {
+		if index := TextIndex(text, p); index != -1 {
+			t.Errorf("TextIndex(%v) = %d, want -1", p, index)
+		}
+	}
+	if got := (Position{Line: 1, Col: 4}).String(); got != "line 2, column 5" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestResolvePositions(t *testing.T) {
+	task := Task{ID: "t1", Category: CategoryMotion, Difficulty: 1, OptimalKeys: "jl",
+		Initial: "one\ntwo", Desired: "one\ntwo", CursorStart: 99, CursorEnd: 99,
+		CursorStartPos: &Position{Line: 0, Col: 1}, CursorEndPos: &Position{Line: 1, Col: 2}}
+	task.resolvePositions()
+	if task.CursorStart != 1 || task.CursorEnd != 6 {
+		t.Errorf("resolved to %d and %d, want 1 and 6", task.CursorStart, task.CursorEnd)
+	}
+
+	task.CursorEndPos = &Position{Line: 2, Col: 0}
+	task.resolvePositions()
+	if !errors.Is(task.validate(), ErrCursorEndOutOfRange) {
+		t.Errorf("a position past the text validates: %v", task.validate())
+	}
+}
+
+func TestLineSpan(t *testing.T) {
+	g := NewSeededTaskGenerator(1)
+	for diff := 1; diff <= 4; diff++ {
+		for i := 0; i < 50; i++ {
+			lines, distance := g.lineSpan(diff)
+			if lines < 2 || lines > maxTaskLines || distance < 1 || distance >= lines {
+				t.Errorf("lineSpan(%d) = %d lines, %d apart", diff, lines, distance)
+			}
+			if lines < diff+1 {
+				t.Errorf("lineSpan(%d) = %d lines, want at least %d", diff, lines, diff+1)
+			}
+		}
+	}
+}
+
+func TestLineComment(t *testing.T) {
+	tests := []struct{ language, want string }{
+		{LanguageGo, "// TODO"},
+		{LanguagePython, "# TODO"},
+		{LanguageShell, "# TODO"},
+		{LanguageHTML, "<!-- TODO -->"},
+		{"sql", "-- TODO"},
+		{LanguageJSON, "TODO"},
+		{"rust", "// TODO"},
+	}
+	for _, tt := range tests {
+		if got := lineComment(tt.language, "TODO"); got != tt.want {
+			t.Errorf("lineComment(%q) = %q, want %q", tt.language, got, tt.want)
+		}
+	}
+}
+
+func TestLinesTemplates(t *testing.T) {
+	corpora := []Corpus{
+		{Text: TextProse, Sources: GetPublicDomainSources()},
+		{Text: TextCode, Sources: GetCodeSources()},
+	}
+	for _, corpus := range corpora {
+		for _, template := range linesTemplates() {
+			info := template.Info()
+			g := NewSeededTaskGenerator(1)
+			for diff := 1; diff <= 4; diff++ {
+				made := 0
+				for i := 0; i < 10; i++ {
+					task, err := g.generateFrom(template, corpus, diff)
+					if errors.Is(err, ErrTemplateNoGeneration) {
+						continue
+					} else if err != nil {
+						t.Errorf("%s from %s at difficulty %d: %v", info.Name, corpus.Text, diff, err)
+						continue
+					}
+					made++
+					if !task.IsMultiline() || task.CursorStartPos == nil {
+						t.Errorf("%s: task %q isn't multi-line", info.Name, task.Initial)
+					}
+				}
+				if made == 0 {
+					t.Errorf("%s made no tasks from %s at difficulty %d", info.Name, corpus.Text, diff)
+				}
+			}
+		}
+	}
+}
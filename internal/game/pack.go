@@ -0,0 +1,332 @@
+package game
+
+import (
+	"archive/zip"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/timlinux/macaco/internal/vim"
+)
+
+// PackManifestFile is the manifest at the root of every pack
+const PackManifestFile = "pack.json"
+
+// Pack errors
+const (
+	ErrPackName           GameError = "name should be lower case letters, digits, - and _"
+	ErrPackVersion        GameError = "version should look like 1.2.0"
+	ErrPackAuthor         GameError = "author is missing"
+	ErrPackLicense        GameError = "license is missing"
+	ErrPackNoTaskFiles    GameError = "task_files is empty"
+	ErrPackNoTasks        GameError = "no valid tasks"
+	ErrPackNoManifest     GameError = "no " + PackManifestFile + " found"
+	ErrPackFeature        GameError = "requires a feature the engine doesn't have"
+	ErrPackDuplicateID    GameError = "task id is used twice"
+	ErrPackIDCollision    GameError = "task id is already taken"
+	ErrPackRoundCollision GameError = "round type is already defined"
+	ErrPackVersionClash   GameError = "another version of the pack is loaded"
+)
+
+// PackManifest describes a task pack
+type PackManifest struct {
+	Name        string              `json:"name"`
+	Version     string              `json:"version"` // major.minor.patch
+	Author      string              `json:"author"`
+	License     string              `json:"license"`
+	Description string              `json:"description,omitempty"`
+	Requires    []string            `json:"requires,omitempty"` // Engine features the tasks need, see vim.Features
+	Rounds      map[string]RoundDef `json:"rounds,omitempty"`
+	TaskFiles   []string            `json:"task_files"` // Relative to the manifest
+}
+
+// Pack is a loaded task pack. Its task IDs are prefixed with the pack name,
+// as in "acme/rename-001", so packs can't collide with each other.
+type Pack struct {
+	Manifest PackManifest `json:"manifest"`
+	Path     string       `json:"path"`
+	Tasks    []Task       `json:"-"`
+	problems []error      // Tasks left out while loading
+}
+
+// PackError reports a pack, or part of one, that failed to load
+type PackError struct {
+	Path string
+	Err  error
+}
+
+func (e *PackError) Error() string {
+	return fmt.Sprintf("pack %s: %v", e.Path, e.Err)
+}
+
+func (e *PackError) Unwrap() error {
+	return e.Err
+}
+
+// packTaskFile is the format of a pack's task files
+type packTaskFile struct {
+	Tasks []Task `json:"tasks"`
+}
+
+// LoadPacks loads every pack in dir: directories and .zip archives with a
+// pack.json manifest. When several versions of a pack are installed only the
+// newest is loaded. The errors say which packs, tasks and rounds were
+// left out and why; a missing dir is not an error.
+func LoadPacks(dir string) ([]*Pack, []error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, []error{err}
+	}
+
+	var packs []*Pack
+	var problems []error
+	for _, entry := range entries {
+		p := filepath.Join(dir, entry.Name())
+		if !entry.IsDir() && !strings.EqualFold(filepath.Ext(p), ".zip") {
+			continue
+		}
+
+		pack, packProblems, err := LoadPack(p)
+		if err != nil {
+			problems = append(problems, packProblems...)
+			problems = append(problems, err)
+			continue
+		}
+		pack.problems = packProblems
+		packs = append(packs, pack)
+	}
+
+	// Only the problems of the packs that are kept matter
+	packs, problems = newestPacks(packs, problems)
+	for _, pack := range packs {
+		problems = append(problems, pack.problems...)
+	}
+	return packs, problems
+}
+
+// LoadPack loads the pack in a directory or .zip archive. It fails if the
+// manifest or a task file can't be read, or no task is valid; tasks that
+// fail validation are returned as problems and left out.
+func LoadPack(p string) (*Pack, []error, error) {
+	fail := func(err error) (*Pack, []error, error) {
+		return nil, nil, &PackError{Path: p, Err: err}
+	}
+
+	var fsys fs.FS
+	if info, err := os.Stat(p); err != nil {
+		return fail(err)
+	} else if info.IsDir() {
+		fsys = os.DirFS(p)
+	} else {
+		archive, err := zip.OpenReader(p)
+		if err != nil {
+			return fail(err)
+		}
+		defer archive.Close()
+		fsys = archive
+	}
+
+	root, err := manifestRoot(fsys)
+	if err != nil {
+		return fail(err)
+	}
+
+	pack := &Pack{Path: p}
+	data, err := fs.ReadFile(fsys, path.Join(root, PackManifestFile))
+	if err != nil {
+		return fail(err)
+	}
+	if err := decodeJSON(PackManifestFile, data, &pack.Manifest, true); err != nil {
+		return fail(err)
+	}
+	if err := pack.Manifest.validate(); err != nil {
+		return fail(err)
+	}
+
+	seen := make(map[string]string)
+	for _, name := range pack.Manifest.TaskFiles {
+		data, err := fs.ReadFile(fsys, path.Join(root, name))
+		if err != nil {
+			return fail(err)
+		}
+		var file packTaskFile
+		if err := decodeJSON(name, data, &file, true); err != nil {
+			return fail(err)
+		}
+		for _, task := range file.Tasks {
+			if other, ok := seen[task.ID]; ok && task.ID != "" {
+				return fail(fmt.Errorf("%s: %w: %s, also in %s", name, ErrPackDuplicateID, task.ID, other))
+			}
+			seen[task.ID] = name
+		}
+		pack.Tasks = append(pack.Tasks, file.Tasks...)
+	}
+
+	var problems []error
+	pack.Tasks, problems = prepareTasks(pack.Tasks)
+	for i, problem := range problems {
+		problems[i] = &PackError{Path: p, Err: problem}
+	}
+	if len(pack.Tasks) == 0 {
+		return nil, problems, &PackError{Path: p, Err: ErrPackNoTasks}
+	}
+	return pack, problems, nil
+}
+
+// manifestRoot finds the directory holding the manifest: the root, or the
+// only directory in it, as archives of a pack directory have
+func manifestRoot(fsys fs.FS) (string, error) {
+	if _, err := fs.Stat(fsys, PackManifestFile); err == nil {
+		return ".", nil
+	}
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		if _, err := fs.Stat(fsys, path.Join(entries[0].Name(), PackManifestFile)); err == nil {
+			return entries[0].Name(), nil
+		}
+	}
+	return "", ErrPackNoManifest
+}
+
+// validate checks the manifest's fields
+func (m *PackManifest) validate() error {
+	if m.Name == "" || strings.Trim(m.Name, "abcdefghijklmnopqrstuvwxyz0123456789-_") != "" {
+		return ErrPackName
+	}
+	if _, ok := parseVersion(m.Version); !ok {
+		return ErrPackVersion
+	}
+	if strings.TrimSpace(m.Author) == "" {
+		return ErrPackAuthor
+	}
+	if strings.TrimSpace(m.License) == "" {
+		return ErrPackLicense
+	}
+	if len(m.TaskFiles) == 0 {
+		return ErrPackNoTaskFiles
+	}
+	for _, feature := range m.Requires {
+		if !vim.HasFeature(feature) {
+			return fmt.Errorf("%w: %s", ErrPackFeature, feature)
+		}
+	}
+	return nil
+}
+
+// parseVersion parses a major.minor.patch version
+func parseVersion(version string) ([3]int, bool) {
+	var v [3]int
+	parts := strings.Split(version, ".")
+	if len(parts) != 3 {
+		return v, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return v, false
+		}
+		v[i] = n
+	}
+	return v, true
+}
+
+// newer returns true if version a is newer than b
+func newer(a, b string) bool {
+	va, _ := parseVersion(a)
+	vb, _ := parseVersion(b)
+	for i := range va {
+		if va[i] != vb[i] {
+			return va[i] > vb[i]
+		}
+	}
+	return false
+}
+
+// newestPacks keeps the newest version of every pack, reporting the
+// others. Packs are returned sorted by name.
+func newestPacks(packs []*Pack, problems []error) ([]*Pack, []error) {
+	byName := make(map[string]*Pack)
+	for _, pack := range packs {
+		name := pack.Manifest.Name
+		kept, ok := byName[name]
+		if !ok {
+			byName[name] = pack
+			continue
+		}
+		if newer(pack.Manifest.Version, kept.Manifest.Version) {
+			byName[name], kept, pack = pack, pack, kept
+		}
+		problems = append(problems, &PackError{Path: pack.Path, Err: fmt.Errorf("%w: %s %s is skipped for %s from %s",
+			ErrPackVersionClash, name, pack.Manifest.Version, kept.Manifest.Version, kept.Path)})
+	}
+
+	var newest []*Pack
+	for _, pack := range byName {
+		newest = append(newest, pack)
+	}
+	sort.Slice(newest, func(i, j int) bool {
+		return newest[i].Manifest.Name < newest[j].Manifest.Name
+	})
+	return newest, problems
+}
+
+// AddPack adds a pack's tasks and rounds to the database. Task IDs get the
+// pack name as a prefix. Pack rounds play the pack's tasks: their source
+// defaults to curated and, without a task list, they pick from all of the
+// pack's tasks. Rounds whose type is taken, and tasks whose ID is, are left
+// out and returned as problems.
+func (db *TaskDatabase) AddPack(pack *Pack) []error {
+	var problems []error
+	var ids []string
+	for _, task := range pack.Tasks {
+		task.ID = pack.Manifest.Name + "/" + task.ID
+		if db.GetTask(task.ID) != nil {
+			problems = append(problems, &PackError{Path: pack.Path, Err: fmt.Errorf("%w: %s", ErrPackIDCollision, task.ID)})
+			continue
+		}
+		db.Tasks = append(db.Tasks, task)
+		ids = append(ids, task.ID)
+	}
+	db.buildLookups()
+
+	if db.Rounds == nil {
+		db.Rounds = make(map[string]RoundDef)
+	}
+	for roundType, def := range pack.Manifest.Rounds {
+		if _, ok := db.Rounds[roundType]; ok {
+			problems = append(problems, &PackError{Path: pack.Path, Err: fmt.Errorf("%w: %s", ErrPackRoundCollision, roundType)})
+			continue
+		}
+
+		if def.Source == "" {
+			def.Source = RoundSourceCurated
+		}
+		if len(def.TaskIDs) == 0 {
+			def.TaskIDs = ids
+		} else {
+			listed := make([]string, len(def.TaskIDs))
+			for i, id := range def.TaskIDs {
+				listed[i] = pack.Manifest.Name + "/" + id
+			}
+			def.TaskIDs = listed
+		}
+
+		if err := db.validateRound(roundType, def); err != nil {
+			problems = append(problems, &PackError{Path: pack.Path, Err: &RoundError{RoundType: roundType, Err: err}})
+			continue
+		}
+		db.Rounds[roundType] = def
+	}
+	return problems
+}
@@ -0,0 +1,249 @@
+package game
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes files, named relative to dir, returning dir
+func writeFiles(t *testing.T, dir string, files map[string]string) string {
+	t.Helper()
+	for name, content := range files {
+		name = filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// packManifest returns the pack.json of a pack with one task file and round
+func packManifest(name, version string) string {
+	return fmt.Sprintf(`{"name": %q, "version": %q, "author": "A. Author",
+		"license": "CC0", "task_files": ["tasks.json"],
+		"rounds": {"%s_round": {"name": "Pack Round"}}}`, name, version, name)
+}
+
+// packTasks returns a task file of valid tasks with the IDs
+func packTasks(ids ...string) string {
+	var tasks []string
+	for _, id := range ids {
+		tasks = append(tasks, fmt.Sprintf(`{"id": %q, "category": "delete",
+			"difficulty": 1, "initial": "one two three", "desired": "one three",
+			"cursor_start": 4, "optimal_keys": "dw"}`, id))
+	}
+	return `{"tasks": [` + strings.Join(tasks, ",") + `]}`
+}
+
+func TestPackManifestValidate(t *testing.T) {
+	valid := PackManifest{Name: "acme", Version: "1.2.0", Author: "A", License: "CC0", TaskFiles: []string{"tasks.json"}}
+	tests := []struct {
+		name   string
+		modify func(*PackManifest)
+		want   error
+	}{
+		{"valid", func(*PackManifest) {}, nil},
+		{"features", func(m *PackManifest) { m.Requires = []string{"counts", "multiline"} }, nil},
+		{"no name", func(m *PackManifest) { m.Name = "" }, ErrPackName},
+		{"upper case name", func(m *PackManifest) { m.Name = "Acme" }, ErrPackName},
+		{"name with a slash", func(m *PackManifest) { m.Name = "acme/tasks" }, ErrPackName},
+		{"two part version", func(m *PackManifest) { m.Version = "1.2" }, ErrPackVersion},
+		{"negative version", func(m *PackManifest) { m.Version = "1.-2.0" }, ErrPackVersion},
+		{"no author", func(m *PackManifest) { m.Author = " " }, ErrPackAuthor},
+		{"no license", func(m *PackManifest) { m.License = "" }, ErrPackLicense},
+		{"no task files", func(m *PackManifest) { m.TaskFiles = nil }, ErrPackNoTaskFiles},
+		{"unknown feature", func(m *PackManifest) { m.Requires = []string{"macros"} }, ErrPackFeature},
+	}
+	for _, tt := range tests {
+		m := valid
+		tt.modify(&m)
+		if err := m.validate(); !errors.Is(err, tt.want) {
+			t.Errorf("%s: validate() = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestNewer(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"1.0.1", "1.0.0", true},
+		{"1.10.0", "1.9.9", true},
+		{"2.0.0", "1.99.99", true},
+		{"1.0.0", "1.0.0", false},
+		{"1.0.0", "1.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := newer(tt.a, tt.b); got != tt.want {
+			t.Errorf("newer(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestLoadPack(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		want     error // From LoadPack
+		tasks    int
+		problems int
+	}{
+		{"valid", map[string]string{
+			"pack.json": packManifest("acme", "1.0.0"), "tasks.json": packTasks("a", "b"),
+		}, nil, 2, 0},
+		{"nested", map[string]string{
+			"acme/pack.json": packManifest("acme", "1.0.0"), "acme/tasks.json": packTasks("a"),
+		}, nil, 1, 0},
+		{"invalid task", map[string]string{
+			"pack.json": `{"name": "acme", "version": "1.0.0", "author": "A", "license": "CC0",
+				"task_files": ["tasks.json", "more.json"]}`,
+			"tasks.json": `{"tasks": [{"id": "bad", "category": "delete", "difficulty": 1,
+				"initial": "one", "desired": "two", "optimal_keys": "dw"}]}`,
+			"more.json": packTasks("a"),
+		}, nil, 1, 1},
+		{"no manifest", map[string]string{"tasks.json": packTasks("a")}, ErrPackNoManifest, 0, 0},
+		{"bad manifest", map[string]string{
+			"pack.json": `{"name": "acme", "version": "1"}`, "tasks.json": packTasks("a"),
+		}, ErrPackVersion, 0, 0},
+		{"duplicate id", map[string]string{
+			"pack.json": packManifest("acme", "1.0.0"), "tasks.json": packTasks("a", "a"),
+		}, ErrPackDuplicateID, 0, 0},
+		{"no valid tasks", map[string]string{
+			"pack.json": packManifest("acme", "1.0.0"), "tasks.json": `{"tasks": []}`,
+		}, ErrPackNoTasks, 0, 0},
+	}
+	for _, tt := range tests {
+		dir := writeFiles(t, t.TempDir(), tt.files)
+		pack, problems, err := LoadPack(dir)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: LoadPack() error %v, want %v", tt.name, err, tt.want)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if len(pack.Tasks) != tt.tasks || len(problems) != tt.problems {
+			t.Errorf("%s: %d tasks and problems %v, want %d and %d", tt.name,
+				len(pack.Tasks), problems, tt.tasks, tt.problems)
+		}
+	}
+}
+
+func TestLoadPackZip(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "acme.zip")
+	f, err := os.Create(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := zip.NewWriter(f)
+	for name, content := range map[string]string{
+		"acme/pack.json":  packManifest("acme", "1.0.0"),
+		"acme/tasks.json": packTasks("a"),
+	} {
+		entry, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	pack, _, err := LoadPack(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pack.Manifest.Name != "acme" || len(pack.Tasks) != 1 {
+		t.Errorf("loaded %s with %d tasks", pack.Manifest.Name, len(pack.Tasks))
+	}
+}
+
+func TestLoadPacks(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"acme-1/pack.json":  packManifest("acme", "1.9.0"),
+		"acme-1/tasks.json": packTasks("a"),
+		"acme-2/pack.json":  packManifest("acme", "1.10.0"),
+		"acme-2/tasks.json": packTasks("a", "b"),
+		"zeta/pack.json":    packManifest("zeta", "0.1.0"),
+		"zeta/tasks.json":   packTasks("z"),
+		"empty/readme.txt":  "not a pack",
+		"notes.txt":         "not a pack either",
+	})
+
+	packs, problems := LoadPacks(dir)
+	if len(packs) != 2 || packs[0].Manifest.Name != "acme" || packs[0].Manifest.Version != "1.10.0" ||
+		packs[1].Manifest.Name != "zeta" {
+		t.Errorf("LoadPacks() = %v, want acme 1.10.0 and zeta", packs)
+	}
+	var clash, missing int
+	for _, problem := range problems {
+		switch {
+		case errors.Is(problem, ErrPackVersionClash):
+			clash++
+		case errors.Is(problem, ErrPackNoManifest):
+			missing++
+		default:
+			t.Errorf("unexpected problem %v", problem)
+		}
+	}
+	if clash != 1 || missing != 1 {
+		t.Errorf("problems %v, want a version clash and a missing manifest", problems)
+	}
+
+	if packs, problems := LoadPacks(filepath.Join(dir, "missing")); packs != nil || problems != nil {
+		t.Errorf("LoadPacks(missing) = %v, %v", packs, problems)
+	}
+}
+
+func TestAddPack(t *testing.T) {
+	dir := writeFiles(t, t.TempDir(), map[string]string{
+		"pack.json":  packManifest("acme", "1.0.0"),
+		"tasks.json": packTasks("a", "b"),
+	})
+	pack, _, err := LoadPack(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := NewEmbeddedTaskDatabase()
+	if problems := db.AddPack(pack); len(problems) != 0 {
+		t.Fatalf("AddPack() = %v", problems)
+	}
+	if db.GetTask("acme/a") == nil || db.GetTask("acme/b") == nil {
+		t.Error("pack tasks aren't in the database under the pack's name")
+	}
+	round, ok := db.Rounds["acme_round"]
+	if !ok || round.Source != RoundSourceCurated || len(round.TaskIDs) != 2 {
+		t.Errorf("pack round %+v, want it curated from the pack's tasks", round)
+	}
+	if tasks := db.BuildRound(round, NewSeededTaskGenerator(1)); len(tasks) != 2 {
+		t.Errorf("pack round has %d tasks, want 2", len(tasks))
+	}
+
+	// Adding it again collides with everything
+	problems := db.AddPack(pack)
+	var ids, rounds int
+	for _, problem := range problems {
+		switch {
+		case errors.Is(problem, ErrPackIDCollision):
+			ids++
+		case errors.Is(problem, ErrPackRoundCollision):
+			rounds++
+		}
+	}
+	if ids != 2 || rounds != 1 {
+		t.Errorf("AddPack() again = %v, want 2 task and 1 round collisions", problems)
+	}
+}
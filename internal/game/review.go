@@ -0,0 +1,174 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/timlinux/macaco/internal/vim"
+)
+
+// TaskReview compares the commands a player used for a task with the
+// optimal solution
+type TaskReview struct {
+	TaskID            string       `json:"task_id"`
+	Success           bool         `json:"success"`
+	Steps             []ReviewStep `json:"steps"`
+	Keystrokes        int          `json:"keystrokes"`
+	OptimalKeystrokes int          `json:"optimal_keystrokes"`
+	Delta             int          `json:"delta"` // Keystrokes over the optimal count
+}
+
+// ReviewStep pairs the commands a player used for one part of a task with
+// the optimal commands that make the same change
+type ReviewStep struct {
+	Used       []string `json:"used"`    // Vim key notation, one entry per command
+	Optimal    []string `json:"optimal"` // Vim key notation, one entry per command
+	Delta      int      `json:"delta"`
+	Suggestion string   `json:"suggestion,omitempty"`
+}
+
+// reviewTask builds the review of a task from the keys the player pressed.
+// resets holds the indices into keys at which the task was reset; anything
+// typed before the last reset is reported as a step of its own.
+func reviewTask(task *Task, newEngine func() *vim.Engine, keys []vim.Key, resets []int, success bool) *TaskReview {
+	review := &TaskReview{
+		TaskID:            task.ID,
+		Success:           success,
+		Keystrokes:        len(keys),
+		OptimalKeystrokes: task.OptimalCount,
+		Delta:             len(keys) - task.OptimalCount,
+	}
+
+	if len(resets) > 0 {
+		last := resets[len(resets)-1]
+		var abandoned []string
+		start := 0
+		for _, reset := range resets {
+			for _, cmd := range vim.SplitCommands(newEngine(), keys[start:reset], nil) {
+				abandoned = append(abandoned, vim.FormatKeys(cmd.Keys))
+			}
+			start = reset
+		}
+		if last > 0 {
+			review.Steps = append(review.Steps, ReviewStep{
+				Used:       abandoned,
+				Delta:      last,
+				Suggestion: fmt.Sprintf("%s spent before resetting", pluralKeys(last)),
+			})
+		}
+		keys = keys[last:]
+	}
+
+	optimal := vim.SplitCommands(newEngine(), task.OptimalKeySequence(), func(e *vim.Engine) bool {
+		return task.IsSolvedBy(e)
+	})
+	used := vim.SplitCommands(newEngine(), keys, nil)
+
+	review.Steps = append(review.Steps, alignCommands(used, optimal, task.Initial)...)
+	return review
+}
+
+// alignCommands splits both command lists into steps that end on the same
+// text. The optimal commands are grouped so that each group ends with an
+// edit; each group is matched with the player's commands up to the first
+// one that leaves the same text. Groups the player never matched are
+// folded into the next one.
+func alignCommands(used, optimal []vim.Command, initial string) []ReviewStep {
+	var groups [][]vim.Command
+	var group []vim.Command
+	text := initial
+	for i, cmd := range optimal {
+		group = append(group, cmd)
+		if cmd.Text != text || i == len(optimal)-1 {
+			groups = append(groups, group)
+			group = nil
+			text = cmd.Text
+		}
+	}
+
+	var steps []ReviewStep
+	var pending []vim.Command
+	next := 0
+	for i, group := range groups {
+		pending = append(pending, group...)
+		end := len(used)
+		if i < len(groups)-1 {
+			end = -1
+			for j := next; j < len(used); j++ {
+				if used[j].Text == group[len(group)-1].Text {
+					end = j + 1
+					break
+				}
+			}
+			if end < 0 {
+				continue
+			}
+		}
+		steps = append(steps, newReviewStep(used[next:end], pending))
+		pending = nil
+		next = end
+	}
+	if next < len(used) {
+		steps = append(steps, newReviewStep(used[next:], nil))
+	}
+	return steps
+}
+
+// newReviewStep compares the player's commands with the optimal ones
+func newReviewStep(used, optimal []vim.Command) ReviewStep {
+	step := ReviewStep{
+		Used:    formatCommands(used),
+		Optimal: formatCommands(optimal),
+		Delta:   countKeys(used) - countKeys(optimal),
+	}
+	if step.Delta > 0 && len(step.Optimal) > 0 {
+		// Leave out the commands both sides share
+		usedDiff, optimalDiff := step.Used, step.Optimal
+		for len(usedDiff) > 0 && len(optimalDiff) > 0 && usedDiff[0] == optimalDiff[0] {
+			usedDiff, optimalDiff = usedDiff[1:], optimalDiff[1:]
+		}
+		for len(usedDiff) > 0 && len(optimalDiff) > 0 && usedDiff[len(usedDiff)-1] == optimalDiff[len(optimalDiff)-1] {
+			usedDiff, optimalDiff = usedDiff[:len(usedDiff)-1], optimalDiff[:len(optimalDiff)-1]
+		}
+		step.Suggestion = fmt.Sprintf("you used %s; %s saves %s",
+			quoteCommands(usedDiff), quoteCommands(optimalDiff), pluralKeys(step.Delta))
+	}
+	return step
+}
+
+func formatCommands(commands []vim.Command) []string {
+	var formatted []string
+	for _, cmd := range commands {
+		formatted = append(formatted, vim.FormatKeys(cmd.Keys))
+	}
+	return formatted
+}
+
+func countKeys(commands []vim.Command) int {
+	n := 0
+	for _, cmd := range commands {
+		n += len(cmd.Keys)
+	}
+	return n
+}
+
+// quoteCommands formats commands as "`dw` + `i`"
+func quoteCommands(commands []string) string {
+	if len(commands) == 0 {
+		return "nothing"
+	}
+	return "`" + strings.Join(commands, "` + `") + "`"
+}
+
+// pluralKeys spells out a keystroke count, "one key" or "12 keys"
+func pluralKeys(n int) string {
+	words := []string{"no", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine", "ten"}
+	count := fmt.Sprint(n)
+	if n >= 0 && n < len(words) {
+		count = words[n]
+	}
+	if n == 1 {
+		return count + " key"
+	}
+	return count + " keys"
+}
@@ -0,0 +1,104 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/timlinux/macaco/internal/vim"
+)
+
+func TestReviewTask(t *testing.T) {
+	deleteWord := &Task{ID: "dw", Initial: "one two three", Desired: "one three", CursorStart: 4, OptimalKeys: "dw", OptimalCount: 2}
+	twoEdits := &Task{ID: "two", Initial: "one two three", Desired: "two thre", OptimalKeys: "dw$x", OptimalCount: 4}
+	motion := &Task{ID: "motion", Initial: "one two three", Desired: "one two three", CursorEnd: 8, OptimalKeys: "2w", OptimalCount: 2}
+
+	tests := []struct {
+		name   string
+		task   *Task
+		keys   string
+		resets []int
+		want   []ReviewStep
+	}{
+		{
+			name: "optimal",
+			task: deleteWord,
+			keys: "dw",
+			want: []ReviewStep{{Used: []string{"dw"}, Optimal: []string{"dw"}}},
+		},
+		{
+			name: "longer",
+			task: deleteWord,
+			keys: "xxxx",
+			want: []ReviewStep{{
+				Used: []string{"x", "x", "x", "x"}, Optimal: []string{"dw"}, Delta: 2,
+				Suggestion: "you used `x` + `x` + `x` + `x`; `dw` saves two keys",
+			}},
+		},
+		{
+			name: "steps end on the same text",
+			task: twoEdits,
+			keys: "xxxx$x",
+			want: []ReviewStep{
+				{Used: []string{"x", "x", "x", "x"}, Optimal: []string{"dw"}, Delta: 2,
+					Suggestion: "you used `x` + `x` + `x` + `x`; `dw` saves two keys"},
+				{Used: []string{"$", "x"}, Optimal: []string{"$", "x"}},
+			},
+		},
+		{
+			name: "shared commands left out of the suggestion",
+			task: twoEdits,
+			keys: "dwllllllx",
+			want: []ReviewStep{
+				{Used: []string{"dw"}, Optimal: []string{"dw"}},
+				{Used: []string{"l", "l", "l", "l", "l", "l", "x"}, Optimal: []string{"$", "x"}, Delta: 5,
+					Suggestion: "you used `l` + `l` + `l` + `l` + `l` + `l`; `$` saves five keys"},
+			},
+		},
+		{
+			name:   "reset",
+			task:   deleteWord,
+			keys:   "xdw",
+			resets: []int{1},
+			want: []ReviewStep{
+				{Used: []string{"x"}, Delta: 1, Suggestion: "one key spent before resetting"},
+				{Used: []string{"dw"}, Optimal: []string{"dw"}},
+			},
+		},
+		{
+			name: "motion",
+			task: motion,
+			keys: "www<BS>",
+			want: []ReviewStep{{Used: []string{"w", "w", "w", "<BS>"}, Optimal: []string{"2w"}, Delta: 2,
+				Suggestion: "you used `w` + `w` + `w` + `<BS>`; `2w` saves two keys"}},
+		},
+	}
+	for _, tt := range tests {
+		keys := vim.ParseKeys(tt.keys)
+		review := reviewTask(tt.task, tt.task.newEngine, keys, tt.resets, true)
+		if !reflect.DeepEqual(review.Steps, tt.want) {
+			t.Errorf("%s: steps\n%+v\nwant\n%+v", tt.name, review.Steps, tt.want)
+		}
+		if review.Keystrokes != len(keys) || review.Delta != len(keys)-tt.task.OptimalCount {
+			t.Errorf("%s: %d keystrokes, delta %d", tt.name, review.Keystrokes, review.Delta)
+		}
+	}
+}
+
+func TestPluralKeys(t *testing.T) {
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{0, "no keys"},
+		{1, "one key"},
+		{2, "two keys"},
+		{10, "ten keys"},
+		{12, "12 keys"},
+		{-1, "-1 keys"},
+	}
+	for _, tt := range tests {
+		if got := pluralKeys(tt.n); got != tt.want {
+			t.Errorf("pluralKeys(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
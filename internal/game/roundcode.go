@@ -0,0 +1,328 @@
+package game
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/timlinux/macaco/internal/vim"
+)
+
+// roundCodePrefix starts every round code, followed by RoundCodeVersion
+const roundCodePrefix = "MCC"
+
+// RoundCodeVersion is the version of the generator round codes replay.
+// Bump it whenever a change to the generator or its templates would make a
+// seed generate different tasks, so that a code never gives teammates on
+// different builds different rounds. Codes of other versions are rejected.
+const RoundCodeVersion = 5
+
+// roundCodeSeedBits is the size of the seeds given to new rounds, small
+// enough to read out to a teammate
+const roundCodeSeedBits = 16
+
+// Limits on what a round code may ask for
+const (
+	maxCodeRoundTypeLen  = 24
+	maxCodeCategoryTasks = 30
+	maxCodeRoundTasks    = 60
+	maxCodeDifficulty    = 4
+	maxCodeHints         = 99
+	maxCodeTextWidth     = 200
+	maxCodeScrollOff     = 50
+)
+
+// ErrInvalidRoundCode is wrapped by the errors ParseRoundCode returns
+const ErrInvalidRoundCode GameError = "invalid round code"
+
+// RoundCodeError reports a round code that can't be parsed
+type RoundCodeError struct {
+	Code   string
+	Reason string
+}
+
+func (e *RoundCodeError) Error() string {
+	return fmt.Sprintf("invalid round code %q: %s", e.Code, e.Reason)
+}
+
+func (e *RoundCodeError) Unwrap() error {
+	return ErrInvalidRoundCode
+}
+
+// RoundCode describes a generated round completely, so anyone with the code
+// gets the same tasks. Codes look like MCC5-3F9A-intermediate: the
+// generator version, the seed in hex and the round type, followed by
+// whatever differs from the round type's built-in defaults:
+//
+//	L23           difficulty range 2 to 3
+//	C8.8.4.4.3.3  tasks per category: motion, delete, change, insert,
+//	              visual and complex
+//	O             tasks in category order rather than shuffled
+//	H3            three hints for the round, H0 for none
+//	TC            tasks made from code rather than prose
+//	W60           textwidth
+//	S3            scrolloff
+//	P30.480.2     30 seconds a task, an 8 minute round clock and a penalty
+//	              of 2 points a second of overtime, P0.0.0 for untimed
+//
+// as in MCC5-3F9A-mixed-L23-C8.8.4.4.3.3-W60. The code carries everything
+// needed, so it also works for rounds the other player hasn't defined.
+type RoundCode struct {
+	Seed          uint64
+	RoundType     string
+	MinDifficulty int
+	MaxDifficulty int
+	Distribution  map[TaskCategory]int
+	Shuffle       bool
+	MaxHints      int         // Hints for the round, or UnlimitedHints
+	Text          string      // TextProse or TextCode
+	Time          TimePolicy  // Time limits the round is played with
+	Vim           vim.Options // Engine flavour the round is played with
+}
+
+// NewRoundCode creates the code of a round type with its built-in
+// distribution and difficulty
+func NewRoundCode(seed uint64, roundType string, opts vim.Options) *RoundCode {
+	minDiff, maxDiff := RoundDifficulty(roundType)
+	return &RoundCode{
+		Seed:          seed,
+		RoundType:     roundType,
+		MinDifficulty: minDiff,
+		MaxDifficulty: maxDiff,
+		Distribution:  RoundDistribution(),
+		Shuffle:       true,
+		MaxHints:      UnlimitedHints,
+		Text:          TextProse,
+		Time:          RoundTimePolicy(roundType),
+		Vim:           opts,
+	}
+}
+
+// RoundCodeFor creates the code of a generated round definition
+func RoundCodeFor(seed uint64, roundType string, def RoundDef, opts vim.Options) *RoundCode {
+	rc := NewRoundCode(seed, roundType, opts)
+	rc.MinDifficulty, rc.MaxDifficulty = def.Difficulty()
+	rc.Distribution = def.Distribution()
+	rc.Shuffle = def.Shuffled()
+	rc.MaxHints = def.HintLimit()
+	rc.Text = def.TextKind()
+	rc.Time = def.TimePolicy()
+	return rc
+}
+
+// IsCodeRoundType returns true if rounds of the type can be shared as a
+// code: the name is lower case letters, digits and underscores, and the
+// round isn't a review, daily or drill round, which are built from other
+// state
+func IsCodeRoundType(roundType string) bool {
+	if roundType == "" || len(roundType) > maxCodeRoundTypeLen || roundType == RoundReview || roundType == RoundDaily || roundType == RoundDrill {
+		return false
+	}
+	for _, c := range roundType {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseRoundCode parses a code made by RoundCode.String. Letters may be in
+// either case.
+func ParseRoundCode(code string) (*RoundCode, error) {
+	fail := func(format string, args ...interface{}) (*RoundCode, error) {
+		return nil, &RoundCodeError{Code: code, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	parts := strings.Split(strings.TrimSpace(code), "-")
+	if len(parts) < 3 || len(parts[0]) < len(roundCodePrefix) || !strings.EqualFold(parts[0][:len(roundCodePrefix)], roundCodePrefix) {
+		return fail("expected %s%d-<seed>-<round type>", roundCodePrefix, RoundCodeVersion)
+	}
+	if version := parts[0][len(roundCodePrefix):]; version != strconv.Itoa(RoundCodeVersion) {
+		if version == "" {
+			version = "0"
+		}
+		return fail("made by another version of MoCaCo (round code version %s, this version plays %d)", version, RoundCodeVersion)
+	}
+
+	seed, err := strconv.ParseUint(parts[1], 16, 64)
+	if err != nil {
+		return fail("seed %q is not a hex number", parts[1])
+	}
+	rc := NewRoundCode(seed, strings.ToLower(parts[2]), vim.Options{})
+
+	seen := make(map[byte]bool)
+	for _, part := range parts[3:] {
+		if part == "" {
+			return fail("empty part")
+		}
+		tag := strings.ToUpper(part[:1])[0]
+		value := part[1:]
+		if seen[tag] {
+			return fail("%c given twice", tag)
+		}
+		seen[tag] = true
+
+		switch tag {
+		case 'L':
+			if len(value) != 2 {
+				return fail("difficulty range %q should be two digits", part)
+			}
+			rc.MinDifficulty, rc.MaxDifficulty = int(value[0]-'0'), int(value[1]-'0')
+		case 'C':
+			counts := strings.Split(value, ".")
+			if len(counts) != len(roundCategories) {
+				return fail("distribution %q should have %d counts", part, len(roundCategories))
+			}
+			for i, c := range counts {
+				n, err := strconv.Atoi(c)
+				if err != nil {
+					return fail("task count %q is not a number", c)
+				}
+				rc.Distribution[roundCategories[i]] = n
+			}
+		case 'O':
+			if value != "" {
+				return fail("unknown part %q", part)
+			}
+			rc.Shuffle = false
+		case 'T':
+			switch strings.ToUpper(value) {
+			case "P":
+				rc.Text = TextProse
+			case "C":
+				rc.Text = TextCode
+			default:
+				return fail("text %q should be TP or TC", part)
+			}
+		case 'P':
+			limits := strings.Split(value, ".")
+			if len(limits) != 3 {
+				return fail("time limits %q should be P<task>.<round>.<penalty>", part)
+			}
+			var n [3]int
+			for i, l := range limits {
+				if n[i], err = strconv.Atoi(l); err != nil {
+					return fail("time limit %q is not a number", l)
+				}
+			}
+			rc.Time = TimePolicy{
+				TaskLimit:       time.Duration(n[0]) * time.Second,
+				RoundLimit:      time.Duration(n[1]) * time.Second,
+				OvertimePenalty: n[2],
+			}
+		case 'H', 'W', 'S':
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fail("%q is not a number", value)
+			}
+			switch tag {
+			case 'H':
+				rc.MaxHints = n
+			case 'W':
+				rc.Vim.TextWidth = n
+			case 'S':
+				rc.Vim.ScrollOff = n
+			}
+		default:
+			return fail("unknown part %q", part)
+		}
+	}
+
+	if reason := rc.validate(); reason != "" {
+		return fail("%s", reason)
+	}
+	return rc, nil
+}
+
+// validate returns why the code can't be played, or "" if it can
+func (rc *RoundCode) validate() string {
+	if !IsCodeRoundType(rc.RoundType) {
+		return fmt.Sprintf("round type %q can't be shared", rc.RoundType)
+	}
+	if rc.MinDifficulty < 1 || rc.MaxDifficulty > maxCodeDifficulty || rc.MinDifficulty > rc.MaxDifficulty {
+		return fmt.Sprintf("difficulty range should be within 1 to %d", maxCodeDifficulty)
+	}
+	total := 0
+	for _, cat := range roundCategories {
+		n := rc.Distribution[cat]
+		if n < 0 || n > maxCodeCategoryTasks {
+			return fmt.Sprintf("task counts should be within 0 to %d", maxCodeCategoryTasks)
+		}
+		total += n
+	}
+	for cat := range rc.Distribution {
+		if !isRoundCategory(cat) {
+			return fmt.Sprintf("unknown category %q", cat)
+		}
+	}
+	switch {
+	case total == 0 || total > maxCodeRoundTasks:
+		return fmt.Sprintf("a round should have 1 to %d tasks", maxCodeRoundTasks)
+	case rc.MaxHints < UnlimitedHints || rc.MaxHints > maxCodeHints:
+		return fmt.Sprintf("hints should be within 0 to %d", maxCodeHints)
+	case rc.Text != TextProse && rc.Text != TextCode:
+		return "text should be prose or code; local text can't be shared"
+	case rc.Time.validate() != nil:
+		return string(ErrBadTimeLimit)
+	case rc.Vim.TextWidth < 0 || rc.Vim.TextWidth > maxCodeTextWidth:
+		return fmt.Sprintf("textwidth should be within 0 to %d", maxCodeTextWidth)
+	case rc.Vim.ScrollOff < 0 || rc.Vim.ScrollOff > maxCodeScrollOff:
+		return fmt.Sprintf("scrolloff should be within 0 to %d", maxCodeScrollOff)
+	}
+	return ""
+}
+
+// String returns the code, leaving out everything that matches the round
+// type's defaults
+func (rc *RoundCode) String() string {
+	parts := []string{fmt.Sprintf("%s%d", roundCodePrefix, RoundCodeVersion), fmt.Sprintf("%04X", rc.Seed), rc.RoundType}
+
+	if minDiff, maxDiff := RoundDifficulty(rc.RoundType); rc.MinDifficulty != minDiff || rc.MaxDifficulty != maxDiff {
+		parts = append(parts, fmt.Sprintf("L%d%d", rc.MinDifficulty, rc.MaxDifficulty))
+	}
+
+	defaults := RoundDistribution()
+	counts := make([]string, len(roundCategories))
+	custom := false
+	for i, cat := range roundCategories {
+		counts[i] = strconv.Itoa(rc.Distribution[cat])
+		custom = custom || rc.Distribution[cat] != defaults[cat]
+	}
+	if custom {
+		parts = append(parts, "C"+strings.Join(counts, "."))
+	}
+
+	if !rc.Shuffle {
+		parts = append(parts, "O")
+	}
+	if rc.MaxHints != UnlimitedHints {
+		parts = append(parts, fmt.Sprintf("H%d", rc.MaxHints))
+	}
+	if rc.Text == TextCode {
+		parts = append(parts, "TC")
+	}
+	if rc.Time != RoundTimePolicy(rc.RoundType) {
+		parts = append(parts, fmt.Sprintf("P%d.%d.%d",
+			int(rc.Time.TaskLimit/time.Second), int(rc.Time.RoundLimit/time.Second), rc.Time.OvertimePenalty))
+	}
+	if rc.Vim.TextWidth > 0 {
+		parts = append(parts, fmt.Sprintf("W%d", rc.Vim.TextWidth))
+	}
+	if rc.Vim.ScrollOff > 0 {
+		parts = append(parts, fmt.Sprintf("S%d", rc.Vim.ScrollOff))
+	}
+	return strings.Join(parts, "-")
+}
+
+// Generate generates the tasks of the round. The problems of tasks the
+// generator gave up on are returned with them.
+func (rc *RoundCode) Generate() ([]Task, []error) {
+	g := NewSeededTaskGenerator(int64(rc.Seed))
+	g.SetText(rc.Text)
+	tasks := g.GenerateRound(rc.Distribution, rc.MinDifficulty, rc.MaxDifficulty)
+	if rc.Shuffle {
+		g.shuffle(tasks)
+	}
+	return tasks, g.Problems()
+}
@@ -0,0 +1,150 @@
+package game
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timlinux/macaco/internal/vim"
+)
+
+func TestRoundCodeString(t *testing.T) {
+	ordered := NewRoundCode(0x3F9A, "mixed", vim.Options{})
+	ordered.Shuffle = false
+
+	custom := NewRoundCode(0x3F9A, "mixed", vim.Options{TextWidth: 60, ScrollOff: 3})
+	custom.MinDifficulty, custom.MaxDifficulty = 2, 3
+	custom.Distribution[CategoryMotion] = 8
+	custom.MaxHints = 0
+	custom.Text = TextCode
+	custom.Time = TimePolicy{TaskLimit: 30 * time.Second, RoundLimit: 8 * time.Minute, OvertimePenalty: 2}
+
+	untimed := NewRoundCode(0x1, "expert", vim.Options{})
+	untimed.Time = TimePolicy{}
+
+	tests := []struct {
+		rc   *RoundCode
+		want string
+	}{
+		{NewRoundCode(0x3F9A, "intermediate", vim.Options{}), "MCC5-3F9A-intermediate"},
+		{NewRoundCode(0x12345, "beginner", vim.Options{}), "MCC5-12345-beginner"},
+		{ordered, "MCC5-3F9A-mixed-O"},
+		{custom, "MCC5-3F9A-mixed-L23-C8.6.6.6.3.3-H0-TC-P30.480.2-W60-S3"},
+		{untimed, "MCC5-0001-expert-P0.0.0"},
+	}
+	for _, tt := range tests {
+		got := tt.rc.String()
+		if got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+			continue
+		}
+		parsed, err := ParseRoundCode(got)
+		if err != nil {
+			t.Errorf("ParseRoundCode(%q): %v", got, err)
+			continue
+		}
+		if !reflect.DeepEqual(parsed, tt.rc) {
+			t.Errorf("ParseRoundCode(%q) = %+v, want %+v", got, parsed, tt.rc)
+		}
+	}
+}
+
+func TestParseRoundCode(t *testing.T) {
+	tests := []struct {
+		code   string
+		reason string // Part of the error, "" if the code parses
+	}{
+		{"MCC5-3F9A-mixed", ""},
+		{"  mcc5-3f9a-MIXED-l23-o  ", ""},
+		{"MCC5-3F9A-mixed-C0.0.0.0.0.1", ""},
+		{"MCC5-3F9A", "expected MCC5-<seed>-<round type>"},
+		{"XYZ3-3F9A-mixed", "expected MCC5"},
+		{"MCC-3F9A-mixed", "round code version 0, this version plays 5"},
+		{"MCC4-3F9A-mixed", "round code version 4, this version plays 5"},
+		{"MCC6-3F9A-mixed", "round code version 6"},
+		{"MCC5-XYZ-mixed", "not a hex number"},
+		{"MCC5-3F9A-review", "can't be shared"},
+		{"MCC5-3F9A-daily", "can't be shared"},
+		{"MCC5-3F9A-my.round", "can't be shared"},
+		{"MCC5-3F9A-mixed-L3", "should be two digits"},
+		{"MCC5-3F9A-mixed-L32", "difficulty range"},
+		{"MCC5-3F9A-mixed-L05", "difficulty range"},
+		{"MCC5-3F9A-mixed-C1.2.3", "should have 6 counts"},
+		{"MCC5-3F9A-mixed-C0.0.0.0.0.0", "1 to 60 tasks"},
+		{"MCC5-3F9A-mixed-C31.0.0.0.0.0", "within 0 to 30"},
+		{"MCC5-3F9A-mixed-Cx.0.0.0.0.1", "not a number"},
+		{"MCC5-3F9A-mixed-O-O", "O given twice"},
+		{"MCC5-3F9A-mixed-Ox", "unknown part"},
+		{"MCC5-3F9A-mixed-TL", "should be TP or TC"},
+		{"MCC5-3F9A-mixed-H100", "hints"},
+		{"MCC5-3F9A-mixed-P30.480", "time limits"},
+		{"MCC5-3F9A-mixed--O", "empty part"},
+		{"MCC5-3F9A-mixed-W201", "textwidth"},
+		{"MCC5-3F9A-mixed-S51", "scrolloff"},
+		{"MCC5-3F9A-mixed-Z1", "unknown part"},
+	}
+	for _, tt := range tests {
+		rc, err := ParseRoundCode(tt.code)
+		if tt.reason == "" {
+			if err != nil {
+				t.Errorf("ParseRoundCode(%q): %v", tt.code, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("ParseRoundCode(%q) = %v, want an error", tt.code, rc)
+			continue
+		}
+		if !errors.Is(err, ErrInvalidRoundCode) {
+			t.Errorf("ParseRoundCode(%q) error %v doesn't wrap ErrInvalidRoundCode", tt.code, err)
+		}
+		if !strings.Contains(err.Error(), tt.reason) {
+			t.Errorf("ParseRoundCode(%q) error %q, want it to mention %q", tt.code, err, tt.reason)
+		}
+	}
+}
+
+func TestRoundCodeGenerate(t *testing.T) {
+	rc, err := ParseRoundCode("MCC5-3F9A-mixed-C2.2.2.2.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := rc.Generate()
+	second, _ := rc.Generate()
+	if len(first) != 10 {
+		t.Errorf("generated %d tasks, want 10", len(first))
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Error("one code gave different tasks twice")
+	}
+
+	rc.Seed++
+	other, _ := rc.Generate()
+	if reflect.DeepEqual(first, other) {
+		t.Error("two seeds gave the same tasks")
+	}
+}
+
+func TestIsCodeRoundType(t *testing.T) {
+	tests := []struct {
+		roundType string
+		want      bool
+	}{
+		{"mixed", true},
+		{"my_round2", true},
+		{"", false},
+		{"Mixed", false},
+		{"my-round", false},
+		{strings.Repeat("a", maxCodeRoundTypeLen+1), false},
+		{RoundReview, false},
+		{RoundDaily, false},
+		{RoundDrill, false},
+	}
+	for _, tt := range tests {
+		if got := IsCodeRoundType(tt.roundType); got != tt.want {
+			t.Errorf("IsCodeRoundType(%q) = %v, want %v", tt.roundType, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,366 @@
+package game
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// Where the tasks of a round come from
+const (
+	RoundSourceCurated   = "curated"   // Tasks from the task database
+	RoundSourceGenerated = "generated" // Procedurally generated tasks
+	RoundSourceMix       = "mix"       // Half curated, generated for the rest
+)
+
+// UnlimitedHints is the hint limit of rounds without max_hints
+const UnlimitedHints = -1
+
+// Round errors
+const (
+	ErrUnknownRoundType  GameError = "unknown round type"
+	ErrNoHintsRemaining  GameError = "no hints remaining"
+	ErrUnknownSource     GameError = "source should be curated, generated or mix"
+	ErrUnknownCategory   GameError = "task_distribution has an unknown category"
+	ErrBadDifficulty     GameError = "difficulty_range should be within 1 to 4, lowest first"
+	ErrUnknownRoundTask  GameError = "tasks lists a task that isn't in the database"
+	ErrReservedRoundType GameError = "round type is reserved"
+)
+
+// RoundDef defines a round type. Everything but the name is optional: a
+// round without a distribution uses the default one, and its source
+// defaults to curated tasks when they were loaded from a tasks file and to
+// generated tasks otherwise.
+type RoundDef struct {
+	Name             string         `json:"name"`
+	Description      string         `json:"description,omitempty"`
+	Order            int            `json:"order,omitempty"`  // Position in round lists
+	Source           string         `json:"source,omitempty"` // curated, generated or mix
+	Length           int            `json:"length,omitempty"` // Tasks per round, scaling the distribution
+	Text             string         `json:"text,omitempty"`   // prose, code, local or books, for generated tasks
+	DifficultyRange  [2]int         `json:"difficulty_range"`
+	TaskDistribution map[string]int `json:"task_distribution"`
+	TaskIDs          []string       `json:"tasks,omitempty"`            // Curated tasks to pick from
+	Shuffle          *bool          `json:"shuffle,omitempty"`          // Defaults to true
+	MaxHints         *int           `json:"max_hints,omitempty"`        // Hints per round, unlimited if missing
+	TaskTimeLimit    int            `json:"task_time_limit,omitempty"`  // Seconds per task, untimed if missing
+	RoundTimeLimit   int            `json:"round_time_limit,omitempty"` // Seconds per round, untimed if missing
+	OvertimePenalty  int            `json:"overtime_penalty,omitempty"` // Efficiency points lost per second past the round limit
+}
+
+// RoundError reports a round definition that failed validation
+type RoundError struct {
+	RoundType string
+	Err       error
+}
+
+func (e *RoundError) Error() string {
+	return fmt.Sprintf("round %s: %v", e.RoundType, e.Err)
+}
+
+func (e *RoundError) Unwrap() error {
+	return e.Err
+}
+
+// DefaultRounds returns the built-in round definitions
+func DefaultRounds() map[string]RoundDef {
+	rounds := map[string]RoundDef{
+		"beginner":     {Name: "Beginner Round", Description: "Basic motions and operations", Order: 1},
+		"intermediate": {Name: "Intermediate Round", Description: "Counts and text objects", Order: 2},
+		"advanced":     {Name: "Advanced Round", Description: "Complex combinations", Order: 3},
+		"expert":       {Name: "Expert Round", Description: "Multi-step transformations", Order: 4},
+		"mixed":        {Name: "Mixed Round", Description: "Random difficulty", Order: 5},
+	}
+	for roundType, def := range rounds {
+		minDiff, maxDiff := RoundDifficulty(roundType)
+		def.DifficultyRange = [2]int{minDiff, maxDiff}
+		def.TaskDistribution = make(map[string]int)
+		for cat, n := range RoundDistribution() {
+			def.TaskDistribution[string(cat)] = n
+		}
+		policy := RoundTimePolicy(roundType)
+		def.TaskTimeLimit = int(policy.TaskLimit / time.Second)
+		def.RoundTimeLimit = int(policy.RoundLimit / time.Second)
+		def.OvertimePenalty = policy.OvertimePenalty
+		rounds[roundType] = def
+	}
+	return rounds
+}
+
+// Distribution returns the number of tasks per category, scaled to Length
+// if it is set
+func (d RoundDef) Distribution() map[TaskCategory]int {
+	if len(d.TaskDistribution) == 0 {
+		return scaleDistribution(RoundDistribution(), d.Length)
+	}
+	dist := make(map[TaskCategory]int)
+	for cat, n := range d.TaskDistribution {
+		dist[TaskCategory(cat)] = n
+	}
+	return scaleDistribution(dist, d.Length)
+}
+
+// Difficulty returns the difficulty range, all levels if it isn't set
+func (d RoundDef) Difficulty() (minDiff, maxDiff int) {
+	if d.DifficultyRange == [2]int{} {
+		return 1, 4
+	}
+	return d.DifficultyRange[0], d.DifficultyRange[1]
+}
+
+// TextKind returns the kind of text generated tasks use, prose if unset
+func (d RoundDef) TextKind() string {
+	if d.Text == "" {
+		return TextProse
+	}
+	return d.Text
+}
+
+// Shuffled returns true if the round's tasks are played in random order
+func (d RoundDef) Shuffled() bool {
+	return d.Shuffle == nil || *d.Shuffle
+}
+
+// HintLimit returns how many hints a round allows, or UnlimitedHints
+func (d RoundDef) HintLimit() int {
+	if d.MaxHints == nil {
+		return UnlimitedHints
+	}
+	return *d.MaxHints
+}
+
+// TimePolicy returns the round's time limits and overtime penalty
+func (d RoundDef) TimePolicy() TimePolicy {
+	return TimePolicy{
+		TaskLimit:       time.Duration(d.TaskTimeLimit) * time.Second,
+		RoundLimit:      time.Duration(d.RoundTimeLimit) * time.Second,
+		OvertimePenalty: d.OvertimePenalty,
+	}
+}
+
+// scaleDistribution scales the counts to add up to length, giving the
+// tasks left over by rounding to the categories that lost most to it
+func scaleDistribution(dist map[TaskCategory]int, length int) map[TaskCategory]int {
+	total := 0
+	for _, n := range dist {
+		total += n
+	}
+	if length <= 0 || total == 0 || total == length {
+		return dist
+	}
+
+	scaled := make(map[TaskCategory]int)
+	remainders := make(map[TaskCategory]int)
+	assigned := 0
+	for _, cat := range roundCategories {
+		scaled[cat] = dist[cat] * length / total
+		remainders[cat] = dist[cat] * length % total
+		assigned += scaled[cat]
+	}
+
+	order := append([]TaskCategory{}, roundCategories...)
+	sort.SliceStable(order, func(i, j int) bool {
+		return remainders[order[i]] > remainders[order[j]]
+	})
+	for i := 0; assigned < length; i++ {
+		scaled[order[i%len(order)]]++
+		assigned++
+	}
+	return scaled
+}
+
+// validateRounds drops the round definitions that can't be played,
+// recording why as problems
+func (db *TaskDatabase) validateRounds() {
+	for roundType, def := range db.Rounds {
+		if err := db.validateRound(roundType, def); err != nil {
+			db.problems = append(db.problems, &RoundError{RoundType: roundType, Err: err})
+			delete(db.Rounds, roundType)
+		}
+	}
+}
+
+// validateRound checks a round definition against the database
+func (db *TaskDatabase) validateRound(roundType string, def RoundDef) error {
+	if roundType == RoundReview || roundType == RoundDaily || roundType == RoundDrill || roundType == RoundLesson {
+		return ErrReservedRoundType
+	}
+	switch def.Source {
+	case "", RoundSourceCurated, RoundSourceGenerated, RoundSourceMix:
+	default:
+		return ErrUnknownSource
+	}
+	if def.Text != "" && !IsTextKind(def.Text) {
+		return ErrUnknownText
+	}
+	if minDiff, maxDiff := def.Difficulty(); minDiff < 1 || maxDiff > 4 || minDiff > maxDiff {
+		return ErrBadDifficulty
+	}
+	for cat := range def.TaskDistribution {
+		if !isRoundCategory(TaskCategory(cat)) {
+			return ErrUnknownCategory
+		}
+	}
+	if err := def.TimePolicy().validate(); err != nil {
+		return err
+	}
+	for _, id := range def.TaskIDs {
+		if db.GetTask(id) == nil {
+			return ErrUnknownRoundTask
+		}
+	}
+	return nil
+}
+
+// RoundCategories returns the task categories rounds are made of
+func RoundCategories() []TaskCategory {
+	return append([]TaskCategory{}, roundCategories...)
+}
+
+// isRoundCategory returns true if rounds have tasks of the category
+func isRoundCategory(cat TaskCategory) bool {
+	for _, c := range roundCategories {
+		if c == cat {
+			return true
+		}
+	}
+	return false
+}
+
+// RoundTypes returns the defined round types in their order
+func (db *TaskDatabase) RoundTypes() []string {
+	var names []string
+	for roundType := range db.Rounds {
+		names = append(names, roundType)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := db.Rounds[names[i]], db.Rounds[names[j]]
+		if a.Order != b.Order {
+			return a.Order < b.Order
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// RoundSource returns where a round's tasks come from
+func (db *TaskDatabase) RoundSource(def RoundDef) string {
+	switch {
+	case def.Source != "":
+		return def.Source
+	case db.generated:
+		return RoundSourceGenerated
+	}
+	return RoundSourceCurated
+}
+
+// playsListedTasks returns true if a round plays exactly its listed tasks
+func (db *TaskDatabase) playsListedTasks(def RoundDef) bool {
+	return db.RoundSource(def) == RoundSourceCurated && len(def.TaskIDs) > 0 && len(def.TaskDistribution) == 0
+}
+
+// RoundLength returns how many tasks a round asks for. Curated rounds may
+// get fewer if the database runs short.
+func (db *TaskDatabase) RoundLength(def RoundDef) int {
+	if db.playsListedTasks(def) {
+		return len(def.TaskIDs)
+	}
+	length := 0
+	for _, n := range def.Distribution() {
+		length += n
+	}
+	return length
+}
+
+// BuildRound picks the tasks of a curated or mixed round. Curated rounds
+// that list tasks but no distribution play exactly those tasks; otherwise
+// tasks are picked at random per category from the listed tasks or the
+// whole database. A mixed round takes half of each category from the
+// database and generates the rest, including whatever the database runs
+// short of, from the round's kind of text.
+func (db *TaskDatabase) BuildRound(def RoundDef, generator *TaskGenerator) []Task {
+	source := db.RoundSource(def)
+	rng := generator.rng
+
+	text := generator.Text()
+	generator.SetText(def.TextKind())
+	defer generator.SetText(text)
+
+	var tasks []Task
+	if db.playsListedTasks(def) {
+		for _, id := range def.TaskIDs {
+			tasks = append(tasks, *db.GetTask(id))
+		}
+	} else {
+		minDiff, maxDiff := def.Difficulty()
+		missing := make(map[TaskCategory]int)
+		for _, cat := range roundCategories {
+			count := def.Distribution()[cat]
+			want := count
+			if source == RoundSourceMix {
+				want = (count + 1) / 2
+			}
+
+			picked := db.pickTasks(def.TaskIDs, cat, minDiff, maxDiff, want, rng)
+			tasks = append(tasks, picked...)
+			if source == RoundSourceMix {
+				missing[cat] = count - len(picked)
+			}
+		}
+		tasks = append(tasks, generator.GenerateRound(missing, minDiff, maxDiff)...)
+	}
+
+	if def.Shuffled() {
+		generator.shuffle(tasks)
+	}
+	return tasks
+}
+
+// GenerateRound generates the tasks of a round without a round code, for
+// rounds from local text or imported books, which other players may not
+// have
+func (d RoundDef) GenerateRound(generator *TaskGenerator) []Task {
+	text := generator.Text()
+	generator.SetText(d.TextKind())
+	defer generator.SetText(text)
+
+	minDiff, maxDiff := d.Difficulty()
+	tasks := generator.GenerateRound(d.Distribution(), minDiff, maxDiff)
+	if d.Shuffled() {
+		generator.shuffle(tasks)
+	}
+	return tasks
+}
+
+// pickTasks picks up to n random tasks of a category and difficulty range,
+// from the listed IDs if there are any. Curated tasks are picked by the
+// difficulty their author set, which says what a round is for better than
+// a score worked out from a short solution.
+func (db *TaskDatabase) pickTasks(ids []string, cat TaskCategory, minDiff, maxDiff, n int, rng *rand.Rand) []Task {
+	pool := db.GetTasksByCategory(cat)
+	if len(ids) > 0 {
+		pool = nil
+		for _, id := range ids {
+			if task := db.GetTask(id); task.Category == cat {
+				pool = append(pool, task)
+			}
+		}
+	}
+
+	var eligible []*Task
+	for _, t := range pool {
+		if t.Difficulty >= minDiff && t.Difficulty <= maxDiff {
+			eligible = append(eligible, t)
+		}
+	}
+
+	var tasks []Task
+	for _, i := range rng.Perm(len(eligible)) {
+		if len(tasks) == n {
+			break
+		}
+		tasks = append(tasks, *eligible[i])
+	}
+	return tasks
+}
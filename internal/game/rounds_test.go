@@ -0,0 +1,189 @@
+package game
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestScaleDistribution(t *testing.T) {
+	tests := []struct {
+		length int
+		want   map[TaskCategory]int
+	}{
+		{0, RoundDistribution()},
+		{30, RoundDistribution()},
+		{10, map[TaskCategory]int{CategoryMotion: 2, CategoryDelete: 2, CategoryChange: 2, CategoryInsert: 2, CategoryVisual: 1, CategoryComplex: 1}},
+		{60, map[TaskCategory]int{CategoryMotion: 12, CategoryDelete: 12, CategoryChange: 12, CategoryInsert: 12, CategoryVisual: 6, CategoryComplex: 6}},
+		// 6*4/30 rounds down to 0 for every category; the remainders
+		// go to the categories that lost most
+		{4, map[TaskCategory]int{CategoryMotion: 1, CategoryDelete: 1, CategoryChange: 1, CategoryInsert: 1, CategoryVisual: 0, CategoryComplex: 0}},
+	}
+	for _, tt := range tests {
+		if got := scaleDistribution(RoundDistribution(), tt.length); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("scaleDistribution(%d) = %v, want %v", tt.length, got, tt.want)
+		}
+	}
+}
+
+func TestRoundDefDefaults(t *testing.T) {
+	var def RoundDef
+	if minDiff, maxDiff := def.Difficulty(); minDiff != 1 || maxDiff != 4 {
+		t.Errorf("Difficulty() = %d, %d, want 1, 4", minDiff, maxDiff)
+	}
+	if !reflect.DeepEqual(def.Distribution(), RoundDistribution()) {
+		t.Errorf("Distribution() = %v", def.Distribution())
+	}
+	if def.TextKind() != TextProse || !def.Shuffled() || def.HintLimit() != UnlimitedHints || def.TimePolicy().IsTimed() {
+		t.Errorf("defaults: text %q, shuffled %v, hints %d, timed %v",
+			def.TextKind(), def.Shuffled(), def.HintLimit(), def.TimePolicy().IsTimed())
+	}
+
+	noShuffle, hints := false, 0
+	def = RoundDef{Shuffle: &noShuffle, MaxHints: &hints, Text: TextCode, TaskTimeLimit: 20}
+	if def.Shuffled() || def.HintLimit() != 0 || def.TextKind() != TextCode || def.TimePolicy().TaskLimit.Seconds() != 20 {
+		t.Errorf("set: shuffled %v, hints %d, text %q, policy %v",
+			def.Shuffled(), def.HintLimit(), def.TextKind(), def.TimePolicy())
+	}
+}
+
+func TestValidateRound(t *testing.T) {
+	db := NewEmbeddedTaskDatabase()
+	tests := []struct {
+		roundType string
+		def       RoundDef
+		want      error
+	}{
+		{"custom", RoundDef{}, nil},
+		{"custom", RoundDef{Source: RoundSourceMix, Text: TextCode, TaskIDs: []string{"motion-w-001"}}, nil},
+		{RoundReview, RoundDef{}, ErrReservedRoundType},
+		{RoundDaily, RoundDef{}, ErrReservedRoundType},
+		{RoundDrill, RoundDef{}, ErrReservedRoundType},
+		{RoundLesson, RoundDef{}, ErrReservedRoundType},
+		{"custom", RoundDef{Source: "internet"}, ErrUnknownSource},
+		{"custom", RoundDef{Text: "poetry"}, ErrUnknownText},
+		{"custom", RoundDef{DifficultyRange: [2]int{3, 2}}, ErrBadDifficulty},
+		{"custom", RoundDef{DifficultyRange: [2]int{0, 2}}, ErrBadDifficulty},
+		{"custom", RoundDef{DifficultyRange: [2]int{1, 5}}, ErrBadDifficulty},
+		{"custom", RoundDef{TaskDistribution: map[string]int{"teleport": 1}}, ErrUnknownCategory},
+		{"custom", RoundDef{TaskTimeLimit: -1}, ErrBadTimeLimit},
+		{"custom", RoundDef{TaskIDs: []string{"no-such-task"}}, ErrUnknownRoundTask},
+	}
+	for _, tt := range tests {
+		if err := db.validateRound(tt.roundType, tt.def); !errors.Is(err, tt.want) {
+			t.Errorf("validateRound(%q, %+v) = %v, want %v", tt.roundType, tt.def, err, tt.want)
+		}
+	}
+}
+
+func TestLoadTaskDatabaseRounds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	data := `{
+		"rounds": {
+			"warmup": {"name": "Warm Up", "order": 2, "tasks": ["w1"]},
+			"first": {"name": "First", "order": 1},
+			"broken": {"name": "Broken", "source": "internet"}
+		},
+		"tasks": [{"id": "w1", "category": "motion", "difficulty": 1,
+			"initial": "one two", "desired": "one two", "cursor_start": 0,
+			"cursor_end": 4, "optimal_keys": "w"}]
+	}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := LoadTaskDatabase(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := db.RoundTypes(), []string{"first", "warmup"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RoundTypes() = %v, want %v", got, want)
+	}
+	var roundErr *RoundError
+	if problems := db.Problems(); len(problems) != 1 || !errors.As(problems[0], &roundErr) ||
+		roundErr.RoundType != "broken" || !errors.Is(roundErr, ErrUnknownSource) {
+		t.Errorf("Problems() = %v, want the broken round", problems)
+	}
+
+	warmup := db.Rounds["warmup"]
+	if got := db.RoundLength(warmup); got != 1 {
+		t.Errorf("RoundLength(warmup) = %d, want 1", got)
+	}
+	tasks := db.BuildRound(warmup, NewSeededTaskGenerator(1))
+	if len(tasks) != 1 || tasks[0].ID != "w1" {
+		t.Errorf("BuildRound(warmup) = %v, want the listed task", tasks)
+	}
+}
+
+func TestBuildRound(t *testing.T) {
+	db := NewEmbeddedTaskDatabase()
+	tests := []struct {
+		name string
+		def  RoundDef
+		want int
+	}{
+		{"curated", RoundDef{Length: 10, Source: RoundSourceCurated}, 10},
+		{"mixed", RoundDef{Length: 10, Source: RoundSourceMix}, 10},
+		{"generated only", RoundDef{TaskDistribution: map[string]int{"motion": 3}, Source: RoundSourceMix}, 3},
+		{"listed", RoundDef{TaskIDs: []string{"motion-w-001", "motion-b-001"}}, 2},
+		{"listed per category", RoundDef{TaskIDs: []string{"motion-w-001", "motion-b-001"}, TaskDistribution: map[string]int{"motion": 1}}, 1},
+	}
+	for _, tt := range tests {
+		tasks := db.BuildRound(tt.def, NewSeededTaskGenerator(1))
+		if len(tasks) != tt.want {
+			t.Errorf("%s: %d tasks, want %d", tt.name, len(tasks), tt.want)
+		}
+		minDiff, maxDiff := tt.def.Difficulty()
+		for _, task := range tasks {
+			if task.Difficulty < minDiff || task.Difficulty > maxDiff {
+				t.Errorf("%s: task %s at level %d", tt.name, task.ID, task.Difficulty)
+			}
+		}
+	}
+}
+
+func TestBuildRoundHardCurated(t *testing.T) {
+	// Short solutions score as level 1 whatever level their author set
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	data := `{
+		"rounds": {
+			"hard": {"name": "Hard", "source": "curated", "difficulty_range": [3, 4],
+				"task_distribution": {"delete": 4}}
+		},
+		"tasks": [
+			{"id": "h1", "category": "delete", "difficulty": 3, "initial": "one two three",
+				"desired": "one three", "cursor_start": 4, "optimal_keys": "dw"},
+			{"id": "h2", "category": "delete", "difficulty": 3, "initial": "one two three",
+				"desired": "one wo three", "cursor_start": 4, "optimal_keys": "x"},
+			{"id": "h3", "category": "delete", "difficulty": 4, "initial": "one two three",
+				"desired": "one two", "cursor_start": 7, "optimal_keys": "D"},
+			{"id": "h4", "category": "delete", "difficulty": 4, "initial": "one two three",
+				"desired": "two three", "cursor_start": 0, "optimal_keys": "dw"},
+			{"id": "easy", "category": "delete", "difficulty": 1, "initial": "one two",
+				"desired": "two", "cursor_start": 0, "optimal_keys": "dw"}
+		]
+	}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := LoadTaskDatabase(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems := db.Problems(); len(problems) > 0 {
+		t.Fatalf("Problems() = %v", problems)
+	}
+
+	hard := db.Rounds["hard"]
+	tasks := db.BuildRound(hard, NewSeededTaskGenerator(1))
+	if got, want := len(tasks), db.RoundLength(hard); got != want || got != 4 {
+		t.Fatalf("BuildRound(hard) = %d tasks, want %d", got, want)
+	}
+	for _, task := range tasks {
+		if task.Difficulty < 3 {
+			t.Errorf("task %s at level %d in a level 3-4 round", task.ID, task.Difficulty)
+		}
+	}
+}
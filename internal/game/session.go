@@ -1,9 +1,12 @@
 package game
 
 import (
+	"math"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/timlinux/macaco/internal/replay"
+	"github.com/timlinux/macaco/internal/solver"
 	"github.com/timlinux/macaco/internal/vim"
 )
 
@@ -18,43 +21,64 @@ const (
 
 // Session represents an active game session
 type Session struct {
-	ID             string       `json:"session_id"`
-	RoundType      string       `json:"round_type"`
-	Tasks          []*Task      `json:"tasks"`
-	CurrentIndex   int          `json:"current_task_index"`
-	State          SessionState `json:"state"`
-	StartedAt      time.Time    `json:"started_at"`
-	CompletedAt    *time.Time   `json:"completed_at,omitempty"`
-	TaskResults    []TaskResult `json:"task_results"`
-	TotalTasks     int          `json:"total_tasks"`
-	SkipsRemaining int          `json:"skips_remaining"`
+	ID             string          `json:"session_id"`
+	RoundType      string          `json:"round_type"`
+	Tasks          []*Task         `json:"tasks"`
+	CurrentIndex   int             `json:"current_task_index"`
+	State          SessionState    `json:"state"`
+	StartedAt      time.Time       `json:"started_at"`
+	CompletedAt    *time.Time      `json:"completed_at,omitempty"`
+	TaskResults    []TaskResult    `json:"task_results"`
+	TotalTasks     int             `json:"total_tasks"`
+	SkipsRemaining int             `json:"skips_remaining"`
+	HintsRemaining int             `json:"hints_remaining"` // UnlimitedHints if the round has no limit
+	Daily          *DailyChallenge `json:"daily,omitempty"`
+	RoundCode      string          `json:"round_code,omitempty"` // Replays the round, see RoundCode
+	Time           TimePolicy      `json:"time_policy"`
+	Drill          *Drill          `json:"drill,omitempty"` // Set for drills, which run until mastered
+	Lesson         *Lesson         `json:"lesson,omitempty"` // Set for a unit's practice
 
 	// Runtime state (not serialized)
 	engine       *vim.Engine
 	taskStart    time.Time
 	keystrokes   int
-	keysUsed     string
+	keysUsed     []vim.Key
 	hintsUsed    int
 	resets       int
+	resetAt      []int // Indices into keysUsed at which the task was reset
+	recorder     *replay.Recorder
 	isPaused     bool
 	pauseStart   time.Time
-	pausedTime   time.Duration
+	pausedTime   time.Duration // Paused during the current task
+	roundPaused  time.Duration // Paused during the round
+	viewHeight   int
+	vimOptions   vim.Options
+	reviews      []*TaskReview
+	statsSaved   bool // The finished round is in the stats file
+
+	// nextDrillTask generates the drill's next task
+	nextDrillTask func() (Task, bool)
 }
 
 // TaskResult stores the result of a completed task
 type TaskResult struct {
-	TaskID           string        `json:"task_id"`
-	Category         TaskCategory  `json:"category"`
-	Difficulty       int           `json:"difficulty"`
-	TimeMs           int64         `json:"time_ms"`
-	Keystrokes       int           `json:"keystrokes"`
-	OptimalKeystrokes int          `json:"optimal_keystrokes"`
-	Efficiency       float64       `json:"efficiency"`
-	Success          bool          `json:"success"`
-	KeysUsed         string        `json:"keys_used"`
-	Resets           int           `json:"resets"`
-	HintsUsed        int           `json:"hints_used"`
-	CompletedAt      time.Time     `json:"completed_at"`
+	TaskID            string            `json:"task_id"`
+	Category          TaskCategory      `json:"category"`
+	Difficulty        int               `json:"difficulty"`
+	TimeMs            int64             `json:"time_ms"`
+	Keystrokes        int               `json:"keystrokes"`
+	OptimalKeystrokes int               `json:"optimal_keystrokes"`
+	Efficiency        float64           `json:"efficiency"`
+	Success           bool              `json:"success"`
+	KeysUsed          string            `json:"keys_used"`          // Vim key notation
+	Commands          []string          `json:"commands,omitempty"` // Commands the task practises
+	Resets            int               `json:"resets"`
+	HintsUsed         int               `json:"hints_used"`
+	TimedOut          bool              `json:"timed_out,omitempty"`   // Skipped when its time ran out
+	OvertimeMs        int64             `json:"overtime_ms,omitempty"` // Played past the round clock
+	Penalty           float64           `json:"penalty,omitempty"`     // Efficiency points lost to overtime
+	Replay            *replay.Recording `json:"replay,omitempty"`      // Every key with its timing
+	CompletedAt       time.Time         `json:"completed_at"`
 }
 
 // NewSession creates a new game session
@@ -69,6 +93,7 @@ func NewSession(roundType string, tasks []*Task) *Session {
 		TaskResults:    make([]TaskResult, 0, len(tasks)),
 		TotalTasks:     len(tasks),
 		SkipsRemaining: 5,
+		HintsRemaining: UnlimitedHints,
 	}
 }
 
@@ -96,32 +121,57 @@ func (s *Session) NextTask() *Task {
 	return nil
 }
 
-// StartTask initializes the current task
+// StartTask initializes the current task. Generated tasks are solved
+// here rather than when generated, so a round costs one search per task
+// played. Their difficulty scores stay those the round was filled by.
 func (s *Session) StartTask() {
 	task := s.CurrentTask()
 	if task == nil {
 		return
 	}
+	if !task.Solved {
+		task.Optimize(solver.DefaultOptions())
+	}
 
-	s.engine = vim.NewEngine(task.Initial)
-	s.engine.SetCursorIndex(task.CursorStart)
+	s.engine = s.newTaskEngine(task)
 	s.taskStart = time.Now()
 	s.keystrokes = 0
-	s.keysUsed = ""
+	s.keysUsed = nil
 	s.hintsUsed = 0
 	s.resets = 0
+	s.resetAt = nil
+	s.recorder = replay.NewRecorder(s.engine)
 	s.pausedTime = 0
 }
 
+// newTaskEngine creates an engine in the task's starting state with the
+// session's vim options and viewport
+func (s *Session) newTaskEngine(task *Task) *vim.Engine {
+	opts := s.vimOptions
+	if task.TextWidth > 0 {
+		opts.TextWidth = task.TextWidth
+	}
+	engine := vim.NewEngine(task.Initial)
+	engine.SetOptions(opts)
+	engine.SetViewportHeight(s.viewHeight)
+	engine.SetCursorIndex(task.CursorStart)
+	return engine
+}
+
 // ProcessKey processes a keystroke and returns the match status
-func (s *Session) ProcessKey(key string) MatchStatus {
+func (s *Session) ProcessKey(key vim.Key) MatchStatus {
 	if s.engine == nil || s.isPaused {
 		return MatchNone
 	}
+	if s.CheckTime() {
+		// The key came too late for the task
+		return MatchNone
+	}
 
 	s.engine.ProcessKey(key)
 	s.keystrokes++
-	s.keysUsed += key
+	s.keysUsed = append(s.keysUsed, key)
+	s.recorder.Record(key, s.ElapsedTime(), s.engine)
 
 	return s.CheckMatch()
 }
@@ -134,21 +184,15 @@ func (s *Session) CheckMatch() MatchStatus {
 	}
 
 	bufferText := s.engine.Text()
-	cursorIdx := s.engine.CursorIndex()
 
+	// Motion tasks check the cursor position, editing tasks the text
+	if task.IsSolvedBy(s.engine) {
+		return MatchComplete
+	}
 	if task.IsMotionTask() {
-		// For motion tasks, check cursor position
-		if cursorIdx == task.CursorEnd {
-			return MatchComplete
-		}
 		return MatchInProgress
 	}
 
-	// For editing tasks, check text match
-	if bufferText == task.Desired {
-		return MatchComplete
-	}
-
 	if bufferText == task.Initial {
 		return MatchNone
 	}
@@ -156,14 +200,15 @@ func (s *Session) CheckMatch() MatchStatus {
 	return MatchInProgress
 }
 
-// CompleteTask marks the current task as complete and advances
+// CompleteTask marks the current task as complete and advances. Tasks
+// finished past the round clock lose the overtime penalty from their
+// efficiency.
 func (s *Session) CompleteTask() *TaskResult {
 	task := s.CurrentTask()
 	if task == nil {
 		return nil
 	}
 
-	elapsed := time.Since(s.taskStart) - s.pausedTime
 	efficiency := 0.0
 	if s.keystrokes > 0 {
 		efficiency = (float64(task.OptimalCount) / float64(s.keystrokes)) * 100
@@ -172,38 +217,26 @@ func (s *Session) CompleteTask() *TaskResult {
 		}
 	}
 
-	result := TaskResult{
-		TaskID:            task.ID,
-		Category:          task.Category,
-		Difficulty:        task.Difficulty,
-		TimeMs:            elapsed.Milliseconds(),
-		Keystrokes:        s.keystrokes,
-		OptimalKeystrokes: task.OptimalCount,
-		Efficiency:        efficiency,
-		Success:           true,
-		KeysUsed:          s.keysUsed,
-		Resets:            s.resets,
-		HintsUsed:         s.hintsUsed,
-		CompletedAt:       time.Now(),
-	}
-
-	s.TaskResults = append(s.TaskResults, result)
-	s.CurrentIndex++
-
-	if s.CurrentIndex >= len(s.Tasks) {
-		s.State = SessionStateCompleted
-		now := time.Now()
-		s.CompletedAt = &now
-	} else {
-		s.StartTask()
+	result := s.taskResult(task, true)
+	result.Efficiency = efficiency
+	if s.Time.OvertimePenalty > 0 {
+		overtime := s.Overtime()
+		if elapsed := s.ElapsedTime(); overtime > elapsed {
+			overtime = elapsed
+		}
+		result.OvertimeMs = overtime.Milliseconds()
+		result.Penalty = math.Min(float64(s.Time.OvertimePenalty)*overtime.Seconds(), efficiency)
+		result.Efficiency -= result.Penalty
 	}
 
+	s.finishTask(result)
 	return &result
 }
 
-// SkipTask skips the current task
+// SkipTask skips the current task. Skips in drills are free, and break
+// the streak of clean solves.
 func (s *Session) SkipTask() bool {
-	if s.SkipsRemaining <= 0 {
+	if s.SkipsRemaining <= 0 && s.Drill == nil {
 		return false
 	}
 
@@ -212,35 +245,83 @@ func (s *Session) SkipTask() bool {
 		return false
 	}
 
-	s.SkipsRemaining--
+	if s.Drill == nil {
+		s.SkipsRemaining--
+	}
+	s.finishTask(s.taskResult(task, false))
+	return true
+}
+
+// CheckTime skips the current task once its time is up, without using a
+// skip, and ends the round when the round clock runs out in a round without
+// an overtime penalty. It returns true if it did either. Solved tasks and
+// paused sessions aren't timed out.
+func (s *Session) CheckTime() bool {
+	task := s.CurrentTask()
+	if task == nil || s.isPaused || s.IsComplete() || s.CheckMatch() == MatchComplete {
+		return false
+	}
+
+	taskOver := s.Time.TaskLimit > 0 && s.ElapsedTime() >= s.Time.TaskLimit
+	roundOver := s.Time.RoundLimit > 0 && s.Time.OvertimePenalty == 0 && s.RoundElapsedTime() >= s.Time.RoundLimit
+	if !taskOver && !roundOver {
+		return false
+	}
+
+	result := s.taskResult(task, false)
+	result.TimedOut = true
+	if taskOver {
+		result.TimeMs = s.Time.TaskLimit.Milliseconds()
+	}
+	s.finishTask(result)
+	if roundOver && !s.IsComplete() {
+		s.end()
+	}
+	return true
+}
 
+// taskResult returns the result of the current task so far, without
+// efficiency
+func (s *Session) taskResult(task *Task, success bool) TaskResult {
 	result := TaskResult{
 		TaskID:            task.ID,
 		Category:          task.Category,
 		Difficulty:        task.Difficulty,
-		TimeMs:            60000, // Max time
+		TimeMs:            s.ElapsedTime().Milliseconds(),
 		Keystrokes:        s.keystrokes,
 		OptimalKeystrokes: task.OptimalCount,
-		Efficiency:        0,
-		Success:           false,
-		KeysUsed:          s.keysUsed,
+		Success:           success,
+		KeysUsed:          vim.FormatKeys(s.keysUsed),
+		Commands:          task.Commands(),
 		Resets:            s.resets,
 		HintsUsed:         s.hintsUsed,
 		CompletedAt:       time.Now(),
 	}
+	if s.recorder != nil {
+		result.Replay = s.recorder.Recording(!success)
+	}
+	return result
+}
 
+// finishTask records the result of the current task and moves on to the
+// next one, ending the session after the last
+func (s *Session) finishTask(result TaskResult) {
 	s.TaskResults = append(s.TaskResults, result)
+	s.reviews = append(s.reviews, s.reviewCurrentTask(result.Success))
 	s.CurrentIndex++
 
-	if s.CurrentIndex >= len(s.Tasks) {
-		s.State = SessionStateCompleted
-		now := time.Now()
-		s.CompletedAt = &now
+	if s.Drill != nil && s.recordDrill(result) || s.CurrentIndex >= len(s.Tasks) {
+		s.end()
 	} else {
 		s.StartTask()
 	}
+}
 
-	return true
+// end marks the session as completed
+func (s *Session) end() {
+	s.State = SessionStateCompleted
+	now := time.Now()
+	s.CompletedAt = &now
 }
 
 // ResetTask resets the current task
@@ -251,13 +332,45 @@ func (s *Session) ResetTask() {
 	}
 
 	s.resets++
+	s.resetAt = append(s.resetAt, len(s.keysUsed))
+	s.recorder.Reset()
 	s.engine.Reset(task.Initial, task.CursorStart)
 	// Timer and keystroke count continue
 }
 
-// UseHint records hint usage
-func (s *Session) UseHint() {
+// reviewCurrentTask compares the keys used for the current task with its
+// optimal solution
+func (s *Session) reviewCurrentTask(success bool) *TaskReview {
+	task := s.CurrentTask()
+	newEngine := func() *vim.Engine { return s.newTaskEngine(task) }
+	return reviewTask(task, newEngine, s.keysUsed, s.resetAt, success)
+}
+
+// Review returns the review of the task result at index, or nil if that
+// task hasn't been finished yet
+func (s *Session) Review(index int) *TaskReview {
+	if index < 0 || index >= len(s.reviews) {
+		return nil
+	}
+	return s.reviews[index]
+}
+
+// Reviews returns the reviews of all finished tasks, in task order
+func (s *Session) Reviews() []*TaskReview {
+	return s.reviews
+}
+
+// UseHint records hint usage. It returns false once the round's hints
+// are used up.
+func (s *Session) UseHint() bool {
+	if s.HintsRemaining == 0 {
+		return false
+	}
+	if s.HintsRemaining > 0 {
+		s.HintsRemaining--
+	}
 	s.hintsUsed++
+	return true
 }
 
 // Pause pauses the session timer
@@ -272,7 +385,9 @@ func (s *Session) Pause() {
 // Resume resumes the session timer
 func (s *Session) Resume() {
 	if s.isPaused {
-		s.pausedTime += time.Since(s.pauseStart)
+		paused := time.Since(s.pauseStart)
+		s.pausedTime += paused
+		s.roundPaused += paused
 		s.isPaused = false
 		s.State = SessionStateActive
 	}
@@ -304,6 +419,30 @@ func (s *Session) CursorPosition() (x, y int) {
 	return s.engine.CursorPosition()
 }
 
+// SetViewportHeight sets the number of buffer lines visible on screen
+func (s *Session) SetViewportHeight(height int) {
+	s.viewHeight = height
+	if s.engine != nil {
+		s.engine.SetViewportHeight(height)
+	}
+}
+
+// SetVimOptions sets the vim options used for every task
+func (s *Session) SetVimOptions(opts vim.Options) {
+	s.vimOptions = opts
+	if s.engine != nil {
+		s.engine.SetOptions(opts)
+	}
+}
+
+// Viewport returns the first visible buffer line and the number of visible lines
+func (s *Session) Viewport() (top, height int) {
+	if s.engine == nil {
+		return 0, s.viewHeight
+	}
+	return s.engine.Viewport()
+}
+
 // CursorIndex returns the current cursor index
 func (s *Session) CursorIndex() int {
 	if s.engine == nil {
@@ -332,6 +471,44 @@ func (s *Session) ElapsedTime() time.Duration {
 	return elapsed
 }
 
+// RoundElapsedTime returns the time the round has been played, leaving out
+// pauses
+func (s *Session) RoundElapsedTime() time.Duration {
+	end := time.Now()
+	if s.CompletedAt != nil {
+		end = *s.CompletedAt
+	} else if s.isPaused {
+		end = s.pauseStart
+	}
+	return end.Sub(s.StartedAt) - s.roundPaused
+}
+
+// TaskTimeRemaining returns the time left for the current task, and false
+// if tasks aren't timed
+func (s *Session) TaskTimeRemaining() (time.Duration, bool) {
+	if s.Time.TaskLimit == 0 {
+		return 0, false
+	}
+	return max(s.Time.TaskLimit-s.ElapsedTime(), 0), true
+}
+
+// RoundTimeRemaining returns the time left on the round clock, and false
+// if the round isn't timed
+func (s *Session) RoundTimeRemaining() (time.Duration, bool) {
+	if s.Time.RoundLimit == 0 {
+		return 0, false
+	}
+	return max(s.Time.RoundLimit-s.RoundElapsedTime(), 0), true
+}
+
+// Overtime returns how long the round has been played past its clock
+func (s *Session) Overtime() time.Duration {
+	if s.Time.RoundLimit == 0 {
+		return 0
+	}
+	return max(s.RoundElapsedTime()-s.Time.RoundLimit, 0)
+}
+
 // TotalElapsedTime returns total session time
 func (s *Session) TotalElapsedTime() time.Duration {
 	if s.CompletedAt != nil {
@@ -348,6 +525,11 @@ func (s *Session) Progress() float64 {
 	return float64(s.CurrentIndex) / float64(s.TotalTasks)
 }
 
+// KeysUsed returns the keys pressed so far on the current task
+func (s *Session) KeysUsed() []vim.Key {
+	return s.keysUsed
+}
+
 // Keystrokes returns the current keystroke count
 func (s *Session) Keystrokes() int {
 	return s.keystrokes
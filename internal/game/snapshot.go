@@ -0,0 +1,185 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/timlinux/macaco/internal/replay"
+	"github.com/timlinux/macaco/internal/vim"
+)
+
+// SnapshotVersion is the version of the saved session format. Snapshots of
+// any other version can't be resumed.
+const SnapshotVersion = 2
+
+// snapshotMaxAge is how long after it was last saved a round can be resumed
+const snapshotMaxAge = 24 * time.Hour
+
+// Snapshot errors
+const (
+	ErrSnapshotExpired      GameError = "the unfinished round was saved over a day ago and was discarded"
+	ErrSnapshotIncompatible GameError = "the unfinished round can't be resumed by this version and was discarded"
+)
+
+// SessionSnapshot is a session saved mid-round, so it can be resumed after
+// a crash or quit
+type SessionSnapshot struct {
+	Version int            `json:"version"`
+	SavedAt time.Time      `json:"saved_at"`
+	Session *Session       `json:"session"`
+	Runtime SessionRuntime `json:"runtime"`
+}
+
+// SessionRuntime is the state a session keeps only while it runs: the
+// engine of the current task with its undo history, what the player did on
+// the task so far, and the clocks
+type SessionRuntime struct {
+	Engine         vim.EngineState   `json:"engine"`
+	Keystrokes     int               `json:"keystrokes"`
+	KeysUsed       string            `json:"keys_used"` // Vim key notation
+	HintsUsed      int               `json:"hints_used"`
+	Resets         int               `json:"resets"`
+	ResetAt        []int             `json:"reset_at,omitempty"`
+	TaskElapsedMs  int64             `json:"task_elapsed_ms"`  // On the current task, leaving out pauses
+	PausedMs       int64             `json:"paused_ms"`        // Paused during the current task
+	RoundElapsedMs int64             `json:"round_elapsed_ms"` // On the round, leaving out pauses
+	Paused         bool              `json:"paused"`
+	ViewHeight     int               `json:"view_height"`
+	ScrollOff      int               `json:"scrolloff,omitempty"`
+	TextWidth      int               `json:"textwidth,omitempty"`
+	Reviews        []*TaskReview     `json:"reviews,omitempty"`
+	Replay         *replay.Recording `json:"replay,omitempty"` // Of the current task so far
+}
+
+// Snapshot returns the session's state for saving. The clocks are saved
+// as the time played so far, so time spent away doesn't count.
+func (s *Session) Snapshot() *SessionSnapshot {
+	paused := s.pausedTime
+	if s.isPaused {
+		paused += time.Since(s.pauseStart)
+	}
+
+	runtime := SessionRuntime{
+		Keystrokes:     s.keystrokes,
+		KeysUsed:       vim.FormatKeys(s.keysUsed),
+		HintsUsed:      s.hintsUsed,
+		Resets:         s.resets,
+		ResetAt:        s.resetAt,
+		TaskElapsedMs:  s.ElapsedTime().Milliseconds(),
+		PausedMs:       paused.Milliseconds(),
+		RoundElapsedMs: s.RoundElapsedTime().Milliseconds(),
+		Paused:         s.isPaused,
+		ViewHeight:     s.viewHeight,
+		ScrollOff:      s.vimOptions.ScrollOff,
+		TextWidth:      s.vimOptions.TextWidth,
+		Reviews:        s.reviews,
+	}
+	if s.engine != nil {
+		runtime.Engine = s.engine.State()
+	}
+	if s.recorder != nil {
+		runtime.Replay = s.recorder.Recording(false)
+	}
+
+	return &SessionSnapshot{
+		Version: SnapshotVersion,
+		SavedAt: time.Now(),
+		Session: s,
+		Runtime: runtime,
+	}
+}
+
+// check returns why a snapshot can't be resumed at now, or nil if it can.
+// Daily challenges can only be resumed on their own UTC day.
+func (snap *SessionSnapshot) check(now time.Time) error {
+	s := snap.Session
+	switch {
+	case snap.Version != SnapshotVersion || s == nil:
+		return ErrSnapshotIncompatible
+	case s.IsComplete() || s.CurrentIndex < 0 || s.CurrentIndex >= len(s.Tasks) || len(snap.Runtime.Engine.Buffer.Lines) == 0:
+		return ErrSnapshotIncompatible
+	case now.Sub(snap.SavedAt) > snapshotMaxAge:
+		return ErrSnapshotExpired
+	case s.Daily != nil && s.Daily.Date != DailyChallengeFor(now).Date:
+		return ErrSnapshotExpired
+	}
+	return nil
+}
+
+// RestoreSession rebuilds the session of a snapshot with its current task
+// as it was saved, and its clocks carrying on from where they stopped
+func RestoreSession(snap *SessionSnapshot) (*Session, error) {
+	if err := snap.check(time.Now()); err != nil {
+		return nil, err
+	}
+
+	s := snap.Session
+	r := snap.Runtime
+	now := time.Now()
+	paused := time.Duration(r.PausedMs) * time.Millisecond
+
+	s.engine = vim.RestoreEngine(r.Engine)
+	s.keystrokes = r.Keystrokes
+	s.keysUsed = vim.ParseKeys(r.KeysUsed)
+	s.hintsUsed = r.HintsUsed
+	s.resets = r.Resets
+	s.resetAt = r.ResetAt
+	if r.Replay != nil {
+		s.recorder = replay.ResumeRecorder(r.Replay)
+	} else {
+		s.recorder = replay.NewRecorder(s.engine)
+	}
+	s.taskStart = now.Add(-time.Duration(r.TaskElapsedMs)*time.Millisecond - paused)
+	s.pausedTime = paused
+	s.roundPaused = now.Sub(s.StartedAt) - time.Duration(r.RoundElapsedMs)*time.Millisecond
+	s.viewHeight = r.ViewHeight
+	s.vimOptions = vim.Options{ScrollOff: r.ScrollOff, TextWidth: r.TextWidth}
+	s.reviews = r.Reviews
+	s.isPaused = false
+	s.State = SessionStateActive
+	if r.Paused {
+		s.Pause()
+	}
+	return s, nil
+}
+
+// SaveSnapshot writes a snapshot to path, replacing it in one step so a
+// crash while saving leaves the last snapshot intact
+func SaveSnapshot(path string, snap *SessionSnapshot) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadSnapshot reads the snapshot at path, or returns nil if there is
+// none. A snapshot that can't be read or resumed is removed, and the error
+// says why.
+func LoadSnapshot(path string) (*SessionSnapshot, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var snap SessionSnapshot
+	if json.Unmarshal(data, &snap) != nil {
+		err = ErrSnapshotIncompatible
+	} else if err = snap.check(time.Now()); err == nil {
+		return &snap, nil
+	}
+	os.Remove(path)
+	return nil, err
+}
@@ -0,0 +1,142 @@
+package game
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/timlinux/macaco/internal/vim"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	s := timedSession(RoundTimePolicy("expert"))
+	s.SetVimOptions(vim.Options{TextWidth: 40, ScrollOff: 2})
+	s.SetViewportHeight(10)
+	s.UseHint()
+	s.ProcessKey("x")
+	s.taskStart = s.taskStart.Add(-5 * time.Second)
+	s.StartedAt = s.StartedAt.Add(-time.Minute)
+
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := SaveSnapshot(path, s.Snapshot()); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := LoadSnapshot(path)
+	if err != nil || snap == nil {
+		t.Fatalf("LoadSnapshot() = %v, %v", snap, err)
+	}
+	restored, err := RestoreSession(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if restored.ID != s.ID || restored.Time != s.Time || len(restored.Tasks) != 2 {
+		t.Errorf("restored %s with %v and %d tasks", restored.ID, restored.Time, len(restored.Tasks))
+	}
+	if restored.BufferText() != "one wo three" || restored.CursorIndex() != 4 {
+		t.Errorf("restored buffer %q at %d", restored.BufferText(), restored.CursorIndex())
+	}
+	if restored.Keystrokes() != 1 || vim.FormatKeys(restored.KeysUsed()) != "x" || restored.hintsUsed != 1 {
+		t.Errorf("restored %d keystrokes %q and %d hints", restored.Keystrokes(), vim.FormatKeys(restored.KeysUsed()), restored.hintsUsed)
+	}
+	if restored.vimOptions.TextWidth != 40 || restored.vimOptions.ScrollOff != 2 || restored.viewHeight != 10 {
+		t.Errorf("restored options %+v and height %d", restored.vimOptions, restored.viewHeight)
+	}
+	if elapsed := restored.ElapsedTime(); elapsed < 5*time.Second || elapsed > 6*time.Second {
+		t.Errorf("task clock restored at %v, want 5s", elapsed)
+	}
+	if elapsed := restored.RoundElapsedTime(); elapsed < time.Minute || elapsed > time.Minute+time.Second {
+		t.Errorf("round clock restored at %v, want 1m", elapsed)
+	}
+
+	// The undo history carries over, and the task plays on
+	restored.ProcessKey("u")
+	if restored.BufferText() != "one two three" {
+		t.Errorf("undo after restoring gave %q", restored.BufferText())
+	}
+	restored.ProcessKey("d")
+	if restored.ProcessKey("w") != MatchComplete {
+		t.Fatal("the restored task can't be finished")
+	}
+	result := restored.CompleteTask()
+	if result.Keystrokes != 4 || result.KeysUsed != "xudw" {
+		t.Errorf("result %d keystrokes %q, want 4 xudw", result.Keystrokes, result.KeysUsed)
+	}
+}
+
+func TestSnapshotPaused(t *testing.T) {
+	s := timedSession(TimePolicy{})
+	s.Pause()
+	restored, err := RestoreSession(s.Snapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !restored.IsPaused() {
+		t.Error("a paused session was restored running")
+	}
+}
+
+func TestSnapshotCheck(t *testing.T) {
+	now := time.Date(2026, 2, 11, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		modify func(*SessionSnapshot)
+		want   error
+	}{
+		{"fresh", func(*SessionSnapshot) {}, nil},
+		{"old version", func(snap *SessionSnapshot) { snap.Version = SnapshotVersion - 1 }, ErrSnapshotIncompatible},
+		{"no session", func(snap *SessionSnapshot) { snap.Session = nil }, ErrSnapshotIncompatible},
+		{"complete", func(snap *SessionSnapshot) { snap.Session.end() }, ErrSnapshotIncompatible},
+		{"task out of range", func(snap *SessionSnapshot) { snap.Session.CurrentIndex = 2 }, ErrSnapshotIncompatible},
+		{"no buffer", func(snap *SessionSnapshot) { snap.Runtime.Engine.Buffer.Lines = nil }, ErrSnapshotIncompatible},
+		{"a day old", func(snap *SessionSnapshot) { snap.SavedAt = now.Add(-25 * time.Hour) }, ErrSnapshotExpired},
+		{"daily today", func(snap *SessionSnapshot) {
+			daily := DailyChallengeFor(now)
+			snap.Session.Daily = &daily
+		}, nil},
+		{"daily yesterday", func(snap *SessionSnapshot) {
+			daily := DailyChallengeFor(now.Add(-13 * time.Hour))
+			snap.Session.Daily = &daily
+		}, ErrSnapshotExpired},
+	}
+	for _, tt := range tests {
+		snap := timedSession(TimePolicy{}).Snapshot()
+		snap.SavedAt = now.Add(-time.Hour)
+		tt.modify(snap)
+		if err := snap.check(now); !errors.Is(err, tt.want) {
+			t.Errorf("%s: check() = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestLoadSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	if snap, err := LoadSnapshot(filepath.Join(dir, "missing.json")); snap != nil || err != nil {
+		t.Errorf("LoadSnapshot(missing) = %v, %v", snap, err)
+	}
+
+	tests := []struct {
+		name string
+		save func(path string)
+		want error
+	}{
+		{"corrupt", func(path string) { os.WriteFile(path, []byte("{not json"), 0644) }, ErrSnapshotIncompatible},
+		{"expired", func(path string) {
+			snap := timedSession(TimePolicy{}).Snapshot()
+			snap.SavedAt = time.Now().Add(-48 * time.Hour)
+			SaveSnapshot(path, snap)
+		}, ErrSnapshotExpired},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.name+".json")
+		tt.save(path)
+		if snap, err := LoadSnapshot(path); snap != nil || !errors.Is(err, tt.want) {
+			t.Errorf("%s: LoadSnapshot() = %v, %v, want %v", tt.name, snap, err, tt.want)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s: the snapshot wasn't removed", tt.name)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package game
+
+import (
+	"github.com/timlinux/macaco/internal/solver"
+)
+
+// SolverProblem describes the task for the solver
+func (t *Task) SolverProblem() solver.Problem {
+	return solver.Problem{
+		Initial:     t.Initial,
+		Desired:     t.Desired,
+		CursorStart: t.CursorStart,
+		CursorEnd:   t.CursorEnd,
+		MotionOnly:  t.IsMotionTask(),
+		TextWidth:   t.TextWidth,
+	}
+}
+
+// Optimize replaces the task's hand-written solution with the shortest one
+// the solver can find. A hand-written solution that works bounds the
+// search, and is kept when the solver finds nothing shorter.
+func (t *Task) Optimize(opts solver.Options) {
+	t.Solved = true
+	problem := t.SolverProblem()
+	current := len(t.OptimalKeySequence())
+	valid := t.OptimalKeys != "" && solver.Verify(problem, t.OptimalKeys)
+	if valid && current < opts.MaxCost {
+		opts.MaxCost = current
+	}
+
+	result := solver.Solve(problem, opts)
+	if result == nil {
+		return
+	}
+
+	if !valid || result.Best.Count < current {
+		t.OptimalKeys = result.Best.Keys
+		t.OptimalCount = result.Best.Count
+	}
+
+	t.AlternativeKeys = nil
+	for _, s := range append([]solver.Solution{result.Best}, result.Alternatives...) {
+		if s.Keys != t.OptimalKeys {
+			t.AlternativeKeys = append(t.AlternativeKeys, s.Keys)
+		}
+	}
+}
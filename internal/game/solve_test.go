@@ -0,0 +1,128 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/timlinux/macaco/internal/solver"
+	"github.com/timlinux/macaco/internal/vim"
+)
+
+func TestTaskOptimize(t *testing.T) {
+	tests := []struct {
+		name      string
+		task      Task
+		wantCount int
+		wantKeys  string // Empty to only check the count
+	}{
+		{
+			name:      "shorter solution replaces the hand-written one",
+			task:      Task{Category: CategoryDelete, Initial: "one two three", Desired: "one three", CursorStart: 4, OptimalKeys: "xxxx"},
+			wantCount: 2,
+		},
+		{
+			name:      "working hand-written solution of the same length is kept",
+			task:      Task{Category: CategoryDelete, Initial: "one two three", Desired: "one three", CursorStart: 4, OptimalKeys: "dw"},
+			wantCount: 2,
+			wantKeys:  "dw",
+		},
+		{
+			name:      "broken hand-written solution is replaced",
+			task:      Task{Category: CategoryDelete, Initial: "abc", Desired: "ab", CursorStart: 2, OptimalKeys: "dd"},
+			wantCount: 1,
+			wantKeys:  "x",
+		},
+		{
+			name:      "motion task",
+			task:      Task{Category: CategoryMotion, Initial: "one two three", Desired: "one two three", CursorEnd: 12, OptimalKeys: "llllllllllll"},
+			wantCount: 1,
+			wantKeys:  "$",
+		},
+	}
+	for _, tt := range tests {
+		task := tt.task
+		task.Optimize(solver.DefaultOptions())
+		if !task.Solved {
+			t.Errorf("%s: task not marked solved", tt.name)
+		}
+		if len(task.OptimalKeySequence()) != tt.wantCount {
+			t.Errorf("%s: optimal keys %q, want %d keys", tt.name, task.OptimalKeys, tt.wantCount)
+		}
+		if tt.wantKeys != "" && task.OptimalKeys != tt.wantKeys {
+			t.Errorf("%s: optimal keys %q, want %q", tt.name, task.OptimalKeys, tt.wantKeys)
+		}
+		if !solver.Verify(task.SolverProblem(), task.OptimalKeys) {
+			t.Errorf("%s: optimal keys %q don't solve the task", tt.name, task.OptimalKeys)
+		}
+		for _, alt := range task.AlternativeKeys {
+			if alt == task.OptimalKeys {
+				t.Errorf("%s: optimal keys %q listed as an alternative", tt.name, alt)
+			}
+		}
+	}
+}
+
+func TestSessionSolvesTasksWhenStarted(t *testing.T) {
+	tasks := []*Task{
+		{ID: "a", Category: CategoryDelete, Initial: "abc", Desired: "ab", CursorStart: 2, OptimalKeys: "lx"},
+		{ID: "b", Category: CategoryDelete, Initial: "one two", Desired: "two", OptimalKeys: "xxxx"},
+	}
+	session := NewSession("test", tasks)
+	if tasks[0].Solved || tasks[1].Solved {
+		t.Fatal("tasks solved before the session started them")
+	}
+
+	session.StartTask()
+	if !tasks[0].Solved || tasks[0].OptimalKeys != "x" {
+		t.Errorf("first task: solved %v with %q, want solved with \"x\"", tasks[0].Solved, tasks[0].OptimalKeys)
+	}
+	if tasks[1].Solved {
+		t.Error("second task solved before it was started")
+	}
+
+	// A task already solved keeps its keys
+	tasks[1].Solved = true
+	session.CurrentIndex = 1
+	session.StartTask()
+	if tasks[1].OptimalKeys != "xxxx" {
+		t.Errorf("solved task re-optimized to %q", tasks[1].OptimalKeys)
+	}
+}
+
+func TestSessionEfficiencyCountsEsc(t *testing.T) {
+	// The solver ends edits in normal mode, so the session mustn't accept a
+	// solution that stops in insert mode, or a longer one would score 100
+	tests := []struct {
+		name string
+		keys string
+		want float64
+	}{
+		{"optimal", "cwnew<Esc>", 100},
+		{"one key worse", "ciwnew<Esc>", 600.0 / 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := &Task{ID: "c", Category: CategoryChange, Initial: "hello old world", Desired: "hello new world",
+				CursorStart: 6, OptimalKeys: "cwnew<Esc>", OptimalCount: 6}
+			s := NewSession("test", []*Task{task})
+			s.StartTask()
+			if task.OptimalCount != 6 || len(task.OptimalKeySequence()) != 6 {
+				t.Fatalf("optimal keys %q, want 6 keys", task.OptimalKeys)
+			}
+
+			keys := vim.ParseKeys(tt.keys)
+			for _, key := range keys[:len(keys)-1] {
+				s.ProcessKey(key)
+			}
+			if s.CheckMatch() == MatchComplete {
+				t.Fatal("task complete in insert mode")
+			}
+			if s.ProcessKey(keys[len(keys)-1]) != MatchComplete {
+				t.Fatal("task not complete after Esc")
+			}
+			result := s.CompleteTask()
+			if diff := result.Efficiency - tt.want; diff < -1e-9 || diff > 1e-9 {
+				t.Errorf("efficiency %v, want %v", result.Efficiency, tt.want)
+			}
+		})
+	}
+}
@@ -1,8 +1,16 @@
 package game
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/timlinux/macaco/internal/solver"
+	"github.com/timlinux/macaco/internal/vim"
 )
 
 // TaskCategory represents the type of vim operation
@@ -30,25 +38,137 @@ const (
 
 // Task represents a vim training task
 type Task struct {
-	ID             string       `json:"id"`
-	Category       TaskCategory `json:"category"`
-	Difficulty     int          `json:"difficulty"`
-	Initial        string       `json:"initial"`
-	Desired        string       `json:"desired"`
-	CursorStart    int          `json:"cursor_start"`
-	CursorEnd      int          `json:"cursor_end,omitempty"`       // For motion tasks
-	HighlightStart int          `json:"highlight_start,omitempty"` // Start of text to modify (legacy)
-	HighlightEnd   int          `json:"highlight_end,omitempty"`   // End of text to modify (legacy)
-	OptimalKeys    string       `json:"optimal_keys"`
-	OptimalCount   int          `json:"optimal_count"`
-	Description    string       `json:"description"`
-	Hint           string       `json:"hint"`
-	Tags           []string     `json:"tags,omitempty"`
+	ID              string       `json:"id"`
+	Category        TaskCategory `json:"category"`
+	Difficulty      int          `json:"difficulty"`
+	DifficultyScore float64      `json:"difficulty_score,omitempty"` // How hard the task is, see CalibratedDifficulty
+	Initial         string       `json:"initial"`
+	Desired         string       `json:"desired"`
+	CursorStart     int          `json:"cursor_start"`
+	CursorEnd       int          `json:"cursor_end,omitempty"`       // For motion tasks
+	CursorStartPos  *Position    `json:"cursor_start_pos,omitempty"` // CursorStart as line and column
+	CursorEndPos    *Position    `json:"cursor_end_pos,omitempty"`   // CursorEnd as line and column
+	HighlightStart  int          `json:"highlight_start,omitempty"`  // Start of text to modify (legacy)
+	HighlightEnd    int          `json:"highlight_end,omitempty"`    // End of text to modify (legacy)
+	OptimalKeys     string       `json:"optimal_keys"`               // Vim key notation, e.g. "cwnew<Esc>"
+	OptimalCount    int          `json:"optimal_count"`
+	AlternativeKeys []string     `json:"alternative_keys,omitempty"` // Other short solutions
+	Description     string       `json:"description"`
+	Hint            string       `json:"hint"`
+	Tags            []string     `json:"tags,omitempty"`
+	TextWidth       int          `json:"textwidth,omitempty"` // For formatting tasks
+	Solved          bool         `json:"solved,omitempty"`    // OptimalKeys have been through the solver
+}
+
+// Position is a place in a task's text, by line and column from 0
+type Position struct {
+	Line int `json:"line"`
+	Col  int `json:"col"`
+}
+
+// String formats a position as editors show it, counting from 1
+func (p Position) String() string {
+	return fmt.Sprintf("line %d, column %d", p.Line+1, p.Col+1)
+}
+
+// TextPosition returns the line and column of a rune index of text
+func TextPosition(text string, index int) Position {
+	var p Position
+	for i, r := range []rune(text) {
+		if i == index {
+			break
+		}
+		if r == '\n' {
+			p.Line++
+			p.Col = 0
+		} else {
+			p.Col++
+		}
+	}
+	return p
+}
+
+// TextIndex returns the rune index of a line and column of text, or -1 if
+// text has no such position
+func TextIndex(text string, p Position) int {
+	lines := strings.Split(text, "\n")
+	if p.Line < 0 || p.Line >= len(lines) || p.Col < 0 || p.Col >= max(utf8.RuneCountInString(lines[p.Line]), 1) {
+		return -1
+	}
+	index := p.Col
+	for _, line := range lines[:p.Line] {
+		index += utf8.RuneCountInString(line) + 1 // +1 for newline
+	}
+	return index
+}
+
+// IsMultiline returns true if the task's text runs over several lines
+func (t *Task) IsMultiline() bool {
+	return strings.Contains(t.Initial, "\n") || strings.Contains(t.Desired, "\n")
+}
+
+// StartPosition returns the line and column the cursor starts at
+func (t *Task) StartPosition() Position {
+	return TextPosition(t.Initial, t.CursorStart)
+}
+
+// EndPosition returns the line and column a motion task's cursor has to
+// reach
+func (t *Task) EndPosition() Position {
+	return TextPosition(t.Initial, t.CursorEnd)
+}
+
+// setPositions fills in the line and column of the cursor positions of a
+// multi-line task from their indexes
+func (t *Task) setPositions() {
+	if !t.IsMultiline() {
+		return
+	}
+	start := t.StartPosition()
+	t.CursorStartPos = &start
+	if t.IsMotionTask() {
+		end := t.EndPosition()
+		t.CursorEndPos = &end
+	}
+}
+
+// resolvePositions sets the cursor indexes from the line and column given
+// in a task file, which take precedence. A position outside the text
+// gives an index of -1, which fails validation.
+func (t *Task) resolvePositions() {
+	if t.CursorStartPos != nil {
+		t.CursorStart = TextIndex(t.Initial, *t.CursorStartPos)
+	}
+	if t.CursorEndPos != nil {
+		t.CursorEnd = TextIndex(t.Initial, *t.CursorEndPos)
+	}
+}
+
+// OptimalKeySequence returns the optimal solution as individual keys
+func (t *Task) OptimalKeySequence() []vim.Key {
+	return vim.ParseKeys(t.OptimalKeys)
+}
+
+// Commands returns the names of the commands the optimal solution uses,
+// such as "dw" or "ci(", each once and in order
+func (t *Task) Commands() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, cmd := range vim.SplitCommands(t.newEngine(), t.OptimalKeySequence(), nil) {
+		name := vim.CommandName(cmd.Keys)
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
 }
 
-// IsMotionTask returns true if this is a motion-only task
+// IsMotionTask returns true if this is a motion-only task. A task whose
+// text doesn't change can only be about moving the cursor, including to
+// index 0.
 func (t *Task) IsMotionTask() bool {
-	return t.Initial == t.Desired && t.CursorEnd > 0
+	return t.Initial == t.Desired
 }
 
 // HasHighlight returns true if the task has text to highlight
@@ -215,20 +335,14 @@ func (t *Task) GetDesiredHighlights() []CharHighlight {
 
 // TaskDatabase holds all available tasks
 type TaskDatabase struct {
-	Version     string                    `json:"version"`
-	LastUpdated string                    `json:"last_updated"`
-	Rounds      map[string]RoundDef       `json:"rounds"`
-	Tasks       []Task                    `json:"tasks"`
-	tasksByID   map[string]*Task          // Lookup cache
-	tasksByCat  map[TaskCategory][]*Task  // Category lookup
-}
-
-// RoundDef defines a round type
-type RoundDef struct {
-	Name             string         `json:"name"`
-	DifficultyRange  [2]int         `json:"difficulty_range"`
-	TaskDistribution map[string]int `json:"task_distribution"`
-	TaskIDs          []string       `json:"tasks,omitempty"`
+	Version     string                   `json:"version"`
+	LastUpdated string                   `json:"last_updated"`
+	Rounds      map[string]RoundDef      `json:"rounds"`
+	Tasks       []Task                   `json:"tasks"`
+	tasksByID   map[string]*Task         // Lookup cache
+	tasksByCat  map[TaskCategory][]*Task // Category lookup
+	problems    []error                  // Tasks and rounds rejected by validation
+	generated   bool                     // Tasks are generated rather than curated
 }
 
 // LoadTaskDatabase loads tasks from a JSON file
@@ -239,14 +353,74 @@ func LoadTaskDatabase(path string) (*TaskDatabase, error) {
 	}
 
 	var db TaskDatabase
-	if err := json.Unmarshal(data, &db); err != nil {
+	if err := decodeJSON(path, data, &db, false); err != nil {
 		return nil, err
 	}
 
+	// Broken tasks are reported rather than shown to players
+	db.Tasks, db.problems = prepareTasks(db.Tasks)
+	if len(db.Tasks) == 0 {
+		return nil, fmt.Errorf("%s: no valid tasks", path)
+	}
+
 	db.buildLookups()
+	db.validateRounds()
+	if len(db.Rounds) == 0 {
+		db.buildRounds()
+	}
 	return &db, nil
 }
 
+// decodeJSON decodes the JSON file name, rejecting unknown fields if
+// strict, and says on which line a syntax or type error is
+func decodeJSON(name string, data []byte, v interface{}, strict bool) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if strict {
+		dec.DisallowUnknownFields()
+	}
+	err := dec.Decode(v)
+	if err == nil {
+		return nil
+	}
+
+	offset := int64(-1)
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	}
+	if offset >= 0 && offset <= int64(len(data)) {
+		line := 1 + bytes.Count(data[:offset], []byte("\n"))
+		return fmt.Errorf("%s:%d: %v", name, line, err)
+	}
+	return fmt.Errorf("%s: %v", name, err)
+}
+
+// prepareTasks stores solutions in canonical notation so they round-trip
+// exactly, and splits off the tasks that fail validation. Only tasks whose
+// hand-written keys solve them are given to the solver to look for shorter
+// ones, so a broken task is reported rather than quietly fixed.
+func prepareTasks(tasks []Task) ([]Task, []error) {
+	for i := range tasks {
+		task := &tasks[i]
+		task.resolvePositions()
+		task.OptimalKeys = vim.NormalizeKeys(task.OptimalKeys)
+		if task.OptimalCount == 0 {
+			task.OptimalCount = len(task.OptimalKeySequence())
+		}
+	}
+
+	valid, problems := validateTasks(tasks)
+	for i := range valid {
+		valid[i].Optimize(solver.DefaultOptions())
+		valid[i].Calibrate(Calibration{})
+	}
+	return valid, problems
+}
+
 // NewEmbeddedTaskDatabase creates a task database with built-in tasks
 // Deprecated: Use NewGeneratedTaskDatabase for procedurally generated tasks
 func NewEmbeddedTaskDatabase() *TaskDatabase {
@@ -280,12 +454,19 @@ func NewGeneratedTaskDatabase() *TaskDatabase {
 		LastUpdated: "2026-02-21",
 		Rounds:      make(map[string]RoundDef),
 		Tasks:       allTasks,
+		problems:    generator.Problems(),
+		generated:   true,
 	}
 	db.buildLookups()
 	db.buildRounds()
 	return db
 }
 
+// Problems returns the validation errors of tasks left out of the database
+func (db *TaskDatabase) Problems() []error {
+	return db.problems
+}
+
 // buildLookups builds internal lookup maps
 func (db *TaskDatabase) buildLookups() {
 	db.tasksByID = make(map[string]*Task)
@@ -300,68 +481,7 @@ func (db *TaskDatabase) buildLookups() {
 
 // buildRounds builds default round definitions
 func (db *TaskDatabase) buildRounds() {
-	db.Rounds = map[string]RoundDef{
-		"beginner": {
-			Name:            "Beginner Round",
-			DifficultyRange: [2]int{1, 1},
-			TaskDistribution: map[string]int{
-				"motion":  6,
-				"delete":  6,
-				"change":  6,
-				"insert":  6,
-				"visual":  3,
-				"complex": 3,
-			},
-		},
-		"intermediate": {
-			Name:            "Intermediate Round",
-			DifficultyRange: [2]int{1, 2},
-			TaskDistribution: map[string]int{
-				"motion":  6,
-				"delete":  6,
-				"change":  6,
-				"insert":  6,
-				"visual":  3,
-				"complex": 3,
-			},
-		},
-		"advanced": {
-			Name:            "Advanced Round",
-			DifficultyRange: [2]int{2, 3},
-			TaskDistribution: map[string]int{
-				"motion":  6,
-				"delete":  6,
-				"change":  6,
-				"insert":  6,
-				"visual":  3,
-				"complex": 3,
-			},
-		},
-		"expert": {
-			Name:            "Expert Round",
-			DifficultyRange: [2]int{3, 4},
-			TaskDistribution: map[string]int{
-				"motion":  6,
-				"delete":  6,
-				"change":  6,
-				"insert":  6,
-				"visual":  3,
-				"complex": 3,
-			},
-		},
-		"mixed": {
-			Name:            "Mixed Round",
-			DifficultyRange: [2]int{1, 4},
-			TaskDistribution: map[string]int{
-				"motion":  6,
-				"delete":  6,
-				"change":  6,
-				"insert":  6,
-				"visual":  3,
-				"complex": 3,
-			},
-		},
-	}
+	db.Rounds = DefaultRounds()
 }
 
 // GetTask returns a task by ID
@@ -374,42 +494,6 @@ func (db *TaskDatabase) GetTasksByCategory(cat TaskCategory) []*Task {
 	return db.tasksByCat[cat]
 }
 
-// GetTasksForRound returns tasks for a specific round type
-func (db *TaskDatabase) GetTasksForRound(roundType string) []*Task {
-	roundDef, ok := db.Rounds[roundType]
-	if !ok {
-		roundDef = db.Rounds["beginner"]
-	}
-
-	var tasks []*Task
-	minDiff, maxDiff := roundDef.DifficultyRange[0], roundDef.DifficultyRange[1]
-
-	categories := []TaskCategory{
-		CategoryMotion, CategoryDelete, CategoryChange,
-		CategoryInsert, CategoryVisual, CategoryComplex,
-	}
-
-	for _, cat := range categories {
-		count := roundDef.TaskDistribution[string(cat)]
-		catTasks := db.GetTasksByCategory(cat)
-
-		// Filter by difficulty
-		var eligible []*Task
-		for _, t := range catTasks {
-			if t.Difficulty >= minDiff && t.Difficulty <= maxDiff {
-				eligible = append(eligible, t)
-			}
-		}
-
-		// Take up to count tasks
-		for i := 0; i < count && i < len(eligible); i++ {
-			tasks = append(tasks, eligible[i])
-		}
-	}
-
-	return tasks
-}
-
 // getEmbeddedTasks returns the built-in task collection
 func getEmbeddedTasks() []Task {
 	return []Task{
@@ -579,7 +663,7 @@ func getEmbeddedTasks() []Task {
 		{
 			ID: "change-cw-001", Category: CategoryChange, Difficulty: 1,
 			Initial: "hello old world", Desired: "hello new world",
-			CursorStart: 6, OptimalKeys: "cwnew<ESC>", OptimalCount: 6,
+			CursorStart: 6, OptimalKeys: "cwnew<Esc>", OptimalCount: 6,
 			Description: "Change word to 'new'",
 			Hint:        "Use 'cw' to change word, type 'new', press ESC",
 			Tags:        []string{"change", "word"},
@@ -595,7 +679,7 @@ func getEmbeddedTasks() []Task {
 		{
 			ID: "change-s-001", Category: CategoryChange, Difficulty: 1,
 			Initial: "hello xorld", Desired: "hello world",
-			CursorStart: 6, OptimalKeys: "sw<ESC>", OptimalCount: 4,
+			CursorStart: 6, OptimalKeys: "sw<Esc>", OptimalCount: 4,
 			Description: "Substitute character",
 			Hint:        "Use 's' to delete char and enter insert mode",
 			Tags:        []string{"change", "substitute"},
@@ -603,7 +687,7 @@ func getEmbeddedTasks() []Task {
 		{
 			ID: "change-cc-001", Category: CategoryChange, Difficulty: 1,
 			Initial: "wrong line", Desired: "right line",
-			CursorStart: 0, OptimalKeys: "ccright line<ESC>", OptimalCount: 13,
+			CursorStart: 0, OptimalKeys: "ccright line<Esc>", OptimalCount: 13,
 			Description: "Change entire line",
 			Hint:        "Use 'cc' to change the entire line",
 			Tags:        []string{"change", "line"},
@@ -611,7 +695,7 @@ func getEmbeddedTasks() []Task {
 		{
 			ID: "change-C-001", Category: CategoryChange, Difficulty: 1,
 			Initial: "keep this wrong part", Desired: "keep this right part",
-			CursorStart: 10, OptimalKeys: "Cright part<ESC>", OptimalCount: 12,
+			CursorStart: 10, OptimalKeys: "Cright part<Esc>", OptimalCount: 12,
 			Description: "Change to end of line",
 			Hint:        "Use 'C' to change from cursor to end of line",
 			Tags:        []string{"change", "line"},
@@ -621,7 +705,7 @@ func getEmbeddedTasks() []Task {
 		{
 			ID: "change-ciw-001", Category: CategoryChange, Difficulty: 2,
 			Initial: "change inside word", Desired: "change outside word",
-			CursorStart: 10, OptimalKeys: "ciwoutside<ESC>", OptimalCount: 11,
+			CursorStart: 10, OptimalKeys: "ciwoutside<Esc>", OptimalCount: 11,
 			Description: "Change inner word",
 			Hint:        "Use 'ciw' to change word regardless of cursor position",
 			Tags:        []string{"change", "text-object"},
@@ -629,7 +713,7 @@ func getEmbeddedTasks() []Task {
 		{
 			ID: "change-ci-quote-001", Category: CategoryChange, Difficulty: 2,
 			Initial: `text = "old value"`, Desired: `text = "new value"`,
-			CursorStart: 10, OptimalKeys: `ci"new value<ESC>`, OptimalCount: 14,
+			CursorStart: 10, OptimalKeys: `ci"new value<Esc>`, OptimalCount: 14,
 			Description: "Change inside quotes",
 			Hint:        `Use 'ci"' to change text inside quotes`,
 			Tags:        []string{"change", "text-object"},
@@ -637,7 +721,7 @@ func getEmbeddedTasks() []Task {
 		{
 			ID: "change-ci-paren-001", Category: CategoryChange, Difficulty: 2,
 			Initial: "func(old, args)", Desired: "func(new, args)",
-			CursorStart: 6, OptimalKeys: "cwnew<ESC>", OptimalCount: 6,
+			CursorStart: 5, OptimalKeys: "cwnew<Esc>", OptimalCount: 6,
 			Description: "Change function argument",
 			Hint:        "Use 'cw' to change the word",
 			Tags:        []string{"change", "word"},
@@ -647,7 +731,7 @@ func getEmbeddedTasks() []Task {
 		{
 			ID: "insert-i-001", Category: CategoryInsert, Difficulty: 1,
 			Initial: "hello world", Desired: "hello beautiful world",
-			CursorStart: 6, OptimalKeys: "ibeautiful <ESC>", OptimalCount: 12,
+			CursorStart: 6, OptimalKeys: "ibeautiful <Esc>", OptimalCount: 12,
 			Description: "Insert text before cursor",
 			Hint:        "Use 'i' to insert before cursor",
 			Tags:        []string{"insert", "basic"},
@@ -655,7 +739,7 @@ func getEmbeddedTasks() []Task {
 		{
 			ID: "insert-a-001", Category: CategoryInsert, Difficulty: 1,
 			Initial: "hello world", Desired: "hello, world",
-			CursorStart: 4, OptimalKeys: "a,<ESC>", OptimalCount: 4,
+			CursorStart: 4, OptimalKeys: "a,<Esc>", OptimalCount: 4,
 			Description: "Append after cursor",
 			Hint:        "Use 'a' to append after cursor",
 			Tags:        []string{"insert", "basic"},
@@ -663,7 +747,7 @@ func getEmbeddedTasks() []Task {
 		{
 			ID: "insert-A-001", Category: CategoryInsert, Difficulty: 1,
 			Initial: "hello world", Desired: "hello world!",
-			CursorStart: 0, OptimalKeys: "A!<ESC>", OptimalCount: 4,
+			CursorStart: 0, OptimalKeys: "A!<Esc>", OptimalCount: 4,
 			Description: "Append at end of line",
 			Hint:        "Use 'A' to append at end of line",
 			Tags:        []string{"insert", "basic"},
@@ -671,7 +755,7 @@ func getEmbeddedTasks() []Task {
 		{
 			ID: "insert-I-001", Category: CategoryInsert, Difficulty: 1,
 			Initial: "world", Desired: "hello world",
-			CursorStart: 2, OptimalKeys: "Ihello <ESC>", OptimalCount: 9,
+			CursorStart: 2, OptimalKeys: "Ihello <Esc>", OptimalCount: 9,
 			Description: "Insert at start of line",
 			Hint:        "Use 'I' to insert at the beginning of line",
 			Tags:        []string{"insert", "basic"},
@@ -679,7 +763,7 @@ func getEmbeddedTasks() []Task {
 		{
 			ID: "insert-o-001", Category: CategoryInsert, Difficulty: 1,
 			Initial: "line one\nline three", Desired: "line one\nline two\nline three",
-			CursorStart: 0, OptimalKeys: "oline two<ESC>", OptimalCount: 11,
+			CursorStart: 0, OptimalKeys: "oline two<Esc>", OptimalCount: 11,
 			Description: "Open new line below",
 			Hint:        "Use 'o' to open new line below and enter insert mode",
 			Tags:        []string{"insert", "line"},
@@ -687,7 +771,7 @@ func getEmbeddedTasks() []Task {
 		{
 			ID: "insert-O-001", Category: CategoryInsert, Difficulty: 1,
 			Initial: "line two\nline three", Desired: "line one\nline two\nline three",
-			CursorStart: 0, OptimalKeys: "Oline one<ESC>", OptimalCount: 11,
+			CursorStart: 0, OptimalKeys: "Oline one<Esc>", OptimalCount: 11,
 			Description: "Open new line above",
 			Hint:        "Use 'O' to open new line above and enter insert mode",
 			Tags:        []string{"insert", "line"},
@@ -697,25 +781,25 @@ func getEmbeddedTasks() []Task {
 		{
 			ID: "visual-vwd-001", Category: CategoryVisual, Difficulty: 2,
 			Initial: "select extra word here", Desired: "select word here",
-			CursorStart: 7, OptimalKeys: "vewd", OptimalCount: 4,
+			CursorStart: 7, OptimalKeys: "vwhd", OptimalCount: 4,
 			Description: "Visual select and delete word",
-			Hint:        "Use 'v' to enter visual, 'e' to extend, 'd' to delete",
+			Hint:        "Use 'v' to enter visual, 'w' and 'h' to extend up to the next word, 'd' to delete",
 			Tags:        []string{"visual", "delete"},
 		},
 		{
 			ID: "visual-Vy-001", Category: CategoryVisual, Difficulty: 2,
 			Initial: "copy this line\npaste here", Desired: "copy this line\ncopy this line\npaste here",
-			CursorStart: 0, OptimalKeys: "Vyjp", OptimalCount: 4,
+			CursorStart: 0, OptimalKeys: "Vyp", OptimalCount: 3,
 			Description: "Visual line yank and paste",
-			Hint:        "Use 'V' for line visual, 'y' yank, 'j' down, 'p' paste",
+			Hint:        "Use 'V' for line visual, 'y' to yank, 'p' to paste below",
 			Tags:        []string{"visual", "yank", "paste"},
 		},
 		{
 			ID: "visual-viw-001", Category: CategoryVisual, Difficulty: 2,
 			Initial: "change this word now", Desired: "change that word now",
-			CursorStart: 9, OptimalKeys: "ciwcthat<ESC>", OptimalCount: 9,
+			CursorStart: 9, OptimalKeys: "viwcthat<Esc>", OptimalCount: 9,
 			Description: "Change inner word",
-			Hint:        "Use 'ciw' to change the word under cursor",
+			Hint:        "Use 'viw' to select the word under cursor, then 'c' to change it",
 			Tags:        []string{"visual", "change"},
 		},
 
@@ -730,8 +814,8 @@ func getEmbeddedTasks() []Task {
 		},
 		{
 			ID: "complex-cf-001", Category: CategoryComplex, Difficulty: 3,
-			Initial: "change until,comma here", Desired: "new text,comma here",
-			CursorStart: 0, OptimalKeys: "cf,new text<ESC>", OptimalCount: 12,
+			Initial: "change until,comma here", Desired: "new text comma here",
+			CursorStart: 0, OptimalKeys: "cf,new text <Esc>", OptimalCount: 13,
 			Description: "Change through character",
 			Hint:        "Use 'cf,' to change through comma",
 			Tags:        []string{"complex", "change", "find"},
@@ -749,14 +833,14 @@ func getEmbeddedTasks() []Task {
 		{
 			ID: "complex-multi-001", Category: CategoryComplex, Difficulty: 4,
 			Initial: "const oldName = 'value';", Desired: "const newName = 'value';",
-			CursorStart: 6, OptimalKeys: "cwnewName<ESC>", OptimalCount: 11,
+			CursorStart: 6, OptimalKeys: "cwnewName<Esc>", OptimalCount: 11,
 			Description: "Change variable name",
 			Hint:        "Navigate to word and use 'cw'",
 			Tags:        []string{"complex", "change"},
 		},
 		{
 			ID: "complex-swap-001", Category: CategoryComplex, Difficulty: 4,
-			Initial: "second first", Desired: "first second",
+			Initial: "second first end", Desired: "first second end",
 			CursorStart: 0, OptimalKeys: "dwwP", OptimalCount: 4,
 			Description: "Swap two words",
 			Hint:        "Delete word, move, paste before",
@@ -765,7 +849,7 @@ func getEmbeddedTasks() []Task {
 		{
 			ID: "complex-dup-001", Category: CategoryComplex, Difficulty: 4,
 			Initial: "duplicate", Desired: "duplicate duplicate",
-			CursorStart: 0, OptimalKeys: "yiwA <ESC>p", OptimalCount: 8,
+			CursorStart: 0, OptimalKeys: "yiwA <Esc>p", OptimalCount: 8,
 			Description: "Duplicate word at end",
 			Hint:        "Yank word, append space at end, paste",
 			Tags:        []string{"complex", "yank", "paste"},
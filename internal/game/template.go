@@ -0,0 +1,346 @@
+package game
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Template registry errors
+const (
+	ErrTemplateNoName       GameError = "template has no name"
+	ErrTemplateExists       GameError = "a template with that name is already registered"
+	ErrTemplateCategory     GameError = "template category is unknown"
+	ErrTemplateDifficulty   GameError = "template difficulty should be a range within 1 to 4"
+	ErrTemplateText         GameError = "template text should be prose, code or empty"
+	ErrNoTemplate           GameError = "no template for the category and difficulty"
+	ErrTemplateWrongTask    GameError = "task category or difficulty differs from the template's"
+	ErrTemplateNoCommands   GameError = "task uses none of the template's commands"
+	ErrTemplateNoGeneration GameError = "template gave up generating a task"
+)
+
+// TemplateInfo describes a task template
+type TemplateInfo struct {
+	Name          string       `json:"name"`
+	Category      TaskCategory `json:"category"`
+	MinDifficulty int          `json:"min_difficulty"` // Round difficulties the template serves
+	MaxDifficulty int          `json:"max_difficulty"`
+	Tags          []string     `json:"tags,omitempty"`
+	Commands      []string     `json:"commands"`       // Commands its tasks exercise, named as Task.Commands names them
+	Text          string       `json:"text,omitempty"` // TextProse or TextCode if the template needs that text
+	Weight        int          `json:"weight"`         // How often it's picked relative to other templates that fit, 1 if 0
+}
+
+// HasTag returns true if the template has the tag
+func (i TemplateInfo) HasTag(tag string) bool {
+	for _, t := range i.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// TaskTemplate is a family of generated tasks, such as deleting a word with
+// dw. Generate makes a task of the template's category at a difficulty
+// within its range from text of the corpus, or returns false when it
+// can't this time. Check is the template's self-check on a task it made,
+// run on the keys the template wrote, before the task is validated.
+// Optimising comes last, and for most tasks waits until a session starts
+// them.
+type TaskTemplate interface {
+	Info() TemplateInfo
+	Generate(rng *rand.Rand, corpus Corpus, difficulty int) (Task, bool)
+	Check(task Task) error
+}
+
+// TemplateError reports a template that failed to make a good task
+type TemplateError struct {
+	Template string
+	Err      error
+}
+
+func (e *TemplateError) Error() string {
+	return fmt.Sprintf("template %s: %v", e.Template, e.Err)
+}
+
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// NewTemplate creates a template from its metadata and a generate
+// function. Its self-check is CheckTemplateTask.
+func NewTemplate(info TemplateInfo, generate func(rng *rand.Rand, corpus Corpus, difficulty int) (Task, bool)) TaskTemplate {
+	return &funcTemplate{info: info, generate: generate}
+}
+
+// funcTemplate is a template made with NewTemplate
+type funcTemplate struct {
+	info     TemplateInfo
+	generate func(rng *rand.Rand, corpus Corpus, difficulty int) (Task, bool)
+}
+
+func (t *funcTemplate) Info() TemplateInfo {
+	return t.info
+}
+
+func (t *funcTemplate) Generate(rng *rand.Rand, corpus Corpus, difficulty int) (Task, bool) {
+	return t.generate(rng, corpus, difficulty)
+}
+
+func (t *funcTemplate) Check(task Task) error {
+	return CheckTemplateTask(t.info, task)
+}
+
+// CheckTemplateTask checks that a task is what its template says it makes:
+// the template's category, a difficulty no lower than its range, and keys
+// that use at least one of the template's commands. Generators then
+// validate the task with the same keys, before anything optimises it.
+func CheckTemplateTask(info TemplateInfo, task Task) error {
+	if task.Category != info.Category || task.Difficulty < info.MinDifficulty || task.Difficulty > 4 {
+		return ErrTemplateWrongTask
+	}
+	for _, name := range task.Commands() {
+		for _, cmd := range info.Commands {
+			if name == cmd {
+				return nil
+			}
+		}
+	}
+	return ErrTemplateNoCommands
+}
+
+// Corpus is the text templates generate tasks from: the sources of one
+// kind of text
+type Corpus struct {
+	Text    string       // TextProse, TextCode, TextLocal or TextBooks
+	Sources []TextSource // Sources of that text
+}
+
+// Sentence returns a random sentence or line of the corpus
+func (c Corpus) Sentence(rng *rand.Rand) string {
+	source := c.Sources[rng.Intn(len(c.Sources))]
+	return source.Sentences[rng.Intn(len(source.Sentences))]
+}
+
+// Lines returns n consecutive lines of one source and the source's
+// language, or false if no source tried has n ASCII lines in a row
+func (c Corpus) Lines(rng *rand.Rand, n int) ([]string, string, bool) {
+	for attempt := 0; attempt < codeLineAttempts; attempt++ {
+		source := c.Sources[rng.Intn(len(c.Sources))]
+		if len(source.Sentences) < n {
+			continue
+		}
+		start := rng.Intn(len(source.Sentences) - n + 1)
+		lines := source.Sentences[start : start+n]
+		if isASCII(strings.Join(lines, "")) {
+			return lines, source.Language, true
+		}
+	}
+	return nil, "", false
+}
+
+// CodeSources returns the sources of the corpus that are code
+func (c Corpus) CodeSources() []TextSource {
+	var sources []TextSource
+	for _, src := range c.Sources {
+		if src.Language != "" {
+			sources = append(sources, src)
+		}
+	}
+	return sources
+}
+
+// Suits returns true if the corpus has the text a template needs
+func (c Corpus) Suits(text string) bool {
+	switch text {
+	case TextProse:
+		return c.Text == TextProse || c.Text == TextBooks
+	case TextCode:
+		return len(c.CodeSources()) > 0
+	}
+	return true
+}
+
+// generator returns a task generator over the corpus, so the built-in
+// templates can share the generator's helpers
+func (c Corpus) generator(rng *rand.Rand) *TaskGenerator {
+	g := &TaskGenerator{text: c.Text, rng: rng}
+	switch c.Text {
+	case TextCode:
+		g.code = c.Sources
+	case TextLocal:
+		g.local = c.Sources
+	case TextBooks:
+		g.imported = c.Sources
+	default:
+		g.sources = c.Sources
+	}
+	return g
+}
+
+// TemplateRegistry holds the templates generators pick from
+type TemplateRegistry struct {
+	mu        sync.RWMutex
+	templates []TaskTemplate
+}
+
+// NewTemplateRegistry creates an empty registry
+func NewTemplateRegistry() *TemplateRegistry {
+	return &TemplateRegistry{}
+}
+
+// defaultTemplates holds the built-in templates and those registered with
+// RegisterTemplate
+var defaultTemplates = newBuiltinRegistry()
+
+// seededTemplates holds only the built-in templates, for seeded generators.
+// Templates registered by other code would change the tasks a seed gives,
+// so daily challenges and round codes would differ between builds.
+var seededTemplates = newBuiltinRegistry()
+
+// DefaultTemplates returns the registry generators use unless given another
+func DefaultTemplates() *TemplateRegistry {
+	return defaultTemplates
+}
+
+// RegisterTemplate adds a template to the default registry, so every
+// generator can pick it except the seeded ones behind daily challenges and
+// round codes. Call it before generating, typically from init.
+func RegisterTemplate(t TaskTemplate) error {
+	return defaultTemplates.Register(t)
+}
+
+// newBuiltinRegistry creates a registry of the built-in templates
+func newBuiltinRegistry() *TemplateRegistry {
+	r := NewTemplateRegistry()
+	for _, t := range builtinTemplates() {
+		if err := r.Register(t); err != nil {
+			panic(err) // A broken built-in template is a programming error
+		}
+	}
+	return r
+}
+
+// Register adds a template, checking its metadata
+func (r *TemplateRegistry) Register(t TaskTemplate) error {
+	info := t.Info()
+	var err error
+	switch {
+	case strings.TrimSpace(info.Name) == "":
+		err = ErrTemplateNoName
+	case !isRoundCategory(info.Category):
+		err = ErrTemplateCategory
+	case info.MinDifficulty < 1 || info.MaxDifficulty > 4 || info.MinDifficulty > info.MaxDifficulty:
+		err = ErrTemplateDifficulty
+	case info.Text != "" && info.Text != TextProse && info.Text != TextCode:
+		err = ErrTemplateText
+	}
+	if err != nil {
+		return &TemplateError{Template: info.Name, Err: err}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.templates {
+		if existing.Info().Name == info.Name {
+			return &TemplateError{Template: info.Name, Err: ErrTemplateExists}
+		}
+	}
+	r.templates = append(r.templates, t)
+	return nil
+}
+
+// Templates returns the templates in the order they were registered
+func (r *TemplateRegistry) Templates() []TaskTemplate {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]TaskTemplate{}, r.templates...)
+}
+
+// Template returns the template with a name, or nil
+func (r *TemplateRegistry) Template(name string) TaskTemplate {
+	for _, t := range r.Templates() {
+		if t.Info().Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// WithTag returns the templates that have a tag
+func (r *TemplateRegistry) WithTag(tag string) []TaskTemplate {
+	var found []TaskTemplate
+	for _, t := range r.Templates() {
+		if t.Info().HasTag(tag) {
+			found = append(found, t)
+		}
+	}
+	return found
+}
+
+// Tags returns the tags of all templates, sorted
+func (r *TemplateRegistry) Tags() []string {
+	seen := make(map[string]bool)
+	var tags []string
+	for _, t := range r.Templates() {
+		for _, tag := range t.Info().Tags {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// Commands returns the commands all templates practise, sorted
+func (r *TemplateRegistry) Commands() []string {
+	seen := make(map[string]bool)
+	var commands []string
+	for _, t := range r.Templates() {
+		for _, cmd := range t.Info().Commands {
+			if !seen[cmd] {
+				seen[cmd] = true
+				commands = append(commands, cmd)
+			}
+		}
+	}
+	sort.Strings(commands)
+	return commands
+}
+
+// Fitting returns the templates for a category and difficulty that the
+// corpus has the text for
+func (r *TemplateRegistry) Fitting(cat TaskCategory, diff int, corpus Corpus) []TaskTemplate {
+	var found []TaskTemplate
+	for _, t := range r.Templates() {
+		info := t.Info()
+		if info.Category == cat && diff >= info.MinDifficulty && diff <= info.MaxDifficulty && corpus.Suits(info.Text) {
+			found = append(found, t)
+		}
+	}
+	return found
+}
+
+// pickTemplate picks one of templates in proportion to their weights
+func pickTemplate(rng *rand.Rand, templates []TaskTemplate) TaskTemplate {
+	weight := func(t TaskTemplate) int {
+		return max(t.Info().Weight, 1)
+	}
+	total := 0
+	for _, t := range templates {
+		total += weight(t)
+	}
+	pick := rng.Intn(total)
+	for _, t := range templates {
+		if pick < weight(t) {
+			return t
+		}
+		pick -= weight(t)
+	}
+	return templates[len(templates)-1]
+}
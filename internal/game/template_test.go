@@ -0,0 +1,194 @@
+package game
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// wordTemplate is a custom template of motion tasks moving a word forward
+func wordTemplate(name string) TaskTemplate {
+	info := TemplateInfo{
+		Name: name, Category: CategoryMotion, MinDifficulty: 1, MaxDifficulty: 4,
+		Tags: []string{"custom", "word"}, Commands: []string{"w"},
+	}
+	return NewTemplate(info, func(rng *rand.Rand, corpus Corpus, difficulty int) (Task, bool) {
+		return Task{
+			ID: name, Category: CategoryMotion, Difficulty: difficulty,
+			Initial: "one two", Desired: "one two", CursorEnd: 4, OptimalKeys: "w",
+		}, true
+	})
+}
+
+func TestTemplateRegistryRegister(t *testing.T) {
+	valid := TemplateInfo{Name: "custom", Category: CategoryDelete, MinDifficulty: 1, MaxDifficulty: 2}
+	tests := []struct {
+		name   string
+		modify func(*TemplateInfo)
+		want   error
+	}{
+		{"valid", func(*TemplateInfo) {}, nil},
+		{"code", func(i *TemplateInfo) { i.Text = TextCode }, nil},
+		{"no name", func(i *TemplateInfo) { i.Name = " " }, ErrTemplateNoName},
+		{"unknown category", func(i *TemplateInfo) { i.Category = "teleport" }, ErrTemplateCategory},
+		{"difficulty 0", func(i *TemplateInfo) { i.MinDifficulty = 0 }, ErrTemplateDifficulty},
+		{"difficulty 5", func(i *TemplateInfo) { i.MaxDifficulty = 5 }, ErrTemplateDifficulty},
+		{"difficulty reversed", func(i *TemplateInfo) { i.MinDifficulty = 3 }, ErrTemplateDifficulty},
+		{"local text", func(i *TemplateInfo) { i.Text = TextLocal }, ErrTemplateText},
+	}
+	for _, tt := range tests {
+		info := valid
+		tt.modify(&info)
+		err := NewTemplateRegistry().Register(NewTemplate(info, nil))
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: Register() = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	r := NewTemplateRegistry()
+	r.Register(wordTemplate("custom"))
+	if err := r.Register(wordTemplate("custom")); !errors.Is(err, ErrTemplateExists) {
+		t.Errorf("registering twice = %v, want %v", err, ErrTemplateExists)
+	}
+}
+
+func TestTemplateRegistryLookups(t *testing.T) {
+	r := NewTemplateRegistry()
+	r.Register(wordTemplate("first"))
+	r.Register(wordTemplate("second"))
+	code := TemplateInfo{Name: "code", Category: CategoryMotion, MinDifficulty: 3, MaxDifficulty: 4,
+		Tags: []string{"brackets"}, Commands: []string{"%"}, Text: TextCode}
+	r.Register(NewTemplate(code, nil))
+
+	names := func(templates []TaskTemplate) []string {
+		var names []string
+		for _, t := range templates {
+			names = append(names, t.Info().Name)
+		}
+		return names
+	}
+	prose := Corpus{Text: TextProse, Sources: GetPublicDomainSources()}
+	codeCorpus := Corpus{Text: TextCode, Sources: GetCodeSources()}
+
+	tests := []struct {
+		name string
+		got  []string
+		want []string
+	}{
+		{"Templates", names(r.Templates()), []string{"first", "second", "code"}},
+		{"WithTag", names(r.WithTag("brackets")), []string{"code"}},
+		{"Tags", r.Tags(), []string{"brackets", "custom", "word"}},
+		{"Commands", r.Commands(), []string{"%", "w"}},
+		{"Fitting prose", names(r.Fitting(CategoryMotion, 3, prose)), []string{"first", "second"}},
+		{"Fitting code", names(r.Fitting(CategoryMotion, 3, codeCorpus)), []string{"first", "second", "code"}},
+		{"Fitting level", names(r.Fitting(CategoryMotion, 1, codeCorpus)), []string{"first", "second"}},
+		{"Fitting category", names(r.Fitting(CategoryDelete, 1, prose)), nil},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s = %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+	if r.Template("second") == nil || r.Template("third") != nil {
+		t.Error("Template() finds the wrong templates")
+	}
+}
+
+func TestCheckTemplateTask(t *testing.T) {
+	info := TemplateInfo{Name: "dw", Category: CategoryDelete, MinDifficulty: 2, MaxDifficulty: 2, Commands: []string{"dw"}}
+	tests := []struct {
+		name   string
+		modify func(*Task)
+		want   error
+	}{
+		{"fits", func(*Task) {}, nil},
+		{"harder", func(t *Task) { t.Difficulty = 4 }, nil},
+		{"easier", func(t *Task) { t.Difficulty = 1 }, ErrTemplateWrongTask},
+		{"category", func(t *Task) { t.Category = CategoryChange }, ErrTemplateWrongTask},
+		{"other keys", func(t *Task) { t.OptimalKeys = "de" }, ErrTemplateNoCommands},
+	}
+	for _, tt := range tests {
+		task := validTask()
+		task.Difficulty = 2
+		tt.modify(&task)
+		if err := CheckTemplateTask(info, task); !errors.Is(err, tt.want) {
+			t.Errorf("%s: CheckTemplateTask() = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestPickTemplate(t *testing.T) {
+	light := wordTemplate("light")
+	heavyInfo := light.Info()
+	heavyInfo.Name, heavyInfo.Weight = "heavy", 3
+	heavy := NewTemplate(heavyInfo, nil)
+
+	rng := rand.New(rand.NewSource(1))
+	counts := make(map[string]int)
+	for i := 0; i < 4000; i++ {
+		counts[pickTemplate(rng, []TaskTemplate{light, heavy}).Info().Name]++
+	}
+	if counts["heavy"] < 2700 || counts["heavy"] > 3300 {
+		t.Errorf("picked %v, want heavy about three times as often", counts)
+	}
+}
+
+func TestProseTemplates(t *testing.T) {
+	corpus := Corpus{Text: TextProse, Sources: GetPublicDomainSources()}
+	for _, template := range proseTemplates() {
+		info := template.Info()
+		g := NewSeededTaskGenerator(1)
+		for diff := info.MinDifficulty; diff <= info.MaxDifficulty; diff++ {
+			made := 0
+			for i := 0; i < 10; i++ {
+				if _, err := g.generateFrom(template, corpus, diff); err == nil {
+					made++
+				} else if !errors.Is(err, ErrTemplateNoGeneration) {
+					t.Errorf("%s at difficulty %d: %v", info.Name, diff, err)
+				}
+			}
+			if made == 0 {
+				t.Errorf("%s made no tasks at difficulty %d", info.Name, diff)
+			}
+		}
+	}
+}
+
+func TestGeneratorTemplates(t *testing.T) {
+	if NewTaskGenerator().Templates() != DefaultTemplates() {
+		t.Error("generators don't use the default templates")
+	}
+	seeded := NewSeededTaskGenerator(1).Templates()
+	if seeded == DefaultTemplates() {
+		t.Error("seeded generators use the default templates, which other code can add to")
+	}
+	builtin := len(builtinTemplates())
+	if got := len(seeded.Templates()); got != builtin {
+		t.Errorf("seeded generators have %d templates, want the %d built-in ones", got, builtin)
+	}
+
+	r := NewTemplateRegistry()
+	r.Register(wordTemplate("custom"))
+	g := NewSeededTaskGenerator(1)
+	g.SetTemplates(r)
+	tasks := g.GenerateRound(map[TaskCategory]int{CategoryMotion: 3}, 1, 4)
+	if len(tasks) != 3 {
+		t.Fatalf("generated %d tasks, want 3", len(tasks))
+	}
+	for _, task := range tasks {
+		if task.ID != "custom" {
+			t.Errorf("task %s isn't from the custom template", task.ID)
+		}
+	}
+	if tasks := g.GenerateRound(map[TaskCategory]int{CategoryDelete: 1}, 1, 4); len(tasks) != 0 {
+		t.Errorf("generated %v without a delete template", tasks)
+	}
+	var noTemplate bool
+	for _, problem := range g.Problems() {
+		noTemplate = noTemplate || errors.Is(problem, ErrNoTemplate)
+	}
+	if !noTemplate {
+		t.Errorf("problems %v, want a missing template", g.Problems())
+	}
+}
@@ -0,0 +1,120 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Limits on the time policy of a round
+const (
+	maxTaskTimeLimit   = 10 * time.Minute
+	maxRoundTimeLimit  = 2 * time.Hour
+	maxOvertimePenalty = 100
+)
+
+// ErrBadTimeLimit is returned for rounds with time limits out of range
+const ErrBadTimeLimit GameError = "time limits should be within 0 to 600 seconds a task, 7200 a round, and a penalty of 0 to 100"
+
+// TimePolicy is the time pressure of a round. The zero value has none. In
+// JSON the limits are milliseconds, see timePolicyJSON.
+type TimePolicy struct {
+	TaskLimit  time.Duration // Time for each task, after which it is skipped; 0 for none
+	RoundLimit time.Duration // Time for the whole round; 0 for none
+
+	// OvertimePenalty is the efficiency points a task loses for every second
+	// it is played past RoundLimit. Without a penalty the round ends when
+	// the round clock runs out.
+	OvertimePenalty int
+}
+
+// timePolicyJSON is how a TimePolicy is saved, with its limits in
+// milliseconds like the other times in saved sessions
+type timePolicyJSON struct {
+	TaskLimitMs     int64 `json:"task_limit_ms"`
+	RoundLimitMs    int64 `json:"round_limit_ms"`
+	OvertimePenalty int   `json:"overtime_penalty"`
+}
+
+// MarshalJSON saves the policy with its limits in milliseconds
+func (p TimePolicy) MarshalJSON() ([]byte, error) {
+	return json.Marshal(timePolicyJSON{
+		TaskLimitMs:     p.TaskLimit.Milliseconds(),
+		RoundLimitMs:    p.RoundLimit.Milliseconds(),
+		OvertimePenalty: p.OvertimePenalty,
+	})
+}
+
+// UnmarshalJSON reads a policy saved by MarshalJSON
+func (p *TimePolicy) UnmarshalJSON(data []byte) error {
+	var saved timePolicyJSON
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return err
+	}
+	*p = TimePolicy{
+		TaskLimit:       time.Duration(saved.TaskLimitMs) * time.Millisecond,
+		RoundLimit:      time.Duration(saved.RoundLimitMs) * time.Millisecond,
+		OvertimePenalty: saved.OvertimePenalty,
+	}
+	return nil
+}
+
+// RoundTimePolicy returns the built-in time policy of a round type: none
+// for beginners, a per-task limit from intermediate up, a round clock for
+// advanced rounds and overtime penalties for experts
+func RoundTimePolicy(roundType string) TimePolicy {
+	switch roundType {
+	case "intermediate":
+		return TimePolicy{TaskLimit: 60 * time.Second}
+	case "advanced":
+		return TimePolicy{TaskLimit: 30 * time.Second, RoundLimit: 10 * time.Minute}
+	case "expert":
+		return TimePolicy{TaskLimit: 30 * time.Second, RoundLimit: 8 * time.Minute, OvertimePenalty: 2}
+	}
+	return TimePolicy{}
+}
+
+// IsTimed returns true if the policy limits the time of tasks or the round
+func (p TimePolicy) IsTimed() bool {
+	return p.TaskLimit > 0 || p.RoundLimit > 0
+}
+
+// validate checks the policy is within the limits rounds may ask for
+func (p TimePolicy) validate() error {
+	if p.TaskLimit < 0 || p.TaskLimit > maxTaskTimeLimit ||
+		p.RoundLimit < 0 || p.RoundLimit > maxRoundTimeLimit ||
+		p.OvertimePenalty < 0 || p.OvertimePenalty > maxOvertimePenalty {
+		return ErrBadTimeLimit
+	}
+	return nil
+}
+
+// String describes the policy for menus, such as "30s a task, 8m round,
+// -2/s overtime"
+func (p TimePolicy) String() string {
+	if !p.IsTimed() {
+		return "untimed"
+	}
+	s := ""
+	if p.TaskLimit > 0 {
+		s = shortDuration(p.TaskLimit) + " a task"
+	}
+	if p.RoundLimit > 0 {
+		if s != "" {
+			s += ", "
+		}
+		s += shortDuration(p.RoundLimit) + " round"
+		if p.OvertimePenalty > 0 {
+			s += fmt.Sprintf(", -%d/s overtime", p.OvertimePenalty)
+		}
+	}
+	return s
+}
+
+// shortDuration formats whole minutes as "8m" and anything else in seconds
+func shortDuration(d time.Duration) string {
+	if d%time.Minute == 0 {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%ds", int(d.Seconds()))
+}
@@ -0,0 +1,179 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/timlinux/macaco/internal/vim"
+)
+
+func TestTimePolicyJSON(t *testing.T) {
+	policy := TimePolicy{TaskLimit: 30 * time.Second, RoundLimit: 8 * time.Minute, OvertimePenalty: 2}
+	data, err := json.Marshal(policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"task_limit_ms":30000,"round_limit_ms":480000,"overtime_penalty":2}`; string(data) != want {
+		t.Errorf("Marshal() = %s, want %s", data, want)
+	}
+
+	var got TimePolicy
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got != policy {
+		t.Errorf("Unmarshal() = %+v, want %+v", got, policy)
+	}
+	if err := json.Unmarshal([]byte(`{"task_limit_ms":"30"}`), &got); err == nil {
+		t.Error("Unmarshal() accepted a string limit")
+	}
+}
+
+func TestTimePolicyValidate(t *testing.T) {
+	tests := []struct {
+		policy TimePolicy
+		want   error
+	}{
+		{TimePolicy{}, nil},
+		{RoundTimePolicy("expert"), nil},
+		{TimePolicy{TaskLimit: 10 * time.Minute, RoundLimit: 2 * time.Hour, OvertimePenalty: 100}, nil},
+		{TimePolicy{TaskLimit: -time.Second}, ErrBadTimeLimit},
+		{TimePolicy{TaskLimit: 11 * time.Minute}, ErrBadTimeLimit},
+		{TimePolicy{RoundLimit: 3 * time.Hour}, ErrBadTimeLimit},
+		{TimePolicy{OvertimePenalty: 101}, ErrBadTimeLimit},
+		{TimePolicy{OvertimePenalty: -1}, ErrBadTimeLimit},
+	}
+	for _, tt := range tests {
+		if err := tt.policy.validate(); !errors.Is(err, tt.want) {
+			t.Errorf("validate(%+v) = %v, want %v", tt.policy, err, tt.want)
+		}
+	}
+}
+
+func TestTimePolicyString(t *testing.T) {
+	tests := []struct {
+		policy TimePolicy
+		want   string
+	}{
+		{RoundTimePolicy("beginner"), "untimed"},
+		{RoundTimePolicy("intermediate"), "1m a task"},
+		{RoundTimePolicy("advanced"), "30s a task, 10m round"},
+		{RoundTimePolicy("expert"), "30s a task, 8m round, -2/s overtime"},
+		{TimePolicy{RoundLimit: 90 * time.Second}, "90s round"},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.String(); got != tt.want {
+			t.Errorf("String(%+v) = %q, want %q", tt.policy, got, tt.want)
+		}
+	}
+}
+
+// timedSession returns a started session of delete tasks with a policy
+func timedSession(policy TimePolicy) *Session {
+	var tasks []*Task
+	for _, id := range []string{"a", "b"} {
+		task := validTask()
+		task.ID = id
+		task.OptimalCount = 2
+		tasks = append(tasks, &task)
+	}
+	s := NewSession("test", tasks)
+	s.Time = policy
+	s.StartTask()
+	return s
+}
+
+func TestSessionTaskLimit(t *testing.T) {
+	s := timedSession(TimePolicy{TaskLimit: 30 * time.Second})
+	if s.CheckTime() {
+		t.Fatal("timed out a task just started")
+	}
+	if left, ok := s.TaskTimeRemaining(); !ok || left <= 0 || left > 30*time.Second {
+		t.Errorf("TaskTimeRemaining() = %v, %v", left, ok)
+	}
+
+	skips := s.SkipsRemaining
+	s.taskStart = time.Now().Add(-31 * time.Second)
+	if s.ProcessKey("d") != MatchNone {
+		t.Error("a key after the task's time was played")
+	}
+	if len(s.TaskResults) != 1 {
+		t.Fatalf("%d results, want the timed out task", len(s.TaskResults))
+	}
+	result := s.TaskResults[0]
+	if !result.TimedOut || result.Success || result.TimeMs != 30000 || result.Keystrokes != 0 {
+		t.Errorf("result %+v, want a timed out task of 30s without keys", result)
+	}
+	if s.SkipsRemaining != skips || s.CurrentIndex != 1 || s.IsComplete() {
+		t.Errorf("timing out used a skip or ended the round")
+	}
+}
+
+func TestSessionRoundLimit(t *testing.T) {
+	s := timedSession(TimePolicy{RoundLimit: time.Minute})
+	s.StartedAt = time.Now().Add(-2 * time.Minute)
+	if left, ok := s.RoundTimeRemaining(); !ok || left != 0 {
+		t.Errorf("RoundTimeRemaining() = %v, %v, want 0", left, ok)
+	}
+	if !s.CheckTime() {
+		t.Fatal("the round clock didn't end the round")
+	}
+	if !s.IsComplete() || len(s.TaskResults) != 1 || !s.TaskResults[0].TimedOut {
+		t.Errorf("complete %v with results %+v, want one timed out task", s.IsComplete(), s.TaskResults)
+	}
+}
+
+func TestSessionOvertimePenalty(t *testing.T) {
+	s := timedSession(TimePolicy{RoundLimit: time.Minute, OvertimePenalty: 2})
+	s.StartedAt = time.Now().Add(-70 * time.Second)
+	s.taskStart = time.Now().Add(-20 * time.Second)
+	if s.CheckTime() {
+		t.Fatal("a round with an overtime penalty ended on its clock")
+	}
+	for _, key := range vim.ParseKeys("dw") {
+		s.ProcessKey(key)
+	}
+	result := s.CompleteTask()
+
+	// 10s over at 2 points a second, from 100% efficiency
+	if result.OvertimeMs < 10000 || result.OvertimeMs > 11000 || result.Penalty < 20 || result.Penalty > 22 {
+		t.Errorf("overtime %dms with penalty %v, want about 10s and 20", result.OvertimeMs, result.Penalty)
+	}
+	if result.Efficiency != 100-result.Penalty {
+		t.Errorf("efficiency %v, want %v", result.Efficiency, 100-result.Penalty)
+	}
+
+	// Overtime is counted from the task's start at most, and the penalty
+	// never takes efficiency below 0
+	s.Time.OvertimePenalty = 100
+	s.StartedAt = time.Now().Add(-10 * time.Minute)
+	s.taskStart = time.Now().Add(-5 * time.Second)
+	for _, key := range vim.ParseKeys("xxxx") {
+		s.ProcessKey(key)
+	}
+	result = s.CompleteTask()
+	if result.OvertimeMs > 6000 || result.Efficiency != 0 || result.Penalty != 50 {
+		t.Errorf("overtime %dms, penalty %v, efficiency %v; want at most 6s, 50 and 0",
+			result.OvertimeMs, result.Penalty, result.Efficiency)
+	}
+}
+
+func TestSessionUntimed(t *testing.T) {
+	s := timedSession(TimePolicy{})
+	s.taskStart = time.Now().Add(-time.Hour)
+	s.StartedAt = s.taskStart
+	if s.CheckTime() {
+		t.Error("an untimed round timed out")
+	}
+	if _, ok := s.TaskTimeRemaining(); ok {
+		t.Error("an untimed task has time remaining")
+	}
+	if _, ok := s.RoundTimeRemaining(); ok {
+		t.Error("an untimed round has time remaining")
+	}
+	if s.Overtime() != 0 {
+		t.Errorf("Overtime() = %v", s.Overtime())
+	}
+}
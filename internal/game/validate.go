@@ -0,0 +1,128 @@
+package game
+
+import (
+	"fmt"
+
+	"github.com/timlinux/macaco/internal/vim"
+)
+
+// Task validation errors
+const (
+	ErrMissingTaskID         GameError = "id is empty"
+	ErrUnknownTaskCategory   GameError = "category is unknown"
+	ErrDifficultyOutOfRange  GameError = "difficulty should be within 1 to 4"
+	ErrCursorStartOutOfRange GameError = "cursor_start is outside the initial text"
+	ErrCursorEndOutOfRange   GameError = "cursor_end is outside the initial text"
+	ErrTaskAlreadySolved     GameError = "task is solved before any key is pressed"
+	ErrNoOptimalKeys         GameError = "optimal_keys is empty"
+	ErrOptimalKeysWrong      GameError = "optimal_keys does not solve the task"
+)
+
+// maxGenerateAttempts is how often a generator retries a task that fails
+// validation before giving up on it
+const maxGenerateAttempts = 5
+
+// TaskError reports a task that failed validation
+type TaskError struct {
+	TaskID string
+	Err    error
+}
+
+func (e *TaskError) Error() string {
+	return fmt.Sprintf("task %s: %v", e.TaskID, e.Err)
+}
+
+func (e *TaskError) Unwrap() error {
+	return e.Err
+}
+
+// Validate checks that the task can be played: it has an ID, a known
+// category and difficulty, the cursor positions lie inside the text, the
+// task isn't already solved, and replaying OptimalKeys through a fresh
+// engine solves it
+func (t *Task) Validate() error {
+	if err := t.validate(); err != nil {
+		return &TaskError{TaskID: t.ID, Err: err}
+	}
+	return nil
+}
+
+func (t *Task) validate() error {
+	switch {
+	case t.ID == "":
+		return ErrMissingTaskID
+	case !isRoundCategory(t.Category):
+		return ErrUnknownTaskCategory
+	case t.Difficulty < 1 || t.Difficulty > 4:
+		return ErrDifficultyOutOfRange
+	}
+
+	engine := t.newEngine()
+	if engine.CursorIndex() != t.CursorStart {
+		return ErrCursorStartOutOfRange
+	}
+
+	if t.IsMotionTask() {
+		probe := vim.NewEngine(t.Initial)
+		probe.SetCursorIndex(t.CursorEnd)
+		if probe.CursorIndex() != t.CursorEnd {
+			return ErrCursorEndOutOfRange
+		}
+	}
+
+	if t.IsSolvedBy(engine) {
+		return ErrTaskAlreadySolved
+	}
+
+	keys := t.OptimalKeySequence()
+	if len(keys) == 0 {
+		return ErrNoOptimalKeys
+	}
+
+	// Replay the keys as a session would, stopping at the first match
+	for _, key := range keys {
+		engine.ProcessKey(key)
+		if t.IsSolvedBy(engine) {
+			return nil
+		}
+	}
+	return ErrOptimalKeysWrong
+}
+
+// newEngine creates an engine in the task's starting state
+func (t *Task) newEngine() *vim.Engine {
+	engine := vim.NewEngine(t.Initial)
+	if t.TextWidth > 0 {
+		engine.SetOptions(vim.Options{TextWidth: t.TextWidth})
+	}
+	engine.SetCursorIndex(t.CursorStart)
+	return engine
+}
+
+// IsSolvedBy returns true if the engine's text and cursor complete the
+// task. Edits must finish back in normal mode, as in the solver, so the
+// Esc that ends an insert is part of every solution.
+func (t *Task) IsSolvedBy(e *vim.Engine) bool {
+	if e.Mode() != vim.ModeNormal {
+		return false
+	}
+	if t.IsMotionTask() {
+		return e.CursorIndex() == t.CursorEnd
+	}
+	return e.Text() == t.Desired
+}
+
+// validateTasks splits tasks into those that pass validation and the
+// errors for those that don't
+func validateTasks(tasks []Task) ([]Task, []error) {
+	var valid []Task
+	var problems []error
+	for _, task := range tasks {
+		if err := task.Validate(); err != nil {
+			problems = append(problems, err)
+			continue
+		}
+		valid = append(valid, task)
+	}
+	return valid, problems
+}
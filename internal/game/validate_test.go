@@ -0,0 +1,115 @@
+package game
+
+import (
+	"errors"
+	"testing"
+)
+
+// validTask returns a task that passes validation, for tests to break
+func validTask() Task {
+	return Task{
+		ID:          "t1",
+		Category:    CategoryDelete,
+		Difficulty:  1,
+		Initial:     "one two three",
+		Desired:     "one three",
+		CursorStart: 4,
+		OptimalKeys: "dw",
+	}
+}
+
+func TestTaskValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Task)
+		want   error
+	}{
+		{"valid", func(*Task) {}, nil},
+		{"missing id", func(t *Task) { t.ID = "" }, ErrMissingTaskID},
+		{"unknown category", func(t *Task) { t.Category = "teleport" }, ErrUnknownTaskCategory},
+		{"difficulty too low", func(t *Task) { t.Difficulty = 0 }, ErrDifficultyOutOfRange},
+		{"difficulty too high", func(t *Task) { t.Difficulty = 5 }, ErrDifficultyOutOfRange},
+		{"cursor start outside text", func(t *Task) { t.CursorStart = 40 }, ErrCursorStartOutOfRange},
+		{"cursor end outside text", func(t *Task) {
+			t.Category = CategoryMotion
+			t.Desired = t.Initial
+			t.CursorEnd = 40
+			t.OptimalKeys = "$"
+		}, ErrCursorEndOutOfRange},
+		{"already at the target", func(t *Task) {
+			t.Category = CategoryMotion
+			t.Desired = t.Initial
+			t.CursorEnd = 4
+			t.OptimalKeys = "w"
+		}, ErrTaskAlreadySolved},
+		{"no keys", func(t *Task) { t.OptimalKeys = "" }, ErrNoOptimalKeys},
+		{"wrong keys", func(t *Task) { t.OptimalKeys = "x" }, ErrOptimalKeysWrong},
+		{"keys left in insert mode still match", func(t *Task) {
+			t.Desired = "one new three"
+			t.OptimalKeys = "cwnew<Esc>"
+		}, nil},
+		{"keys solving part way through", func(t *Task) { t.OptimalKeys = "dwdw" }, nil},
+		{"motion", func(t *Task) {
+			t.Category = CategoryMotion
+			t.Desired = t.Initial
+			t.CursorStart = 0
+			t.CursorEnd = 8
+			t.OptimalKeys = "2w"
+		}, nil},
+		{"formatting uses the task's textwidth", func(t *Task) {
+			t.Category = CategoryComplex
+			t.Initial = "aaa bbb ccc"
+			t.Desired = "aaa bbb\nccc"
+			t.CursorStart = 0
+			t.TextWidth = 8
+			t.OptimalKeys = "gqq"
+		}, nil},
+	}
+	for _, tt := range tests {
+		task := validTask()
+		tt.modify(&task)
+		err := task.Validate()
+		if !errors.Is(err, tt.want) || (tt.want == nil) != (err == nil) {
+			t.Errorf("%s: Validate() = %v, want %v", tt.name, err, tt.want)
+			continue
+		}
+		var taskErr *TaskError
+		if err != nil && (!errors.As(err, &taskErr) || taskErr.TaskID != task.ID) {
+			t.Errorf("%s: error %v doesn't name task %q", tt.name, err, task.ID)
+		}
+	}
+}
+
+func TestPrepareTasks(t *testing.T) {
+	long := validTask()
+	long.ID = "long"
+	long.OptimalKeys = "xxxx"
+
+	broken := validTask()
+	broken.ID = "broken"
+	broken.OptimalKeys = "x" // The solver could fix it, but mustn't
+
+	esc := validTask()
+	esc.ID = "esc"
+	esc.Desired = "one new three"
+	esc.OptimalKeys = "cwnew<ESC>"
+
+	valid, problems := prepareTasks([]Task{long, broken, esc})
+	if len(valid) != 2 || valid[0].ID != "long" || valid[1].ID != "esc" {
+		t.Fatalf("prepareTasks kept %v, want long and esc", valid)
+	}
+	if len(problems) != 1 || !errors.Is(problems[0], ErrOptimalKeysWrong) {
+		t.Errorf("problems = %v, want the broken task's wrong keys", problems)
+	}
+	if valid[0].OptimalKeys != "dw" || !valid[0].Solved {
+		t.Errorf("long task: keys %q solved %v, want \"dw\" from the solver", valid[0].OptimalKeys, valid[0].Solved)
+	}
+	if valid[1].DifficultyScore < MinDifficultyScore {
+		t.Errorf("esc task not scored: %v", valid[1].DifficultyScore)
+	}
+	for _, task := range valid {
+		if task.OptimalCount != len(task.OptimalKeySequence()) {
+			t.Errorf("%s: optimal count %d for keys %q", task.ID, task.OptimalCount, task.OptimalKeys)
+		}
+	}
+}
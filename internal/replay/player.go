@@ -0,0 +1,201 @@
+package replay
+
+import (
+	"time"
+
+	"github.com/timlinux/macaco/internal/vim"
+)
+
+// Speeds are the playback speeds a player steps through
+var Speeds = []float64{0.25, 0.5, 1, 2, 4, 8}
+
+// defaultSpeed is the index of normal speed in Speeds
+const defaultSpeed = 2
+
+// Player plays a recording back by feeding its keys to a vim engine at the
+// times they were pressed
+type Player struct {
+	rec     *Recording
+	keys    []vim.Key
+	engine  *vim.Engine
+	pos     int           // Keys played so far
+	elapsed time.Duration // Playback time from the start of the task
+	speed   int           // Index into Speeds
+	playing bool
+}
+
+// NewPlayer creates a player paused at the start of a recording
+func NewPlayer(rec *Recording) *Player {
+	p := &Player{
+		rec:   rec,
+		keys:  rec.KeyList(),
+		speed: defaultSpeed,
+	}
+	p.Restart()
+	return p
+}
+
+// Recording returns the recording being played
+func (p *Player) Recording() *Recording {
+	return p.rec
+}
+
+// Engine returns the engine the keys are played on
+func (p *Player) Engine() *vim.Engine {
+	return p.engine
+}
+
+// Restart goes back to the start of the recording, keeping the speed and
+// whether it is playing
+func (p *Player) Restart() {
+	p.engine = p.rec.NewEngine()
+	p.pos = 0
+	p.elapsed = 0
+}
+
+// Play starts or carries on playing, from the start if it had finished
+func (p *Player) Play() {
+	if p.Done() {
+		p.Restart()
+	}
+	p.playing = true
+}
+
+// Pause stops playing
+func (p *Player) Pause() {
+	p.playing = false
+}
+
+// Toggle plays if paused and pauses if playing
+func (p *Player) Toggle() {
+	if p.playing {
+		p.Pause()
+	} else {
+		p.Play()
+	}
+}
+
+// Playing returns true if the player is playing
+func (p *Player) Playing() bool {
+	return p.playing
+}
+
+// Faster steps up to the next speed
+func (p *Player) Faster() {
+	if p.speed < len(Speeds)-1 {
+		p.speed++
+	}
+}
+
+// Slower steps down to the previous speed
+func (p *Player) Slower() {
+	if p.speed > 0 {
+		p.speed--
+	}
+}
+
+// Speed returns the playback speed, 1 being as recorded
+func (p *Player) Speed() float64 {
+	return Speeds[p.speed]
+}
+
+// Step pauses and plays the next key
+func (p *Player) Step() {
+	p.Pause()
+	if !p.Done() {
+		p.elapsed = p.keyTime(p.pos)
+		p.playKey()
+	}
+}
+
+// Back pauses and goes back one key, by playing the recording again from
+// the start up to the key before
+func (p *Player) Back() {
+	p.Pause()
+	p.Seek(p.pos - 1)
+}
+
+// Seek plays the recording from the start up to key n
+func (p *Player) Seek(n int) {
+	if n < 0 {
+		n = 0
+	}
+	p.Restart()
+	for p.pos < n && !p.Done() {
+		p.elapsed = p.keyTime(p.pos)
+		p.playKey()
+	}
+}
+
+// Advance moves playback on by d of real time, playing every key pressed
+// by then at the current speed. It stops at the end of the recording.
+func (p *Player) Advance(d time.Duration) {
+	if !p.playing {
+		return
+	}
+	p.elapsed += time.Duration(float64(d) * p.Speed())
+	for !p.Done() && p.keyTime(p.pos) <= p.elapsed {
+		p.playKey()
+	}
+	if p.Done() {
+		p.elapsed = p.rec.Duration()
+		p.playing = false
+	}
+}
+
+// PlayTo plays every key pressed by at from the start of the task,
+// following another clock rather than the player's own, as a ghost follows
+// the task clock of a race
+func (p *Player) PlayTo(at time.Duration) {
+	for !p.Done() && p.keyTime(p.pos) <= at {
+		p.playKey()
+	}
+	p.elapsed = at
+	if p.Done() && at > p.rec.Duration() {
+		p.elapsed = p.rec.Duration()
+	}
+}
+
+// playKey plays the next key, resetting the engine first if the task was
+// reset before it
+func (p *Player) playKey() {
+	if p.rec.IsReset(p.pos) {
+		p.engine.Reset(p.rec.Initial, p.rec.CursorStart)
+	}
+	p.engine.ProcessKey(p.keys[p.pos])
+	p.pos++
+}
+
+// keyTime returns when key i was pressed
+func (p *Player) keyTime(i int) time.Duration {
+	return time.Duration(p.rec.Times[i]) * time.Millisecond
+}
+
+// Done returns true once every key has been played
+func (p *Player) Done() bool {
+	return p.pos >= p.Len()
+}
+
+// Position returns the number of keys played
+func (p *Player) Position() int {
+	return p.pos
+}
+
+// Len returns the number of keys in the recording
+func (p *Player) Len() int {
+	n := p.rec.Len()
+	if len(p.keys) < n {
+		n = len(p.keys)
+	}
+	return n
+}
+
+// Elapsed returns how far into the task playback is
+func (p *Player) Elapsed() time.Duration {
+	return p.elapsed
+}
+
+// Played returns the keys played so far
+func (p *Player) Played() []vim.Key {
+	return p.keys[:p.pos]
+}
@@ -0,0 +1,124 @@
+package replay
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/timlinux/macaco/internal/vim"
+)
+
+func TestPlayerAdvance(t *testing.T) {
+	// Keys at 1s, 2s and 3s
+	rec := record("one two three", "wdw").Recording(false)
+	tests := []struct {
+		speedSteps int // Faster if positive, slower if negative
+		advance    time.Duration
+		wantKeys   int
+	}{
+		{0, 1500 * time.Millisecond, 1},
+		{0, 3 * time.Second, 3},
+		{1, time.Second, 2},
+		{-1, 3 * time.Second, 1},
+		{-10, 4 * time.Second, 1}, // Slowest is a quarter speed
+		{10, 400 * time.Millisecond, 3},
+	}
+	for _, tt := range tests {
+		p := NewPlayer(rec)
+		for i := 0; i < tt.speedSteps; i++ {
+			p.Faster()
+		}
+		for i := 0; i > tt.speedSteps; i-- {
+			p.Slower()
+		}
+		p.Advance(time.Hour) // Paused players don't move
+		if p.Position() != 0 {
+			t.Fatal("a paused player played")
+		}
+		p.Play()
+		p.Advance(tt.advance)
+		if p.Position() != tt.wantKeys {
+			t.Errorf("at speed %v, %v played %d keys, want %d", p.Speed(), tt.advance, p.Position(), tt.wantKeys)
+		}
+	}
+}
+
+func TestPlayerEnd(t *testing.T) {
+	rec := record("one two three", "wdw").Recording(false)
+	p := NewPlayer(rec)
+	p.Play()
+	p.Advance(time.Minute)
+	if !p.Done() || p.Playing() || p.Elapsed() != 3*time.Second {
+		t.Errorf("done %v, playing %v at %v; want stopped at the end", p.Done(), p.Playing(), p.Elapsed())
+	}
+	if p.Engine().Text() != "one three" {
+		t.Errorf("played to %q", p.Engine().Text())
+	}
+
+	// Playing again starts over
+	p.Play()
+	if p.Position() != 0 || p.Engine().Text() != "one two three" {
+		t.Errorf("replay starts at key %d with %q", p.Position(), p.Engine().Text())
+	}
+}
+
+func TestPlayerStepping(t *testing.T) {
+	rec := record("one two three", "wdwx").Recording(false)
+	p := NewPlayer(rec)
+	p.Play()
+
+	steps := []struct {
+		step     func()
+		wantPos  int
+		wantText string
+	}{
+		{p.Step, 1, "one two three"},
+		{p.Step, 2, "one two three"},
+		{p.Step, 3, "one three"},
+		{p.Back, 2, "one two three"},
+		{func() { p.Seek(4) }, 4, "one hree"},
+		{func() { p.Seek(-1) }, 0, "one two three"},
+		{func() { p.Seek(99) }, 4, "one hree"},
+	}
+	for i, s := range steps {
+		s.step()
+		if p.Position() != s.wantPos || p.Engine().Text() != s.wantText {
+			t.Errorf("step %d: at %d with %q, want %d with %q", i, p.Position(), p.Engine().Text(), s.wantPos, s.wantText)
+		}
+	}
+	if p.Playing() {
+		t.Error("stepping didn't pause")
+	}
+	if got := vim.FormatKeys(p.Played()); got != "wdwx" {
+		t.Errorf("Played() = %q", got)
+	}
+}
+
+func TestPlayerReplaysResets(t *testing.T) {
+	rec := record("abc", "xx|l").Recording(false)
+	p := NewPlayer(rec)
+	var texts []string
+	for !p.Done() {
+		p.Step()
+		texts = append(texts, p.Engine().Text())
+	}
+	if want := []string{"bc", "c", "abc"}; !reflect.DeepEqual(texts, want) {
+		t.Errorf("played %q, want %q", texts, want)
+	}
+	if p.Engine().CursorIndex() != 1 {
+		t.Errorf("cursor at %d after the reset, want 1", p.Engine().CursorIndex())
+	}
+}
+
+func TestPlayerPlayTo(t *testing.T) {
+	rec := record("one two three", "wdw").Recording(false)
+	p := NewPlayer(rec)
+	p.PlayTo(2 * time.Second)
+	if p.Position() != 2 || p.Elapsed() != 2*time.Second {
+		t.Errorf("PlayTo(2s) at key %d, %v", p.Position(), p.Elapsed())
+	}
+	p.PlayTo(time.Minute)
+	if !p.Done() || p.Elapsed() != rec.Duration() {
+		t.Errorf("PlayTo(1m) at key %d, %v", p.Position(), p.Elapsed())
+	}
+}
@@ -0,0 +1,170 @@
+// Package replay records the keys of a task attempt with their timing and
+// plays them back through a vim engine
+package replay
+
+import (
+	"strings"
+	"time"
+
+	"github.com/timlinux/macaco/internal/vim"
+)
+
+// Version is the version of the recording format
+const Version = 1
+
+// modeLetters gives each vim mode a letter in Recording.Modes
+const modeLetters = "nivVbc"
+
+// Recording is every key of a task attempt, when it was pressed and the
+// state it left behind. It is stored column by column, and the buffer text
+// only for the keys that changed it, to keep the stats file small.
+type Recording struct {
+	Version     int          `json:"v"`
+	Initial     string       `json:"initial"`
+	CursorStart int          `json:"cursor_start"`
+	TextWidth   int          `json:"textwidth,omitempty"`
+	ScrollOff   int          `json:"scrolloff,omitempty"`
+	ViewHeight  int          `json:"view_height,omitempty"`
+	Keys        string       `json:"keys"`              // Vim key notation
+	Times       []int64      `json:"times"`             // Milliseconds from the start of the task, one per key
+	Cursors     []int        `json:"cursors"`           // Cursor index after each key
+	Modes       string       `json:"modes"`             // Mode after each key, one letter each: n, i, v, V or b
+	Texts       []TextChange `json:"texts,omitempty"`   // Buffer text after each key that changed it
+	Resets      []int        `json:"resets,omitempty"`  // Keys before which the task was reset to Initial
+	Skipped     bool         `json:"skipped,omitempty"` // The attempt didn't solve the task
+}
+
+// TextChange is the buffer text after a key
+type TextChange struct {
+	Key  int    `json:"k"`
+	Text string `json:"t"`
+}
+
+// Len returns the number of keys recorded
+func (r *Recording) Len() int {
+	return len(r.Times)
+}
+
+// Duration returns the time from the start of the task to the last key
+func (r *Recording) Duration() time.Duration {
+	if r.Len() == 0 {
+		return 0
+	}
+	return time.Duration(r.Times[r.Len()-1]) * time.Millisecond
+}
+
+// KeyList returns the recorded keys
+func (r *Recording) KeyList() []vim.Key {
+	return vim.ParseKeys(r.Keys)
+}
+
+// IsReset returns true if the task was reset before key i
+func (r *Recording) IsReset(i int) bool {
+	for _, at := range r.Resets {
+		if at == i {
+			return true
+		}
+	}
+	return false
+}
+
+// Recorder builds the recording of a task attempt as keys are pressed
+type Recorder struct {
+	rec  Recording
+	keys strings.Builder
+	text string // Buffer text after the last key
+}
+
+// NewRecorder starts recording an attempt at a task from the state of an
+// engine before the first key
+func NewRecorder(engine *vim.Engine) *Recorder {
+	state := engine.State()
+	return &Recorder{
+		rec: Recording{
+			Version:     Version,
+			Initial:     engine.Text(),
+			CursorStart: engine.CursorIndex(),
+			TextWidth:   state.TextWidth,
+			ScrollOff:   state.ScrollOff,
+			ViewHeight:  state.ViewHeight,
+		},
+		text: engine.Text(),
+	}
+}
+
+// ResumeRecorder carries on a recording, for a task resumed part way
+func ResumeRecorder(rec *Recording) *Recorder {
+	r := &Recorder{rec: *rec, text: rec.Initial}
+	r.rec.Times = append([]int64{}, rec.Times...)
+	r.rec.Cursors = append([]int{}, rec.Cursors...)
+	r.rec.Texts = append([]TextChange{}, rec.Texts...)
+	r.rec.Resets = append([]int{}, rec.Resets...)
+	r.keys.WriteString(rec.Keys)
+	if n := len(rec.Texts); n > 0 {
+		r.text = rec.Texts[n-1].Text
+		if resets := len(rec.Resets); resets > 0 && rec.Resets[resets-1] > rec.Texts[n-1].Key {
+			r.text = rec.Initial // Reset since the last change
+		}
+	}
+	return r
+}
+
+// Record adds a key pressed at the given time from the start of the task,
+// with the state the engine was left in
+func (r *Recorder) Record(key vim.Key, at time.Duration, engine *vim.Engine) {
+	r.keys.WriteString(key.String())
+	r.rec.Times = append(r.rec.Times, at.Milliseconds())
+	r.rec.Cursors = append(r.rec.Cursors, engine.CursorIndex())
+	r.rec.Modes += string(modeLetter(engine.Mode()))
+	if text := engine.Text(); text != r.text {
+		r.rec.Texts = append(r.rec.Texts, TextChange{Key: r.rec.Len() - 1, Text: text})
+		r.text = text
+	}
+}
+
+// Reset records that the task was reset before the next key. The next key
+// starts from the initial text again, so its change is recorded from there.
+func (r *Recorder) Reset() {
+	r.rec.Resets = append(r.rec.Resets, r.rec.Len())
+	r.text = r.rec.Initial
+}
+
+// Recording returns a copy of the recording so far
+func (r *Recorder) Recording(skipped bool) *Recording {
+	rec := r.rec
+	rec.Keys = r.keys.String()
+	rec.Times = append([]int64{}, r.rec.Times...)
+	rec.Cursors = append([]int{}, r.rec.Cursors...)
+	rec.Texts = append([]TextChange{}, r.rec.Texts...)
+	rec.Resets = append([]int{}, r.rec.Resets...)
+	rec.Skipped = skipped
+	return &rec
+}
+
+// modeLetter returns the letter of a mode in Recording.Modes
+func modeLetter(mode vim.Mode) byte {
+	if int(mode) < 0 || int(mode) >= len(modeLetters) {
+		return modeLetters[0]
+	}
+	return modeLetters[mode]
+}
+
+// FromKeys records keys played on an engine one every interval, such as a
+// task's optimal solution, to show how it's done
+func FromKeys(engine *vim.Engine, keys []vim.Key, interval time.Duration) *Recording {
+	r := NewRecorder(engine)
+	for i, key := range keys {
+		engine.ProcessKey(key)
+		r.Record(key, time.Duration(i+1)*interval, engine)
+	}
+	return r.Recording(false)
+}
+
+// NewEngine creates an engine in the recording's starting state
+func (r *Recording) NewEngine() *vim.Engine {
+	engine := vim.NewEngine(r.Initial)
+	engine.SetOptions(vim.Options{TextWidth: r.TextWidth, ScrollOff: r.ScrollOff})
+	engine.SetViewportHeight(r.ViewHeight)
+	engine.SetCursorIndex(r.CursorStart)
+	return engine
+}
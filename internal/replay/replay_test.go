@@ -0,0 +1,139 @@
+package replay
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/timlinux/macaco/internal/vim"
+)
+
+// record plays keys on an engine over text, a second apart, resetting the
+// engine before the keys marked "|"
+func record(text, keys string) *Recorder {
+	engine := vim.NewEngine(text)
+	r := NewRecorder(engine)
+	for i, key := range vim.ParseKeys(keys) {
+		if key == "|" {
+			r.Reset()
+			engine.Reset(text, 0)
+			continue
+		}
+		engine.ProcessKey(key)
+		r.Record(key, time.Duration(i+1)*time.Second, engine)
+	}
+	return r
+}
+
+func TestRecorder(t *testing.T) {
+	rec := record("one two", "wdwiyes<Esc>").Recording(false)
+	want := &Recording{
+		Version: Version,
+		Initial: "one two",
+		Keys:    "wdwiyes<Esc>",
+		Times:   []int64{1000, 2000, 3000, 4000, 5000, 6000, 7000, 8000},
+		Cursors: []int{4, 4, 3, 3, 4, 5, 6, 5},
+		Modes:   "nnniiiin",
+		Texts: []TextChange{
+			{Key: 2, Text: "one "},
+			{Key: 4, Text: "oney "},
+			{Key: 5, Text: "oneye "},
+			{Key: 6, Text: "oneyes "},
+		},
+		Resets: []int{},
+	}
+	if !reflect.DeepEqual(rec, want) {
+		t.Errorf("Recording() =\n%+v\nwant\n%+v", rec, want)
+	}
+	if rec.Len() != 8 || rec.Duration() != 8*time.Second {
+		t.Errorf("Len() = %d, Duration() = %v", rec.Len(), rec.Duration())
+	}
+	if !record("one two", "").Recording(true).Skipped {
+		t.Error("skipped recording not marked")
+	}
+}
+
+func TestRecorderReset(t *testing.T) {
+	// The same change after a reset is recorded again, as it changes the
+	// text from the initial one
+	rec := record("abc", "x|x").Recording(false)
+	if want := []int{1}; !reflect.DeepEqual(rec.Resets, want) {
+		t.Errorf("Resets = %v, want %v", rec.Resets, want)
+	}
+	want := []TextChange{{Key: 0, Text: "bc"}, {Key: 1, Text: "bc"}}
+	if !reflect.DeepEqual(rec.Texts, want) {
+		t.Errorf("Texts = %v, want %v", rec.Texts, want)
+	}
+	if rec.IsReset(0) || !rec.IsReset(1) {
+		t.Error("IsReset() marks the wrong keys")
+	}
+}
+
+func TestResumeRecorder(t *testing.T) {
+	tests := []struct {
+		name      string
+		before    string // Keys recorded before resuming
+		after     string // Keys recorded after
+		wantTexts []TextChange
+	}{
+		{"no changes", "l", "x", []TextChange{{Key: 1, Text: "ac"}}},
+		{"after a change", "x", "x", []TextChange{{Key: 0, Text: "bc"}, {Key: 1, Text: "c"}}},
+		{"no change since", "xl", "l", []TextChange{{Key: 0, Text: "bc"}}},
+		{"reset since the change", "x|", "x", []TextChange{{Key: 0, Text: "bc"}, {Key: 1, Text: "bc"}}},
+	}
+	for _, tt := range tests {
+		engine := vim.NewEngine("abc")
+		r := NewRecorder(engine)
+		play := func(keys string) {
+			for _, key := range vim.ParseKeys(keys) {
+				if key == "|" {
+					r.Reset()
+					engine.Reset("abc", 0)
+					continue
+				}
+				engine.ProcessKey(key)
+				r.Record(key, time.Second, engine)
+			}
+		}
+		play(tt.before)
+		saved := r.Recording(false)
+		r = ResumeRecorder(saved)
+		play(tt.after)
+
+		rec := r.Recording(false)
+		if !reflect.DeepEqual(rec.Texts, tt.wantTexts) {
+			t.Errorf("%s: Texts = %v, want %v", tt.name, rec.Texts, tt.wantTexts)
+		}
+		if rec.Keys != stripResets(tt.before)+tt.after {
+			t.Errorf("%s: Keys = %q", tt.name, rec.Keys)
+		}
+		if saved.Len() != len(vim.ParseKeys(stripResets(tt.before))) {
+			t.Errorf("%s: resuming changed the saved recording", tt.name)
+		}
+	}
+}
+
+// stripResets removes the reset marks from keys given to record
+func stripResets(keys string) string {
+	var kept []vim.Key
+	for _, key := range vim.ParseKeys(keys) {
+		if key != "|" {
+			kept = append(kept, key)
+		}
+	}
+	return vim.FormatKeys(kept)
+}
+
+func TestFromKeys(t *testing.T) {
+	engine := vim.NewEngine("one two")
+	engine.SetOptions(vim.Options{TextWidth: 20, ScrollOff: 1})
+	rec := FromKeys(engine, vim.ParseKeys("dw"), 300*time.Millisecond)
+	if rec.Keys != "dw" || !reflect.DeepEqual(rec.Times, []int64{300, 600}) || rec.TextWidth != 20 || rec.ScrollOff != 1 {
+		t.Errorf("FromKeys() = %+v", rec)
+	}
+
+	fresh := rec.NewEngine()
+	if fresh.Text() != "one two" || fresh.CursorIndex() != 0 {
+		t.Errorf("NewEngine() at %q %d, want the start", fresh.Text(), fresh.CursorIndex())
+	}
+}
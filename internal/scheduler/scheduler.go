@@ -0,0 +1,263 @@
+// Package scheduler decides which commands and task categories a player
+// should review next, using the SM-2 spaced-repetition algorithm
+package scheduler
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/timlinux/macaco/internal/stats"
+)
+
+// FileName is the name of the schedule file, kept next to stats.json
+const FileName = "schedule.json"
+
+// SM-2 parameters
+const (
+	initialEase = 2.5
+	minEase     = 1.3
+	day         = 24 * time.Hour
+)
+
+// slowTaskMs is how long a task may take before it counts as hard
+const slowTaskMs = 20000
+
+// Kind tells what an item tracks
+type Kind string
+
+const (
+	KindCommand  Kind = "command"
+	KindCategory Kind = "category"
+)
+
+// Item is the memory state of one command or category
+type Item struct {
+	Kind         Kind      `json:"kind"`
+	Name         string    `json:"name"`
+	Ease         float64   `json:"ease"`
+	IntervalDays int       `json:"interval_days"`
+	Repetitions  int       `json:"repetitions"` // Successful reviews in a row
+	Reviews      int       `json:"reviews"`
+	Lapses       int       `json:"lapses"`
+	LastQuality  int       `json:"last_quality"`
+	LastReviewed time.Time `json:"last_reviewed"`
+	Due          time.Time `json:"due"`
+}
+
+// IsDue returns true if the item should be reviewed at now
+func (i *Item) IsDue(now time.Time) bool {
+	return !now.Before(i.Due)
+}
+
+// Priority returns how urgently the item needs review. Due items grow
+// more urgent the longer they are overdue, and items with a low ease
+// (the ones the player keeps getting wrong) weigh more.
+func (i *Item) Priority(now time.Time) float64 {
+	weakness := initialEase / i.Ease
+	if !i.IsDue(now) {
+		return 0.2 * weakness
+	}
+	overdue := now.Sub(i.Due).Hours() / 24
+	return (1 + math.Log1p(overdue)) * weakness
+}
+
+// review applies one SM-2 review with quality 0-5
+func (i *Item) review(quality int, at time.Time) {
+	i.Reviews++
+	i.LastQuality = quality
+	i.LastReviewed = at
+
+	// Getting an item right before it is due, as happens when a round has
+	// several tasks for one command, leaves its schedule alone
+	if quality >= 3 && at.Before(i.Due) {
+		return
+	}
+
+	switch {
+	case quality < 3:
+		i.Repetitions = 0
+		i.IntervalDays = 1
+		i.Lapses++
+	case i.Repetitions == 0:
+		i.Repetitions, i.IntervalDays = 1, 1
+	case i.Repetitions == 1:
+		i.Repetitions, i.IntervalDays = 2, 6
+	default:
+		i.Repetitions++
+		i.IntervalDays = int(math.Round(float64(i.IntervalDays) * i.Ease))
+	}
+
+	q := float64(5 - quality)
+	i.Ease = math.Max(minEase, i.Ease+0.1-q*(0.08+q*0.02))
+
+	i.Due = at.Add(time.Duration(i.IntervalDays) * day)
+}
+
+// State is the schedule file
+type State struct {
+	Version     string           `json:"version"`
+	LastUpdated time.Time        `json:"last_updated"`
+	Items       map[string]*Item `json:"items"`
+}
+
+// Scheduler keeps the memory state of every command and category a player
+// has practised
+type Scheduler struct {
+	filePath string
+	state    *State
+}
+
+// PathFor returns the schedule file path next to a stats file
+func PathFor(statsFile string) string {
+	return filepath.Join(filepath.Dir(statsFile), FileName)
+}
+
+// New loads the schedule from filePath, starting empty if there is none
+func New(filePath string) *Scheduler {
+	s := &Scheduler{filePath: filePath}
+	if err := s.load(); err != nil {
+		s.state = &State{Version: "1.0.0", Items: make(map[string]*Item)}
+	}
+	return s
+}
+
+// load loads the schedule from file
+func (s *Scheduler) load() error {
+	data, err := os.ReadFile(s.filePath)
+	if err != nil {
+		return err
+	}
+
+	s.state = &State{}
+	if err := json.Unmarshal(data, s.state); err != nil {
+		return err
+	}
+	if s.state.Items == nil {
+		s.state.Items = make(map[string]*Item)
+	}
+	return nil
+}
+
+// Save saves the schedule to file
+func (s *Scheduler) Save() error {
+	if err := os.MkdirAll(filepath.Dir(s.filePath), 0755); err != nil {
+		return err
+	}
+
+	s.state.LastUpdated = time.Now()
+
+	data, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.filePath, data, 0644)
+}
+
+// Quality grades a task attempt on the SM-2 scale of 0 to 5. A failed task
+// is a 0 with hints and a 1 without; a solved one starts at 5 and loses a
+// point for low efficiency, another for very low efficiency, one for using
+// hints and one for being slow.
+func Quality(task *stats.TaskStats) int {
+	if !task.Success {
+		if task.HintsUsed > 0 {
+			return 0
+		}
+		return 1
+	}
+
+	quality := 5
+	if task.Efficiency < 90 {
+		quality--
+	}
+	if task.Efficiency < 60 {
+		quality--
+	}
+	if task.HintsUsed > 0 {
+		quality--
+	}
+	if task.TimeMs > slowTaskMs {
+		quality--
+	}
+	return max(quality, 1)
+}
+
+// Record feeds a task attempt into the schedule of its category and of
+// every command it practises
+func (s *Scheduler) Record(task *stats.TaskStats) {
+	quality := Quality(task)
+	at := task.CompletedAt
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	s.item(KindCategory, task.Category).review(quality, at)
+	for _, name := range task.Commands {
+		s.item(KindCommand, name).review(quality, at)
+	}
+}
+
+// RecordSession feeds every task attempt of a session into the schedule
+func (s *Scheduler) RecordSession(session *stats.SessionStats) {
+	for _, task := range session.Tasks {
+		s.Record(task)
+	}
+}
+
+// item returns the item for a command or category, creating it if needed
+func (s *Scheduler) item(kind Kind, name string) *Item {
+	key := string(kind) + ":" + name
+	item, ok := s.state.Items[key]
+	if !ok {
+		item = &Item{Kind: kind, Name: name, Ease: initialEase}
+		s.state.Items[key] = item
+	}
+	return item
+}
+
+// Item returns the state of a command or category, or nil if it has never
+// been practised
+func (s *Scheduler) Item(kind Kind, name string) *Item {
+	return s.state.Items[string(kind)+":"+name]
+}
+
+// Items returns the items of a kind, most urgent first
+func (s *Scheduler) Items(kind Kind, now time.Time) []*Item {
+	var items []*Item
+	for _, item := range s.state.Items {
+		if item.Kind == kind {
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(a, b int) bool {
+		pa, pb := items[a].Priority(now), items[b].Priority(now)
+		if pa != pb {
+			return pa > pb
+		}
+		return items[a].Name < items[b].Name
+	})
+	return items
+}
+
+// Due returns the items of a kind that are due at now, most urgent first
+func (s *Scheduler) Due(kind Kind, now time.Time) []*Item {
+	var due []*Item
+	for _, item := range s.Items(kind, now) {
+		if item.IsDue(now) {
+			due = append(due, item)
+		}
+	}
+	return due
+}
+
+// Priorities returns the review priority of every item of a kind by name
+func (s *Scheduler) Priorities(kind Kind, now time.Time) map[string]float64 {
+	priorities := make(map[string]float64)
+	for _, item := range s.Items(kind, now) {
+		priorities[item.Name] = item.Priority(now)
+	}
+	return priorities
+}
@@ -0,0 +1,139 @@
+package scheduler
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/timlinux/macaco/internal/stats"
+)
+
+var start = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+func TestQuality(t *testing.T) {
+	tests := []struct {
+		name string
+		task stats.TaskStats
+		want int
+	}{
+		{"failed with hints", stats.TaskStats{HintsUsed: 1}, 0},
+		{"failed", stats.TaskStats{}, 1},
+		{"perfect", stats.TaskStats{Success: true, Efficiency: 100}, 5},
+		{"inefficient", stats.TaskStats{Success: true, Efficiency: 80}, 4},
+		{"very inefficient", stats.TaskStats{Success: true, Efficiency: 50}, 3},
+		{"hinted", stats.TaskStats{Success: true, Efficiency: 100, HintsUsed: 2}, 4},
+		{"slow", stats.TaskStats{Success: true, Efficiency: 100, TimeMs: 30000}, 4},
+		{"everything wrong but solved", stats.TaskStats{Success: true, Efficiency: 10, HintsUsed: 1, TimeMs: 30000}, 1},
+	}
+	for _, tt := range tests {
+		if got := Quality(&tt.task); got != tt.want {
+			t.Errorf("%s: Quality = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReview(t *testing.T) {
+	type review struct {
+		days    int // After start
+		quality int
+	}
+	tests := []struct {
+		name         string
+		reviews      []review
+		wantInterval int
+		wantReps     int
+		wantLapses   int
+		wantEase     float64
+	}{
+		{"first success", []review{{0, 5}}, 1, 1, 0, 2.6},
+		{"second success", []review{{0, 5}, {1, 5}}, 6, 2, 0, 2.7},
+		{"third success", []review{{0, 5}, {1, 5}, {7, 5}}, 16, 3, 0, 2.8},
+		{"hard success lowers ease", []review{{0, 3}}, 1, 1, 0, 2.36},
+		{"early success changes nothing", []review{{0, 5}, {0, 5}}, 1, 1, 0, 2.6},
+		{"failure starts over", []review{{0, 5}, {1, 5}, {7, 1}}, 1, 0, 1, 2.16},
+		{"early failure counts", []review{{0, 5}, {0, 0}}, 1, 0, 1, 1.8},
+		{"ease has a floor", []review{{0, 0}, {0, 0}, {0, 0}}, 1, 0, 3, minEase},
+	}
+	for _, tt := range tests {
+		item := &Item{Ease: initialEase}
+		for _, r := range tt.reviews {
+			item.review(r.quality, start.AddDate(0, 0, r.days))
+		}
+		if item.IntervalDays != tt.wantInterval || item.Repetitions != tt.wantReps || item.Lapses != tt.wantLapses {
+			t.Errorf("%s: interval %d, repetitions %d, lapses %d; want %d, %d, %d", tt.name,
+				item.IntervalDays, item.Repetitions, item.Lapses, tt.wantInterval, tt.wantReps, tt.wantLapses)
+		}
+		if math.Abs(item.Ease-tt.wantEase) > 1e-9 {
+			t.Errorf("%s: ease %v, want %v", tt.name, item.Ease, tt.wantEase)
+		}
+		if item.Reviews != len(tt.reviews) {
+			t.Errorf("%s: %d reviews, want %d", tt.name, item.Reviews, len(tt.reviews))
+		}
+	}
+}
+
+func TestPriority(t *testing.T) {
+	due := &Item{Ease: initialEase, Due: start}
+	weak := &Item{Ease: minEase, Due: start}
+	notDue := &Item{Ease: initialEase, Due: start.AddDate(0, 0, 3)}
+
+	if p := due.Priority(start); p != 1 {
+		t.Errorf("item due now has priority %v, want 1", p)
+	}
+	if due.Priority(start.AddDate(0, 0, 5)) <= due.Priority(start) {
+		t.Error("overdue item isn't more urgent than one just due")
+	}
+	if weak.Priority(start) <= due.Priority(start) {
+		t.Error("item with low ease isn't more urgent")
+	}
+	if notDue.Priority(start) >= due.Priority(start) {
+		t.Error("item not due is as urgent as one due")
+	}
+}
+
+func TestSchedulerRecordAndSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	s := New(path)
+	s.RecordSession(&stats.SessionStats{Tasks: []*stats.TaskStats{
+		{Category: "delete", Commands: []string{"dw", "x"}, Success: true, Efficiency: 100, CompletedAt: start},
+		{Category: "motion", Commands: []string{"w"}, CompletedAt: start},
+	}})
+
+	if item := s.Item(KindCommand, "dw"); item == nil || item.IntervalDays != 1 || item.Lapses != 0 {
+		t.Errorf("dw = %+v, want a first success", item)
+	}
+	if item := s.Item(KindCommand, "w"); item == nil || item.Lapses != 1 {
+		t.Errorf("w = %+v, want a lapse", item)
+	}
+	if s.Item(KindCommand, "cw") != nil {
+		t.Error("unpractised command has an item")
+	}
+
+	now := start.AddDate(0, 0, 1)
+	if due := s.Due(KindCategory, now); len(due) != 2 {
+		t.Errorf("%d categories due, want 2", len(due))
+	}
+	commands := s.Items(KindCommand, now)
+	if len(commands) != 3 || commands[0].Name != "w" {
+		t.Errorf("commands by urgency = %+v, want w, the failed one, first", commands)
+	}
+	if p := s.Priorities(KindCommand, now); len(p) != 3 || p["w"] <= p["dw"] {
+		t.Errorf("priorities = %v", p)
+	}
+
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded := New(path)
+	if item := loaded.Item(KindCommand, "dw"); item == nil || !item.Due.Equal(s.Item(KindCommand, "dw").Due) {
+		t.Errorf("loaded dw = %+v", item)
+	}
+}
+
+func TestNewWithoutFile(t *testing.T) {
+	s := New(filepath.Join(t.TempDir(), "missing", FileName))
+	if items := s.Items(KindCommand, start); len(items) != 0 {
+		t.Errorf("new schedule has items %+v", items)
+	}
+}
@@ -0,0 +1,154 @@
+package solver
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/timlinux/macaco/internal/vim"
+)
+
+// action is one command the search may try
+type action struct {
+	keys []vim.Key
+	edit bool // Changes text, so useless for motion problems
+}
+
+// Curated command alphabet. Undo, visual mode and the repeat commands are
+// left out as they never shorten a solution the search can find.
+var (
+	baseMotions = []string{
+		"h", "j", "k", "l", "w", "b", "e", "0", "^", "$",
+		"gg", "G", "%", "{", "}",
+	}
+	countedMotions = []string{"h", "j", "k", "l", "w", "b", "e"}
+	baseEdits      = []string{
+		"x", "X", "D", "dd", "J",
+		"dw", "db", "de", "d$", "d0", "d^", "dG", "dgg", "d2w", "d3w",
+		"diw", "daw", "2x", "3x", "2dd",
+		"p", "P", "yy", "yw", "ye", "yb", "yiw", "yaw", "y$",
+	}
+	insertCommands = []string{
+		"i", "a", "I", "A", "o", "O", "s", "S", "C", "cc",
+		"cw", "ce", "cb", "c$", "c0", "ciw", "caw",
+	}
+	formatCommands = []string{"gqq", "gqip", "gqap", "gqG", "gqj"}
+	textObjects    = map[rune]string{
+		'"': `"`, '\'': "'", '`': "`",
+		'(': "(", ')': "(", '[': "[", ']': "[",
+		'{': "{", '}': "{", '<': "<lt>", '>': "<lt>",
+	}
+)
+
+// buildAlphabet returns the actions worth trying for a problem. Find
+// commands are only generated for characters around the target, replace
+// commands for characters of the desired text.
+func buildAlphabet(p Problem) []action {
+	var actions []action
+	add := func(edit bool, keys ...string) {
+		for _, k := range keys {
+			actions = append(actions, action{keys: vim.ParseKeys(k), edit: edit})
+		}
+	}
+
+	add(false, baseMotions...)
+	for n := 2; n <= 5; n++ {
+		for _, m := range countedMotions {
+			add(false, fmt.Sprintf("%d%s", n, m))
+		}
+	}
+
+	for _, r := range targetRunes(p) {
+		c := escape(r)
+		add(false, "f"+c, "F"+c, "t"+c, "T"+c)
+		add(true, "dt"+c, "df"+c, "dT"+c, "dF"+c, "ct"+c, "cf"+c)
+	}
+
+	add(true, baseEdits...)
+	add(true, insertCommands...)
+	if p.TextWidth > 0 {
+		add(true, formatCommands...)
+	}
+
+	// Text objects for the brackets and quotes present
+	objects := make(map[string]bool)
+	for _, r := range distinctRunes(p.Initial) {
+		if obj, ok := textObjects[r]; ok && !objects[obj] {
+			objects[obj] = true
+			for _, op := range []string{"d", "c", "y"} {
+				add(true, op+"i"+obj, op+"a"+obj)
+			}
+		}
+	}
+
+	for _, r := range changedRunes(p) {
+		add(true, "r"+escape(r))
+	}
+
+	return actions
+}
+
+// targetRunes returns the characters worth jumping to with f, t, F and T:
+// those around the target cursor for motion problems, and those around the
+// changed part of the text for edit problems
+func targetRunes(p Problem) []rune {
+	text := []rune(p.Initial)
+	var around []int
+	if p.MotionOnly {
+		around = []int{p.CursorEnd - 1, p.CursorEnd, p.CursorEnd + 1}
+	} else {
+		start, end, _ := changedRange(p)
+		around = []int{start - 1, start, start + 1, end - 1, end, end + 1}
+	}
+
+	var runes []rune
+	for _, i := range around {
+		if i >= 0 && i < len(text) {
+			runes = append(runes, text[i])
+		}
+	}
+	return distinctRunes(string(runes))
+}
+
+// changedRunes returns the characters of the desired text that differ from
+// the initial text, the only ones worth typing with r
+func changedRunes(p Problem) []rune {
+	if p.MotionOnly {
+		return nil
+	}
+	start, _, desiredEnd := changedRange(p)
+	return distinctRunes(string([]rune(p.Desired)[start:desiredEnd]))
+}
+
+// changedRange returns the part of the initial text, start..end, that has
+// to be replaced by start..desiredEnd of the desired text
+func changedRange(p Problem) (start, end, desiredEnd int) {
+	text, desired := []rune(p.Initial), []rune(p.Desired)
+	for start < len(text) && start < len(desired) && text[start] == desired[start] {
+		start++
+	}
+	end, desiredEnd = len(text), len(desired)
+	for end > start && desiredEnd > start && text[end-1] == desired[desiredEnd-1] {
+		end--
+		desiredEnd--
+	}
+	return start, end, desiredEnd
+}
+
+// distinctRunes returns the printable characters of s in order of first use
+func distinctRunes(s string) []rune {
+	seen := make(map[rune]bool)
+	var runes []rune
+	for _, r := range s {
+		if r < ' ' || seen[r] {
+			continue
+		}
+		seen[r] = true
+		runes = append(runes, r)
+	}
+	return runes
+}
+
+// escape returns a character in vim notation
+func escape(r rune) string {
+	return strings.ReplaceAll(string(r), "<", "<lt>")
+}
@@ -0,0 +1,231 @@
+// Package solver finds short key sequences that solve vim training tasks by
+// searching over vim engine states.
+package solver
+
+import (
+	"container/heap"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/timlinux/macaco/internal/vim"
+)
+
+// Problem describes what a key sequence has to achieve
+type Problem struct {
+	Initial     string
+	Desired     string
+	CursorStart int
+	CursorEnd   int  // Target cursor index for motion problems
+	MotionOnly  bool // Only the cursor has to move
+	TextWidth   int  // textwidth for formatting problems
+}
+
+// Solution is a key sequence that solves a problem
+type Solution struct {
+	Keys  string `json:"keys"` // Vim notation
+	Count int    `json:"count"`
+}
+
+// Result holds the shortest solution found and a few alternatives
+type Result struct {
+	Best         Solution   `json:"best"`
+	Alternatives []Solution `json:"alternatives,omitempty"`
+	Exhaustive   bool       `json:"exhaustive"` // Search finished within its bounds
+}
+
+// Options bounds the search
+type Options struct {
+	MaxCost      int // Longest solution considered, in keystrokes
+	MaxStates    int // Number of states expanded before giving up
+	Alternatives int // Number of alternative solutions to return
+	Slack        int // Alternatives may be this many keys longer than the best
+}
+
+// DefaultOptions returns bounds suitable for generating tasks on the fly
+func DefaultOptions() Options {
+	return Options{
+		MaxCost:      24,
+		MaxStates:    100,
+		Alternatives: 3,
+		Slack:        1,
+	}
+}
+
+// node is a search state reached by a key sequence
+type node struct {
+	engine *vim.Engine
+	keys   []vim.Key
+	cost   int
+	index  int
+}
+
+// queue is a priority queue of nodes ordered by cost
+type queue []*node
+
+func (q queue) Len() int { return len(q) }
+func (q queue) Less(i, j int) bool {
+	if q[i].cost != q[j].cost {
+		return q[i].cost < q[j].cost
+	}
+	return q[i].index < q[j].index
+}
+func (q queue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *queue) Push(x interface{}) { *q = append(*q, x.(*node)) }
+func (q *queue) Pop() interface{} {
+	old := *q
+	n := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return n
+}
+
+// Solve runs a uniform-cost search from the initial state. It returns nil
+// if no solution exists within the bounds.
+func Solve(p Problem, opts Options) *Result {
+	start := newEngine(p)
+	if isGoal(p, start) {
+		return nil
+	}
+
+	actions := buildAlphabet(p)
+	visited := map[string]bool{stateKey(start): true}
+	pq := &queue{{engine: start}}
+	var found []Solution
+	seen := make(map[string]bool)
+	limit := opts.MaxCost
+	expanded := 0
+	counter := 0
+	exhaustive := true
+
+	for pq.Len() > 0 {
+		current := heap.Pop(pq).(*node)
+		if current.cost+1 > limit {
+			break
+		}
+		if expanded >= opts.MaxStates {
+			exhaustive = false
+			break
+		}
+		expanded++
+
+		for _, action := range actions {
+			if p.MotionOnly && action.edit {
+				continue
+			}
+			next := current.engine.Clone()
+			keys := action.keys
+			next.ProcessKeys(keys)
+
+			// Entering insert mode only helps if we type the right text
+			if next.Mode() == vim.ModeInsert {
+				typed, ok := insertion(p, next)
+				if !ok || current.cost+len(keys)+len(typed) > limit {
+					continue
+				}
+				next.ProcessKeys(typed)
+				keys = append(keys, typed...)
+			}
+			if next.Mode() != vim.ModeNormal || next.GetPendingKeys() != "" {
+				continue
+			}
+
+			cost := current.cost + len(keys)
+			if cost > limit {
+				continue
+			}
+			path := func() []vim.Key {
+				return append(append([]vim.Key{}, current.keys...), keys...)
+			}
+
+			if isGoal(p, next) {
+				solution := Solution{Keys: vim.FormatKeys(path()), Count: cost}
+				if !seen[solution.Keys] {
+					seen[solution.Keys] = true
+					found = append(found, solution)
+					limit = min(limit, cost+opts.Slack)
+				}
+				continue
+			}
+
+			key := stateKey(next)
+			if visited[key] {
+				continue
+			}
+			visited[key] = true
+			counter++
+			heap.Push(pq, &node{engine: next, keys: path(), cost: cost, index: counter})
+		}
+	}
+
+	if len(found) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].Count < found[j].Count
+	})
+
+	result := &Result{Best: found[0], Exhaustive: exhaustive}
+	for _, s := range found[1:] {
+		if len(result.Alternatives) >= opts.Alternatives {
+			break
+		}
+		result.Alternatives = append(result.Alternatives, s)
+	}
+	return result
+}
+
+// Verify returns true if the key sequence solves the problem
+func Verify(p Problem, keys string) bool {
+	e := newEngine(p)
+	e.ProcessKeys(vim.ParseKeys(keys))
+	return isGoal(p, e)
+}
+
+// newEngine creates an engine in the problem's starting state
+func newEngine(p Problem) *vim.Engine {
+	e := vim.NewEngine(p.Initial)
+	e.SetOptions(vim.Options{TextWidth: p.TextWidth})
+	e.SetCursorIndex(p.CursorStart)
+	return e
+}
+
+// isGoal returns true if the engine state solves the problem. Edits must
+// finish back in normal mode.
+func isGoal(p Problem, e *vim.Engine) bool {
+	if e.Mode() != vim.ModeNormal {
+		return false
+	}
+	if p.MotionOnly {
+		return e.Text() == p.Initial && e.CursorIndex() == p.CursorEnd
+	}
+	return e.Text() == p.Desired
+}
+
+// stateKey identifies an engine state for duplicate detection
+func stateKey(e *vim.Engine) string {
+	return e.Text() + "\x00" + strconv.Itoa(e.CursorIndex()) + "\x00" +
+		e.Mode().String() + "\x00" + e.Buffer().GetRegister()
+}
+
+// insertion returns the keys to type so that the buffer matches the
+// desired text, if typing at the cursor can achieve that
+func insertion(p Problem, e *vim.Engine) ([]vim.Key, bool) {
+	text := []rune(e.Text())
+	desired := []rune(p.Desired)
+	cursor := e.CursorIndex()
+	if cursor > len(text) {
+		return nil, false
+	}
+
+	before, after := text[:cursor], text[cursor:]
+	if len(desired) < len(text) ||
+		string(desired[:len(before)]) != string(before) ||
+		string(desired[len(desired)-len(after):]) != string(after) {
+		return nil, false
+	}
+
+	typed := string(desired[len(before) : len(desired)-len(after)])
+	keys := vim.ParseKeys(strings.ReplaceAll(typed, "<", "<lt>"))
+	return append(keys, vim.KeyEsc), true
+}
@@ -0,0 +1,89 @@
+package solver
+
+import (
+	"testing"
+)
+
+// searchOptions bounds tests loosely enough for the search to finish
+func searchOptions() Options {
+	return Options{MaxCost: 12, MaxStates: 5000, Alternatives: 3, Slack: 1}
+}
+
+func TestSolve(t *testing.T) {
+	tests := []struct {
+		name      string
+		problem   Problem
+		wantCount int // 0 for no solution
+	}{
+		{"delete a character", Problem{Initial: "abc", Desired: "ab", CursorStart: 2}, 1},
+		{"delete a word", Problem{Initial: "one two three", Desired: "one three", CursorStart: 4}, 2},
+		{"move then delete", Problem{Initial: "one two three", Desired: "one three", CursorStart: 0}, 3},
+		{"delete a line", Problem{Initial: "one\ntwo\nthree", Desired: "one\nthree", CursorStart: 4}, 2},
+		{"motion to line end", Problem{Initial: "one two three", CursorStart: 0, CursorEnd: 12, MotionOnly: true}, 1},
+		{"motion by words", Problem{Initial: "one two three", CursorStart: 0, CursorEnd: 8, MotionOnly: true}, 2},
+		{"motion down", Problem{Initial: "one\ntwo", CursorStart: 0, CursorEnd: 4, MotionOnly: true}, 1},
+		{"already solved", Problem{Initial: "abc", Desired: "abc"}, 0},
+		{"out of reach", Problem{Initial: "abc", Desired: "xyz completely different text", CursorStart: 0}, 0},
+	}
+	for _, tt := range tests {
+		opts := searchOptions()
+		if tt.name == "out of reach" {
+			opts.MaxCost = 3
+		}
+		result := Solve(tt.problem, opts)
+		if tt.wantCount == 0 {
+			if result != nil {
+				t.Errorf("%s: found %q, want no solution", tt.name, result.Best.Keys)
+			}
+			continue
+		}
+		if result == nil {
+			t.Errorf("%s: no solution, want %d keys", tt.name, tt.wantCount)
+			continue
+		}
+		if result.Best.Count != tt.wantCount {
+			t.Errorf("%s: best %q is %d keys, want %d", tt.name, result.Best.Keys, result.Best.Count, tt.wantCount)
+		}
+		for _, s := range append([]Solution{result.Best}, result.Alternatives...) {
+			if !Verify(tt.problem, s.Keys) {
+				t.Errorf("%s: solution %q doesn't solve the problem", tt.name, s.Keys)
+			}
+			if s.Count > result.Best.Count+opts.Slack {
+				t.Errorf("%s: alternative %q is more than %d keys longer than the best", tt.name, s.Keys, opts.Slack)
+			}
+		}
+		if len(result.Alternatives) > opts.Alternatives {
+			t.Errorf("%s: %d alternatives, want at most %d", tt.name, len(result.Alternatives), opts.Alternatives)
+		}
+	}
+}
+
+func TestSolveStopsAtMaxStates(t *testing.T) {
+	opts := searchOptions()
+	opts.MaxStates = 1
+	result := Solve(Problem{Initial: "one two three four", Desired: "one four", CursorStart: 0}, opts)
+	if result != nil && result.Exhaustive {
+		t.Errorf("search of one state reported exhaustive with %q", result.Best.Keys)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		problem Problem
+		keys    string
+		want    bool
+	}{
+		{Problem{Initial: "one two", Desired: "two"}, "dw", true},
+		{Problem{Initial: "one two", Desired: "two"}, "x", false},
+		{Problem{Initial: "one two", Desired: "new two"}, "cwnew<Esc>", true},
+		{Problem{Initial: "one two", Desired: "new two"}, "cwnew", false}, // Still in insert mode
+		{Problem{Initial: "one two", CursorEnd: 4, MotionOnly: true}, "w", true},
+		{Problem{Initial: "one two", CursorEnd: 4, MotionOnly: true}, "dw", false},
+		{Problem{Initial: "aaa bbb ccc", Desired: "aaa bbb\nccc", TextWidth: 8}, "gqq", true},
+	}
+	for _, tt := range tests {
+		if got := Verify(tt.problem, tt.keys); got != tt.want {
+			t.Errorf("Verify(%+v, %q) = %v, want %v", tt.problem, tt.keys, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package stats
+
+import "math"
+
+// ScoringVersion is the version of the scoring model. Version 1 was a
+// single 8 second target for every task with no points; sessions scored
+// with it have no Scoring. Bump it whenever scores from the new model would
+// mean something different.
+const ScoringVersion = 2
+
+// legacyTargetTimeMs is the one target time of every task in version 1,
+// and of levels a model has no target for
+const legacyTargetTimeMs = 8000
+
+// ScoringModel is how a session was scored. It is stored with the session,
+// so scores from older models can still be read.
+type ScoringModel struct {
+	Version          int                `json:"version"`
+	TargetTimesMs    map[int]int64      `json:"target_times_ms"`   // Target time of a task by difficulty level
+	CategoryTime     map[string]float64 `json:"category_time"`     // Factor on the target time by category, 1 if missing
+	LevelWeights     map[int]float64    `json:"level_weights"`     // Factor on a task's points by difficulty level
+	EfficiencyPoints float64            `json:"efficiency_points"` // Points for 100% efficiency
+	SpeedPoints      float64            `json:"speed_points"`      // Points for finishing within the target time
+	HintPenalty      float64            `json:"hint_penalty"`      // Points lost for each hint
+	ResetPenalty     float64            `json:"reset_penalty"`     // Points lost for each reset
+	Grades           []GradeThreshold   `json:"grades"`            // Best grade first
+}
+
+// GradeThreshold is what a session needs for a grade. Zero values don't
+// ask for anything.
+type GradeThreshold struct {
+	Grade      string  `json:"grade"`
+	Completion float64 `json:"completion,omitempty"` // Percent of tasks solved
+	Efficiency float64 `json:"efficiency,omitempty"` // Average efficiency, percent
+	TimeRatio  float64 `json:"time_ratio,omitempty"` // Most average time over target time
+	Score      float64 `json:"score,omitempty"`      // Percent of the round's possible points
+}
+
+// TaskScore is the points breakdown of a task attempt. Failed tasks score
+// nothing.
+type TaskScore struct {
+	TargetTimeMs int64   `json:"target_time_ms"`
+	Efficiency   float64 `json:"efficiency"`              // Points for efficiency, before the weight
+	Speed        float64 `json:"speed"`                   // Points for speed, before the weight
+	HintPenalty  float64 `json:"hint_penalty,omitempty"`  // Points lost to hints, before the weight
+	ResetPenalty float64 `json:"reset_penalty,omitempty"` // Points lost to resets, before the weight
+	Weight       float64 `json:"weight"`                  // For the task's difficulty
+	Points       float64 `json:"points"`
+	MaxPoints    float64 `json:"max_points"`
+}
+
+// RoundScore adds up the points breakdowns of a session's tasks, weighted
+type RoundScore struct {
+	Efficiency   float64 `json:"efficiency"`
+	Speed        float64 `json:"speed"`
+	HintPenalty  float64 `json:"hint_penalty"`
+	ResetPenalty float64 `json:"reset_penalty"`
+	Points       float64 `json:"points"`
+	MaxPoints    float64 `json:"max_points"`
+}
+
+// Percent returns the points as a percentage of the possible points
+func (r RoundScore) Percent() float64 {
+	if r.MaxPoints == 0 {
+		return 0
+	}
+	return r.Points / r.MaxPoints * 100
+}
+
+// DefaultScoring returns the built-in scoring model. Tasks are worth up to
+// 100 points, 60 for efficiency and 40 for speed, times a weight for their
+// difficulty.
+func DefaultScoring() *ScoringModel {
+	return &ScoringModel{
+		Version:       ScoringVersion,
+		TargetTimesMs: map[int]int64{1: 5000, 2: 8000, 3: 12000, 4: 20000},
+		CategoryTime: map[string]float64{
+			"motion":  0.8,
+			"delete":  1,
+			"change":  1.2,
+			"insert":  1.2,
+			"visual":  1.2,
+			"complex": 1.5,
+		},
+		LevelWeights:     map[int]float64{1: 1, 2: 1.5, 3: 2, 4: 3},
+		EfficiencyPoints: 60,
+		SpeedPoints:      40,
+		HintPenalty:      10,
+		ResetPenalty:     5,
+		Grades:           DefaultGrades(),
+	}
+}
+
+// DefaultGrades returns the built-in grade thresholds
+func DefaultGrades() []GradeThreshold {
+	return []GradeThreshold{
+		{Grade: "S", Completion: 100, Efficiency: 95, TimeRatio: 1, Score: 90},
+		{Grade: "A", Completion: 100, Efficiency: 85, TimeRatio: 1.2, Score: 75},
+		{Grade: "B", Completion: 90, Efficiency: 75},
+		{Grade: "C", Completion: 75, Efficiency: 60},
+		{Grade: "D", Completion: 50},
+	}
+}
+
+// TargetTime returns the target time of a task of a difficulty level and
+// category, in milliseconds
+func (m *ScoringModel) TargetTime(difficulty int, category string) int64 {
+	target, ok := m.TargetTimesMs[difficulty]
+	if !ok {
+		target = legacyTargetTimeMs
+	}
+	if factor, ok := m.CategoryTime[category]; ok {
+		target = int64(float64(target) * factor)
+	}
+	return target
+}
+
+// weight returns the factor on the points of a task of a difficulty level
+func (m *ScoringModel) weight(difficulty int) float64 {
+	if w, ok := m.LevelWeights[difficulty]; ok {
+		return w
+	}
+	return 1
+}
+
+// ScoreTask works out the points of a task attempt. Efficiency points are
+// in proportion to efficiency, after any overtime penalty. Speed points are
+// all given within the target time and fall off in proportion beyond it.
+// Hints and resets cost points, down to none.
+func (m *ScoringModel) ScoreTask(t *TaskStats) TaskScore {
+	score := TaskScore{
+		TargetTimeMs: m.TargetTime(t.Difficulty, t.Category),
+		Weight:       m.weight(t.Difficulty),
+	}
+	score.MaxPoints = (m.EfficiencyPoints + m.SpeedPoints) * score.Weight
+	if !t.Success {
+		return score
+	}
+
+	score.Efficiency = m.EfficiencyPoints * t.Efficiency / 100
+	score.Speed = m.SpeedPoints
+	if t.TimeMs > score.TargetTimeMs {
+		score.Speed *= float64(score.TargetTimeMs) / float64(t.TimeMs)
+	}
+	score.HintPenalty = m.HintPenalty * float64(t.HintsUsed)
+	score.ResetPenalty = m.ResetPenalty * float64(t.Resets)
+	points := score.Efficiency + score.Speed - score.HintPenalty - score.ResetPenalty
+	score.Points = math.Max(points, 0) * score.Weight
+	return score
+}
+
+// Score scores every task of the session, adds up the round's points and
+// grades it with the model, which is stored with the session
+func (s *SessionStats) Score(m *ScoringModel) {
+	s.Scoring = m
+	s.Points = RoundScore{}
+	var timeRatio float64
+	for _, task := range s.Tasks {
+		score := m.ScoreTask(task)
+		task.Score = &score
+		s.Points.Efficiency += score.Efficiency * score.Weight
+		s.Points.Speed += score.Speed * score.Weight
+		s.Points.HintPenalty += score.HintPenalty * score.Weight
+		s.Points.ResetPenalty += score.ResetPenalty * score.Weight
+		s.Points.Points += score.Points
+		s.Points.MaxPoints += score.MaxPoints
+		if score.TargetTimeMs > 0 {
+			timeRatio += float64(task.TimeMs) / float64(score.TargetTimeMs)
+		}
+	}
+	if len(s.Tasks) > 0 {
+		s.TimeRatio = timeRatio / float64(len(s.Tasks))
+	}
+	s.Grade = m.Grade(s)
+}
+
+// Grade returns the first grade whose thresholds the session meets, or F
+func (m *ScoringModel) Grade(s *SessionStats) string {
+	if s.TasksAttempted == 0 {
+		return "F"
+	}
+	completion := float64(s.TasksCompleted) / float64(s.TasksAttempted) * 100
+	for _, g := range m.Grades {
+		if completion >= g.Completion &&
+			s.AvgEfficiency >= g.Efficiency &&
+			(g.TimeRatio == 0 || s.TimeRatio <= g.TimeRatio) &&
+			s.Points.Percent() >= g.Score {
+			return g.Grade
+		}
+	}
+	return "F"
+}
+
+// ModelVersion returns the version of the scoring model the session was
+// scored with, 1 for sessions from before models were stored
+func (s *SessionStats) ModelVersion() int {
+	if s.Scoring == nil {
+		return 1
+	}
+	return s.Scoring.Version
+}
@@ -0,0 +1,203 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+// near reports whether two scores are equal but for rounding
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestTargetTime(t *testing.T) {
+	m := DefaultScoring()
+	tests := []struct {
+		difficulty int
+		category   string
+		want       int64
+	}{
+		{1, "motion", 4000},
+		{1, "delete", 5000},
+		{3, "complex", 18000},
+		{4, "change", 24000},
+		{2, "unknown", 8000},
+		{5, "delete", legacyTargetTimeMs},
+	}
+	for _, tt := range tests {
+		if got := m.TargetTime(tt.difficulty, tt.category); got != tt.want {
+			t.Errorf("TargetTime(%d, %q) = %d, want %d", tt.difficulty, tt.category, got, tt.want)
+		}
+	}
+}
+
+func TestScoreTask(t *testing.T) {
+	tests := []struct {
+		name string
+		task TaskStats
+		want TaskScore
+	}{
+		{
+			name: "failed",
+			task: TaskStats{Difficulty: 2, Category: "delete", Efficiency: 100, TimeMs: 1000},
+			want: TaskScore{TargetTimeMs: 8000, Weight: 1.5, MaxPoints: 150},
+		},
+		{
+			name: "perfect within target",
+			task: TaskStats{Difficulty: 1, Category: "delete", Efficiency: 100, TimeMs: 4000, Success: true},
+			want: TaskScore{TargetTimeMs: 5000, Efficiency: 60, Speed: 40, Weight: 1, Points: 100, MaxPoints: 100},
+		},
+		{
+			name: "half efficient at twice the target",
+			task: TaskStats{Difficulty: 2, Category: "delete", Efficiency: 50, TimeMs: 16000, Success: true},
+			want: TaskScore{TargetTimeMs: 8000, Efficiency: 30, Speed: 20, Weight: 1.5, Points: 75, MaxPoints: 150},
+		},
+		{
+			name: "hints and resets",
+			task: TaskStats{Difficulty: 1, Category: "delete", Efficiency: 100, TimeMs: 1000, Success: true, HintsUsed: 2, Resets: 1},
+			want: TaskScore{TargetTimeMs: 5000, Efficiency: 60, Speed: 40, HintPenalty: 20, ResetPenalty: 5, Weight: 1, Points: 75, MaxPoints: 100},
+		},
+		{
+			name: "penalties down to none",
+			task: TaskStats{Difficulty: 1, Category: "delete", Efficiency: 10, TimeMs: 50000, Success: true, HintsUsed: 3},
+			want: TaskScore{TargetTimeMs: 5000, Efficiency: 6, Speed: 4, HintPenalty: 30, Weight: 1, Points: 0, MaxPoints: 100},
+		},
+		{
+			name: "unknown level",
+			task: TaskStats{Difficulty: 7, Category: "delete", Efficiency: 100, TimeMs: 1000, Success: true},
+			want: TaskScore{TargetTimeMs: 8000, Efficiency: 60, Speed: 40, Weight: 1, Points: 100, MaxPoints: 100},
+		},
+	}
+	m := DefaultScoring()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := m.ScoreTask(&tt.task)
+			if got.TargetTimeMs != tt.want.TargetTimeMs ||
+				!near(got.Efficiency, tt.want.Efficiency) ||
+				!near(got.Speed, tt.want.Speed) ||
+				!near(got.HintPenalty, tt.want.HintPenalty) ||
+				!near(got.ResetPenalty, tt.want.ResetPenalty) ||
+				!near(got.Weight, tt.want.Weight) ||
+				!near(got.Points, tt.want.Points) ||
+				!near(got.MaxPoints, tt.want.MaxPoints) {
+				t.Errorf("ScoreTask = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionScore(t *testing.T) {
+	s := &SessionStats{
+		TasksAttempted: 2,
+		TasksCompleted: 2,
+		AvgEfficiency:  75,
+		Tasks: []*TaskStats{
+			{Difficulty: 1, Category: "delete", Efficiency: 100, TimeMs: 4000, Success: true},
+			{Difficulty: 2, Category: "delete", Efficiency: 50, TimeMs: 16000, Success: true, Resets: 1},
+		},
+	}
+	m := DefaultScoring()
+	s.Score(m)
+
+	if s.Scoring != m || s.ModelVersion() != ScoringVersion {
+		t.Errorf("Score didn't store the model: %+v", s.Scoring)
+	}
+	for i, task := range s.Tasks {
+		if task.Score == nil {
+			t.Errorf("task %d has no score", i)
+		}
+	}
+	want := RoundScore{Efficiency: 105, Speed: 70, ResetPenalty: 7.5, Points: 167.5, MaxPoints: 250}
+	got := s.Points
+	if !near(got.Efficiency, want.Efficiency) || !near(got.Speed, want.Speed) ||
+		!near(got.HintPenalty, want.HintPenalty) || !near(got.ResetPenalty, want.ResetPenalty) ||
+		!near(got.Points, want.Points) || !near(got.MaxPoints, want.MaxPoints) {
+		t.Errorf("Points = %+v, want %+v", got, want)
+	}
+	if !near(s.TimeRatio, 1.4) {
+		t.Errorf("TimeRatio = %v, want 1.4", s.TimeRatio)
+	}
+	if s.Grade != "B" {
+		t.Errorf("Grade = %q, want B", s.Grade)
+	}
+}
+
+func TestGrade(t *testing.T) {
+	tests := []struct {
+		name       string
+		attempted  int
+		completed  int
+		efficiency float64
+		timeRatio  float64
+		points     float64
+		want       string
+	}{
+		{"no tasks", 0, 0, 100, 0.5, 100, "F"},
+		{"best", 10, 10, 96, 0.9, 95, "S"},
+		{"slower", 10, 10, 96, 1.1, 95, "A"},
+		{"too slow for A", 10, 10, 96, 1.3, 95, "B"},
+		{"too few points for A", 10, 10, 96, 0.9, 50, "B"},
+		{"some failed", 10, 8, 70, 2, 50, "C"},
+		{"half solved", 10, 5, 0, 3, 10, "D"},
+		{"mostly failed", 10, 4, 100, 0.5, 40, "F"},
+	}
+	m := DefaultScoring()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &SessionStats{
+				TasksAttempted: tt.attempted,
+				TasksCompleted: tt.completed,
+				AvgEfficiency:  tt.efficiency,
+				TimeRatio:      tt.timeRatio,
+				Points:         RoundScore{Points: tt.points, MaxPoints: 100},
+			}
+			if got := m.Grade(s); got != tt.want {
+				t.Errorf("Grade = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGradeConfigured(t *testing.T) {
+	m := DefaultScoring()
+	m.Grades = []GradeThreshold{{Grade: "Pass", Completion: 50}}
+	tests := []struct {
+		completed int
+		want      string
+	}{
+		{10, "Pass"},
+		{5, "Pass"},
+		{4, "F"},
+	}
+	for _, tt := range tests {
+		s := &SessionStats{TasksAttempted: 10, TasksCompleted: tt.completed}
+		if got := m.Grade(s); got != tt.want {
+			t.Errorf("Grade with %d of 10 solved = %q, want %q", tt.completed, got, tt.want)
+		}
+	}
+}
+
+func TestRoundScorePercent(t *testing.T) {
+	tests := []struct {
+		score RoundScore
+		want  float64
+	}{
+		{RoundScore{}, 0},
+		{RoundScore{Points: 75, MaxPoints: 150}, 50},
+		{RoundScore{Points: 250, MaxPoints: 250}, 100},
+	}
+	for _, tt := range tests {
+		if got := tt.score.Percent(); !near(got, tt.want) {
+			t.Errorf("%+v.Percent() = %v, want %v", tt.score, got, tt.want)
+		}
+	}
+}
+
+func TestModelVersion(t *testing.T) {
+	if got := (&SessionStats{}).ModelVersion(); got != 1 {
+		t.Errorf("ModelVersion() without a model = %d, want 1", got)
+	}
+	if got := (&SessionStats{Scoring: DefaultScoring()}).ModelVersion(); got != ScoringVersion {
+		t.Errorf("ModelVersion() = %d, want %d", got, ScoringVersion)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/timlinux/macaco/internal/replay"
 )
 
 // Tracker manages statistics persistence and aggregation
@@ -25,8 +26,16 @@ type StatsData struct {
 	Sessions    []*SessionStats `json:"sessions"`
 	Achievements []Achievement  `json:"achievements"`
 	Preferences  *Preferences   `json:"preferences"`
+	Daily        *DailyStats    `json:"daily,omitempty"`
+	Drills       []*DrillStats  `json:"drills,omitempty"` // Kept apart from graded sessions
+	Lessons      map[string]*LessonProgress `json:"lessons,omitempty"` // Progress through the curriculum by unit
+	BestRuns     map[string]*SessionStats   `json:"best_runs,omitempty"` // Fastest clean run of each round code, kept apart from the rolling Sessions
 }
 
+// maxBestRuns is how many round codes keep a best run. Past it, the best
+// run played longest ago is dropped.
+const maxBestRuns = 200
+
 // LifetimeStats aggregates statistics over all sessions
 type LifetimeStats struct {
 	TotalRounds        int                       `json:"total_rounds"`
@@ -70,6 +79,7 @@ type BestRecord struct {
 type SessionStats struct {
 	SessionID      string                    `json:"session_id"`
 	RoundType      string                    `json:"round_type"`
+	RoundCode      string                    `json:"round_code,omitempty"`
 	StartedAt      time.Time                 `json:"started_at"`
 	CompletedAt    time.Time                 `json:"completed_at,omitempty"`
 	TotalTimeMs    int64                     `json:"total_time_ms"`
@@ -78,24 +88,87 @@ type SessionStats struct {
 	Grade          string                    `json:"grade"`
 	AvgEfficiency  float64                   `json:"avg_efficiency"`
 	AvgTimeMs      int64                     `json:"avg_time_ms"`
+	TimedOut       int                       `json:"timed_out,omitempty"` // Tasks skipped when their time ran out
+	Penalty        float64                   `json:"penalty,omitempty"`   // Efficiency points lost to overtime
+	TimeRatio      float64                   `json:"time_ratio,omitempty"` // Average time over target time
+	Points         RoundScore                `json:"points"`
+	Scoring        *ScoringModel             `json:"scoring,omitempty"` // Model the session was scored with, nil for version 1
 	CategoryStats  map[string]*CategoryStats `json:"category_stats,omitempty"`
 	Tasks          []*TaskStats              `json:"tasks,omitempty"`
 }
 
 // TaskStats holds statistics for a single task attempt
 type TaskStats struct {
-	TaskID            string    `json:"task_id"`
-	Category          string    `json:"category"`
-	Difficulty        int       `json:"difficulty"`
-	TimeMs            int64     `json:"time_ms"`
-	Keystrokes        int       `json:"keystrokes"`
-	OptimalKeystrokes int       `json:"optimal_keystrokes"`
-	Efficiency        float64   `json:"efficiency"`
-	Success           bool      `json:"success"`
-	KeysUsed          string    `json:"keys_used"`
-	Resets            int       `json:"resets"`
-	HintsUsed         int       `json:"hints_used"`
-	CompletedAt       time.Time `json:"completed_at"`
+	TaskID            string            `json:"task_id"`
+	Category          string            `json:"category"`
+	Difficulty        int               `json:"difficulty"`
+	TimeMs            int64             `json:"time_ms"`
+	Keystrokes        int               `json:"keystrokes"`
+	OptimalKeystrokes int               `json:"optimal_keystrokes"`
+	Efficiency        float64           `json:"efficiency"`
+	Success           bool              `json:"success"`
+	KeysUsed          string            `json:"keys_used"`
+	Commands          []string          `json:"commands,omitempty"` // Commands the task practises
+	Resets            int               `json:"resets"`
+	HintsUsed         int               `json:"hints_used"`
+	TimedOut          bool              `json:"timed_out,omitempty"`   // Skipped when its time ran out
+	OvertimeMs        int64             `json:"overtime_ms,omitempty"` // Played past the round clock
+	Penalty           float64           `json:"penalty,omitempty"`     // Efficiency points lost to overtime
+	Replay            *replay.Recording `json:"replay,omitempty"`      // Every key with its timing
+	Score             *TaskScore        `json:"score,omitempty"`       // Points breakdown
+	CompletedAt       time.Time         `json:"completed_at"`
+}
+
+// DrillStats records a drill: what it practised, whether it was mastered
+// and how it went. Drills aren't graded and don't count toward lifetime
+// stats.
+type DrillStats struct {
+	SessionID     string       `json:"session_id"`
+	Drill         string       `json:"drill"` // What it practised, such as "commands f, t"
+	Category      string       `json:"category,omitempty"`
+	Tag           string       `json:"tag,omitempty"`
+	Commands      []string     `json:"commands,omitempty"`
+	Goal          string       `json:"goal"` // The mastery criterion
+	Mastered      bool         `json:"mastered"`
+	CleanSolves   int          `json:"clean_solves"`
+	StartedAt     time.Time    `json:"started_at"`
+	CompletedAt   time.Time    `json:"completed_at"`
+	TotalTimeMs   int64        `json:"total_time_ms"`
+	AvgEfficiency float64      `json:"avg_efficiency"`
+	Tasks         []*TaskStats `json:"tasks,omitempty"`
+}
+
+// LessonProgress records the player's attempts at a unit of the
+// curriculum. Lessons aren't graded and don't count toward lifetime stats.
+type LessonProgress struct {
+	Unit           string     `json:"unit"`
+	Attempts       int        `json:"attempts"`
+	Passed         bool       `json:"passed"`
+	PassedAt       *time.Time `json:"passed_at,omitempty"` // First pass
+	BestEfficiency float64    `json:"best_efficiency"`
+	BestCompletion float64    `json:"best_completion"` // Percent of practice tasks solved
+	LastPlayedAt   time.Time  `json:"last_played_at"`
+}
+
+// DailyStats tracks daily challenge attempts and the daily streak
+type DailyStats struct {
+	CurrentStreak int            `json:"current_streak"` // Consecutive days completed
+	LongestStreak int            `json:"longest_streak"`
+	LastCompleted string         `json:"last_completed,omitempty"` // UTC date, YYYY-MM-DD
+	History       []*DailyResult `json:"history"`
+}
+
+// DailyResult records one day's challenge attempt
+type DailyResult struct {
+	Date          string    `json:"date"` // UTC date, YYYY-MM-DD
+	Number        int       `json:"number"`
+	SessionID     string    `json:"session_id"`
+	StartedAt     time.Time `json:"started_at"`
+	Completed     bool      `json:"completed"`
+	Grade         string    `json:"grade,omitempty"`
+	TotalTimeMs   int64     `json:"total_time_ms,omitempty"`
+	AvgEfficiency float64   `json:"avg_efficiency,omitempty"`
+	Streak        int       `json:"streak,omitempty"` // Streak after this day
 }
 
 // Achievement represents an unlocked achievement
@@ -205,6 +278,7 @@ func (t *Tracker) RecordSession(session *SessionStats) {
 	if len(t.data.Sessions) > 100 {
 		t.data.Sessions = t.data.Sessions[len(t.data.Sessions)-100:]
 	}
+	t.recordBestRun(session)
 
 	// Update lifetime stats
 	t.updateLifetimeStats(session)
@@ -319,6 +393,208 @@ func (t *Tracker) GetRecentSessions(count int) []*SessionStats {
 	return sessions[len(sessions)-count:]
 }
 
+// BestRun returns the fastest clean run of a round code of tasks tasks:
+// every task solved in time, with a replay. It returns nil if there is none.
+func (t *Tracker) BestRun(roundCode string, tasks int) *SessionStats {
+	if roundCode == "" {
+		return nil
+	}
+	var best *SessionStats
+	if run := t.data.BestRuns[roundCode]; run != nil && len(run.Tasks) == tasks {
+		best = run
+	}
+	// Stats files from before best runs were kept only have the sessions
+	for _, s := range t.data.Sessions {
+		if s.RoundCode != roundCode || len(s.Tasks) != tasks || !s.IsClean() || !s.HasReplays() {
+			continue
+		}
+		if best == nil || s.TotalTimeMs < best.TotalTimeMs {
+			best = s
+		}
+	}
+	return best
+}
+
+// recordBestRun keeps a clean run with replays as its round code's best
+// run if it beats the one kept, so it outlives the session history. A run
+// of a different number of tasks, from a build that generates the code
+// differently, replaces the one kept.
+func (t *Tracker) recordBestRun(s *SessionStats) {
+	if s.RoundCode == "" || !s.IsClean() || !s.HasReplays() {
+		return
+	}
+	if best := t.data.BestRuns[s.RoundCode]; best != nil && len(best.Tasks) == len(s.Tasks) && best.TotalTimeMs <= s.TotalTimeMs {
+		return
+	}
+	if t.data.BestRuns == nil {
+		t.data.BestRuns = make(map[string]*SessionStats)
+	}
+	t.data.BestRuns[s.RoundCode] = s
+
+	if len(t.data.BestRuns) > maxBestRuns {
+		oldest := ""
+		for code, run := range t.data.BestRuns {
+			if oldest == "" || run.StartedAt.Before(t.data.BestRuns[oldest].StartedAt) {
+				oldest = code
+			}
+		}
+		delete(t.data.BestRuns, oldest)
+	}
+}
+
+// IsClean returns true if every task of the session was solved without
+// running out of time
+func (s *SessionStats) IsClean() bool {
+	if s.TimedOut > 0 {
+		return false
+	}
+	for _, task := range s.Tasks {
+		if !task.Success || task.TimedOut {
+			return false
+		}
+	}
+	return true
+}
+
+// HasReplays returns true if every task of the session has a replay.
+// Sessions saved before keys were recorded have none.
+func (s *SessionStats) HasReplays() bool {
+	if len(s.Tasks) == 0 {
+		return false
+	}
+	for _, task := range s.Tasks {
+		if task.Replay == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// RecordDrill records a finished drill
+func (t *Tracker) RecordDrill(drill *DrillStats) {
+	t.data.Drills = append(t.data.Drills, drill)
+	if len(t.data.Drills) > 100 {
+		t.data.Drills = t.data.Drills[len(t.data.Drills)-100:]
+	}
+}
+
+// GetDrills returns the recorded drills, oldest first
+func (t *Tracker) GetDrills() []*DrillStats {
+	return t.data.Drills
+}
+
+// RecordLesson records an attempt at a unit. A unit stays passed once it
+// has been passed.
+func (t *Tracker) RecordLesson(unit string, s *SessionStats, passed bool) *LessonProgress {
+	if t.data.Lessons == nil {
+		t.data.Lessons = make(map[string]*LessonProgress)
+	}
+	p := t.data.Lessons[unit]
+	if p == nil {
+		p = &LessonProgress{Unit: unit}
+		t.data.Lessons[unit] = p
+	}
+
+	p.Attempts++
+	p.LastPlayedAt = s.CompletedAt
+	if s.AvgEfficiency > p.BestEfficiency {
+		p.BestEfficiency = s.AvgEfficiency
+	}
+	if s.TasksAttempted > 0 {
+		if completion := float64(s.TasksCompleted) / float64(s.TasksAttempted) * 100; completion > p.BestCompletion {
+			p.BestCompletion = completion
+		}
+	}
+	if passed && !p.Passed {
+		p.Passed = true
+		at := s.CompletedAt
+		p.PassedAt = &at
+	}
+	return p
+}
+
+// GetLessonProgress returns the player's progress on a unit, or nil if it
+// was never attempted
+func (t *Tracker) GetLessonProgress(unit string) *LessonProgress {
+	return t.data.Lessons[unit]
+}
+
+// daily returns the daily challenge stats, creating them if needed
+func (t *Tracker) daily() *DailyStats {
+	if t.data.Daily == nil {
+		t.data.Daily = &DailyStats{History: make([]*DailyResult, 0)}
+	}
+	return t.data.Daily
+}
+
+// dailyResult returns the attempt for a date, or nil
+func (t *Tracker) dailyResult(date string) *DailyResult {
+	for _, r := range t.daily().History {
+		if r.Date == date {
+			return r
+		}
+	}
+	return nil
+}
+
+// HasDailyAttempt returns true if the challenge of date was already started
+func (t *Tracker) HasDailyAttempt(date string) bool {
+	return t.dailyResult(date) != nil
+}
+
+// StartDaily records the attempt at a day's challenge. Each day allows one
+// attempt, so it returns false if that day was already started.
+func (t *Tracker) StartDaily(date string, number int, sessionID string) bool {
+	if t.HasDailyAttempt(date) {
+		return false
+	}
+	daily := t.daily()
+	daily.History = append(daily.History, &DailyResult{
+		Date:      date,
+		Number:    number,
+		SessionID: sessionID,
+		StartedAt: time.Now(),
+	})
+	return true
+}
+
+// CompleteDaily records the result of a day's challenge and extends the
+// streak if the previous day was completed too
+func (t *Tracker) CompleteDaily(date string, session *SessionStats) {
+	result := t.dailyResult(date)
+	if result == nil || result.Completed {
+		return
+	}
+
+	daily := t.daily()
+	if day, err := time.Parse("2006-01-02", date); err == nil &&
+		daily.LastCompleted == day.AddDate(0, 0, -1).Format("2006-01-02") {
+		daily.CurrentStreak++
+	} else {
+		daily.CurrentStreak = 1
+	}
+	daily.LongestStreak = max(daily.LongestStreak, daily.CurrentStreak)
+	daily.LastCompleted = date
+
+	result.Completed = true
+	result.Grade = session.Grade
+	result.TotalTimeMs = session.TotalTimeMs
+	result.AvgEfficiency = session.AvgEfficiency
+	result.Streak = daily.CurrentStreak
+}
+
+// GetDaily returns the daily challenge history and streak. The current
+// streak reads as zero once a day has been missed.
+func (t *Tracker) GetDaily(today string) DailyStats {
+	daily := *t.daily()
+	if day, err := time.Parse("2006-01-02", today); err == nil &&
+		daily.LastCompleted != today &&
+		daily.LastCompleted != day.AddDate(0, 0, -1).Format("2006-01-02") {
+		daily.CurrentStreak = 0
+	}
+	return daily
+}
+
 // GetAchievements returns all achievements
 func (t *Tracker) GetAchievements() []Achievement {
 	return t.data.Achievements
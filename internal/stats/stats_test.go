@@ -0,0 +1,241 @@
+package stats
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/timlinux/macaco/internal/replay"
+)
+
+// newTestTracker creates a tracker with an empty stats file in a
+// temporary directory
+func newTestTracker(t *testing.T) *Tracker {
+	t.Helper()
+	tracker, err := NewTracker(filepath.Join(t.TempDir(), "stats.json"))
+	if err != nil {
+		t.Fatalf("NewTracker: %v", err)
+	}
+	return tracker
+}
+
+func TestDailyStreak(t *testing.T) {
+	tests := []struct {
+		name        string
+		completed   []string
+		today       string
+		wantCurrent int
+		wantLongest int
+	}{
+		{"one day", []string{"2026-02-10"}, "2026-02-10", 1, 1},
+		{"still going the next day", []string{"2026-02-10"}, "2026-02-11", 1, 1},
+		{"consecutive days", []string{"2026-02-09", "2026-02-10", "2026-02-11"}, "2026-02-11", 3, 3},
+		{"missed a day", []string{"2026-02-09", "2026-02-10"}, "2026-02-12", 0, 2},
+		{"restarted", []string{"2026-02-01", "2026-02-02", "2026-02-05"}, "2026-02-05", 1, 2},
+		{"across a month", []string{"2026-01-31", "2026-02-01"}, "2026-02-01", 2, 2},
+	}
+	for _, tt := range tests {
+		tracker := newTestTracker(t)
+		for i, date := range tt.completed {
+			if !tracker.StartDaily(date, i+1, "session-"+date) {
+				t.Fatalf("%s: couldn't start %s", tt.name, date)
+			}
+			tracker.CompleteDaily(date, &SessionStats{Grade: "A"})
+		}
+		daily := tracker.GetDaily(tt.today)
+		if daily.CurrentStreak != tt.wantCurrent || daily.LongestStreak != tt.wantLongest {
+			t.Errorf("%s: streak %d, longest %d; want %d, %d", tt.name,
+				daily.CurrentStreak, daily.LongestStreak, tt.wantCurrent, tt.wantLongest)
+		}
+	}
+}
+
+func TestDailyOneAttempt(t *testing.T) {
+	tracker := newTestTracker(t)
+	if tracker.HasDailyAttempt("2026-02-10") {
+		t.Error("attempt before starting")
+	}
+	if !tracker.StartDaily("2026-02-10", 41, "a") {
+		t.Fatal("first start refused")
+	}
+	if !tracker.HasDailyAttempt("2026-02-10") {
+		t.Error("no attempt after starting")
+	}
+	if tracker.StartDaily("2026-02-10", 41, "b") {
+		t.Error("second start of a day allowed")
+	}
+
+	// Completing twice only counts once
+	tracker.CompleteDaily("2026-02-10", &SessionStats{Grade: "B"})
+	tracker.CompleteDaily("2026-02-10", &SessionStats{Grade: "A"})
+	daily := tracker.GetDaily("2026-02-10")
+	if len(daily.History) != 1 || daily.History[0].Grade != "B" || daily.CurrentStreak != 1 {
+		t.Errorf("history %+v, streak %d", daily.History, daily.CurrentStreak)
+	}
+
+	// A day never started can't be completed
+	tracker.CompleteDaily("2026-02-11", &SessionStats{})
+	if tracker.HasDailyAttempt("2026-02-11") {
+		t.Error("completing created an attempt")
+	}
+}
+
+// bestRunSession returns a clean session of a round code with a replay of
+// every task
+func bestRunSession(id, code string, timeMs int64, tasks int) *SessionStats {
+	s := &SessionStats{SessionID: id, RoundCode: code, TotalTimeMs: timeMs}
+	for i := 0; i < tasks; i++ {
+		s.Tasks = append(s.Tasks, &TaskStats{
+			TaskID:  "task",
+			Success: true,
+			Replay:  &replay.Recording{Keys: "dw"},
+		})
+	}
+	return s
+}
+
+func TestIsClean(t *testing.T) {
+	tests := []struct {
+		name    string
+		session *SessionStats
+		want    bool
+	}{
+		{"all solved", &SessionStats{Tasks: []*TaskStats{{Success: true}, {Success: true}}}, true},
+		{"no tasks", &SessionStats{}, true},
+		{"failed task", &SessionStats{Tasks: []*TaskStats{{Success: true}, {Success: false}}}, false},
+		{"timed out task", &SessionStats{Tasks: []*TaskStats{{Success: true, TimedOut: true}}}, false},
+		{"timed out count", &SessionStats{TimedOut: 1, Tasks: []*TaskStats{{Success: true}}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.session.IsClean(); got != tt.want {
+				t.Errorf("IsClean() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasReplays(t *testing.T) {
+	missing := bestRunSession("s", "code", 1000, 2)
+	missing.Tasks[0].Replay = nil
+	tests := []struct {
+		name    string
+		session *SessionStats
+		want    bool
+	}{
+		{"every task", bestRunSession("s", "code", 1000, 2), true},
+		{"no tasks", &SessionStats{}, false},
+		{"one missing", missing, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.session.HasReplays(); got != tt.want {
+				t.Errorf("HasReplays() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBestRun(t *testing.T) {
+	const code = "MCC3-3F9A-mixed-3-O"
+	timedOut := bestRunSession("timed-out", code, 100, 3)
+	timedOut.TimedOut = 1
+	failed := bestRunSession("failed", code, 200, 3)
+	failed.Tasks[2].Success = false
+	noReplay := bestRunSession("no-replay", code, 300, 3)
+	noReplay.Tasks[0].Replay = nil
+
+	tracker := newTestTracker(t)
+	for _, s := range []*SessionStats{
+		bestRunSession("slow", code, 9000, 3),
+		timedOut,
+		failed,
+		noReplay,
+		bestRunSession("other-code", "MCC3-0001-mixed-3-O", 400, 3),
+		bestRunSession("fewer-tasks", code, 500, 2),
+		bestRunSession("no-code", "", 600, 3),
+		bestRunSession("fast", code, 5000, 3),
+		bestRunSession("slower", code, 7000, 3),
+	} {
+		tracker.RecordSession(s)
+	}
+
+	tests := []struct {
+		name  string
+		code  string
+		tasks int
+		want  string
+	}{
+		{"fastest clean run", code, 3, "fast"},
+		{"task count", code, 2, "fewer-tasks"},
+		{"other code", "MCC3-0001-mixed-3-O", 3, "other-code"},
+		{"no code", "", 3, ""},
+		{"unplayed code", "MCC3-FFFF-mixed-3-O", 3, ""},
+		{"no run of that length", code, 5, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			best := tracker.BestRun(tt.code, tt.tasks)
+			got := ""
+			if best != nil {
+				got = best.SessionID
+			}
+			if got != tt.want {
+				t.Errorf("BestRun(%q, %d) = %q, want %q", tt.code, tt.tasks, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBestRunOutlivesHistory(t *testing.T) {
+	const code = "MCC3-3F9A-mixed-3-O"
+	tracker := newTestTracker(t)
+	tracker.RecordSession(bestRunSession("best", code, 1000, 3))
+	tracker.RecordSession(bestRunSession("slower", code, 2000, 3))
+	for i := 0; i < 120; i++ {
+		tracker.RecordSession(bestRunSession(fmt.Sprintf("other-%d", i), "", 500, 3))
+	}
+
+	if best := tracker.BestRun(code, 3); best == nil || best.SessionID != "best" {
+		t.Fatalf("BestRun() = %v, want the run that left the history", best)
+	}
+
+	// It survives saving, and a faster run replaces it
+	if err := tracker.Save(); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := NewTracker(tracker.filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if best := reloaded.BestRun(code, 3); best == nil || best.SessionID != "best" {
+		t.Fatalf("BestRun() after reloading = %v, want best", best)
+	}
+	reloaded.RecordSession(bestRunSession("faster", code, 900, 3))
+	for i := 0; i < 120; i++ {
+		reloaded.RecordSession(bestRunSession(fmt.Sprintf("other-%d", i), "", 500, 3))
+	}
+	if best := reloaded.BestRun(code, 3); best == nil || best.SessionID != "faster" {
+		t.Errorf("BestRun() = %v, want faster", best)
+	}
+}
+
+func TestBestRunsLimit(t *testing.T) {
+	tracker := newTestTracker(t)
+	start := time.Date(2026, 2, 11, 9, 0, 0, 0, time.UTC)
+	for i := 0; i <= maxBestRuns; i++ {
+		s := bestRunSession(fmt.Sprintf("run-%d", i), fmt.Sprintf("code-%d", i), 1000, 1)
+		s.StartedAt = start.Add(time.Duration(i) * time.Minute)
+		tracker.RecordSession(s)
+	}
+	if got := len(tracker.data.BestRuns); got != maxBestRuns {
+		t.Errorf("%d best runs kept, want %d", got, maxBestRuns)
+	}
+	if _, ok := tracker.data.BestRuns["code-0"]; ok {
+		t.Error("the best run played longest ago was kept")
+	}
+	if _, ok := tracker.data.BestRuns[fmt.Sprintf("code-%d", maxBestRuns)]; !ok {
+		t.Error("the newest best run was dropped")
+	}
+}
@@ -8,9 +8,12 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/timlinux/macaco/internal/api"
+	"github.com/timlinux/macaco/internal/coach"
 	"github.com/timlinux/macaco/internal/config"
 	"github.com/timlinux/macaco/internal/game"
+	"github.com/timlinux/macaco/internal/replay"
 	"github.com/timlinux/macaco/internal/stats"
+	"github.com/timlinux/macaco/internal/vim"
 )
 
 // View represents the current screen
@@ -21,14 +24,34 @@ const (
 	ViewGame
 	ViewStats
 	ViewHelp
+	ViewReview
+	ViewReplay
+	ViewDrill
+	ViewLessons
+	ViewLesson
 )
 
+// maxCoachHabits is how many habits the stats screen shows
+const maxCoachHabits = 3
+
+// snapshotInterval is how often a round in play is saved
+const snapshotInterval = 5 * time.Second
+
+// countdownWarning is when the header's task and round clocks turn red
+const countdownWarning = 5 * time.Second
+
 // App is the main TUI application
 type App struct {
 	cfg       *config.Config
 	engine    *game.Engine
 	client    *api.Client
 	roundType string
+	roundCode string // Replays a shared round instead of roundType
+	text      string // Text to generate tasks from, "" for the round's own
+
+	// Rounds offered in the menu, nine to a page
+	roundTypes []string
+	menuPage   int
 
 	// State
 	view         View
@@ -37,7 +60,35 @@ type App struct {
 	matchStatus  game.MatchStatus
 	showHint     bool
 	hintLevel    int
+	showReview   bool
+	reviewIndex  int
 	sessionStats *stats.SessionStats
+	coachReport  *coach.Report
+	menuMessage  string
+	loadWarning  string // Tasks, rounds or packs that failed to load
+	enteringCode bool
+	codeInput    string
+	saved        *game.SessionSnapshot // Unfinished round the menu offers to resume
+	lastSave     time.Time
+	player       *replay.Player // Replay of the reviewed task
+	replayTask   *game.Task
+	watchOptimal bool // Replaying the optimal solution rather than the player's run
+	ghostOn      bool           // Race the best run of rounds with a code
+	ghost        *game.Ghost    // Best run of the round in play
+	ghostPlayer  *replay.Player // Ghost's run of the current task
+	ghostIndex   int            // Task the ghost player is for
+
+	// Drill picker
+	drillChoices    []drillChoice // Drills the picker offers
+	drillIndex      int           // Drill picked
+	drillSolves     int           // Clean solves in a row to master it
+	drillEfficiency float64       // Efficiency a solve needs to be clean
+
+	// Lessons
+	lessonUnits []game.UnitStatus // Units of the curriculum and progress on them
+	lessonIndex int               // Unit picked
+	lessonUnit  *game.Unit        // Unit being read
+	lessonPage  int               // Page of the unit shown
 
 	// UI state
 	styles     *Styles
@@ -48,14 +99,34 @@ type App struct {
 
 // NewApp creates a new TUI application
 func NewApp(cfg *config.Config, engine *game.Engine, client *api.Client, roundType string) *App {
-	return &App{
-		cfg:       cfg,
-		engine:    engine,
-		client:    client,
-		roundType: roundType,
-		view:      ViewMenu,
-		styles:    NewStyles(GetTheme(cfg.Theme)),
+	a := &App{
+		cfg:         cfg,
+		engine:      engine,
+		client:      client,
+		roundType:   roundType,
+		roundTypes:  menuRoundTypes(engine, client),
+		loadWarning: loadWarning(engine),
+		view:        ViewMenu,
+		showReview:  cfg.ShowReview,
+		styles:      NewStyles(GetTheme(cfg.Theme)),
+	}
+	a.checkSavedSession()
+	return a
+}
+
+// loadWarning summarises what failed to load into the task database
+func loadWarning(engine *game.Engine) string {
+	if engine == nil {
+		return ""
 	}
+	problems := engine.TaskProblems()
+	switch len(problems) {
+	case 0:
+		return ""
+	case 1:
+		return "Left out: " + problems[0].Error()
+	}
+	return fmt.Sprintf("Left out: %v (and %d more, see GET /api/v1/packs)", problems[0], len(problems)-1)
 }
 
 // Run starts the TUI application
@@ -88,13 +159,24 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		a.width = msg.Width
 		a.height = msg.Height
+		if a.session != nil {
+			a.session.SetViewportHeight(a.bufferViewportHeight())
+		}
 		return a, nil
 
 	case tea.KeyMsg:
 		return a.handleKeyPress(msg)
 
 	case tickMsg:
+		if (a.view == ViewReplay || a.view == ViewLesson) && a.player != nil && !a.lastUpdate.IsZero() {
+			a.player.Advance(time.Time(msg).Sub(a.lastUpdate))
+		}
 		a.lastUpdate = time.Time(msg)
+		a.checkTime()
+		a.advanceGhost()
+		if a.view == ViewGame && a.lastUpdate.Sub(a.lastSave) >= snapshotInterval {
+			a.saveSession()
+		}
 		return a, tickCmd()
 
 	case completeTaskMsg:
@@ -113,6 +195,9 @@ func (a *App) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	// Global keys
 	switch key {
 	case "ctrl+c":
+		if a.view == ViewGame {
+			a.saveSession()
+		}
 		return a, tea.Quit
 	}
 
@@ -125,6 +210,16 @@ func (a *App) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return a.handleStatsKeys(key)
 	case ViewHelp:
 		return a.handleHelpKeys(key)
+	case ViewReview:
+		return a.handleReviewKeys(key)
+	case ViewReplay:
+		return a.handleReplayKeys(key)
+	case ViewDrill:
+		return a.handleDrillKeys(key)
+	case ViewLessons:
+		return a.handleLessonsKeys(key)
+	case ViewLesson:
+		return a.handleLessonKeys(key)
 	}
 
 	return a, nil
@@ -132,22 +227,44 @@ func (a *App) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 // handleMenuKeys handles keys in menu view
 func (a *App) handleMenuKeys(key string) (tea.Model, tea.Cmd) {
+	if a.enteringCode {
+		return a.handleCodeKeys(key)
+	}
+
+	a.roundCode = ""
+	if len(key) == 1 && key[0] >= '1' && key[0] <= '9' {
+		if i := a.menuPage*roundsPerPage + int(key[0]-'1'); i < len(a.roundTypes) {
+			a.roundType = a.roundTypes[i]
+			a.startGame()
+		}
+		return a, nil
+	}
+
 	switch key {
-	case "1":
-		a.roundType = "beginner"
-		a.startGame()
-	case "2":
-		a.roundType = "intermediate"
-		a.startGame()
-	case "3":
-		a.roundType = "advanced"
-		a.startGame()
-	case "4":
-		a.roundType = "expert"
-		a.startGame()
-	case "5":
-		a.roundType = "mixed"
-		a.startGame()
+	case "n", "right":
+		if a.menuPage < a.menuPages()-1 {
+			a.menuPage++
+		}
+	case "p", "left":
+		if a.menuPage > 0 {
+			a.menuPage--
+		}
+	case "r":
+		if a.saved != nil {
+			a.resumeGame()
+		}
+	case "c":
+		a.enteringCode = true
+		a.codeInput = ""
+		a.menuMessage = ""
+	case "t":
+		a.text = nextText(a.text)
+	case "g":
+		a.ghostOn = !a.ghostOn
+	case "d":
+		a.openDrillPicker()
+	case "l":
+		a.openLessons()
 	case "q":
 		return a, tea.Quit
 	case "?":
@@ -174,6 +291,12 @@ func (a *App) handleGameKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "ctrl+p":
 		a.togglePause()
 		return a, nil
+	case "ctrl+o":
+		a.showReview = !a.showReview
+		return a, nil
+	case "ctrl+x":
+		a.endDrill()
+		return a, nil
 	case "?":
 		a.view = ViewHelp
 		return a, nil
@@ -186,12 +309,12 @@ func (a *App) handleGameKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 	// Process vim keys
 	if a.session != nil {
-		// Convert tea key to vim key
-		vimKey := a.convertKey(msg)
-		if vimKey != "" {
+		// Convert tea key to vim keys: pasted or fast input arrives as
+		// several runes at once
+		for _, vimKey := range a.convertKey(msg) {
 			a.matchStatus = a.session.ProcessKey(vimKey)
 
-			// Check for task completion
+			// Check for task completion, dropping any keys after it
 			if a.matchStatus == game.MatchComplete {
 				return a, tea.Tick(time.Duration(a.cfg.AutoAdvanceDelay)*time.Millisecond, func(t time.Time) tea.Msg {
 					return completeTaskMsg{}
@@ -202,6 +325,54 @@ func (a *App) handleGameKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 	return a, nil
 }
+// roundsPerPage is how many round types the menu shows at once, one for
+// each digit key
+const roundsPerPage = 9
+
+// menuRoundTypes returns the round types to offer in the menu
+func menuRoundTypes(engine *game.Engine, client *api.Client) []string {
+	var roundTypes []string
+	if engine != nil {
+		roundTypes = engine.GetRoundTypes()
+	} else if client != nil {
+		roundTypes, _ = client.GetRoundTypes()
+	}
+	return roundTypes
+}
+
+// menuPages returns how many pages the menu's round types take
+func (a *App) menuPages() int {
+	return max((len(a.roundTypes)+roundsPerPage-1)/roundsPerPage, 1)
+}
+
+// menuPageRounds returns the round types on the menu page shown
+func (a *App) menuPageRounds() []string {
+	start := min(a.menuPage*roundsPerPage, len(a.roundTypes))
+	return a.roundTypes[start:min(start+roundsPerPage, len(a.roundTypes))]
+}
+
+// handleCodeKeys handles typing a round code in the menu
+func (a *App) handleCodeKeys(key string) (tea.Model, tea.Cmd) {
+	switch key {
+	case "esc":
+		a.enteringCode = false
+	case "enter":
+		a.enteringCode = false
+		if a.codeInput != "" {
+			a.roundCode = a.codeInput
+			a.startGame()
+		}
+	case "backspace":
+		if a.codeInput != "" {
+			a.codeInput = a.codeInput[:len(a.codeInput)-1]
+		}
+	default:
+		if len(key) == 1 && key != " " {
+			a.codeInput += strings.ToUpper(key)
+		}
+	}
+	return a, nil
+}
 
 // completeTaskMsg is sent when a task should be completed
 type completeTaskMsg struct{}
@@ -211,6 +382,15 @@ func (a *App) handleStatsKeys(key string) (tea.Model, tea.Cmd) {
 	switch key {
 	case "enter", " ":
 		a.view = ViewMenu
+		a.checkSavedSession()
+		if a.session != nil && a.session.IsLesson() {
+			a.openLessons()
+		}
+	case "r":
+		if a.session != nil && len(a.session.Reviews()) > 0 {
+			a.reviewIndex = 0
+			a.view = ViewReview
+		}
 	case "q":
 		return a, tea.Quit
 	}
@@ -230,38 +410,64 @@ func (a *App) handleHelpKeys(key string) (tea.Model, tea.Cmd) {
 	return a, nil
 }
 
-// convertKey converts a tea key to a vim key string
-func (a *App) convertKey(msg tea.KeyMsg) string {
+// convertKey converts a tea key to vim keys, one per rune when several
+// runes arrive together
+func (a *App) convertKey(msg tea.KeyMsg) []vim.Key {
 	switch msg.Type {
 	case tea.KeyEsc:
-		return "esc"
+		return []vim.Key{vim.KeyEsc}
 	case tea.KeyEnter:
-		return "enter"
+		return []vim.Key{vim.KeyEnter}
 	case tea.KeyBackspace:
-		return "backspace"
+		return []vim.Key{vim.KeyBackspace}
 	case tea.KeySpace:
-		return " "
+		return []vim.Key{" "}
 	case tea.KeyTab:
-		return "\t"
-	case tea.KeyCtrlR:
-		return "\x12"
+		return []vim.Key{vim.KeyTab}
+	case tea.KeyRunes:
+		keys := make([]vim.Key, 0, len(msg.Runes))
+		for _, r := range msg.Runes {
+			keys = append(keys, vim.Key(string(r)))
+		}
+		return keys
 	default:
-		if msg.Type == tea.KeyRunes {
-			return string(msg.Runes)
+		key, err := vim.ParseKey(msg.String())
+		if err != nil {
+			return nil
 		}
-		return msg.String()
+		return []vim.Key{key}
 	}
 }
 
 // startGame starts a new game session
 func (a *App) startGame() {
+	a.menuMessage = ""
 	if a.engine != nil {
-		a.session = a.engine.CreateSession(a.roundType)
+		var session *game.Session
+		var err error
+		if a.roundCode != "" {
+			session, err = a.engine.CreateSessionFromCode(a.roundCode)
+		} else {
+			session, err = a.engine.CreateSessionWithText(a.roundType, a.text)
+		}
+		if err != nil {
+			a.menuMessage = err.Error()
+			return
+		}
+		a.session = session
 		a.sessionID = a.session.ID
+		a.session.SetViewportHeight(a.bufferViewportHeight())
 	} else if a.client != nil {
-		resp, err := a.client.CreateSession(a.roundType)
+		var resp *api.SessionResponse
+		var err error
+		if a.roundCode != "" {
+			resp, err = a.client.CreateSessionFromCode(a.roundCode)
+		} else {
+			resp, err = a.client.CreateSessionWithText(a.roundType, a.text)
+		}
 		if err != nil {
-			// Handle error - for now just return to menu
+			// Stay in the menu and say why
+			a.menuMessage = err.Error()
 			return
 		}
 		a.sessionID = resp.SessionID
@@ -269,6 +475,11 @@ func (a *App) startGame() {
 		// In client mode, we'd need to sync state from server
 	}
 
+	a.loadGhost()
+
+	// The new round takes the place of any saved one
+	a.saved = nil
+	a.lastSave = time.Time{}
 	a.view = ViewGame
 	a.matchStatus = game.MatchNone
 	a.showHint = false
@@ -287,8 +498,7 @@ func (a *App) completeTask() {
 	a.hintLevel = 0
 
 	if a.session.IsComplete() {
-		a.sessionStats, _ = a.engine.GetSessionStats(a.sessionID)
-		a.view = ViewStats
+		a.showRoundStats()
 	}
 }
 
@@ -309,13 +519,84 @@ func (a *App) skipTask() {
 			a.hintLevel = 0
 
 			if a.session.IsComplete() {
-				a.sessionStats, _ = a.engine.GetSessionStats(a.sessionID)
-				a.view = ViewStats
+				a.showRoundStats()
 			}
 		}
 	}
 }
 
+// checkTime moves on when the current task or the round runs out of time
+func (a *App) checkTime() {
+	if a.view != ViewGame || a.session == nil || !a.session.CheckTime() {
+		return
+	}
+
+	a.matchStatus = game.MatchNone
+	a.showHint = false
+	a.hintLevel = 0
+
+	if a.session.IsComplete() {
+		a.showRoundStats()
+	}
+}
+
+// showRoundStats shows the stats of the finished round, and drops its
+// saved state
+func (a *App) showRoundStats() {
+	a.engine.RecordSession(a.sessionID)
+	a.sessionStats, _ = a.engine.GetSessionStats(a.sessionID)
+	a.coachReport = a.engine.GetCoachReport()
+	a.saveSession()
+	a.view = ViewStats
+}
+
+// saveSession saves the round so it can be resumed after a crash or quit
+func (a *App) saveSession() {
+	if a.engine == nil || a.session == nil {
+		return
+	}
+	a.engine.SaveSession(a.sessionID)
+	a.lastSave = time.Now()
+}
+
+// checkSavedSession looks for an unfinished round to offer in the menu,
+// saying why if one had to be discarded
+func (a *App) checkSavedSession() {
+	a.saved = nil
+	if a.engine == nil {
+		return
+	}
+	saved, err := a.engine.SavedSession()
+	if err != nil {
+		a.menuMessage = err.Error()
+		return
+	}
+	a.saved = saved
+}
+
+// resumeGame resumes the saved round, paused so the player can get ready
+func (a *App) resumeGame() {
+	a.menuMessage = ""
+	a.saved = nil
+	session, err := a.engine.RestoreSavedSession()
+	if err != nil {
+		a.menuMessage = err.Error()
+		return
+	}
+
+	a.session = session
+	a.sessionID = session.ID
+	a.session.SetViewportHeight(a.bufferViewportHeight())
+	a.session.Pause()
+	a.lastSave = time.Now()
+	a.loadGhost()
+
+	a.view = ViewGame
+	a.matchStatus = a.session.CheckMatch()
+	a.showHint = false
+	a.hintLevel = 0
+}
+
 // toggleHint toggles hint display
 func (a *App) toggleHint() {
 	if a.showHint {
@@ -325,11 +606,11 @@ func (a *App) toggleHint() {
 			a.hintLevel = 0
 		}
 	} else {
+		if a.session != nil && !a.session.UseHint() {
+			return
+		}
 		a.showHint = true
 		a.hintLevel = 0
-		if a.session != nil {
-			a.session.UseHint()
-		}
 	}
 }
 
@@ -355,6 +636,16 @@ func (a *App) View() string {
 		return a.renderStats()
 	case ViewHelp:
 		return a.renderHelp()
+	case ViewReview:
+		return a.renderReview()
+	case ViewReplay:
+		return a.renderReplay()
+	case ViewDrill:
+		return a.renderDrillPicker()
+	case ViewLessons:
+		return a.renderLessons()
+	case ViewLesson:
+		return a.renderLesson()
 	default:
 		return ""
 	}
@@ -370,19 +661,31 @@ func (a *App) renderMenu() string {
 
 	subtitle := a.styles.Subtitle.Render("Master vim motions through competitive practice")
 
-	menu := strings.Join([]string{
+	lines := []string{
 		"",
 		"Select a round type:",
 		"",
-		"  [1] Beginner     - Basic motions and operations",
-		"  [2] Intermediate - Counts and text objects",
-		"  [3] Advanced     - Complex combinations",
-		"  [4] Expert       - Multi-step transformations",
-		"  [5] Mixed        - Random difficulty",
+	}
+	for i, roundType := range a.menuPageRounds() {
+		lines = append(lines, fmt.Sprintf("  [%d] %s", i+1, a.menuEntry(roundType)))
+	}
+	if pages := a.menuPages(); pages > 1 {
+		lines = append(lines, fmt.Sprintf("  [n/p] More rounds - page %d of %d", a.menuPage+1, pages))
+	}
+	if a.saved != nil {
+		lines = append(lines, "  [r] Resume round - "+savedEntry(a.saved))
+	}
+	lines = append(lines,
+		"  [c] Round code   - Replay a round shared with you",
+		"  [t] Text         - "+a.textEntry(),
+		"  [g] Ghost        - "+a.ghostEntry(),
+		"  [d] Drill        - Practise one kind of task until mastered",
+		"  [l] Lessons      - Learn vim from zero, one unit at a time",
 		"",
 		"  [?] Help",
 		"  [q] Quit",
-	}, "\n")
+	)
+	menu := strings.Join(lines, "\n")
 
 	content := lipgloss.JoinVertical(
 		lipgloss.Center,
@@ -390,6 +693,18 @@ func (a *App) renderMenu() string {
 		subtitle,
 		a.styles.Content.Render(menu),
 	)
+	if a.enteringCode {
+		prompt := "Round code: " + a.codeInput + "█"
+		content = lipgloss.JoinVertical(lipgloss.Center, content,
+			a.styles.Content.Render(prompt),
+			a.styles.Hint.Render("ENTER to start, ESC to cancel"))
+	}
+	if a.menuMessage != "" {
+		content = lipgloss.JoinVertical(lipgloss.Center, content, a.styles.StatusError.Render(a.menuMessage))
+	}
+	if a.loadWarning != "" {
+		content = lipgloss.JoinVertical(lipgloss.Center, content, a.styles.Hint.Render(a.loadWarning))
+	}
 
 	return lipgloss.Place(
 		a.width, a.height,
@@ -398,6 +713,96 @@ func (a *App) renderMenu() string {
 	)
 }
 
+// savedEntry describes an unfinished round for the menu
+func savedEntry(snap *game.SessionSnapshot) string {
+	s := snap.Session
+	round := s.RoundType
+	if s.Daily != nil {
+		round = s.Daily.String()
+	} else if s.Drill != nil {
+		return fmt.Sprintf("drill of %s, %d clean in a row, saved %s", s.Drill.String(), s.Drill.Streak, snap.SavedAt.Format("Jan 2 15:04"))
+	} else if s.Lesson != nil {
+		round = "lesson " + s.Lesson.Title
+	}
+	return fmt.Sprintf("%s, task %d/%d, saved %s", round, s.CurrentIndex+1, s.TotalTasks, snap.SavedAt.Format("Jan 2 15:04"))
+}
+
+// nextText returns the text the menu switches to after text
+func nextText(text string) string {
+	switch text {
+	case "":
+		return game.TextCode
+	case game.TextCode:
+		return game.TextLocal
+	case game.TextLocal:
+		return game.TextBooks
+	}
+	return ""
+}
+
+// textEntry describes what generated tasks are made from for the menu
+func (a *App) textEntry() string {
+	switch a.text {
+	case game.TextCode:
+		return "Code: Go, Python, JSON, shell and HTML"
+	case game.TextLocal:
+		if a.engine == nil {
+			return "Your files, from corpus_paths"
+		}
+		return fmt.Sprintf("Your files: %d from corpus_paths", len(a.engine.GetTextSources(game.TextLocal)))
+	case game.TextBooks:
+		if a.engine == nil {
+			return "Your imported books"
+		}
+		return fmt.Sprintf("Your imported books: %d", len(a.engine.GetTextSources(game.TextBooks)))
+	}
+	return "Each round's own, prose unless it says"
+}
+
+// menuEntry describes a round type for the menu
+func (a *App) menuEntry(roundType string) string {
+	switch roundType {
+	case game.RoundReview:
+		return "Review       - Commands that are due or weak"
+	case game.RoundDaily:
+		return a.dailyMenuEntry()
+	}
+
+	def, ok := game.DefaultRounds()[roundType]
+	if a.engine != nil {
+		def, ok = a.engine.GetRound(roundType)
+	}
+	description := def.Description
+	if !ok || description == "" {
+		description = def.Name
+	}
+
+	if policy := def.TimePolicy(); ok && policy.IsTimed() {
+		description += " (" + policy.String() + ")"
+	}
+
+	label := strings.ReplaceAll(roundType, "_", " ")
+	label = strings.ToUpper(label[:1]) + label[1:]
+	return fmt.Sprintf("%-12s - %s", label, description)
+}
+
+// dailyMenuEntry describes today's daily challenge for the menu
+func (a *App) dailyMenuEntry() string {
+	entry := "Daily        - " + game.DailyChallengeFor(time.Now()).String()
+	if a.engine == nil {
+		return entry
+	}
+
+	status := a.engine.GetDailyStatus()
+	if status.Played {
+		entry += ", played"
+	}
+	if status.Stats.CurrentStreak > 0 {
+		entry += fmt.Sprintf(", streak %d", status.Stats.CurrentStreak)
+	}
+	return entry
+}
+
 // renderGame renders the game view
 func (a *App) renderGame() string {
 	if a.session == nil {
@@ -423,17 +828,26 @@ func (a *App) renderGame() string {
 	var content strings.Builder
 
 	// Previous task (dimmed) - only show if we have completed at least one task
+	reviewHeight := 0
 	if a.session.CurrentIndex > 0 {
 		if prev := a.session.PreviousTask(); prev != nil {
 			var prevText string
 			if prev.IsMotionTask() {
-				prevText = fmt.Sprintf("Previous: %q (cursor moved)", prev.Initial)
+				prevText = fmt.Sprintf("Previous: %s (cursor moved)", previewText(prev.Initial))
 			} else {
-				prevText = fmt.Sprintf("Previous: %q -> %q", prev.Initial, prev.Desired)
+				prevText = fmt.Sprintf("Previous: %s -> %s", previewText(prev.Initial), previewText(prev.Desired))
 			}
 			content.WriteString(a.styles.PreviousTask.Width(a.width).Align(lipgloss.Center).Render(prevText))
 			content.WriteString("\n")
 		}
+		if a.showReview {
+			if review := a.session.Review(a.session.CurrentIndex - 1); review != nil {
+				panel := a.styles.PreviousTask.Align(lipgloss.Left).Render(a.renderReviewPanel(review))
+				content.WriteString(lipgloss.PlaceHorizontal(a.width, lipgloss.Center, panel))
+				content.WriteString("\n")
+				reviewHeight = lipgloss.Height(panel)
+			}
+		}
 	}
 	content.WriteString("\n")
 
@@ -444,8 +858,9 @@ func (a *App) renderGame() string {
 		bufferText := a.session.BufferText()
 		cursorIdx := a.session.CursorIndex()
 
-		// Build display buffer with cursor and optional highlighting
-		displayBuffer := a.renderBufferWithHighlight(bufferText, cursorIdx, task)
+		// Build display buffer with cursor and optional highlighting, with
+		// the ghost's beside it when racing one
+		displayBuffer := a.withGhost(a.renderBufferWithHighlight(bufferText, cursorIdx, task), task)
 
 		// Check if this is a motion task (text stays same, only cursor moves)
 		isMotionTask := task.IsMotionTask()
@@ -455,23 +870,20 @@ func (a *App) renderGame() string {
 			// For motion tasks, show:
 			// 1. Current buffer with cursor position
 			// 2. The same text below (reference) with caret showing target
-			targetPos := task.CursorEnd
-
-			// Build caret line: spaces up to target, then ^
-			// Must be same length as desired text for alignment
-			caretLine := strings.Repeat(" ", targetPos) + "^"
-
-			// Combine reference text and caret as a single block so they stay aligned
-			referenceBlock := task.Desired + "\n" + caretLine
+			referenceBlock := caretBlock(task)
 
-			// Instruction for motion task
+			// Instruction for motion task, with the line and column when
+			// there are several lines
 			instruction := "Move your cursor to the caret (^)"
+			if task.IsMultiline() {
+				instruction += " at " + task.EndPosition().String()
+			}
 
 			taskDisplay = lipgloss.JoinVertical(
 				lipgloss.Center,
 				displayBuffer,
 				"",
-				a.styles.CurrentTask.Foreground(a.styles.Theme.Dimmed).Render(referenceBlock),
+				a.blockStyle(a.styles.CurrentTask, task).Foreground(a.styles.Theme.Dimmed).Render(referenceBlock),
 				"",
 				a.styles.Subtitle.Foreground(a.styles.Theme.Dimmed).Render(instruction),
 			)
@@ -492,7 +904,7 @@ func (a *App) renderGame() string {
 			)
 		}
 
-		content.WriteString(lipgloss.Place(a.width, contentHeight-6, lipgloss.Center, lipgloss.Center, taskDisplay))
+		content.WriteString(lipgloss.Place(a.width, max(contentHeight-6-reviewHeight, 0), lipgloss.Center, lipgloss.Center, taskDisplay))
 		content.WriteString("\n")
 
 		// Hint (if enabled)
@@ -507,9 +919,9 @@ func (a *App) renderGame() string {
 	if next := a.session.NextTask(); next != nil {
 		var nextText string
 		if next.IsMotionTask() {
-			nextText = fmt.Sprintf("Next: %q (move cursor)", next.Initial)
+			nextText = fmt.Sprintf("Next: %s (move cursor)", previewText(next.Initial))
 		} else {
-			nextText = fmt.Sprintf("Next: %q -> %q", next.Initial, next.Desired)
+			nextText = fmt.Sprintf("Next: %s -> %s", previewText(next.Initial), previewText(next.Desired))
 		}
 		content.WriteString(a.styles.NextTask.Width(a.width).Align(lipgloss.Center).Render(nextText))
 	}
@@ -534,10 +946,27 @@ func (a *App) renderHeader() string {
 
 	// Progress - prominent display like baboon
 	progress := fmt.Sprintf("Task %d/%d", a.session.CurrentIndex+1, a.session.TotalTasks)
+	if a.session.Drill != nil {
+		progress = drillProgress(a.session.Drill, a.session.CurrentIndex+1)
+	}
+	if a.session.HintsRemaining != game.UnlimitedHints {
+		progress += fmt.Sprintf(" | Hints %d", a.session.HintsRemaining)
+	}
 
-	// Timer
-	elapsed := a.session.ElapsedTime()
-	timer := fmt.Sprintf("%02d:%02d", int(elapsed.Minutes()), int(elapsed.Seconds())%60)
+	// Timer, counting down when tasks are timed
+	timer := formatDuration(a.session.ElapsedTime())
+	if remaining, ok := a.session.TaskTimeRemaining(); ok {
+		timer = a.countdown(remaining)
+	}
+
+	// Round clock, then the overtime played past it
+	if remaining, ok := a.session.RoundTimeRemaining(); ok {
+		if overtime := a.session.Overtime(); overtime > 0 {
+			progress += " | " + a.styles.StatusError.Render("Overtime +"+formatDuration(overtime))
+		} else {
+			progress += " | Round " + a.countdown(remaining)
+		}
+	}
 
 	// Category
 	category := ""
@@ -545,15 +974,32 @@ func (a *App) renderHeader() string {
 		category = string(task.Category)
 	}
 
+	// Cursor position, which multi-line tasks give their targets in
+	position := ""
+	if task != nil && task.IsMultiline() {
+		p := game.TextPosition(a.session.BufferText(), a.session.CursorIndex())
+		position = fmt.Sprintf("Ln %d, Col %d ", p.Line+1, p.Col+1)
+	}
+
 	// Paused indicator
 	paused := ""
 	if a.session.IsPaused() {
 		paused = a.styles.StatusError.Render(" [PAUSED] ")
 	}
 
-	left := fmt.Sprintf("MoCaCo | %s | %s", a.roundType, category)
+	round := a.session.RoundType
+	if a.session.Daily != nil {
+		round = a.session.Daily.String()
+	} else if a.session.Drill != nil {
+		round = "drill"
+	} else if a.session.Lesson != nil {
+		round = "lesson: " + a.session.Lesson.Title
+	} else if a.session.RoundCode != "" {
+		round = a.session.RoundCode
+	}
+	left := fmt.Sprintf("MoCaCo | %s | %s", round, category)
 	center := progress
-	right := fmt.Sprintf("%s %s %s", timer, modeStr, paused)
+	right := fmt.Sprintf("%s%s %s %s", position, timer, modeStr, paused)
 
 	// Calculate spacing for three-column layout
 	headerWidth := a.width - 4
@@ -579,6 +1025,16 @@ func (a *App) renderHeader() string {
 	)
 }
 
+// countdown formats the time left on a clock, rounding up so it reaches
+// 00:00 as time runs out, and highlights its last seconds
+func (a *App) countdown(remaining time.Duration) string {
+	clock := formatDuration((remaining + time.Second - 1).Truncate(time.Second))
+	if remaining <= countdownWarning {
+		return a.styles.StatusError.Render(clock)
+	}
+	return clock
+}
+
 // renderFooter renders the footer bar
 func (a *App) renderFooter() string {
 	hints := []string{
@@ -586,8 +1042,12 @@ func (a *App) renderFooter() string {
 		a.styles.HelpKey.Render("Ctrl+S") + " Skip",
 		a.styles.HelpKey.Render("Ctrl+H") + " Hint",
 		a.styles.HelpKey.Render("Ctrl+P") + " Pause",
-		a.styles.HelpKey.Render("?") + " Help",
+		a.styles.HelpKey.Render("Ctrl+O") + " Review",
+	}
+	if a.session != nil && a.session.IsDrill() {
+		hints = append(hints, a.styles.HelpKey.Render("Ctrl+X")+" End drill")
 	}
+	hints = append(hints, a.styles.HelpKey.Render("?")+" Help")
 
 	return a.styles.Footer.Width(a.width).Render(strings.Join(hints, "  |  "))
 }
@@ -615,20 +1075,44 @@ func (a *App) renderStats() string {
 	var b strings.Builder
 
 	// Title
+	heading := "ROUND COMPLETE!"
+	if a.session != nil && a.session.Daily != nil {
+		heading = fmt.Sprintf("DAILY #%d COMPLETE! (%s)", a.session.Daily.Number, a.session.Daily.Date)
+	}
+	drill := a.session != nil && a.session.IsDrill()
+	if drill {
+		heading = "DRILL ENDED"
+		if a.session.Drill.Mastered {
+			heading = "DRILL MASTERED!"
+		}
+	}
+	lesson := a.session != nil && a.session.IsLesson()
+	if lesson {
+		heading = "UNIT NOT PASSED YET"
+		if a.session.Lesson.Passed {
+			heading = "UNIT PASSED!"
+		}
+	}
 	title := lipgloss.NewStyle().
 		Foreground(a.styles.Theme.Success).
 		Bold(true).
 		MarginBottom(1).
-		Render("ROUND COMPLETE!")
+		Render(heading)
 
 	b.WriteString(lipgloss.Place(a.width, 3, lipgloss.Center, lipgloss.Center, title))
 	b.WriteString("\n")
 
-	// Grade
-	grade := a.styles.GradeStyle(a.sessionStats.Grade).Render(
-		fmt.Sprintf("Grade: %s", a.sessionStats.Grade),
-	)
-	b.WriteString(lipgloss.Place(a.width, 1, lipgloss.Center, lipgloss.Center, grade))
+	// Grade, or for drills and lessons, which aren't graded, how they went
+	if drill {
+		b.WriteString(a.renderDrillSummary())
+	} else if lesson {
+		b.WriteString(a.renderLessonSummary())
+	} else {
+		grade := a.styles.GradeStyle(a.sessionStats.Grade).Render(
+			fmt.Sprintf("Grade: %s", a.sessionStats.Grade),
+		)
+		b.WriteString(lipgloss.Place(a.width, 1, lipgloss.Center, lipgloss.Center, grade))
+	}
 	b.WriteString("\n\n")
 
 	// Summary
@@ -642,6 +1126,29 @@ func (a *App) renderStats() string {
 	b.WriteString(lipgloss.Place(a.width, 1, lipgloss.Center, lipgloss.Center, summary))
 	b.WriteString("\n\n")
 
+	if points := a.renderPoints(); points != "" && !drill && !lesson {
+		b.WriteString(points)
+		b.WriteString("\n\n")
+	}
+
+	if a.session != nil && a.session.Daily != nil && a.engine != nil {
+		daily := a.engine.GetDailyStatus().Stats
+		streak := fmt.Sprintf("Daily streak: %d  |  Longest: %d", daily.CurrentStreak, daily.LongestStreak)
+		b.WriteString(lipgloss.Place(a.width, 1, lipgloss.Center, lipgloss.Center, streak))
+		b.WriteString("\n\n")
+	}
+
+	if a.session != nil && a.session.RoundCode != "" {
+		code := "Round code: " + a.session.RoundCode + "  (share it to race the same tasks)"
+		b.WriteString(lipgloss.Place(a.width, 1, lipgloss.Center, lipgloss.Center, code))
+		b.WriteString("\n\n")
+	}
+
+	if deltas := a.renderGhostDeltas(); deltas != "" {
+		b.WriteString(deltas)
+		b.WriteString("\n")
+	}
+
 	// Category breakdown
 	b.WriteString(a.styles.Title.Render("Category Breakdown"))
 	b.WriteString("\n")
@@ -657,12 +1164,46 @@ func (a *App) renderStats() string {
 		}
 	}
 
+	if a.coachReport != nil && len(a.coachReport.Habits) > 0 {
+		b.WriteString("\n")
+		b.WriteString(a.styles.Title.Render("Habits to Work On"))
+		b.WriteString("\n")
+		for _, habit := range a.coachReport.Top(maxCoachHabits) {
+			b.WriteString(fmt.Sprintf("  %-30s %d keys lost in %d session(s)\n", habit.Name, habit.Wasted, habit.Sessions))
+			if len(habit.Evidence) > 0 {
+				b.WriteString(a.styles.Label.Render("    e.g. " + habit.Evidence[0].String()))
+				b.WriteString("\n")
+			}
+			drill := "    Drill: " + habit.Drill.Description
+			if len(habit.Drill.Commands) > 0 {
+				drill += " (" + strings.Join(habit.Drill.Commands, ", ") + ")"
+			}
+			b.WriteString(a.styles.Hint.Render(drill))
+			b.WriteString("\n")
+		}
+	}
+
 	b.WriteString("\n")
-	b.WriteString(a.styles.Hint.Render("Press ENTER to continue"))
+	b.WriteString(a.styles.Hint.Render("Press ENTER to continue, R to review your solutions"))
 
 	return b.String()
 }
 
+// renderPoints renders the round's points and how they were made up
+func (a *App) renderPoints() string {
+	if a.sessionStats.Scoring == nil {
+		return ""
+	}
+	p := a.sessionStats.Points
+	total := fmt.Sprintf("Points: %.0f / %.0f (%.0f%%)", p.Points, p.MaxPoints, p.Percent())
+	breakdown := fmt.Sprintf("efficiency +%.0f  |  speed +%.0f  |  hints -%.0f  |  resets -%.0f  (weighted by difficulty)",
+		p.Efficiency, p.Speed, p.HintPenalty, p.ResetPenalty)
+	return lipgloss.JoinVertical(lipgloss.Center,
+		lipgloss.PlaceHorizontal(a.width, lipgloss.Center, a.styles.Title.Render(total)),
+		lipgloss.PlaceHorizontal(a.width, lipgloss.Center, a.styles.Label.Render(breakdown)),
+	)
+}
+
 // renderHelp renders the help view
 func (a *App) renderHelp() string {
 	var b strings.Builder
@@ -677,16 +1218,25 @@ GAME CONTROLS
   Ctrl+S    Skip current task
   Ctrl+H    Show/cycle hints
   Ctrl+P    Pause/resume timer
+  Ctrl+O    Show/hide the review of the previous task
+  Ctrl+X    End a drill before it's mastered
   Ctrl+C    Quit
 
 VIM BASICS
   h/j/k/l   Move cursor left/down/up/right
   w/b/e     Word motions
   0/$       Line start/end
+  {/}       Paragraph start/end
+  H/M/L     Screen top/middle/bottom
+  Ctrl+D/U  Scroll half page down/up
+  zz/zt/zb  Center/top/bottom cursor line
   i/a       Insert before/after cursor
   I/A       Insert at line start/end
   o/O       Open line below/above
   d/c/y     Delete/change/yank operators
+  gq/gw     Format text to textwidth
+  dd/p      Delete line/put it back
+  J         Join line below
   x         Delete character
   r         Replace character
   u         Undo
@@ -703,6 +1253,8 @@ FIND MOTIONS
   F{char}   Find character backward
   T{char}   Until character backward
 
+New to vim? Lessons ([l] in the menu) teach these a unit at a time.
+
 Press ESC or ? to close this help
 `
 	b.WriteString(helpText)
@@ -738,7 +1290,11 @@ func (a *App) renderBufferWithHighlight(text string, cursorIdx int, task *game.T
 
 			// Handle cursor position - show block cursor
 			if i == cursorIdx {
-				charStr = "█"
+				if r == '\n' {
+					charStr = "█\n"
+				} else {
+					charStr = "█"
+				}
 			}
 
 			// Apply appropriate color based on highlight type
@@ -769,20 +1325,25 @@ func (a *App) renderBufferWithHighlight(text string, cursorIdx int, task *game.T
 			result.WriteString("█")
 		}
 
-		return result.String()
+		return alignBlock(a.clipToViewport(result.String()))
 	}
 
 	// Buffer has been modified - no highlighting, just show with cursor
 	var displayBuffer string
 	if cursorIdx >= 0 && cursorIdx < len(runes) {
-		displayBuffer = string(runes[:cursorIdx]) + "█" + string(runes[cursorIdx+1:])
+		if runes[cursorIdx] == '\n' {
+			// Cursor past the end of a line in insert mode
+			displayBuffer = string(runes[:cursorIdx]) + "█" + string(runes[cursorIdx:])
+		} else {
+			displayBuffer = string(runes[:cursorIdx]) + "█" + string(runes[cursorIdx+1:])
+		}
 	} else if cursorIdx >= len(runes) && len(runes) > 0 {
 		displayBuffer = text + "█"
 	} else {
 		displayBuffer = text
 	}
 
-	return statusStyle.Render(displayBuffer)
+	return a.blockStyle(statusStyle, task).Render(a.clipToViewport(displayBuffer))
 }
 
 // renderDesiredWithHighlight renders the desired text with highlighting
@@ -825,7 +1386,107 @@ func (a *App) renderDesiredWithHighlight(task *game.Task) string {
 		}
 	}
 
-	return result.String()
+	// Only the live buffer scrolls with the viewport; the target is shown
+	// whole
+	return alignBlock(result.String())
+}
+
+// blockStyle left-aligns the lines of a multi-line task within style, so
+// the lines keep their indentation and columns line up with the caret
+func (a *App) blockStyle(style lipgloss.Style, task *game.Task) lipgloss.Style {
+	if task.IsMultiline() {
+		return style.Align(lipgloss.Left)
+	}
+	return style
+}
+
+// alignBlock pads the lines of text to the same width, so centering the
+// text centers it as a block rather than line by line
+func alignBlock(text string) string {
+	lines := strings.Split(text, "\n")
+	if len(lines) == 1 {
+		return text
+	}
+	width := 0
+	for _, line := range lines {
+		width = max(width, lipgloss.Width(line))
+	}
+	for i, line := range lines {
+		lines[i] = line + strings.Repeat(" ", width-lipgloss.Width(line))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// caretBlock returns a motion task's text with a caret under the target
+// line, pointing at the target column
+func caretBlock(task *game.Task) string {
+	target := task.EndPosition()
+	lines := strings.Split(task.Desired, "\n")
+	width := 0
+	for _, line := range lines {
+		width = max(width, len([]rune(line)))
+	}
+
+	var b strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(line)
+		if i == target.Line {
+			// Pad the caret line to the width of the text so it stays aligned
+			caret := strings.Repeat(" ", target.Col) + "^"
+			b.WriteString("\n" + caret + strings.Repeat(" ", max(width-len(caret), 0)))
+		}
+	}
+	return b.String()
+}
+
+// previewText quotes text for the previous and next task lines, showing
+// only the first line of a multi-line text
+func previewText(text string) string {
+	lines := strings.Split(text, "\n")
+	if len(lines) == 1 {
+		return fmt.Sprintf("%q", text)
+	}
+	return fmt.Sprintf("%q (+%d lines)", lines[0], len(lines)-1)
+}
+
+// bufferViewportHeight returns how many buffer lines fit on screen. The
+// buffer and the desired text share the space between header and footer.
+func (a *App) bufferViewportHeight() int {
+	height := (a.height-14)/2 - 4
+	if height < 3 {
+		height = 3
+	}
+	return height
+}
+
+// clipToViewport keeps only the lines inside the session viewport
+func (a *App) clipToViewport(text string) string {
+	if a.session == nil {
+		return text
+	}
+	top, height := a.session.Viewport()
+	return clipLines(text, top, height)
+}
+
+// clipLines returns the height lines of text from line top, or all of it
+// if height is 0
+func clipLines(text string, top, height int) string {
+	lines := strings.Split(text, "\n")
+	if height <= 0 || (top == 0 && len(lines) <= height) {
+		return text
+	}
+
+	end := top + height
+	if end > len(lines) {
+		end = len(lines)
+	}
+	if top > end {
+		top = end
+	}
+	return strings.Join(lines[top:end], "\n")
 }
 
 func formatDuration(d time.Duration) string {
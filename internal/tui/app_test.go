@@ -0,0 +1,71 @@
+package tui
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/timlinux/macaco/internal/vim"
+)
+
+func TestConvertKey(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  tea.KeyMsg
+		want []vim.Key
+	}{
+		{"escape", tea.KeyMsg{Type: tea.KeyEsc}, []vim.Key{vim.KeyEsc}},
+		{"enter", tea.KeyMsg{Type: tea.KeyEnter}, []vim.Key{vim.KeyEnter}},
+		{"space", tea.KeyMsg{Type: tea.KeySpace}, []vim.Key{" "}},
+		{"rune", tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("w")}, []vim.Key{"w"}},
+		{"pasted runes", tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("dw")}, []vim.Key{"d", "w"}},
+		{"less than", tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("<")}, []vim.Key{"<"}},
+		{"ctrl", tea.KeyMsg{Type: tea.KeyCtrlR}, []vim.Key{"<C-r>"}},
+		{"unknown", tea.KeyMsg{Type: tea.KeyF5}, nil},
+	}
+	a := &App{}
+	for _, tt := range tests {
+		if got := a.convertKey(tt.msg); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: convertKey = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMenuPages(t *testing.T) {
+	var rounds []string
+	for i := 1; i <= 20; i++ {
+		rounds = append(rounds, fmt.Sprintf("round%02d", i))
+	}
+	tests := []struct {
+		name  string
+		keys  []string
+		page  []string
+		pages int
+	}{
+		{"first page", nil, rounds[:9], 3},
+		{"next page", []string{"n"}, rounds[9:18], 3},
+		{"last page", []string{"n", "right"}, rounds[18:], 3},
+		{"past the last page", []string{"n", "n", "n"}, rounds[18:], 3},
+		{"back a page", []string{"n", "n", "p"}, rounds[9:18], 3},
+		{"before the first page", []string{"left"}, rounds[:9], 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &App{roundTypes: rounds}
+			for _, key := range tt.keys {
+				a.handleMenuKeys(key)
+			}
+			if got := a.menuPageRounds(); !reflect.DeepEqual(got, tt.page) {
+				t.Errorf("menuPageRounds() = %v, want %v", got, tt.page)
+			}
+			if got := a.menuPages(); got != tt.pages {
+				t.Errorf("menuPages() = %d, want %d", got, tt.pages)
+			}
+		})
+	}
+
+	if a := (&App{}); a.menuPages() != 1 || len(a.menuPageRounds()) != 0 {
+		t.Errorf("no rounds: %d pages of %v", a.menuPages(), a.menuPageRounds())
+	}
+}
@@ -0,0 +1,221 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/timlinux/macaco/internal/game"
+)
+
+// drillRows is how many drill choices the picker shows at once
+const drillRows = 12
+
+// drillChoice is a drill the picker offers
+type drillChoice struct {
+	label string
+	spec  game.DrillSpec
+}
+
+// drillChoices returns the drills to pick from: any task, then each
+// category, tag and command the templates practise
+func drillChoices(templates *game.TemplateRegistry) []drillChoice {
+	choices := []drillChoice{{label: "Any task"}}
+	for _, cat := range game.RoundCategories() {
+		choices = append(choices, drillChoice{
+			label: "Category  " + string(cat),
+			spec:  game.DrillSpec{Category: cat},
+		})
+	}
+	for _, tag := range templates.Tags() {
+		choices = append(choices, drillChoice{
+			label: "Tag       " + tag,
+			spec:  game.DrillSpec{Tag: tag},
+		})
+	}
+	for _, cmd := range templates.Commands() {
+		choices = append(choices, drillChoice{
+			label: "Command   " + cmd,
+			spec:  game.DrillSpec{Commands: []string{cmd}},
+		})
+	}
+	return choices
+}
+
+// openDrillPicker shows the drill picker, keeping the last pick and
+// mastery criterion
+func (a *App) openDrillPicker() {
+	templates := game.DefaultTemplates()
+	if a.engine != nil {
+		templates = a.engine.GetTemplates()
+	}
+	a.drillChoices = drillChoices(templates)
+	if a.drillIndex >= len(a.drillChoices) {
+		a.drillIndex = 0
+	}
+	if a.drillSolves == 0 {
+		a.drillSolves = game.DefaultDrillSolves
+		a.drillEfficiency = game.DefaultDrillEfficiency
+	}
+	a.menuMessage = ""
+	a.view = ViewDrill
+}
+
+// handleDrillKeys handles keys in the drill picker
+func (a *App) handleDrillKeys(key string) (tea.Model, tea.Cmd) {
+	switch key {
+	case "j", "down":
+		if a.drillIndex < len(a.drillChoices)-1 {
+			a.drillIndex++
+		}
+	case "k", "up":
+		if a.drillIndex > 0 {
+			a.drillIndex--
+		}
+	case "+", "=":
+		a.drillSolves = min(a.drillSolves+1, 100)
+	case "-":
+		a.drillSolves = max(a.drillSolves-1, 1)
+	case ">", ".":
+		if a.drillEfficiency < 100 {
+			a.drillEfficiency += 5
+		}
+	case "<", ",":
+		if a.drillEfficiency > 5 {
+			a.drillEfficiency -= 5
+		}
+	case "enter":
+		a.startDrill()
+	case "esc", "q":
+		a.menuMessage = ""
+		a.view = ViewMenu
+	}
+	return a, nil
+}
+
+// startDrill starts the picked drill, staying in the picker and saying why
+// if it can't
+func (a *App) startDrill() {
+	spec := a.drillChoices[a.drillIndex].spec
+	spec.Text = a.text
+	spec.Solves = a.drillSolves
+	spec.Efficiency = a.drillEfficiency
+
+	a.menuMessage = ""
+	if a.engine != nil {
+		session, err := a.engine.CreateDrill(spec)
+		if err != nil {
+			a.menuMessage = err.Error()
+			return
+		}
+		a.session = session
+		a.sessionID = session.ID
+		a.session.SetViewportHeight(a.bufferViewportHeight())
+	} else if a.client != nil {
+		resp, err := a.client.CreateDrill(spec)
+		if err != nil {
+			a.menuMessage = err.Error()
+			return
+		}
+		a.sessionID = resp.SessionID
+	}
+
+	// Drills have no round code, so no ghost to race
+	a.ghost = nil
+	a.ghostPlayer = nil
+
+	// The drill takes the place of any saved round
+	a.saved = nil
+	a.lastSave = time.Time{}
+	a.view = ViewGame
+	a.matchStatus = game.MatchNone
+	a.showHint = false
+	a.hintLevel = 0
+}
+
+// endDrill stops the drill in play. A drill ended before any task was
+// finished goes back to the menu rather than to empty stats.
+func (a *App) endDrill() {
+	if a.session == nil || !a.session.IsDrill() {
+		return
+	}
+	a.session.EndDrill()
+	a.matchStatus = game.MatchNone
+	a.showHint = false
+	a.hintLevel = 0
+
+	if len(a.session.TaskResults) == 0 {
+		a.saveSession()
+		a.session = nil
+		a.view = ViewMenu
+		a.checkSavedSession()
+		return
+	}
+	a.showRoundStats()
+}
+
+// renderDrillPicker renders the drill picker
+func (a *App) renderDrillPicker() string {
+	title := a.styles.Title.Render("Drill")
+	subtitle := a.styles.Subtitle.Render("Practise one kind of task until you've mastered it")
+
+	// Keep the picked choice in the window of rows shown
+	first := 0
+	if a.drillIndex >= drillRows {
+		first = a.drillIndex - drillRows + 1
+	}
+	last := min(first+drillRows, len(a.drillChoices))
+
+	var lines []string
+	for i := first; i < last; i++ {
+		label := a.drillChoices[i].label
+		if i == a.drillIndex {
+			lines = append(lines, a.styles.StatusComplete.Render("> "+label))
+		} else {
+			lines = append(lines, "  "+label)
+		}
+	}
+	lines = append(lines, a.styles.Label.Render(fmt.Sprintf("  (%d/%d)", a.drillIndex+1, len(a.drillChoices))))
+
+	goal := game.DrillSpec{Solves: a.drillSolves, Efficiency: a.drillEfficiency}.Goal()
+	content := lipgloss.JoinVertical(
+		lipgloss.Center,
+		title,
+		subtitle,
+		a.styles.Content.Render(strings.Join(lines, "\n")),
+		"Mastered after "+goal,
+		a.styles.Hint.Render("j/k pick  |  +/- solves  |  </> efficiency  |  ENTER start  |  ESC back"),
+	)
+	if a.menuMessage != "" {
+		content = lipgloss.JoinVertical(lipgloss.Center, content, a.styles.StatusError.Render(a.menuMessage))
+	}
+
+	return lipgloss.Place(
+		a.width, a.height,
+		lipgloss.Center, lipgloss.Center,
+		content,
+	)
+}
+
+// drillProgress describes how close the drill in play is to mastery, for
+// the header
+func drillProgress(drill *game.Drill, task int) string {
+	return fmt.Sprintf("Drill task %d | Clean %d/%d in a row", task, drill.Streak, drill.Solves)
+}
+
+// renderDrillSummary renders what a finished drill practised and whether
+// it was mastered, for the stats screen
+func (a *App) renderDrillSummary() string {
+	drill := a.session.Drill
+	result := a.styles.StatusError.Render(fmt.Sprintf("Not mastered yet: %d clean solves in all", drill.Clean))
+	if drill.Mastered {
+		result = a.styles.StatusComplete.Render("Mastered!")
+	}
+	return lipgloss.JoinVertical(lipgloss.Center,
+		lipgloss.PlaceHorizontal(a.width, lipgloss.Center, a.styles.Title.Render("Drill: "+drill.String())),
+		lipgloss.PlaceHorizontal(a.width, lipgloss.Center, a.styles.Label.Render("Goal: "+drill.Goal())),
+		lipgloss.PlaceHorizontal(a.width, lipgloss.Center, result),
+	)
+}
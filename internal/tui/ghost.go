@@ -0,0 +1,129 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/timlinux/macaco/internal/game"
+	"github.com/timlinux/macaco/internal/replay"
+)
+
+// loadGhost loads the best recorded run of the round to race, when ghost
+// racing is on and the round has a code to match runs by
+func (a *App) loadGhost() {
+	a.ghost = nil
+	a.ghostPlayer = nil
+	if !a.ghostOn || a.engine == nil || a.session == nil || a.session.RoundCode == "" {
+		return
+	}
+	a.ghost = a.engine.GetGhost(a.session.RoundCode, a.session.TotalTasks)
+}
+
+// advanceGhost plays the ghost's run of the current task up to the task
+// clock, starting its replay when the player moves on to a new task
+func (a *App) advanceGhost() {
+	if a.ghost == nil || a.session == nil || a.view != ViewGame {
+		return
+	}
+	task := a.session.CurrentTask()
+	if task == nil {
+		return
+	}
+	if a.ghostPlayer == nil || a.ghostIndex != a.session.CurrentIndex {
+		a.ghostPlayer = nil
+		a.ghostIndex = a.session.CurrentIndex
+		if run := a.ghost.Task(a.ghostIndex, task.ID); run != nil {
+			a.ghostPlayer = replay.NewPlayer(run.Replay)
+		}
+	}
+	if a.ghostPlayer != nil {
+		a.ghostPlayer.PlayTo(a.session.ElapsedTime())
+	}
+}
+
+// renderGhost renders the ghost's buffer as it races the current task,
+// with its keys and whether it has finished
+func (a *App) renderGhost(task *game.Task) string {
+	p := a.ghostPlayer
+	if p == nil {
+		return ""
+	}
+
+	engine := p.Engine()
+	status := game.MatchInProgress
+	if task.IsSolvedBy(engine) {
+		status = game.MatchComplete
+	}
+	top, height := engine.Viewport()
+	buffer := withCursor(engine.Text(), engine.CursorIndex())
+	display := a.blockStyle(a.styles.BufferStyle(status.String()), task).
+		Foreground(a.styles.Theme.Dimmed).
+		Render(clipLines(buffer, top, height))
+
+	progress := fmt.Sprintf("Ghost  key %d/%d", p.Position(), p.Len())
+	if p.Done() {
+		progress = "Ghost done in " + formatReplayTime(p.Recording().Duration())
+	}
+	return lipgloss.JoinVertical(lipgloss.Center,
+		a.styles.Label.Render(progress),
+		display,
+	)
+}
+
+// withGhost puts the ghost's buffer beside the player's, when racing one
+func (a *App) withGhost(display string, task *game.Task) string {
+	ghost := a.renderGhost(task)
+	if ghost == "" {
+		return display
+	}
+	you := lipgloss.JoinVertical(lipgloss.Center, a.styles.Label.Render("You"), display)
+	return lipgloss.JoinHorizontal(lipgloss.Top, you, "   ", ghost)
+}
+
+// ghostEntry describes the ghost racing setting for the menu
+func (a *App) ghostEntry() string {
+	if a.ghostOn {
+		return "On: race your best run of a round code"
+	}
+	return "Off"
+}
+
+// renderGhostDeltas lists how far ahead or behind the ghost the player
+// finished each task
+func (a *App) renderGhostDeltas() string {
+	if a.ghost == nil || a.session == nil {
+		return ""
+	}
+	deltas := a.ghost.Deltas(a.session.TaskResults)
+	if len(deltas) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(a.styles.Title.Render(fmt.Sprintf("Ghost Race (best run %s)", a.ghost.PlayedAt.Format("Jan 2 15:04"))))
+	b.WriteString("\n")
+	for _, d := range deltas {
+		b.WriteString(fmt.Sprintf("  Task %-3d %s vs %s  %s\n", d.Index+1,
+			formatReplayTime(time.Duration(d.TimeMs)*time.Millisecond),
+			formatReplayTime(time.Duration(d.GhostTimeMs)*time.Millisecond),
+			a.formatDelta(d.DeltaMs)))
+	}
+	total := deltas[len(deltas)-1].TotalMs
+	b.WriteString(fmt.Sprintf("  Overall %s\n", a.formatDelta(total)))
+	return b.String()
+}
+
+// formatDelta formats a time difference from the ghost, green when ahead
+// and red when behind
+func (a *App) formatDelta(ms int64) string {
+	d := time.Duration(ms) * time.Millisecond
+	switch {
+	case ms < 0:
+		return a.styles.StatusComplete.Render(fmt.Sprintf("-%.1fs ahead", -d.Seconds()))
+	case ms > 0:
+		return a.styles.StatusError.Render(fmt.Sprintf("+%.1fs behind", d.Seconds()))
+	}
+	return "level"
+}
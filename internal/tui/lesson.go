@@ -0,0 +1,319 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/timlinux/macaco/internal/game"
+	"github.com/timlinux/macaco/internal/replay"
+)
+
+// openLessons shows the units of the curriculum, picking the first one
+// not passed yet
+func (a *App) openLessons() {
+	if a.engine == nil {
+		a.menuMessage = game.ErrLessonsOffline.Error()
+		return
+	}
+	a.lessonUnits = a.engine.GetLessonStatus()
+	a.lessonIndex = 0
+	for i, unit := range a.lessonUnits {
+		if unit.Unlocked && (unit.Progress == nil || !unit.Progress.Passed) {
+			a.lessonIndex = i
+			break
+		}
+	}
+	a.menuMessage = ""
+	a.view = ViewLessons
+}
+
+// handleLessonsKeys handles keys in the list of units
+func (a *App) handleLessonsKeys(key string) (tea.Model, tea.Cmd) {
+	switch key {
+	case "j", "down":
+		if a.lessonIndex < len(a.lessonUnits)-1 {
+			a.lessonIndex++
+		}
+		a.menuMessage = ""
+	case "k", "up":
+		if a.lessonIndex > 0 {
+			a.lessonIndex--
+		}
+		a.menuMessage = ""
+	case "enter":
+		if len(a.lessonUnits) == 0 {
+			break
+		}
+		if unit := a.lessonUnits[a.lessonIndex]; unit.Unlocked {
+			a.openUnit(unit.ID)
+		} else {
+			a.menuMessage = fmt.Sprintf("Pass %q to unlock this unit", a.lessonUnits[a.lessonIndex-1].Title)
+		}
+	case "esc", "q":
+		a.menuMessage = ""
+		a.view = ViewMenu
+	}
+	return a, nil
+}
+
+// openUnit starts a unit from its first explanation screen
+func (a *App) openUnit(unitID string) {
+	a.lessonUnit, _ = a.engine.GetCurriculum().Unit(unitID)
+	if a.lessonUnit == nil {
+		return
+	}
+	a.menuMessage = ""
+	a.showLessonPage(0)
+	a.view = ViewLesson
+}
+
+// lessonPages returns how many pages the unit has: its screens, its
+// examples and the page that starts the practice
+func (a *App) lessonPages() int {
+	return len(a.lessonUnit.Screens) + len(a.lessonUnit.Examples) + 1
+}
+
+// lessonExample returns the example on the current page, or nil
+func (a *App) lessonExample() *game.LessonExample {
+	i := a.lessonPage - len(a.lessonUnit.Screens)
+	if i < 0 || i >= len(a.lessonUnit.Examples) {
+		return nil
+	}
+	return &a.lessonUnit.Examples[i]
+}
+
+// showLessonPage turns to a page of the unit, playing its example if it
+// has one
+func (a *App) showLessonPage(page int) {
+	a.lessonPage = page
+	a.player = nil
+	a.replayTask = nil
+	if example := a.lessonExample(); example != nil {
+		a.replayTask = &example.Task
+		a.player = replay.NewPlayer(optimalReplay(a.replayTask, nil))
+		a.player.Play()
+	}
+}
+
+// handleLessonKeys handles keys while reading a unit
+func (a *App) handleLessonKeys(key string) (tea.Model, tea.Cmd) {
+	last := a.lessonPages() - 1
+	switch key {
+	case "l", "right", "enter", " ":
+		if a.lessonPage < last {
+			a.showLessonPage(a.lessonPage + 1)
+		} else if key == "enter" {
+			a.startLesson()
+		}
+	case "h", "left":
+		if a.lessonPage > 0 {
+			a.showLessonPage(a.lessonPage - 1)
+		}
+	case "r":
+		if a.player != nil {
+			a.player.Restart()
+			a.player.Play()
+		}
+	case "esc", "q":
+		a.player = nil
+		a.openLessons()
+	}
+	return a, nil
+}
+
+// startLesson starts the unit's practice, staying on the page and saying
+// why if it can't
+func (a *App) startLesson() {
+	session, err := a.engine.CreateLesson(a.lessonUnit.ID)
+	if err != nil {
+		a.menuMessage = err.Error()
+		return
+	}
+	a.session = session
+	a.sessionID = session.ID
+	a.session.SetViewportHeight(a.bufferViewportHeight())
+
+	// Lessons have no round code, so no ghost to race
+	a.player = nil
+	a.ghost = nil
+	a.ghostPlayer = nil
+
+	// The lesson takes the place of any saved round
+	a.saved = nil
+	a.lastSave = time.Time{}
+	a.menuMessage = ""
+	a.view = ViewGame
+	a.matchStatus = game.MatchNone
+	a.showHint = false
+	a.hintLevel = 0
+}
+
+// renderLessons renders the list of units, with which are passed and
+// which are locked
+func (a *App) renderLessons() string {
+	title := a.styles.Title.Render("Lessons")
+	subtitle := a.styles.Subtitle.Render("Learn vim from zero: pass each unit to unlock the next")
+
+	var lines []string
+	for i, unit := range a.lessonUnits {
+		status := "      "
+		switch {
+		case unit.Progress != nil && unit.Progress.Passed:
+			status = "passed"
+		case !unit.Unlocked:
+			status = "locked"
+		}
+		line := fmt.Sprintf("%d. %-28s %s  %s", i+1, unit.Title, status, strings.Join(unit.Commands, " "))
+		switch {
+		case i == a.lessonIndex:
+			lines = append(lines, a.styles.StatusComplete.Render("> "+line))
+		case !unit.Unlocked:
+			lines = append(lines, a.styles.Label.Render("  "+line))
+		default:
+			lines = append(lines, "  "+line)
+		}
+	}
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Center,
+		title,
+		subtitle,
+		a.styles.Content.Render(strings.Join(lines, "\n")),
+	)
+	if len(a.lessonUnits) > 0 {
+		if summary := a.lessonUnits[a.lessonIndex].Summary; summary != "" {
+			content = lipgloss.JoinVertical(lipgloss.Center, content, summary)
+		}
+	}
+	content = lipgloss.JoinVertical(lipgloss.Center, content,
+		a.styles.Hint.Render("j/k pick  |  ENTER open  |  ESC back"))
+	if a.menuMessage != "" {
+		content = lipgloss.JoinVertical(lipgloss.Center, content, a.styles.StatusError.Render(a.menuMessage))
+	}
+
+	return lipgloss.Place(
+		a.width, a.height,
+		lipgloss.Center, lipgloss.Center,
+		content,
+	)
+}
+
+// renderLesson renders the current page of a unit: an explanation screen,
+// a worked example playing back, or the page that starts the practice
+func (a *App) renderLesson() string {
+	unit := a.lessonUnit
+	if unit == nil {
+		return "No lesson open"
+	}
+
+	heading := a.styles.Title.Render(fmt.Sprintf("%s  (%d/%d)", unit.Title, a.lessonPage+1, a.lessonPages()))
+
+	var body string
+	hint := "h/l page  |  ESC units"
+	switch example := a.lessonExample(); {
+	case a.lessonPage < len(unit.Screens):
+		screen := unit.Screens[a.lessonPage]
+		body = lipgloss.JoinVertical(lipgloss.Left,
+			a.styles.Subtitle.Render(screen.Title),
+			"",
+			screen.Text,
+		)
+	case example != nil:
+		body = a.renderLessonExample(example)
+		hint = "h/l page  |  r replay  |  ESC units"
+	default:
+		body = lipgloss.JoinVertical(lipgloss.Left,
+			a.styles.Subtitle.Render("Practice"),
+			"",
+			fmt.Sprintf("%d tasks using only what you've learnt so far:", len(unit.Practice)),
+			"  "+strings.Join(unit.Known(), " "),
+			"",
+			"To pass: "+unit.Pass.String(),
+		)
+		hint = "ENTER start practice  |  h page back  |  ESC units"
+	}
+
+	content := lipgloss.JoinVertical(lipgloss.Center,
+		heading,
+		a.styles.Content.Render(body),
+		a.styles.Hint.Render(hint),
+	)
+	if a.menuMessage != "" {
+		content = lipgloss.JoinVertical(lipgloss.Center, content, a.styles.StatusError.Render(a.menuMessage))
+	}
+
+	return lipgloss.Place(
+		a.width, a.height,
+		lipgloss.Center, lipgloss.Center,
+		content,
+	)
+}
+
+// renderLessonExample renders a worked example as its keys play back, with
+// the text wanted and the explanation
+func (a *App) renderLessonExample(example *game.LessonExample) string {
+	p := a.player
+	task := &example.Task
+	if p == nil {
+		return ""
+	}
+
+	engine := p.Engine()
+	status := game.MatchInProgress
+	if task.IsSolvedBy(engine) {
+		status = game.MatchComplete
+	}
+	top, height := engine.Viewport()
+	display := a.blockStyle(a.styles.BufferStyle(status.String()), task).
+		Render(clipLines(withCursor(engine.Text(), engine.CursorIndex()), top, height))
+
+	var target string
+	if task.IsMotionTask() {
+		target = a.blockStyle(a.styles.CurrentTask, task).Foreground(a.styles.Theme.Dimmed).Render(caretBlock(task))
+	} else {
+		target = a.renderDesiredWithHighlight(task)
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Center,
+		a.styles.Subtitle.Render("Example: "+task.Description),
+		"",
+		display,
+		a.styles.Separator.Render("↓"),
+		target,
+		"",
+		"Keys: "+a.renderPlayedKeys(p),
+		"",
+		example.Explanation,
+	)
+}
+
+// renderLessonSummary renders whether the unit was passed and what that
+// unlocked, for the stats screen
+func (a *App) renderLessonSummary() string {
+	lesson := a.session.Lesson
+	result := a.styles.StatusError.Render("Not passed yet: practise again to unlock the next unit")
+	if lesson.Passed {
+		result = a.styles.StatusComplete.Render("Passed!" + a.nextUnitEntry(lesson.Unit))
+	}
+	return lipgloss.JoinVertical(lipgloss.Center,
+		lipgloss.PlaceHorizontal(a.width, lipgloss.Center, a.styles.Title.Render("Lesson: "+lesson.Title)),
+		lipgloss.PlaceHorizontal(a.width, lipgloss.Center, a.styles.Label.Render("To pass: "+lesson.Pass.String())),
+		lipgloss.PlaceHorizontal(a.width, lipgloss.Center, result),
+	)
+}
+
+// nextUnitEntry names the unit after unitID, which passing it unlocks
+func (a *App) nextUnitEntry(unitID string) string {
+	if a.engine == nil {
+		return ""
+	}
+	units := a.engine.GetCurriculum().Units
+	_, i := a.engine.GetCurriculum().Unit(unitID)
+	if i < 0 || i+1 >= len(units) {
+		return " You've finished the curriculum."
+	}
+	return fmt.Sprintf(" %q is unlocked.", units[i+1].Title)
+}
@@ -0,0 +1,191 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/timlinux/macaco/internal/game"
+	"github.com/timlinux/macaco/internal/replay"
+	"github.com/timlinux/macaco/internal/vim"
+)
+
+// optimalKeyInterval is how far apart the keys of an optimal solution are
+// played
+const optimalKeyInterval = 400 * time.Millisecond
+
+// watchReplay opens the replay of the reviewed task, the player's own run
+// or, if optimal is true, its optimal solution
+func (a *App) watchReplay(optimal bool) {
+	if a.session == nil || a.reviewIndex >= len(a.session.TaskResults) {
+		return
+	}
+	task := a.session.Tasks[a.reviewIndex]
+	rec := a.session.TaskResults[a.reviewIndex].Replay
+	if optimal {
+		rec = optimalReplay(task, rec)
+	}
+	if rec == nil {
+		return
+	}
+
+	a.player = replay.NewPlayer(rec)
+	a.replayTask = task
+	a.watchOptimal = optimal
+	a.player.Play()
+	a.view = ViewReplay
+}
+
+// optimalReplay records the optimal solution of a task, starting from the
+// same state as the player's run when there is one
+func optimalReplay(task *game.Task, run *replay.Recording) *replay.Recording {
+	var engine *vim.Engine
+	if run != nil {
+		engine = run.NewEngine()
+	} else {
+		engine = vim.NewEngine(task.Initial)
+		engine.SetOptions(vim.Options{TextWidth: task.TextWidth})
+		engine.SetCursorIndex(task.CursorStart)
+	}
+	return replay.FromKeys(engine, task.OptimalKeySequence(), optimalKeyInterval)
+}
+
+// handleReplayKeys handles keys in the replay view
+func (a *App) handleReplayKeys(key string) (tea.Model, tea.Cmd) {
+	switch key {
+	case " ", "p":
+		a.player.Toggle()
+	case "l", "right":
+		a.player.Step()
+	case "h", "left":
+		a.player.Back()
+	case "+", "=", "up":
+		a.player.Faster()
+	case "-", "down":
+		a.player.Slower()
+	case "0", "home":
+		a.player.Restart()
+	case "o":
+		a.watchReplay(!a.watchOptimal)
+	case "esc", "enter", "w":
+		a.player = nil
+		a.view = ViewReview
+	case "q":
+		return a, tea.Quit
+	}
+	return a, nil
+}
+
+// renderReplay renders the replay view: the buffer as the keys are played,
+// the text wanted and the keys so far
+func (a *App) renderReplay() string {
+	p := a.player
+	task := a.replayTask
+	if p == nil || task == nil {
+		return "Nothing to replay"
+	}
+
+	var b strings.Builder
+
+	whose := "your run"
+	if a.watchOptimal {
+		whose = "optimal solution"
+	}
+	title := a.styles.Title.Render(fmt.Sprintf("Replay %d/%d - %s", a.reviewIndex+1, len(a.session.TaskResults), whose))
+	b.WriteString(lipgloss.Place(a.width, 3, lipgloss.Center, lipgloss.Center, title))
+	b.WriteString("\n")
+
+	engine := p.Engine()
+	status := game.MatchInProgress
+	if task.IsSolvedBy(engine) {
+		status = game.MatchComplete
+	}
+	top, height := engine.Viewport()
+	buffer := withCursor(engine.Text(), engine.CursorIndex())
+	display := a.blockStyle(a.styles.BufferStyle(status.String()), task).Render(clipLines(buffer, top, height))
+
+	var target string
+	if task.IsMotionTask() {
+		target = a.blockStyle(a.styles.CurrentTask, task).Foreground(a.styles.Theme.Dimmed).Render(caretBlock(task))
+	} else {
+		target = a.renderDesiredWithHighlight(task)
+	}
+	taskDisplay := lipgloss.JoinVertical(lipgloss.Center,
+		display,
+		a.styles.Separator.Render("↓"),
+		target,
+		"",
+		a.styles.Label.Render(engine.Mode().String()),
+	)
+	b.WriteString(lipgloss.PlaceHorizontal(a.width, lipgloss.Center, taskDisplay))
+	b.WriteString("\n\n")
+
+	state := "paused"
+	switch {
+	case p.Playing():
+		state = "playing"
+	case p.Done():
+		state = "finished"
+	}
+	progress := fmt.Sprintf("key %d/%d  %s / %s  %gx  %s",
+		p.Position(), p.Len(), formatReplayTime(p.Elapsed()), formatReplayTime(p.Recording().Duration()), p.Speed(), state)
+	b.WriteString(lipgloss.PlaceHorizontal(a.width, lipgloss.Center, a.styles.Subtitle.Render(progress)))
+	b.WriteString("\n")
+	b.WriteString(lipgloss.PlaceHorizontal(a.width, lipgloss.Center, a.renderPlayedKeys(p)))
+	b.WriteString("\n\n")
+	b.WriteString(a.styles.Hint.Render("SPACE play/pause  |  h/l step  |  +/- speed  |  0 restart  |  o " + a.otherReplay() + "  |  ESC back to review"))
+
+	return b.String()
+}
+
+// otherReplay names the replay the o key switches to
+func (a *App) otherReplay() string {
+	if a.watchOptimal {
+		return "your run"
+	}
+	return "optimal"
+}
+
+// renderPlayedKeys lists the keys played so far with the last one
+// highlighted, and marks where the task was reset
+func (a *App) renderPlayedKeys(p *replay.Player) string {
+	played := p.Played()
+	if len(played) == 0 {
+		return a.styles.Hint.Render("(no keys yet)")
+	}
+
+	var b strings.Builder
+	for i, key := range played {
+		if p.Recording().IsReset(i) {
+			b.WriteString(a.styles.StatusError.Render(" ⟲ "))
+		}
+		if i == len(played)-1 {
+			b.WriteString(a.styles.StatusComplete.Render(key.String()))
+		} else {
+			b.WriteString(key.String())
+		}
+	}
+	return b.String()
+}
+
+// withCursor shows a block cursor at index in text
+func withCursor(text string, index int) string {
+	runes := []rune(text)
+	switch {
+	case index < 0:
+		return text
+	case index >= len(runes):
+		return text + "█"
+	case runes[index] == '\n':
+		// Cursor past the end of a line in insert mode
+		return string(runes[:index]) + "█" + string(runes[index:])
+	}
+	return string(runes[:index]) + "█" + string(runes[index+1:])
+}
+
+// formatReplayTime formats a time into a task to a tenth of a second
+func formatReplayTime(d time.Duration) string {
+	return fmt.Sprintf("%s.%d", formatDuration(d), d.Milliseconds()/100%10)
+}
@@ -0,0 +1,110 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/timlinux/macaco/internal/game"
+)
+
+// handleReviewKeys handles keys in the review view
+func (a *App) handleReviewKeys(key string) (tea.Model, tea.Cmd) {
+	switch key {
+	case "l", "n", "right", "j", "down":
+		if a.session != nil && a.reviewIndex < len(a.session.Reviews())-1 {
+			a.reviewIndex++
+		}
+	case "h", "p", "left", "k", "up":
+		if a.reviewIndex > 0 {
+			a.reviewIndex--
+		}
+	case "w":
+		a.watchReplay(false)
+	case "o":
+		a.watchReplay(true)
+	case "esc", "r", "enter", " ":
+		a.view = ViewStats
+	case "q":
+		return a, tea.Quit
+	}
+	return a, nil
+}
+
+// renderReview renders the review view, one finished task at a time
+func (a *App) renderReview() string {
+	if a.session == nil || a.session.Review(a.reviewIndex) == nil {
+		return "No tasks to review"
+	}
+
+	var b strings.Builder
+
+	title := a.styles.Title.Render(fmt.Sprintf("Solution Review %d/%d", a.reviewIndex+1, len(a.session.Reviews())))
+	b.WriteString(lipgloss.Place(a.width, 3, lipgloss.Center, lipgloss.Center, title))
+	b.WriteString("\n")
+
+	task := a.session.Tasks[a.reviewIndex]
+	if task.IsMotionTask() {
+		b.WriteString(a.styles.Subtitle.Render(fmt.Sprintf("%q (move cursor)", task.Initial)))
+	} else {
+		b.WriteString(a.styles.Subtitle.Render(fmt.Sprintf("%q -> %q", task.Initial, task.Desired)))
+	}
+	b.WriteString("\n")
+	b.WriteString(a.renderReviewPanel(a.session.Review(a.reviewIndex)))
+	b.WriteString("\n\n")
+	b.WriteString(a.styles.Hint.Render("h/l previous/next task  |  w watch your run  |  o watch the optimal solution  |  ESC back to statistics"))
+
+	return b.String()
+}
+
+// renderReviewPanel lists the player's commands next to the optimal ones,
+// step by step, with the keystrokes each step lost or saved
+func (a *App) renderReviewPanel(review *game.TaskReview) string {
+	var b strings.Builder
+
+	outcome := "solved"
+	if !review.Success {
+		outcome = "skipped"
+	}
+	b.WriteString(fmt.Sprintf("%s in %d keys, optimal %d (%s)\n",
+		outcome, review.Keystrokes, review.OptimalKeystrokes, formatDelta(review.Delta)))
+
+	usedWidth := len("You")
+	for _, step := range review.Steps {
+		usedWidth = max(usedWidth, lipgloss.Width(strings.Join(step.Used, " ")))
+	}
+
+	b.WriteString(a.styles.Label.Render(fmt.Sprintf("  %-*s   %s", usedWidth, "You", "Optimal")))
+	b.WriteString("\n")
+	for _, step := range review.Steps {
+		used := strings.Join(step.Used, " ")
+		optimal := strings.Join(step.Optimal, " ")
+		line := fmt.Sprintf("  %-*s │ %-12s ", usedWidth, used, optimal)
+		delta := formatDelta(step.Delta)
+		switch {
+		case step.Delta > 0:
+			delta = a.styles.StatusError.Render(delta)
+		case step.Delta < 0:
+			delta = a.styles.StatusComplete.Render(delta)
+		}
+		b.WriteString(line + delta + "\n")
+	}
+
+	for _, step := range review.Steps {
+		if step.Suggestion != "" {
+			b.WriteString(a.styles.Hint.Render("  " + step.Suggestion))
+			b.WriteString("\n")
+		}
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// formatDelta formats a keystroke difference as "+2", "-1" or "="
+func formatDelta(delta int) string {
+	if delta == 0 {
+		return "="
+	}
+	return fmt.Sprintf("%+d", delta)
+}
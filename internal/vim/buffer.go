@@ -157,11 +157,11 @@ func (b *Buffer) clampCursor() {
 		b.cursorX = 0
 	}
 
-	// In normal mode, cursor can't be past last character
+	// In normal and visual mode, cursor can't be past last character
 	// In insert mode, cursor can be at end of line (after last char)
-	if b.mode == ModeNormal {
-		if lineLen > 0 && b.cursorX >= lineLen {
-			b.cursorX = lineLen - 1
+	if b.mode != ModeInsert {
+		if b.cursorX >= lineLen {
+			b.cursorX = max(lineLen-1, 0)
 		}
 	} else {
 		if b.cursorX > lineLen {
@@ -295,6 +295,35 @@ func (b *Buffer) DeleteLine() string {
 	return deleted
 }
 
+// JoinLines joins count lines, at least two, starting at the current line,
+// as J does. Leading whitespace of each joined line is removed and a space
+// put in its place, unless the line so far ends in whitespace or the joined
+// line is empty or starts with ')'. The cursor is left where the last line
+// was joined. It returns false if there is no line below to join.
+func (b *Buffer) JoinLines(count int) bool {
+	if b.cursorY >= len(b.lines)-1 {
+		return false
+	}
+	last := min(b.cursorY+max(count, 2)-1, len(b.lines)-1)
+
+	line := b.lines[b.cursorY]
+	x := 0
+	for _, next := range b.lines[b.cursorY+1 : last+1] {
+		next = strings.TrimLeft(next, " \t")
+		x = utf8.RuneCountInString(line)
+		if line != "" && next != "" && !strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\t") && next[0] != ')' {
+			line += " "
+		}
+		line += next
+	}
+
+	b.lines[b.cursorY] = line
+	b.lines = append(b.lines[:b.cursorY+1], b.lines[last+1:]...)
+	b.cursorX = x
+	b.clampCursor()
+	return true
+}
+
 // DeleteToEndOfLine deletes from cursor to end of line
 func (b *Buffer) DeleteToEndOfLine() string {
 	if len(b.lines) == 0 {
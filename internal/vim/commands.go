@@ -0,0 +1,207 @@
+package vim
+
+import "strings"
+
+// Keys that start operators and insert mode commands
+const (
+	operatorKeys = "dcy<>"
+	insertKeys   = "iIaAoOsSC"
+)
+
+// Command is one complete normal mode command and the text it left behind
+type Command struct {
+	Keys []Key
+	Text string
+}
+
+// SplitCommands replays keys through engine and cuts them into commands.
+// A command ends when the engine is back in normal mode with no keys
+// pending, so "3dw", "cwfoo<Esc>", "viwd" and "fx" are one command each;
+// an unfinished command at the end is returned as is. If stop is set,
+// replay ends at the first command after which stop returns true.
+func SplitCommands(engine *Engine, keys []Key, stop func(*Engine) bool) []Command {
+	var commands []Command
+	var current []Key
+	for _, key := range keys {
+		engine.ProcessKey(key)
+		current = append(current, key)
+		if engine.Mode() != ModeNormal || engine.GetPendingKeys() != "" {
+			continue
+		}
+		commands = append(commands, Command{Keys: current, Text: engine.Text()})
+		current = nil
+		if stop != nil && stop(engine) {
+			return commands
+		}
+	}
+	if len(current) > 0 {
+		commands = append(commands, Command{Keys: current, Text: engine.Text()})
+	}
+	return commands
+}
+
+// SplitKeys cuts keys into commands with SplitCommands, returning only
+// their keys
+func SplitKeys(engine *Engine, keys []Key) [][]Key {
+	var commands [][]Key
+	for _, cmd := range SplitCommands(engine, keys, nil) {
+		commands = append(commands, cmd.Keys)
+	}
+	return commands
+}
+
+// countLength returns how many keys of a leading count there are
+func countLength(keys []Key) int {
+	i := 0
+	for i < len(keys) && len(keys[i]) == 1 && keys[i][0] >= '0' && keys[i][0] <= '9' {
+		if i == 0 && keys[i] == "0" {
+			break // 0 on its own is a motion
+		}
+		i++
+	}
+	return i
+}
+
+// motionLength returns how many keys the motion after operator op takes,
+// including the text typed afterwards if the operator enters insert mode
+func motionLength(keys []Key, op Key, insert bool) int {
+	i := countLength(keys)
+	switch {
+	case i >= len(keys):
+		return len(keys)
+	case keys[i] == op:
+		i++ // dd, cc, gqq and friends
+	case len(keys[i]) == 1 && strings.Contains("fFtTia`'g", string(keys[i])):
+		i = min(i+2, len(keys))
+	default:
+		i++
+	}
+	if insert {
+		i += insertLength(keys[i:])
+	}
+	return i
+}
+
+// insertLength returns how many keys are typed in insert mode, up to and
+// including the <Esc> that leaves it
+func insertLength(keys []Key) int {
+	for i, k := range keys {
+		if k == KeyEsc {
+			return i + 1
+		}
+	}
+	return len(keys)
+}
+
+// CommandName names the command a key sequence from SplitCommands runs,
+// without counts, typed text or character arguments: "3dw" is "dw",
+// "cwfoo<Esc>" is "cw", "fx" is "f", "ci(" stays "ci(" and "viwd" is "vd"
+func CommandName(cmd []Key) string {
+	cmd = cmd[countLength(cmd):]
+	if len(cmd) == 0 {
+		return ""
+	}
+
+	k := string(cmd[0])
+	switch {
+	case len(k) == 1 && strings.Contains(insertKeys, k):
+		return k
+	case k == "v" || k == "V":
+		for i := 1; i < len(cmd); i++ {
+			switch op := string(cmd[i]); {
+			case len(op) == 1 && strings.Contains("dxcsyJ<>r", op):
+				return k + op
+			case len(op) == 1 && strings.Contains("fFtTia", op):
+				i++ // Skip the argument
+			}
+		}
+		return k
+	case k == "g" && len(cmd) > 1 && strings.Contains("qwuU~", string(cmd[1])):
+		return k + string(cmd[1]) + motionName(cmd[2:], cmd[1])
+	case len(k) == 1 && strings.Contains(operatorKeys, k):
+		return k + motionName(cmd[1:], cmd[0])
+	case k == "g" || k == "z":
+		if len(cmd) > 1 {
+			return k + string(cmd[1])
+		}
+	}
+	return k
+}
+
+// motionName names the motion after an operator, like CommandName does
+func motionName(keys []Key, op Key) string {
+	keys = keys[countLength(keys):]
+	switch {
+	case len(keys) == 0:
+		return ""
+	case keys[0] == op:
+		return string(op)
+	case len(keys) > 1 && (keys[0] == "i" || keys[0] == "a" || keys[0] == "g"):
+		return string(keys[0]) + string(keys[1])
+	}
+	return string(keys[0])
+}
+
+// CommandTraits describes what a command from SplitCommands involves
+// beyond its name
+type CommandTraits struct {
+	Count      bool // A count, as in 3w or d2w
+	TextObject bool // A text object, as in diw or vaw
+	Register   bool // The register: yanks and puts
+	Typed      int  // Keys typed in insert mode, not counting the <Esc>
+}
+
+// Traits returns what a command from SplitCommands involves
+func Traits(cmd []Key) CommandTraits {
+	var t CommandTraits
+	i := countLength(cmd)
+	t.Count = i > 0
+	if i >= len(cmd) {
+		return t
+	}
+
+	k := string(cmd[i])
+	insertAt := -1
+	switch {
+	case len(k) == 1 && strings.Contains(insertKeys, k):
+		insertAt = i + 1
+	case k == "p" || k == "P":
+		t.Register = true
+	case k == "v" || k == "V":
+	visual:
+		for j := i + 1; j < len(cmd); j++ {
+			switch s := string(cmd[j]); {
+			case s == "y":
+				t.Register = true
+				break visual
+			case s == "c" || s == "s":
+				insertAt = j + 1
+				break visual
+			case s == "i" || s == "a":
+				t.TextObject = true
+				j++ // Skip the object
+			case len(s) == 1 && strings.Contains("fFtTgr", s):
+				j++ // Skip the argument
+			case len(s) == 1 && s >= "1" && s <= "9":
+				t.Count = true
+			}
+		}
+	case len(k) == 1 && strings.Contains(operatorKeys, k):
+		t.Register = k == "y"
+		motion := cmd[i+1:]
+		n := countLength(motion)
+		t.Count = t.Count || n > 0
+		t.TextObject = n+1 < len(motion) && (motion[n] == "i" || motion[n] == "a")
+		if k == "c" {
+			insertAt = i + 1 + motionLength(motion, cmd[i], false)
+		}
+	}
+
+	if insertAt >= 0 && insertAt <= len(cmd) {
+		t.Typed = insertLength(cmd[insertAt:])
+		if t.Typed > 0 && cmd[insertAt+t.Typed-1] == KeyEsc {
+			t.Typed--
+		}
+	}
+	return t
+}
@@ -0,0 +1,119 @@
+package vim
+
+import (
+	"reflect"
+	"testing"
+)
+
+// formatCommands writes each command in vim notation
+func formatCommands(commands [][]Key) []string {
+	var formatted []string
+	for _, cmd := range commands {
+		formatted = append(formatted, FormatKeys(cmd))
+	}
+	return formatted
+}
+
+func TestSplitKeys(t *testing.T) {
+	tests := []struct {
+		text string
+		keys string
+		want []string
+	}{
+		{"one two three", "", nil},
+		{"one two three", "wwb", []string{"w", "w", "b"}},
+		{"one two three", "3dw", []string{"3dw"}},
+		{"one two three", "d2w", []string{"d2w"}},
+		{"one two three", "cwnew<Esc>w", []string{"cwnew<Esc>", "w"}},
+		{"one two three", "viwdx", []string{"viwd", "x"}},
+		{"one two three", "fxfe", []string{"fx", "fe"}},
+		{"say (hi)", "fhci(yo<Esc>", []string{"fh", "ci(yo<Esc>"}},
+		{"one two", "gqqu<C-r>", []string{"gqq", "u", "<C-r>"}},
+		{"one two", "rXx", []string{"rX", "x"}},
+		{"one\ntwo", "ddp", []string{"dd", "p"}},
+		{"one two", "dw", []string{"dw"}},
+		{"one two", "d", []string{"d"}},                // Unfinished
+		{"", "cwx<Esc>", []string{"cw", "x", "<Esc>"}}, // No word to change, so no insert mode
+	}
+	for _, tt := range tests {
+		got := formatCommands(SplitKeys(NewEngine(tt.text), ParseKeys(tt.keys)))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitKeys(%q on %q) = %q, want %q", tt.keys, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSplitCommandsText(t *testing.T) {
+	commands := SplitCommands(NewEngine("one two three"), ParseKeys("wdwx"), nil)
+	want := []Command{
+		{Keys: []Key{"w"}, Text: "one two three"},
+		{Keys: []Key{"d", "w"}, Text: "one three"},
+		{Keys: []Key{"x"}, Text: "one hree"},
+	}
+	if !reflect.DeepEqual(commands, want) {
+		t.Errorf("SplitCommands = %+v, want %+v", commands, want)
+	}
+
+	stopped := SplitCommands(NewEngine("one two three"), ParseKeys("wdwx"), func(e *Engine) bool {
+		return e.Text() == "one three"
+	})
+	if len(stopped) != 2 {
+		t.Errorf("SplitCommands with stop = %+v, want it to stop after dw", stopped)
+	}
+}
+
+func TestCommandName(t *testing.T) {
+	tests := []struct {
+		keys string
+		want string
+	}{
+		{"w", "w"},
+		{"3w", "w"},
+		{"0", "0"},
+		{"dw", "dw"},
+		{"3d2w", "dw"},
+		{"dd", "dd"},
+		{"cwfoo<Esc>", "cw"},
+		{"ciwfoo<Esc>", "ciw"},
+		{"ci(", "ci("},
+		{"fx", "f"},
+		{"viwd", "vd"},
+		{"vfxd", "vd"},
+		{"V", "V"},
+		{"gqq", "gqq"},
+		{"gqap", "gqap"},
+		{"gg", "gg"},
+		{"zz", "zz"},
+		{"ifoo<Esc>", "i"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := CommandName(ParseKeys(tt.keys)); got != tt.want {
+			t.Errorf("CommandName(%q) = %q, want %q", tt.keys, got, tt.want)
+		}
+	}
+}
+
+func TestTraits(t *testing.T) {
+	tests := []struct {
+		keys string
+		want CommandTraits
+	}{
+		{"w", CommandTraits{}},
+		{"3w", CommandTraits{Count: true}},
+		{"d2w", CommandTraits{Count: true}},
+		{"diw", CommandTraits{TextObject: true}},
+		{"yy", CommandTraits{Register: true}},
+		{"p", CommandTraits{Register: true}},
+		{"vey", CommandTraits{Register: true}},
+		{"vawd", CommandTraits{TextObject: true}},
+		{"ifoo<Esc>", CommandTraits{Typed: 3}},
+		{"cwab<Esc>", CommandTraits{Typed: 2}},
+		{"ciwab<Esc>", CommandTraits{TextObject: true, Typed: 2}},
+	}
+	for _, tt := range tests {
+		if got := Traits(ParseKeys(tt.keys)); got != tt.want {
+			t.Errorf("Traits(%q) = %+v, want %+v", tt.keys, got, tt.want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package vim
 import (
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 // Engine processes vim commands and manages buffer state
@@ -14,6 +15,9 @@ type Engine struct {
 	lastSearch  rune
 	searchDir   int // 1 = forward, -1 = backward
 	lastMotion  string
+	viewport    Viewport
+	options     Options
+	visualStart int // Cursor index where visual mode started
 }
 
 // NewEngine creates a new vim engine with the given text
@@ -25,6 +29,21 @@ func NewEngine(text string) *Engine {
 	}
 }
 
+// Clone returns an independent copy of the engine state. Undo and redo
+// history are not copied.
+func (e *Engine) Clone() *Engine {
+	return &Engine{
+		buffer:      e.buffer.Clone(),
+		pendingKeys: e.pendingKeys,
+		lastSearch:  e.lastSearch,
+		searchDir:   e.searchDir,
+		lastMotion:  e.lastMotion,
+		viewport:    e.viewport,
+		options:     e.options,
+		visualStart: e.visualStart,
+	}
+}
+
 // Buffer returns the current buffer
 func (e *Engine) Buffer() *Buffer {
 	return e.buffer
@@ -54,6 +73,7 @@ func (e *Engine) CursorIndex() int {
 // SetCursorIndex sets the cursor position by index
 func (e *Engine) SetCursorIndex(index int) {
 	e.buffer.SetCursorIndex(index)
+	e.scrollToCursor()
 }
 
 // Mode returns the current mode
@@ -62,16 +82,32 @@ func (e *Engine) Mode() Mode {
 }
 
 // ProcessKey processes a single key input
-func (e *Engine) ProcessKey(key string) bool {
-	e.pendingKeys += key
+func (e *Engine) ProcessKey(key Key) bool {
+	raw := key.raw()
+	if raw == "" {
+		// Key has no meaning to the engine (e.g. arrow keys)
+		return false
+	}
+	e.pendingKeys += raw
 
 	// Try to parse and execute the pending keys
 	consumed, remaining := e.parseAndExecute(e.pendingKeys)
 	e.pendingKeys = remaining
 
+	// Keep the cursor inside the buffer and on screen
+	e.buffer.clampCursor()
+	e.scrollToCursor()
+
 	return consumed
 }
 
+// ProcessKeys processes a sequence of keys in order
+func (e *Engine) ProcessKeys(keys []Key) {
+	for _, key := range keys {
+		e.ProcessKey(key)
+	}
+}
+
 // parseAndExecute parses pending keys and executes commands
 func (e *Engine) parseAndExecute(keys string) (consumed bool, remaining string) {
 	if len(keys) == 0 {
@@ -99,26 +135,27 @@ func (e *Engine) handleInsertMode(keys string) (bool, string) {
 	}
 
 	switch keys {
-	case "esc", "\x1b":
+	case "\x1b":
 		e.buffer.SetMode(ModeNormal)
 		MoveLeft(e.buffer, 1)
 		return true, ""
-	case "backspace", "\x7f":
+	case "\x7f":
 		if e.buffer.cursorX > 0 {
 			MoveLeft(e.buffer, 1)
 			e.buffer.Delete(1)
 		}
 		return true, ""
-	case "enter", "\r", "\n":
+	case "\r":
 		e.buffer.Insert("\n")
 		return true, ""
 	default:
 		// Regular character input
-		if len(keys) == 1 && keys[0] >= 32 {
+		if utf8.RuneCountInString(keys) == 1 && (keys[0] >= 32 || keys[0] == '\t') {
 			e.buffer.Insert(keys)
 			return true, ""
 		}
-		return false, keys
+		// Other control keys are ignored in insert mode
+		return false, ""
 	}
 }
 
@@ -128,30 +165,38 @@ func (e *Engine) handleNormalMode(keys string) (bool, string) {
 		return false, ""
 	}
 
-	// Parse count prefix
-	count := 1
-	idx := 0
-	for idx < len(keys) && keys[idx] >= '1' && keys[idx] <= '9' {
-		idx++
-	}
-	for idx < len(keys) && keys[idx] >= '0' && keys[idx] <= '9' {
-		idx++
-	}
-	if idx > 0 {
-		count = 0
-		for i := 0; i < idx; i++ {
-			count = count*10 + int(keys[i]-'0')
-		}
-		if count == 0 {
-			count = 1
-		}
-	}
+	count, idx := parseCount(keys)
+	countKeys := keys[:idx]
 	keys = keys[idx:]
 
 	if len(keys) == 0 {
-		return false, strings.Repeat("0", idx) // Return the count as pending
+		return false, countKeys // Return the count as pending
+	}
+
+	consumed, remaining := e.executeNormal(keys, count)
+	if !consumed && remaining != "" {
+		// Keep the count while waiting for the rest of the command
+		remaining = countKeys + remaining
 	}
+	return consumed, remaining
+}
 
+// parseCount parses a count prefix, returning the count (1 if there is
+// none) and the number of digits
+func parseCount(keys string) (count, digits int) {
+	// A leading 0 is the line-start motion, not a count
+	if len(keys) == 0 || keys[0] < '1' || keys[0] > '9' {
+		return 1, 0
+	}
+	for digits < len(keys) && keys[digits] >= '0' && keys[digits] <= '9' {
+		count = count*10 + int(keys[digits]-'0')
+		digits++
+	}
+	return count, digits
+}
+
+// executeNormal executes a normal mode command whose count has been parsed
+func (e *Engine) executeNormal(keys string, count int) (bool, string) {
 	// Handle commands
 	switch {
 	// Mode changes
@@ -167,13 +212,12 @@ func (e *Engine) handleNormalMode(keys string) (bool, string) {
 		e.buffer.SetMode(ModeInsert)
 		return true, ""
 	case keys == "A":
-		MoveToLineEnd(e.buffer)
-		e.buffer.cursorX++ // Move past last character
+		e.buffer.cursorX = utf8.RuneCountInString(e.buffer.CurrentLine()) // Past last character
 		e.buffer.SetMode(ModeInsert)
 		return true, ""
 	case keys == "o":
 		e.saveUndo()
-		MoveToLineEnd(e.buffer)
+		e.buffer.cursorX = utf8.RuneCountInString(e.buffer.CurrentLine())
 		e.buffer.Insert("\n")
 		e.buffer.SetMode(ModeInsert)
 		return true, ""
@@ -185,9 +229,11 @@ func (e *Engine) handleNormalMode(keys string) (bool, string) {
 		e.buffer.SetMode(ModeInsert)
 		return true, ""
 	case keys == "v":
+		e.visualStart = e.buffer.CursorIndex()
 		e.buffer.SetMode(ModeVisual)
 		return true, ""
 	case keys == "V":
+		e.visualStart = e.buffer.CursorIndex()
 		e.buffer.SetMode(ModeVisualLine)
 		return true, ""
 
@@ -235,6 +281,56 @@ func (e *Engine) handleNormalMode(keys string) (bool, string) {
 	case keys == "%":
 		MoveToMatchingBracket(e.buffer)
 		return true, ""
+	case keys == "}":
+		MoveParagraphForward(e.buffer, count)
+		return true, ""
+	case keys == "{":
+		MoveParagraphBackward(e.buffer, count)
+		return true, ""
+
+	// Formatting
+	case strings.HasPrefix(keys, "gq") || strings.HasPrefix(keys, "gw"):
+		return e.handleFormatOperator(keys[:2], keys[2:], count)
+
+	// Screen motions
+	case keys == "H":
+		e.moveToScreenLine(count, true)
+		return true, ""
+	case keys == "M":
+		e.moveToScreenMiddle()
+		return true, ""
+	case keys == "L":
+		e.moveToScreenLine(count, false)
+		return true, ""
+
+	// Scrolling
+	case keys == "\x04": // Ctrl-D
+		e.scrollHalfPage(count, 1)
+		return true, ""
+	case keys == "\x15": // Ctrl-U
+		e.scrollHalfPage(count, -1)
+		return true, ""
+	case keys == "\x05": // Ctrl-E
+		e.scrollLines(count)
+		return true, ""
+	case keys == "\x19": // Ctrl-Y
+		e.scrollLines(-count)
+		return true, ""
+	case keys == "\x06": // Ctrl-F
+		e.scrollPage(count, 1)
+		return true, ""
+	case keys == "\x02": // Ctrl-B
+		e.scrollPage(count, -1)
+		return true, ""
+	case keys == "zt" || keys == "zz" || keys == "zb":
+		e.scrollCursorTo(keys[1])
+		return true, ""
+	case keys == "z\r" || keys == "z." || keys == "z-":
+		// Same as zt/zz/zb, but also move to the first non-blank
+		pos := map[byte]byte{'\r': 't', '.': 'z', '-': 'b'}[keys[1]]
+		e.scrollCursorTo(pos)
+		MoveToFirstNonBlank(e.buffer)
+		return true, ""
 
 	// Find character
 	case len(keys) >= 2 && keys[0] == 'f':
@@ -302,6 +398,12 @@ func (e *Engine) handleNormalMode(keys string) (bool, string) {
 			e.buffer.SetRegister(deleted + "\n")
 		}
 		return true, ""
+	case keys == "J":
+		if e.buffer.cursorY < len(e.buffer.lines)-1 {
+			e.saveUndo()
+			e.buffer.JoinLines(count)
+		}
+		return true, ""
 	case keys == "D":
 		e.saveUndo()
 		deleted := e.buffer.DeleteToEndOfLine()
@@ -311,20 +413,24 @@ func (e *Engine) handleNormalMode(keys string) (bool, string) {
 		return e.handleOperatorPending("d", keys[1:], count)
 
 	// Change operations
+	// Insert mode is entered first so the cursor may rest past the end of the line
 	case keys == "cc" || keys == "S":
+		// The line is emptied rather than removed
 		e.saveUndo()
-		e.buffer.DeleteLine()
 		e.buffer.SetMode(ModeInsert)
+		e.buffer.SetRegister(e.buffer.CurrentLine() + "\n")
+		e.buffer.lines[e.buffer.cursorY] = ""
+		e.buffer.cursorX = 0
 		return true, ""
 	case keys == "C":
 		e.saveUndo()
-		e.buffer.DeleteToEndOfLine()
 		e.buffer.SetMode(ModeInsert)
+		e.buffer.SetRegister(e.buffer.DeleteToEndOfLine())
 		return true, ""
 	case keys == "s":
 		e.saveUndo()
-		e.buffer.Delete(count)
 		e.buffer.SetMode(ModeInsert)
+		e.buffer.SetRegister(e.buffer.Delete(count))
 		return true, ""
 	case strings.HasPrefix(keys, "c"):
 		return e.handleOperatorPending("c", keys[1:], count)
@@ -354,15 +460,19 @@ func (e *Engine) handleNormalMode(keys string) (bool, string) {
 		e.saveUndo()
 		reg := e.buffer.GetRegister()
 		if strings.HasSuffix(reg, "\n") {
-			// Line-wise paste
-			MoveToLineEnd(e.buffer)
-			e.buffer.cursorX++
+			// Line-wise paste, leaving the cursor on the first pasted line
+			y := e.buffer.cursorY
+			e.buffer.cursorX = utf8.RuneCountInString(e.buffer.CurrentLine())
 			e.buffer.Insert("\n" + strings.TrimSuffix(reg, "\n"))
-			MoveDown(e.buffer, 1)
+			e.buffer.cursorY = y + 1
 			MoveToFirstNonBlank(e.buffer)
 		} else {
-			MoveRight(e.buffer, 1)
+			// Paste after the cursor, leaving it on the last pasted character
+			if utf8.RuneCountInString(e.buffer.CurrentLine()) > 0 {
+				e.buffer.cursorX++
+			}
 			e.buffer.Insert(reg)
+			e.buffer.cursorX--
 		}
 		return true, ""
 	case keys == "P":
@@ -370,12 +480,14 @@ func (e *Engine) handleNormalMode(keys string) (bool, string) {
 		reg := e.buffer.GetRegister()
 		if strings.HasSuffix(reg, "\n") {
 			// Line-wise paste above
+			y := e.buffer.cursorY
 			MoveToLineStart(e.buffer)
 			e.buffer.Insert(strings.TrimSuffix(reg, "\n") + "\n")
-			MoveUp(e.buffer, 1)
+			e.buffer.cursorY = y
 			MoveToFirstNonBlank(e.buffer)
 		} else {
 			e.buffer.Insert(reg)
+			e.buffer.cursorX--
 		}
 		return true, ""
 
@@ -388,7 +500,7 @@ func (e *Engine) handleNormalMode(keys string) (bool, string) {
 		return true, ""
 
 	// Pending - wait for more input
-	case keys == "g" || keys == "d" || keys == "c" || keys == "y" || keys == "f" || keys == "F" || keys == "t" || keys == "T" || keys == "r":
+	case keys == "g" || keys == "z" || keys == "d" || keys == "c" || keys == "y" || keys == "f" || keys == "F" || keys == "t" || keys == "T" || keys == "r":
 		return false, keys
 
 	default:
@@ -403,30 +515,67 @@ func (e *Engine) handleOperatorPending(op, motion string, count int) (bool, stri
 		return false, op // Still waiting for motion
 	}
 
+	// A count after the operator multiplies the count before it (d2w, 2d3w)
+	if motion[0] >= '1' && motion[0] <= '9' {
+		n, digits := 0, 0
+		for digits < len(motion) && motion[digits] >= '0' && motion[digits] <= '9' {
+			n = n*10 + int(motion[digits]-'0')
+			digits++
+		}
+		done, remaining := e.handleOperatorPending(op, motion[digits:], count*n)
+		if !done && strings.HasPrefix(remaining, op) {
+			// Keep the count while waiting for the rest of the motion
+			remaining = op + motion[:digits] + remaining[len(op):]
+		}
+		return done, remaining
+	}
+
 	startX, startY := e.buffer.CursorPosition()
 	startIdx := e.buffer.CursorIndex()
 
 	// Handle text objects
+	if motion == "i" || motion == "a" {
+		return false, op + motion // Need the object
+	}
 	if len(motion) >= 2 && (motion[0] == 'i' || motion[0] == 'a') {
 		return e.handleTextObject(op, motion, count)
 	}
 
-	// Execute motion
+	// Execute motion. Inclusive motions also take the character they land on,
+	// and line motions take whole lines.
 	moved := false
+	inclusive := false
+	linewise := false
 	switch motion[0] {
+	case 'j':
+		moved = MoveDown(e.buffer, count)
+		linewise = true
+		motion = motion[1:]
+	case 'k':
+		moved = MoveUp(e.buffer, count)
+		linewise = true
+		motion = motion[1:]
 	case 'w':
-		moved = MoveWordForward(e.buffer, count)
+		if op == "c" && !e.onBlank() {
+			// As in vim, cw on a word changes to the end of the word like ce
+			moved = e.changeWordEnd(count)
+			inclusive = true
+		} else {
+			moved = MoveWordForward(e.buffer, count)
+			inclusive = e.wordMotionAtLineEnd(startY)
+		}
 		motion = motion[1:]
 	case 'b':
 		moved = MoveWordBackward(e.buffer, count)
 		motion = motion[1:]
 	case 'e':
 		moved = MoveWordEnd(e.buffer, count)
-		MoveRight(e.buffer, 1) // Include the character at end
+		inclusive = true
 		motion = motion[1:]
 	case '$':
-		moved = MoveToLineEnd(e.buffer)
-		MoveRight(e.buffer, 1) // Include last char for d$
+		MoveToLineEnd(e.buffer)
+		moved = utf8.RuneCountInString(e.buffer.CurrentLine()) > 0
+		inclusive = true
 		motion = motion[1:]
 	case '0':
 		// d0 deletes from cursor to start of line
@@ -453,7 +602,7 @@ func (e *Engine) handleOperatorPending(op, motion string, count int) (bool, stri
 			} else {
 				moved = MoveToChar(e.buffer, char, count, true)
 			}
-			MoveRight(e.buffer, 1) // Include target char
+			inclusive = true
 			motion = motion[2:]
 		} else {
 			return false, op + motion // Need more input
@@ -475,15 +624,18 @@ func (e *Engine) handleOperatorPending(op, motion string, count int) (bool, stri
 			return false, op + motion // Need more input
 		}
 	case 'G':
+		// Like dd on the last line, dG there still takes the line
 		if count > 1 {
-			moved = MoveToLine(e.buffer, count)
+			MoveToLine(e.buffer, count)
 		} else {
-			moved = MoveToBufferEnd(e.buffer)
+			MoveToBufferEnd(e.buffer)
 		}
+		moved, linewise = true, true
 		motion = motion[1:]
 	case 'g':
 		if len(motion) >= 2 && motion[1] == 'g' {
-			moved = MoveToBufferStart(e.buffer)
+			MoveToBufferStart(e.buffer)
+			moved, linewise = true, true
 			motion = motion[2:]
 		} else {
 			return false, op + motion
@@ -497,7 +649,19 @@ func (e *Engine) handleOperatorPending(op, motion string, count int) (bool, stri
 		return true, motion
 	}
 
+	if linewise {
+		first, last := startY, e.buffer.cursorY
+		if first > last {
+			first, last = last, first
+		}
+		e.visualLineOperator(op, first, last)
+		return true, motion
+	}
+
 	endIdx := e.buffer.CursorIndex()
+	if inclusive {
+		endIdx++
+	}
 
 	// Ensure startIdx < endIdx
 	if startIdx > endIdx {
@@ -513,9 +677,9 @@ func (e *Engine) handleOperatorPending(op, motion string, count int) (bool, stri
 		deleted := e.buffer.Delete(endIdx - startIdx)
 		e.buffer.SetRegister(deleted)
 	case "c":
+		e.buffer.SetMode(ModeInsert)
 		deleted := e.buffer.Delete(endIdx - startIdx)
 		e.buffer.SetRegister(deleted)
-		e.buffer.SetMode(ModeInsert)
 	case "y":
 		// Yank without modifying buffer
 		text := e.buffer.Text()
@@ -528,6 +692,45 @@ func (e *Engine) handleOperatorPending(op, motion string, count int) (bool, stri
 	return true, motion
 }
 
+// onBlank returns true if the cursor is on whitespace or an empty line
+func (e *Engine) onBlank() bool {
+	runes := []rune(e.buffer.CurrentLine())
+	x := e.buffer.cursorX
+	return x >= len(runes) || unicode.IsSpace(runes[x])
+}
+
+// wordMotionAtLineEnd fixes up the end of an operator w motion. As in vim
+// it stops at the end of the starting line instead of crossing to the next,
+// and takes the last character when there is no further word to reach.
+func (e *Engine) wordMotionAtLineEnd(startY int) bool {
+	if e.buffer.cursorY != startY {
+		e.buffer.cursorY = startY
+		e.buffer.cursorX = utf8.RuneCountInString(e.buffer.CurrentLine()) - 1
+		e.buffer.clampCursor()
+		return true
+	}
+	runes := []rune(e.buffer.CurrentLine())
+	x := e.buffer.cursorX
+	if x != len(runes)-1 || unicode.IsSpace(runes[x]) {
+		return false
+	}
+	return x > 0 && classifyChar(runes[x-1]) == classifyChar(runes[x])
+}
+
+// changeWordEnd moves to the end of the word for cw. Unlike e, it stays put
+// when the cursor is already on the last character of a word.
+func (e *Engine) changeWordEnd(count int) bool {
+	runes := []rune(e.buffer.CurrentLine())
+	x := e.buffer.cursorX
+	if x+1 >= len(runes) || classifyChar(runes[x+1]) != classifyChar(runes[x]) {
+		count--
+	}
+	if count > 0 {
+		MoveWordEnd(e.buffer, count)
+	}
+	return true
+}
+
 // handleTextObject handles inner and around text objects
 func (e *Engine) handleTextObject(op, motion string, count int) (bool, string) {
 	if len(motion) < 2 {
@@ -567,11 +770,12 @@ func (e *Engine) handleTextObject(op, motion string, count int) (bool, string) {
 
 		if !inner {
 			// Include trailing whitespace for 'aw'
+			end := wordEnd
 			for wordEnd < len(runes) && unicode.IsSpace(runes[wordEnd]) {
 				wordEnd++
 			}
 			// If no trailing space, include leading space
-			if wordEnd == x+1 || (wordEnd < len(runes) && !unicode.IsSpace(runes[wordEnd-1])) {
+			if wordEnd == end {
 				for wordStart > 0 && unicode.IsSpace(runes[wordStart-1]) {
 					wordStart--
 				}
@@ -593,9 +797,9 @@ func (e *Engine) handleTextObject(op, motion string, count int) (bool, string) {
 			deleted := e.buffer.Delete(numChars)
 			e.buffer.SetRegister(deleted)
 		case "c":
+			e.buffer.SetMode(ModeInsert)
 			deleted := e.buffer.Delete(numChars)
 			e.buffer.SetRegister(deleted)
-			e.buffer.SetMode(ModeInsert)
 		case "y":
 			e.buffer.SetRegister(string(runes[wordStart:wordEnd]))
 			e.buffer.SetCursorPosition(startX, startY)
@@ -614,7 +818,7 @@ func (e *Engine) handleTextObject(op, motion string, count int) (bool, string) {
 
 		// First, check if cursor is inside a quote pair
 		// Look backward for opening quote
-		for i := x; i >= 0; i-- {
+		for i := min(x, len(runes)-1); i >= 0; i-- {
 			if runes[i] == quote {
 				// Count quotes before this to determine if it's open or close
 				quoteCount := 0
@@ -690,9 +894,9 @@ func (e *Engine) handleTextObject(op, motion string, count int) (bool, string) {
 				deleted := e.buffer.Delete(numChars)
 				e.buffer.SetRegister(deleted)
 			case "c":
+				e.buffer.SetMode(ModeInsert)
 				deleted := e.buffer.Delete(numChars)
 				e.buffer.SetRegister(deleted)
-				e.buffer.SetMode(ModeInsert)
 			case "y":
 				e.buffer.SetRegister(string(runes[start:end]))
 				e.buffer.SetCursorPosition(startX, startY)
@@ -724,7 +928,7 @@ func (e *Engine) handleBracketObject(op string, open, close rune, inner bool, re
 	// Find opening bracket (searching backward from cursor)
 	openIdx := -1
 	depth := 0
-	for i := cursorIdx; i >= 0; i-- {
+	for i := min(cursorIdx, len(runes)-1); i >= 0; i-- {
 		if runes[i] == close {
 			depth++
 		} else if runes[i] == open {
@@ -768,6 +972,13 @@ func (e *Engine) handleBracketObject(op string, open, close rune, inner bool, re
 		endIdx = closeIdx + 1
 	}
 
+	// Handle empty inner brackets
+	if startIdx == endIdx && op == "c" {
+		e.saveUndo()
+		e.buffer.SetCursorIndex(startIdx)
+		e.buffer.SetMode(ModeInsert)
+		return true, remaining
+	}
 	if startIdx >= endIdx {
 		return true, remaining
 	}
@@ -780,9 +991,9 @@ func (e *Engine) handleBracketObject(op string, open, close rune, inner bool, re
 		deleted := e.buffer.Delete(endIdx - startIdx)
 		e.buffer.SetRegister(deleted)
 	case "c":
+		e.buffer.SetMode(ModeInsert)
 		deleted := e.buffer.Delete(endIdx - startIdx)
 		e.buffer.SetRegister(deleted)
-		e.buffer.SetMode(ModeInsert)
 	case "y":
 		e.buffer.SetRegister(string(runes[startIdx:endIdx]))
 	}
@@ -790,57 +1001,6 @@ func (e *Engine) handleBracketObject(op string, open, close rune, inner bool, re
 	return true, remaining
 }
 
-// handleVisualMode handles keys in visual mode
-func (e *Engine) handleVisualMode(keys string) (bool, string) {
-	if len(keys) == 0 {
-		return false, ""
-	}
-
-	switch keys {
-	case "esc", "\x1b", "v", "V":
-		e.buffer.SetMode(ModeNormal)
-		return true, ""
-	case "d", "x":
-		// Delete visual selection (simplified - just exits visual mode for now)
-		e.buffer.SetMode(ModeNormal)
-		return true, ""
-	case "y":
-		// Yank visual selection
-		e.buffer.SetMode(ModeNormal)
-		return true, ""
-	// Add motion support in visual mode
-	case "h":
-		MoveLeft(e.buffer, 1)
-		return true, ""
-	case "l":
-		MoveRight(e.buffer, 1)
-		return true, ""
-	case "j":
-		MoveDown(e.buffer, 1)
-		return true, ""
-	case "k":
-		MoveUp(e.buffer, 1)
-		return true, ""
-	case "w":
-		MoveWordForward(e.buffer, 1)
-		return true, ""
-	case "b":
-		MoveWordBackward(e.buffer, 1)
-		return true, ""
-	case "e":
-		MoveWordEnd(e.buffer, 1)
-		return true, ""
-	case "$":
-		MoveToLineEnd(e.buffer)
-		return true, ""
-	case "0":
-		MoveToLineStart(e.buffer)
-		return true, ""
-	default:
-		return false, ""
-	}
-}
-
 // saveUndo saves current state for undo
 func (e *Engine) saveUndo() {
 	e.undoStack = append(e.undoStack, e.buffer.Clone())
@@ -884,11 +1044,13 @@ func (e *Engine) Reset(text string, cursorPos int) {
 	e.undoStack = nil
 	e.redoStack = nil
 	e.pendingKeys = ""
+	e.viewport.top = 0
+	e.scrollToCursor()
 }
 
-// GetPendingKeys returns any pending key sequence
+// GetPendingKeys returns any pending key sequence in vim notation
 func (e *Engine) GetPendingKeys() string {
-	return e.pendingKeys
+	return NormalizeKeys(e.pendingKeys)
 }
 
 // LineCount returns the number of lines in the buffer
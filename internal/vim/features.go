@@ -0,0 +1,33 @@
+package vim
+
+// Features lists what the engine supports, by the names task packs use to
+// say what their tasks need. Only list a feature once it works: each has
+// an example in features_test.go.
+var Features = []string{
+	"counts",            // 3w, 2dd
+	"word-motions",      // w b e
+	"line-motions",      // 0 ^ $ gg G
+	"find-motions",      // f F t T ; ,
+	"paragraph-motions", // { } %
+	"screen-motions",    // H M L and scrolling
+	"operators",         // d c y with motions
+	"text-objects",      // iw aw i( a" ...
+	"visual",            // v
+	"visual-line",       // V
+	"replace",           // r
+	"put",               // p P
+	"join",              // J
+	"undo",              // u <C-r>
+	"format",            // gq gw
+	"multiline",         // Tasks over several lines, cursor_end_pos
+}
+
+// HasFeature returns true if the engine supports a feature
+func HasFeature(name string) bool {
+	for _, f := range Features {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
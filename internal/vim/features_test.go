@@ -0,0 +1,67 @@
+package vim
+
+import "testing"
+
+// featureExamples shows each advertised feature working. Every entry of
+// Features needs one, so a feature can't be listed before it works.
+var featureExamples = map[string]struct {
+	text   string
+	keys   string
+	want   string
+	cursor int
+}{
+	"counts":            {"one two three four", "3w", "one two three four", 14},
+	"word-motions":      {"one two three", "wwb", "one two three", 4},
+	"line-motions":      {"one\n  two", "G^", "one\n  two", 6},
+	"find-motions":      {"a-b-c-d", "f-;;,", "a-b-c-d", 3},
+	"paragraph-motions": {"(x)\n\nend", "%}", "(x)\n\nend", 4},
+	"screen-motions":    {"1\n2\n3\n4\n5\n6", "L", "1\n2\n3\n4\n5\n6", 8},
+	"operators":         {"one two three", "wd$", "one ", 3},
+	"text-objects":      {"say (hello there)", "fhci(hi<Esc>", "say (hi)", 6},
+	"visual":            {"one two three", "wvex", "one  three", 4},
+	"visual-line":       {"one\ntwo\nthree", "jVd", "one\nthree", 4},
+	"replace":           {"cat", "rb", "bat", 0},
+	"put":               {"one two", "dwP", "one two", 3},
+	"join":              {"one\ntwo", "J", "one two", 3},
+	"undo":              {"one two", "dwxu<C-r>", "wo", 0},
+	"format":            {"one two three four", "gqq", "one two\nthree four", 8},
+	"multiline":         {"one\ntwo\nthree", "jdd", "one\nthree", 4},
+}
+
+func TestFeaturesWork(t *testing.T) {
+	for _, feature := range Features {
+		t.Run(feature, func(t *testing.T) {
+			ex, ok := featureExamples[feature]
+			if !ok {
+				t.Fatalf("feature %q has no example showing it works", feature)
+			}
+			e := NewEngine(ex.text)
+			e.SetViewportHeight(5)
+			e.SetOptions(Options{TextWidth: 10})
+			e.ProcessKeys(ParseKeys(ex.keys))
+			if got := e.Text(); got != ex.want {
+				t.Errorf("%q on %q gave %q, want %q", ex.keys, ex.text, got, ex.want)
+			}
+			if got := e.CursorIndex(); got != ex.cursor {
+				t.Errorf("%q on %q left the cursor at %d, want %d", ex.keys, ex.text, got, ex.cursor)
+			}
+		})
+	}
+}
+
+func TestHasFeature(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"counts", true},
+		{"multiline", true},
+		{"macros", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := HasFeature(tt.name); got != tt.want {
+			t.Errorf("HasFeature(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
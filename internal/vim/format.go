@@ -0,0 +1,240 @@
+package vim
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// defaultTextWidth is used when the textwidth option is 0, matching vim's
+// fallback of the screen width capped at 79
+const defaultTextWidth = 79
+
+// commentLeaders are recognised at the start of a line (after indentation)
+// and repeated on every wrapped line. Only comment syntax counts, so
+// Markdown lists and quotes are reflowed as prose.
+var commentLeaders = []string{"//", "#", "--"}
+
+// handleFormatOperator handles gq and gw with a motion or text object
+func (e *Engine) handleFormatOperator(op, motion string, count int) (bool, string) {
+	if len(motion) == 0 {
+		return false, op // Still waiting for motion
+	}
+
+	// A count after the operator multiplies the count before it (gq3j)
+	if motion[0] >= '1' && motion[0] <= '9' {
+		n, digits := parseCount(motion)
+		done, remaining := e.handleFormatOperator(op, motion[digits:], count*n)
+		if !done && strings.HasPrefix(remaining, op) {
+			// Keep the count while waiting for the rest of the motion
+			remaining = op + motion[:digits] + remaining[len(op):]
+		}
+		return done, remaining
+	}
+
+	y := e.buffer.cursorY
+	last := len(e.buffer.lines) - 1
+	start, end := y, y
+	remaining := ""
+
+	switch {
+	// gqq, gqgq, gww, gwgw
+	case motion == op[1:] || motion == op:
+		end = y + count - 1
+	case motion == "i" || motion == "a":
+		return false, op + motion // Need the object
+	case motion == "ip" || motion == "ap":
+		start, end = paragraphBounds(e.buffer, y)
+		if motion == "ap" {
+			for end < last && isBlankLine(e.buffer.lines[end+1]) {
+				end++
+			}
+		}
+	case len(motion) == 2 && (motion[0] == 'i' || motion[0] == 'a'):
+		// Other text objects lie within the cursor line
+	default:
+		// Any other motion formats the lines from the cursor to where it
+		// lands
+		var done bool
+		done, remaining, end = e.formatMotionLine(motion, count)
+		if !done {
+			if remaining != "" {
+				return false, op + remaining
+			}
+			return false, ""
+		}
+	}
+
+	if start > end {
+		start, end = end, start
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end > last {
+		end = last
+	}
+
+	e.formatLines(start, end, op == "gw")
+	return true, remaining
+}
+
+// formatMotionLine runs a motion on a copy of the engine and returns the
+// line it lands on. Commands that aren't motions, because they change the
+// text, mode or register, are rejected.
+func (e *Engine) formatMotionLine(motion string, count int) (done bool, remaining string, line int) {
+	if motion == "u" || motion == "\x12" {
+		return false, "", 0 // Undo and redo
+	}
+
+	probe := e.Clone()
+	probe.pendingKeys = ""
+	done, remaining = probe.executeNormal(motion, count)
+	if !done {
+		return false, remaining, 0
+	}
+	if probe.buffer.Mode() != ModeNormal || probe.buffer.Text() != e.buffer.Text() ||
+		probe.buffer.GetRegister() != e.buffer.GetRegister() {
+		return false, "", 0
+	}
+	return true, remaining, probe.buffer.cursorY
+}
+
+// formatLines re-wraps lines start..end to textwidth. With keepCursor the
+// cursor stays on the same character (gw), otherwise it moves to the last
+// formatted line (gq).
+func (e *Engine) formatLines(start, end int, keepCursor bool) {
+	width := e.options.TextWidth
+	if width <= 0 {
+		width = defaultTextWidth
+	}
+
+	// Remember the cursor as a count of non-blank characters into the range
+	cursorOffset := 0
+	if keepCursor {
+		for y := start; y <= e.buffer.cursorY && y <= end; y++ {
+			runes := []rune(e.buffer.lines[y])
+			limit := len(runes)
+			if y == e.buffer.cursorY {
+				limit = min(e.buffer.cursorX, len(runes))
+			}
+			for _, r := range runes[:limit] {
+				if !unicode.IsSpace(r) {
+					cursorOffset++
+				}
+			}
+		}
+	}
+
+	formatted := formatText(e.buffer.lines[start:end+1], width)
+
+	e.saveUndo()
+	newLines := make([]string, 0, len(e.buffer.lines)-(end-start+1)+len(formatted))
+	newLines = append(newLines, e.buffer.lines[:start]...)
+	newLines = append(newLines, formatted...)
+	newLines = append(newLines, e.buffer.lines[end+1:]...)
+	e.buffer.lines = newLines
+
+	if !keepCursor {
+		e.buffer.cursorY = start + len(formatted) - 1
+		e.buffer.clampCursor()
+		MoveToFirstNonBlank(e.buffer)
+		return
+	}
+
+	// Walk the formatted lines to find the same character again
+	for y := start; y < start+len(formatted); y++ {
+		for x, r := range []rune(e.buffer.lines[y]) {
+			if unicode.IsSpace(r) {
+				continue
+			}
+			if cursorOffset == 0 {
+				e.buffer.cursorY = y
+				e.buffer.cursorX = x
+				e.buffer.clampCursor()
+				return
+			}
+			cursorOffset--
+		}
+	}
+	e.buffer.cursorY = start + len(formatted) - 1
+	e.buffer.clampCursor()
+}
+
+// formatText joins and re-wraps lines to the given width. Blank lines
+// separate paragraphs and are kept. Each paragraph keeps the indentation
+// and comment leader of its first line; a change of leader starts a new
+// paragraph.
+func formatText(lines []string, width int) []string {
+	var result []string
+	var prefix string
+	var words []string
+
+	flush := func() {
+		if words != nil {
+			result = append(result, wrapWords(prefix, words, width)...)
+		}
+		words = nil
+	}
+
+	for _, line := range lines {
+		if isBlankLine(line) {
+			flush()
+			result = append(result, line)
+			continue
+		}
+
+		linePrefix, text := splitLeader(line)
+		if words != nil && strings.TrimSpace(linePrefix) != strings.TrimSpace(prefix) {
+			flush()
+		}
+		if words == nil {
+			prefix = linePrefix
+			words = []string{}
+		}
+		words = append(words, strings.Fields(text)...)
+	}
+	flush()
+
+	return result
+}
+
+// wrapWords lays out words after prefix, breaking lines at width
+func wrapWords(prefix string, words []string, width int) []string {
+	if len(words) == 0 {
+		return []string{strings.TrimRightFunc(prefix, unicode.IsSpace)}
+	}
+
+	var lines []string
+	current := prefix + words[0]
+	for _, word := range words[1:] {
+		if utf8.RuneCountInString(current)+1+utf8.RuneCountInString(word) > width {
+			lines = append(lines, current)
+			current = prefix + word
+			continue
+		}
+		current += " " + word
+	}
+	return append(lines, current)
+}
+
+// splitLeader splits a line into its indentation plus comment leader, and
+// the remaining text
+func splitLeader(line string) (prefix, text string) {
+	trimmed := strings.TrimLeftFunc(line, unicode.IsSpace)
+	indent := line[:len(line)-len(trimmed)]
+
+	for _, leader := range commentLeaders {
+		if strings.HasPrefix(trimmed, leader) {
+			rest := trimmed[len(leader):]
+			body := strings.TrimLeft(rest, " \t")
+			return indent + leader + rest[:len(rest)-len(body)], body
+		}
+	}
+	return indent, trimmed
+}
+
+// isBlankLine returns true if the line contains only whitespace
+func isBlankLine(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
@@ -0,0 +1,89 @@
+package vim
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFormatText(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		width int
+		want  []string
+	}{
+		{"short line kept", []string{"one two"}, 20, []string{"one two"}},
+		{"wrapped", []string{"one two three four"}, 10, []string{"one two", "three four"}},
+		{"joined", []string{"one", "two", "three"}, 20, []string{"one two three"}},
+		{"long word on its own line", []string{"a verylongword b"}, 5, []string{"a", "verylongword", "b"}},
+		{"blank lines split paragraphs", []string{"one", "two", "", "three"}, 20, []string{"one two", "", "three"}},
+		{"indent kept", []string{"  one two three"}, 9, []string{"  one two", "  three"}},
+		{"comment leader repeated", []string{"// one two three"}, 10, []string{"// one two", "// three"}},
+		{"hash leader", []string{"# one two three"}, 9, []string{"# one two", "# three"}},
+		{"leader change splits", []string{"// one", "two"}, 20, []string{"// one", "two"}},
+		{"markdown list is prose", []string{"- one two three"}, 9, []string{"- one two", "three"}},
+		{"empty comment", []string{"//"}, 10, []string{"//"}},
+	}
+	for _, tt := range tests {
+		if got := formatText(tt.lines, tt.width); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: formatText(%q, %d) = %q, want %q", tt.name, tt.lines, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestFormatOperator(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		keys   string
+		want   string
+		cursor int
+	}{
+		{"gqq", "one two three\nfour", "gqq", "one two\nthree\nfour", 8},
+		{"gqgq", "one two three", "gqgq", "one two\nthree", 8},
+		{"gqj", "one\ntwo\nthree", "gqj", "one two\nthree", 0},
+		{"gq2j", "a\nb\nc\nd", "gq2j", "a b c\nd", 0},
+		{"2gqj counts multiply", "a\nb\nc\nd", "2gqj", "a b c\nd", 0},
+		{"gqG", "a\nb\n\nc\nd", "gqG", "a b\n\nc d", 5},
+		{"gqap", "a\nb\n\nc", "gqap", "a b\n\nc", 4},
+		{"gqip", "a\nb\n\nc", "jgqip", "a b\n\nc", 0},
+		{"gq}", "a\nb\n\nc", "gq}", "a b\n\nc", 4},
+		{"gwip keeps cursor", "one two\nthree", "wgwip", "one two\nthree", 4},
+		{"gw keeps cursor across wrap", "one two three", "2wgww", "one two\nthree", 8},
+		{"undo is not a motion", "one two three", "gqu", "one two three", 0},
+		{"change is not a motion", "one two three", "gqx", "one two three", 0},
+		{"comments", "// one two three", "gqq", "// one two\n// three", 11},
+		{"undo formatting", "one two three", "gqqu", "one two three", 0},
+	}
+	for _, tt := range tests {
+		e := NewEngine(tt.text)
+		e.SetOptions(Options{TextWidth: 10})
+		e.ProcessKeys(ParseKeys(tt.keys))
+		if got := e.Text(); got != tt.want {
+			t.Errorf("%s: %q on %q gave %q, want %q", tt.name, tt.keys, tt.text, got, tt.want)
+		}
+		if got := e.CursorIndex(); got != tt.cursor {
+			t.Errorf("%s: %q left the cursor at %d, want %d", tt.name, tt.keys, got, tt.cursor)
+		}
+		if e.Mode() != ModeNormal {
+			t.Errorf("%s: %q left mode %v", tt.name, tt.keys, e.Mode())
+		}
+	}
+}
+
+func TestFormatDefaultTextWidth(t *testing.T) {
+	long := ""
+	for i := 0; i < 20; i++ {
+		long += "word "
+	}
+	e := NewEngine(long)
+	e.ProcessKeys(ParseKeys("gqq"))
+	for _, line := range e.Buffer().lines {
+		if len(line) > defaultTextWidth {
+			t.Errorf("line %q is over %d characters", line, defaultTextWidth)
+		}
+	}
+	if e.LineCount() != 2 {
+		t.Errorf("got %d lines, want 2", e.LineCount())
+	}
+}
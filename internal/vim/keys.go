@@ -0,0 +1,206 @@
+package vim
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Key is a single keystroke in canonical form. Printable keys hold the
+// character itself ("w", " ", "<"); special keys hold their vim notation
+// ("<Esc>", "<CR>", "<C-r>").
+type Key string
+
+// Special keys
+const (
+	KeyEsc       Key = "<Esc>"
+	KeyEnter     Key = "<CR>"
+	KeyBackspace Key = "<BS>"
+	KeyTab       Key = "<Tab>"
+	KeyDelete    Key = "<Del>"
+	KeyUp        Key = "<Up>"
+	KeyDown      Key = "<Down>"
+	KeyLeft      Key = "<Left>"
+	KeyRight     Key = "<Right>"
+)
+
+// keyNames maps lower-cased notation names to their canonical key
+var keyNames = map[string]Key{
+	"esc":       KeyEsc,
+	"cr":        KeyEnter,
+	"enter":     KeyEnter,
+	"return":    KeyEnter,
+	"bs":        KeyBackspace,
+	"backspace": KeyBackspace,
+	"tab":       KeyTab,
+	"del":       KeyDelete,
+	"delete":    KeyDelete,
+	"up":        KeyUp,
+	"down":      KeyDown,
+	"left":      KeyLeft,
+	"right":     KeyRight,
+	"space":     " ",
+	"lt":        "<",
+	"bar":       "|",
+	"bslash":    "\\",
+}
+
+// CtrlKey returns the key for Ctrl plus the given letter
+func CtrlKey(r rune) Key {
+	return Key(fmt.Sprintf("<C-%c>", unicode.ToLower(r)))
+}
+
+// IsSpecial returns true if the key is a named or modified key
+func (k Key) IsSpecial() bool {
+	return len(k) > 2 && k[0] == '<' && k[len(k)-1] == '>'
+}
+
+// String returns the key in vim notation
+func (k Key) String() string {
+	if k == "<" {
+		return "<lt>"
+	}
+	return string(k)
+}
+
+// raw returns the byte sequence the engine understands for this key,
+// or an empty string if the engine has no use for it
+func (k Key) raw() string {
+	switch k {
+	case KeyEsc:
+		return "\x1b"
+	case KeyEnter:
+		return "\r"
+	case KeyBackspace:
+		return "\x7f"
+	case KeyTab:
+		return "\t"
+	}
+	if r, ok := k.ctrlLetter(); ok {
+		return string(r - 'a' + 1)
+	}
+	if k.IsSpecial() {
+		return ""
+	}
+	return string(k)
+}
+
+// ctrlLetter returns the letter of a <C-x> key
+func (k Key) ctrlLetter() (rune, bool) {
+	s := string(k)
+	if len(s) != 5 || !strings.HasPrefix(s, "<C-") || s[4] != '>' {
+		return 0, false
+	}
+	r := rune(s[3])
+	if r < 'a' || r > 'z' {
+		return 0, false
+	}
+	return r, true
+}
+
+// ParseKey parses a single key. It accepts vim notation ("<Esc>", "<C-r>"),
+// a single character, raw control characters ("\x1b", "\r") and the key
+// names produced by terminal libraries ("esc", "enter", "ctrl+r").
+func ParseKey(s string) (Key, error) {
+	if s == "" {
+		return "", fmt.Errorf("empty key")
+	}
+
+	if utf8.RuneCountInString(s) == 1 {
+		r, _ := utf8.DecodeRuneInString(s)
+		return keyFromRune(r), nil
+	}
+
+	if strings.HasPrefix(s, "<") && strings.HasSuffix(s, ">") {
+		if k, ok := parseNotation(s[1 : len(s)-1]); ok {
+			return k, nil
+		}
+	}
+
+	lower := strings.ToLower(s)
+	if k, ok := keyNames[lower]; ok {
+		return k, nil
+	}
+	if strings.HasPrefix(lower, "ctrl+") && len(lower) == 6 {
+		if k, ok := parseNotation("C-" + lower[5:]); ok {
+			return k, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown key %q", s)
+}
+
+// ParseKeys parses a key sequence in vim notation, e.g. "cwnew<Esc>".
+// As in vim, a '<' that does not start a recognised key name is taken
+// literally.
+func ParseKeys(s string) []Key {
+	var keys []Key
+	for i := 0; i < len(s); {
+		if s[i] == '<' {
+			if end := strings.IndexByte(s[i:], '>'); end > 1 {
+				if k, ok := parseNotation(s[i+1 : i+end]); ok {
+					keys = append(keys, k)
+					i += end + 1
+					continue
+				}
+			}
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		keys = append(keys, keyFromRune(r))
+		i += size
+	}
+	return keys
+}
+
+// FormatKeys serializes a key sequence to vim notation
+func FormatKeys(keys []Key) string {
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k.String())
+	}
+	return b.String()
+}
+
+// NormalizeKeys rewrites a key sequence in canonical notation, so that
+// "<ESC>" and "<esc>" both become "<Esc>"
+func NormalizeKeys(s string) string {
+	return FormatKeys(ParseKeys(s))
+}
+
+// parseNotation parses the inside of a <...> key name
+func parseNotation(name string) (Key, bool) {
+	lower := strings.ToLower(name)
+	if k, ok := keyNames[lower]; ok {
+		return k, true
+	}
+	if len(lower) == 3 && lower[:2] == "c-" {
+		r := rune(lower[2])
+		if r >= 'a' && r <= 'z' {
+			return CtrlKey(r), true
+		}
+		if r == '[' {
+			return KeyEsc, true
+		}
+	}
+	return "", false
+}
+
+// keyFromRune converts a single character, including raw control
+// characters, to its canonical key
+func keyFromRune(r rune) Key {
+	switch r {
+	case '\x1b':
+		return KeyEsc
+	case '\r', '\n':
+		return KeyEnter
+	case '\x7f', '\b':
+		return KeyBackspace
+	case '\t':
+		return KeyTab
+	}
+	if r >= 1 && r <= 26 {
+		return CtrlKey('a' + r - 1)
+	}
+	return Key(string(r))
+}
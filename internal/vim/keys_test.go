@@ -0,0 +1,120 @@
+package vim
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Key
+		wantErr bool
+	}{
+		{"w", "w", false},
+		{"<", "<", false},
+		{"<Esc>", KeyEsc, false},
+		{"<esc>", KeyEsc, false},
+		{"<C-[>", KeyEsc, false},
+		{"<CR>", KeyEnter, false},
+		{"<C-r>", "<C-r>", false},
+		{"<C-R>", "<C-r>", false},
+		{"<lt>", "<", false},
+		{"<Space>", " ", false},
+		{"\x1b", KeyEsc, false},
+		{"\r", KeyEnter, false},
+		{"\x12", "<C-r>", false},
+		{"esc", KeyEsc, false},
+		{"enter", KeyEnter, false},
+		{"ctrl+d", "<C-d>", false},
+		{"", "", true},
+		{"<Nope>", "", true},
+		{"ctrl+", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseKey(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseKey(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseKey(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []Key
+	}{
+		{"", nil},
+		{"dw", []Key{"d", "w"}},
+		{"cwnew<Esc>", []Key{"c", "w", "n", "e", "w", KeyEsc}},
+		{"u<C-r>", []Key{"u", "<C-r>"}},
+		{"i<lt>b><Esc>", []Key{"i", "<", "b", ">", KeyEsc}},
+		{"i<b><Esc>", []Key{"i", "<", "b", ">", KeyEsc}},
+		{"a<", []Key{"a", "<"}},
+		{"f\x1b", []Key{"f", KeyEsc}},
+		{"ié", []Key{"i", "é"}},
+	}
+	for _, tt := range tests {
+		if got := ParseKeys(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseKeys(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFormatKeys(t *testing.T) {
+	tests := []struct {
+		keys []Key
+		want string
+	}{
+		{nil, ""},
+		{[]Key{"d", "w"}, "dw"},
+		{[]Key{"i", "<", KeyEsc}, "i<lt><Esc>"},
+		{[]Key{"u", CtrlKey('R')}, "u<C-r>"},
+	}
+	for _, tt := range tests {
+		if got := FormatKeys(tt.keys); got != tt.want {
+			t.Errorf("FormatKeys(%q) = %q, want %q", tt.keys, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeKeys(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"cwx<ESC>", "cwx<Esc>"},
+		{"cwx<esc>", "cwx<Esc>"},
+		{"cwx\x1b", "cwx<Esc>"},
+		{"i<lt><Esc>", "i<lt><Esc>"},
+		{"i<x", "i<lt>x"},
+		{"<c-R>", "<C-r>"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeKeys(tt.in); got != tt.want {
+			t.Errorf("NormalizeKeys(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestKeysDriveEngine(t *testing.T) {
+	tests := []struct {
+		text, keys, want string
+	}{
+		{"one two", "cwnew<Esc>", "new two"},
+		{"one two", "dwu<C-r>", "two"},
+		{"a b", "ciw<lt>x><Esc>", "<x> b"},
+		{"ab", "i<CR><Esc>", "\nab"},
+		{"abc", "A<BS><Esc>", "ab"},
+	}
+	for _, tt := range tests {
+		e := NewEngine(tt.text)
+		e.ProcessKeys(ParseKeys(tt.keys))
+		if got := e.Text(); got != tt.want {
+			t.Errorf("%q on %q gave %q, want %q", tt.keys, tt.text, got, tt.want)
+		}
+	}
+}
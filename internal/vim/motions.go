@@ -204,6 +204,9 @@ func moveWordBackwardOnce(b *Buffer) bool {
 	if b.cursorX > 0 {
 		b.cursorX--
 	}
+	if b.cursorX >= len(runes) {
+		b.cursorX = max(len(runes)-1, 0)
+	}
 
 	// Skip whitespace backward
 	for b.cursorX > 0 && unicode.IsSpace(runes[b.cursorX]) {
@@ -447,3 +450,66 @@ func MoveToMatchingBracket(b *Buffer) bool {
 
 	return false
 }
+
+// MoveParagraphForward moves cursor to the blank line after the paragraph
+func MoveParagraphForward(b *Buffer, count int) bool {
+	startY := b.cursorY
+	b.cursorY = paragraphForward(b, b.cursorY, count)
+	b.cursorX = 0
+	if b.cursorY == len(b.lines)-1 && !isBlankLine(b.lines[b.cursorY]) {
+		b.cursorX = utf8.RuneCountInString(b.lines[b.cursorY])
+	}
+	b.clampCursor()
+	return b.cursorY != startY
+}
+
+// MoveParagraphBackward moves cursor to the blank line before the paragraph
+func MoveParagraphBackward(b *Buffer, count int) bool {
+	startY := b.cursorY
+	b.cursorY = paragraphBackward(b, b.cursorY, count)
+	b.cursorX = 0
+	b.clampCursor()
+	return b.cursorY != startY
+}
+
+// paragraphForward returns the line a '}' motion lands on
+func paragraphForward(b *Buffer, y, count int) int {
+	last := len(b.lines) - 1
+	for i := 0; i < count && y < last; i++ {
+		// Skip blank lines, then the paragraph itself
+		for y < last && isBlankLine(b.lines[y]) {
+			y++
+		}
+		for y < last && !isBlankLine(b.lines[y]) {
+			y++
+		}
+	}
+	return y
+}
+
+// paragraphBackward returns the line a '{' motion lands on
+func paragraphBackward(b *Buffer, y, count int) int {
+	for i := 0; i < count && y > 0; i++ {
+		for y > 0 && isBlankLine(b.lines[y]) {
+			y--
+		}
+		for y > 0 && !isBlankLine(b.lines[y]) {
+			y--
+		}
+	}
+	return y
+}
+
+// paragraphBounds returns the first and last line of the paragraph (or run
+// of blank lines) containing line y
+func paragraphBounds(b *Buffer, y int) (start, end int) {
+	blank := isBlankLine(b.lines[y])
+	start, end = y, y
+	for start > 0 && isBlankLine(b.lines[start-1]) == blank {
+		start--
+	}
+	for end < len(b.lines)-1 && isBlankLine(b.lines[end+1]) == blank {
+		end++
+	}
+	return start, end
+}
@@ -0,0 +1,106 @@
+package vim
+
+// BufferState is a snapshot of a buffer's text, cursor, mode and register
+type BufferState struct {
+	Lines    []string `json:"lines"`
+	CursorX  int      `json:"cursor_x"`
+	CursorY  int      `json:"cursor_y"`
+	Mode     Mode     `json:"mode"`
+	Register string   `json:"register,omitempty"`
+}
+
+// EngineState is a snapshot of everything an engine holds, including its
+// undo and redo history and keys of an unfinished command, so an engine
+// can be saved and carry on where it left off
+type EngineState struct {
+	Buffer      BufferState   `json:"buffer"`
+	Undo        []BufferState `json:"undo,omitempty"`
+	Redo        []BufferState `json:"redo,omitempty"`
+	PendingKeys string        `json:"pending_keys,omitempty"` // Raw keys, as the engine reads them
+	LastSearch  string        `json:"last_search,omitempty"`  // Character of the last f, t, F or T
+	SearchDir   int           `json:"search_dir,omitempty"`
+	LastMotion  string        `json:"last_motion,omitempty"`
+	ViewTop     int           `json:"view_top"`
+	ViewHeight  int           `json:"view_height"`
+	ScrollOff   int           `json:"scrolloff,omitempty"`
+	TextWidth   int           `json:"textwidth,omitempty"`
+	VisualStart int           `json:"visual_start"`
+}
+
+// State returns a snapshot of the buffer
+func (b *Buffer) State() BufferState {
+	return BufferState{
+		Lines:    append([]string{}, b.lines...),
+		CursorX:  b.cursorX,
+		CursorY:  b.cursorY,
+		Mode:     b.mode,
+		Register: b.register,
+	}
+}
+
+// RestoreBuffer creates a buffer from a snapshot
+func RestoreBuffer(state BufferState) *Buffer {
+	b := &Buffer{
+		lines:    append([]string{}, state.Lines...),
+		cursorX:  state.CursorX,
+		cursorY:  state.CursorY,
+		mode:     state.Mode,
+		register: state.Register,
+	}
+	if len(b.lines) == 0 {
+		b.lines = []string{""}
+	}
+	b.clampCursor()
+	return b
+}
+
+// State returns a snapshot of the engine
+func (e *Engine) State() EngineState {
+	state := EngineState{
+		Buffer:      e.buffer.State(),
+		PendingKeys: e.pendingKeys,
+		SearchDir:   e.searchDir,
+		LastMotion:  e.lastMotion,
+		ViewTop:     e.viewport.top,
+		ViewHeight:  e.viewport.height,
+		ScrollOff:   e.options.ScrollOff,
+		TextWidth:   e.options.TextWidth,
+		VisualStart: e.visualStart,
+	}
+	if e.lastSearch != 0 {
+		state.LastSearch = string(e.lastSearch)
+	}
+	for _, b := range e.undoStack {
+		state.Undo = append(state.Undo, b.State())
+	}
+	for _, b := range e.redoStack {
+		state.Redo = append(state.Redo, b.State())
+	}
+	return state
+}
+
+// RestoreEngine creates an engine from a snapshot
+func RestoreEngine(state EngineState) *Engine {
+	e := &Engine{
+		buffer:      RestoreBuffer(state.Buffer),
+		undoStack:   make([]*Buffer, 0, len(state.Undo)),
+		redoStack:   make([]*Buffer, 0, len(state.Redo)),
+		pendingKeys: state.PendingKeys,
+		searchDir:   state.SearchDir,
+		lastMotion:  state.LastMotion,
+		viewport:    Viewport{top: state.ViewTop, height: state.ViewHeight},
+		options:     Options{ScrollOff: state.ScrollOff, TextWidth: state.TextWidth},
+		visualStart: state.VisualStart,
+	}
+	for _, r := range state.LastSearch {
+		e.lastSearch = r
+		break
+	}
+	for _, b := range state.Undo {
+		e.undoStack = append(e.undoStack, RestoreBuffer(b))
+	}
+	for _, b := range state.Redo {
+		e.redoStack = append(e.redoStack, RestoreBuffer(b))
+	}
+	return e
+}
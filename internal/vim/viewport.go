@@ -0,0 +1,231 @@
+package vim
+
+// Viewport tracks which lines of the buffer are visible on screen
+type Viewport struct {
+	top    int // First visible line
+	height int // Number of visible lines, 0 means the whole buffer
+}
+
+// Options holds vim options that affect command behaviour
+type Options struct {
+	ScrollOff int // Minimum lines kept above and below the cursor
+	TextWidth int // Line length for gq/gw, 0 means 79
+}
+
+// SetViewportHeight sets the number of visible lines
+func (e *Engine) SetViewportHeight(height int) {
+	if height < 0 {
+		height = 0
+	}
+	e.viewport.height = height
+	e.scrollToCursor()
+}
+
+// Viewport returns the first visible line and the number of visible lines
+func (e *Engine) Viewport() (top, height int) {
+	return e.viewport.top, e.viewHeight()
+}
+
+// Options returns the current option values
+func (e *Engine) Options() Options {
+	return e.options
+}
+
+// SetOptions replaces the current option values
+func (e *Engine) SetOptions(opts Options) {
+	e.options = opts
+	e.scrollToCursor()
+}
+
+// viewHeight returns the effective viewport height
+func (e *Engine) viewHeight() int {
+	if e.viewport.height <= 0 {
+		return len(e.buffer.lines)
+	}
+	return e.viewport.height
+}
+
+// scrollOff returns the effective scrolloff, which vim limits to half the window
+func (e *Engine) scrollOff() int {
+	so := e.options.ScrollOff
+	if half := (e.viewHeight() - 1) / 2; so > half {
+		so = half
+	}
+	if so < 0 {
+		so = 0
+	}
+	return so
+}
+
+// bottomLine returns the last visible line
+func (e *Engine) bottomLine() int {
+	bottom := e.viewport.top + e.viewHeight() - 1
+	if last := len(e.buffer.lines) - 1; bottom > last {
+		bottom = last
+	}
+	return bottom
+}
+
+// setTop sets the first visible line, keeping it inside the buffer
+func (e *Engine) setTop(top int) {
+	if last := len(e.buffer.lines) - 1; top > last {
+		top = last
+	}
+	if top < 0 {
+		top = 0
+	}
+	e.viewport.top = top
+}
+
+// scrollToCursor scrolls the viewport so the cursor respects scrolloff
+func (e *Engine) scrollToCursor() {
+	so := e.scrollOff()
+	y := e.buffer.cursorY
+	height := e.viewHeight()
+
+	if y-so < e.viewport.top {
+		e.setTop(y - so)
+	}
+	if y+so > e.viewport.top+height-1 {
+		// Don't scroll past the end of the buffer just for scrolloff
+		top := y + so - height + 1
+		if maxTop := len(e.buffer.lines) - height; top > maxTop {
+			top = max(maxTop, y-height+1)
+		}
+		e.setTop(top)
+	}
+}
+
+// cursorIntoView moves the cursor into the viewport after a scroll
+func (e *Engine) cursorIntoView() {
+	so := e.scrollOff()
+	minY := e.viewport.top + so
+	if e.viewport.top == 0 {
+		minY = 0
+	}
+	maxY := e.viewport.top + e.viewHeight() - 1 - so
+	if last := len(e.buffer.lines) - 1; e.viewport.top+e.viewHeight()-1 >= last {
+		maxY = last
+	}
+
+	y := e.buffer.cursorY
+	if y < minY {
+		y = minY
+	}
+	if y > maxY {
+		y = maxY
+	}
+	if y != e.buffer.cursorY {
+		e.buffer.cursorY = y
+		e.buffer.clampCursor()
+		MoveToFirstNonBlank(e.buffer)
+	}
+}
+
+// moveToScreenLine moves the cursor to a line relative to the viewport
+// for H (fromTop), L (!fromTop) with a count
+func (e *Engine) moveToScreenLine(count int, fromTop bool) {
+	so := e.scrollOff()
+	last := len(e.buffer.lines) - 1
+	top, bottom := e.viewport.top, e.bottomLine()
+
+	var y int
+	if fromTop {
+		y = top + count - 1
+		if top > 0 && y < top+so {
+			y = top + so
+		}
+	} else {
+		y = bottom - count + 1
+		if bottom < last && y > bottom-so {
+			y = bottom - so
+		}
+	}
+	if y < top {
+		y = top
+	}
+	if y > bottom {
+		y = bottom
+	}
+
+	e.buffer.cursorY = y
+	e.buffer.clampCursor()
+	MoveToFirstNonBlank(e.buffer)
+}
+
+// moveToScreenMiddle moves the cursor to the middle visible line (M)
+func (e *Engine) moveToScreenMiddle() {
+	top, bottom := e.viewport.top, e.bottomLine()
+	e.buffer.cursorY = top + (bottom-top)/2
+	e.buffer.clampCursor()
+	MoveToFirstNonBlank(e.buffer)
+}
+
+// scrollHalfPage scrolls the viewport and cursor by half a window (Ctrl-D/U).
+// A count larger than one sets the number of lines instead.
+func (e *Engine) scrollHalfPage(count, dir int) {
+	amount := e.viewHeight() / 2
+	if count > 1 {
+		amount = count
+	}
+	if amount < 1 {
+		amount = 1
+	}
+
+	last := len(e.buffer.lines) - 1
+	if dir > 0 {
+		if e.bottomLine() < last {
+			e.setTop(e.viewport.top + amount)
+		}
+		e.buffer.cursorY += amount
+	} else {
+		e.setTop(e.viewport.top - amount)
+		e.buffer.cursorY -= amount
+	}
+	e.buffer.clampCursor()
+	MoveToFirstNonBlank(e.buffer)
+	e.cursorIntoView()
+}
+
+// scrollPage scrolls the viewport by whole windows (Ctrl-F/B), keeping two
+// lines of context as vim does
+func (e *Engine) scrollPage(count, dir int) {
+	page := e.viewHeight() - 2
+	if page < 1 {
+		page = 1
+	}
+
+	if dir > 0 {
+		if e.bottomLine() >= len(e.buffer.lines)-1 {
+			return
+		}
+		e.setTop(e.viewport.top + count*page)
+	} else {
+		e.setTop(e.viewport.top - count*page)
+	}
+	e.cursorIntoView()
+}
+
+// scrollLines scrolls the viewport without moving the cursor unless it
+// would leave the window (Ctrl-E/Y)
+func (e *Engine) scrollLines(n int) {
+	e.setTop(e.viewport.top + n)
+	e.cursorIntoView()
+}
+
+// scrollCursorTo positions the cursor line at the top, middle or bottom of
+// the window (zt, zz, zb)
+func (e *Engine) scrollCursorTo(pos byte) {
+	so := e.scrollOff()
+	y := e.buffer.cursorY
+	height := e.viewHeight()
+
+	switch pos {
+	case 't':
+		e.setTop(y - so)
+	case 'z':
+		e.setTop(y - (height-1)/2)
+	case 'b':
+		e.setTop(y - height + 1 + so)
+	}
+}
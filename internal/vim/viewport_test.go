@@ -0,0 +1,71 @@
+package vim
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// numberedLines returns n lines "l0", "l1", ...
+func numberedLines(n int) string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("l%d", i)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestViewportMotions(t *testing.T) {
+	tests := []struct {
+		keys      string
+		scrollOff int
+		wantLine  int
+		wantTop   int
+	}{
+		{"L", 0, 4, 0},
+		{"3H", 0, 2, 0},
+		{"M", 0, 2, 0},
+		{"2L", 0, 3, 0},
+		{"L", 1, 3, 0},
+		{"5j", 0, 5, 1},
+		{"5j", 1, 5, 2},
+		{"<C-d>", 0, 2, 2},
+		{"G<C-u>", 0, 17, 13},
+		{"<C-e>", 0, 1, 1},
+		{"G<C-y>", 0, 18, 14},
+		{"<C-f>", 0, 3, 3},
+		{"G<C-b>", 0, 16, 12},
+		{"10Gzt", 0, 9, 9},
+		{"10Gzz", 0, 9, 7},
+		{"10Gzb", 0, 9, 5},
+		{"10Gzt", 1, 9, 8},
+		{"G", 0, 19, 15},
+		{"Ggg", 0, 0, 0},
+	}
+	for _, tt := range tests {
+		e := NewEngine(numberedLines(20))
+		e.SetOptions(Options{ScrollOff: tt.scrollOff})
+		e.SetViewportHeight(5)
+		e.ProcessKeys(ParseKeys(tt.keys))
+		_, line := e.CursorPosition()
+		top, height := e.Viewport()
+		if line != tt.wantLine || top != tt.wantTop {
+			t.Errorf("%q with scrolloff %d: cursor line %d, top %d; want line %d, top %d",
+				tt.keys, tt.scrollOff, line, top, tt.wantLine, tt.wantTop)
+		}
+		if height != 5 {
+			t.Errorf("%q: viewport height %d, want 5", tt.keys, height)
+		}
+	}
+}
+
+func TestViewportWholeBuffer(t *testing.T) {
+	e := NewEngine(numberedLines(3))
+	if top, height := e.Viewport(); top != 0 || height != 3 {
+		t.Errorf("unset viewport = %d, %d; want 0, 3", top, height)
+	}
+	e.ProcessKeys(ParseKeys("L"))
+	if _, line := e.CursorPosition(); line != 2 {
+		t.Errorf("L without a viewport height went to line %d, want 2", line)
+	}
+}
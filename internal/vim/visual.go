@@ -0,0 +1,203 @@
+package vim
+
+import (
+	"strings"
+	"unicode"
+)
+
+// visualMotions are the single-key motions that extend a visual selection
+const visualMotions = "hjklwbe0^$G{}HML%"
+
+// handleVisualMode handles keys in visual and visual line mode
+func (e *Engine) handleVisualMode(keys string) (bool, string) {
+	if len(keys) == 0 {
+		return false, ""
+	}
+
+	switch keys {
+	case "\x1b":
+		e.buffer.SetMode(ModeNormal)
+		return true, ""
+	case "v", "V":
+		// The same key leaves visual mode, the other one switches kind
+		mode := ModeVisual
+		if keys == "V" {
+			mode = ModeVisualLine
+		}
+		if e.buffer.Mode() == mode {
+			mode = ModeNormal
+		}
+		e.buffer.SetMode(mode)
+		return true, ""
+	case "o":
+		// Move to the other end of the selection
+		cursor := e.buffer.CursorIndex()
+		e.buffer.SetCursorIndex(e.visualStart)
+		e.visualStart = cursor
+		return true, ""
+	case "d", "x":
+		e.visualOperator("d")
+		return true, ""
+	case "c", "s":
+		e.visualOperator("c")
+		return true, ""
+	case "y":
+		e.visualOperator("y")
+		return true, ""
+	case "J":
+		e.visualJoin()
+		return true, ""
+	case "i", "a":
+		return false, keys // Need the object
+	case "iw", "aw":
+		e.selectWord(keys == "aw")
+		return true, ""
+	}
+
+	return e.visualMotion(keys)
+}
+
+// visualMotion moves the cursor, and with it the end of the selection
+func (e *Engine) visualMotion(keys string) (bool, string) {
+	count, digits := parseCount(keys)
+	motion := keys[digits:]
+
+	switch {
+	case motion == "":
+		return false, keys
+	case len(motion) == 1 && strings.ContainsAny(motion, "fFtTg"):
+		return false, keys // Need the target
+	case len(motion) == 1 && strings.Contains(visualMotions, motion),
+		len(motion) == 2 && strings.ContainsAny(motion[:1], "fFtT"),
+		motion == "gg":
+		return e.executeNormal(motion, count)
+	}
+	return false, ""
+}
+
+// visualRange returns the selected character range, start..end exclusive
+func (e *Engine) visualRange() (start, end int) {
+	start, end = e.visualStart, e.buffer.CursorIndex()
+	if start > end {
+		start, end = end, start
+	}
+	return start, end + 1
+}
+
+// visualOperator applies d, c or y to the selection and leaves visual mode
+func (e *Engine) visualOperator(op string) {
+	linewise := e.buffer.Mode() == ModeVisualLine
+	start, end := e.visualRange()
+	e.buffer.SetMode(ModeNormal)
+
+	if linewise {
+		e.visualLineOperator(op, lineAt(e.buffer.lines, start), lineAt(e.buffer.lines, end-1))
+		return
+	}
+
+	runes := []rune(e.buffer.Text())
+	end = min(end, len(runes))
+	e.buffer.SetRegister(string(runes[start:end]))
+
+	switch op {
+	case "d":
+		e.saveUndo()
+		e.buffer.SetCursorIndex(start)
+		e.buffer.Delete(end - start)
+	case "c":
+		e.saveUndo()
+		e.buffer.SetMode(ModeInsert)
+		e.buffer.SetCursorIndex(start)
+		e.buffer.Delete(end - start)
+	case "y":
+		e.buffer.SetCursorIndex(start)
+	}
+}
+
+// visualJoin joins the selected lines, at least two, and leaves visual mode
+func (e *Engine) visualJoin() {
+	start, end := e.visualRange()
+	first, last := lineAt(e.buffer.lines, start), lineAt(e.buffer.lines, end-1)
+	e.buffer.SetMode(ModeNormal)
+	e.buffer.SetCursorPosition(0, first)
+	if first < len(e.buffer.lines)-1 {
+		e.saveUndo()
+		e.buffer.JoinLines(last - first + 1)
+	}
+}
+
+// visualLineOperator applies d, c or y to lines first..last
+func (e *Engine) visualLineOperator(op string, first, last int) {
+	lines := e.buffer.lines
+	e.buffer.SetRegister(strings.Join(lines[first:last+1], "\n") + "\n")
+
+	switch op {
+	case "d":
+		e.saveUndo()
+		newLines := append(append([]string{}, lines[:first]...), lines[last+1:]...)
+		if len(newLines) == 0 {
+			newLines = []string{""}
+		}
+		e.buffer.lines = newLines
+		e.buffer.cursorY = first
+		e.buffer.clampCursor()
+		MoveToFirstNonBlank(e.buffer)
+	case "c":
+		// The lines are replaced by one empty line
+		e.saveUndo()
+		newLines := append(append([]string{}, lines[:first]...), "")
+		e.buffer.lines = append(newLines, lines[last+1:]...)
+		e.buffer.SetMode(ModeInsert)
+		e.buffer.SetCursorPosition(0, first)
+	case "y":
+		e.buffer.cursorY = first
+		e.buffer.clampCursor()
+	}
+}
+
+// selectWord selects the word under the cursor (iw), with the whitespace
+// around it for aw
+func (e *Engine) selectWord(around bool) {
+	runes := []rune(e.buffer.CurrentLine())
+	x := e.buffer.cursorX
+	if x >= len(runes) {
+		return
+	}
+
+	start, end := x, x
+	class := classifyChar(runes[x])
+	for start > 0 && classifyChar(runes[start-1]) == class {
+		start--
+	}
+	for end+1 < len(runes) && classifyChar(runes[end+1]) == class {
+		end++
+	}
+	if around {
+		wordEnd := end
+		for end+1 < len(runes) && unicode.IsSpace(runes[end+1]) {
+			end++
+		}
+		// Without trailing whitespace, take the leading whitespace instead
+		if end == wordEnd {
+			for start > 0 && unicode.IsSpace(runes[start-1]) {
+				start--
+			}
+		}
+	}
+
+	lineStart := e.buffer.CursorIndex() - x
+	e.visualStart = lineStart + start
+	e.buffer.cursorX = end
+}
+
+// lineAt returns the line containing a character index
+func lineAt(lines []string, index int) int {
+	pos := 0
+	for y, line := range lines {
+		pos += len([]rune(line)) + 1 // +1 for newline
+		if index < pos {
+			return y
+		}
+	}
+	return len(lines) - 1
+}